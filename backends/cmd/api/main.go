@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"os"
@@ -42,11 +43,32 @@ func main() {
 		log.Fatal(err)
 	}
 
-	server, err := wire.InitializeRestApi(*config, logger, validator, repo)
+	services, err := wire.InitializeServices(*config, logger, validator, repo)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	server, err := wire.InitializeRestApi(*config, logger, validator, services)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// background jobs live until we are asked to stop
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go services.Users.RunDeletions(ctx, config.Deletion.Interval)
+	go services.Exports.Run(ctx, config.Export.CleanupInterval)
+	go services.Webhooks.Run(ctx, config.Webhooks.Interval)
+	go services.Relay.Run(ctx, config.Outbox.Interval)
+	go services.Reminders.Run(ctx, config.Reminders.Interval)
+	go services.Digests.Run(ctx, config.Digest.Interval)
+	go services.Attachments.Run(ctx, config.Attachments.CleanupInterval)
+	go services.Attachments.RunThumbnails(ctx, config.Attachments.ThumbnailInterval)
+	go services.Todos.RunRebalancing(ctx, config.Ordering.RebalanceInterval)
+	if services.EventsListener != nil {
+		go services.EventsListener.Listen(ctx)
+	}
+
 	go func() {
 		err := server.Run()
 		if err != nil {
@@ -60,6 +82,10 @@ func main() {
 	<-quit
 
 	logger.Info("Gracefully stopping server")
+	cancel()
+	if err := services.Events.Close(); err != nil {
+		logger.Error("Error closing events bus", logging.String("error", err.Error()))
+	}
 	if err := repo.Close(); err != nil {
 		logger.Fatal("Error closing store", logging.String("error", err.Error()))
 	}
@@ -18,7 +18,25 @@ type (
 			Level  string `env:"LOG_LEVEL" env-default:"debug"`
 			Output string `env:"LOG_OUTPUT" env-default:"stdout"`
 		}
-		Database database
+		// TrustedProxies are addresses or CIDRs of proxies whose
+		// X-Forwarded-For and X-Real-IP headers we believe. With none
+		// of them clients are identified only by their own address.
+		TrustedProxies []string `env:"TRUSTED_PROXIES"`
+
+		Database  database
+		Lockout   lockout
+		Limits    rateLimit
+		Export    export
+		Deletion  deletion
+		Events    events
+		Webhooks  webhooks
+		Outbox    outbox
+		Reminders reminders
+		Mail      mail
+		Digest    digest
+
+		Attachments attachments
+		Ordering    ordering
 	}
 	database struct {
 		Host           string `env:"POSTGRES_HOST" env-default:"localhost"`
@@ -28,6 +46,137 @@ type (
 		Name           string `env:"POSTGRES_DB" env-default:"todos"`
 		WithMigrations bool   `env:"WITH_MIGRATIONS" env-default:"false"`
 	}
+	// lockout configures brute-force protection for signing in.
+	// Backend can be "memory" for a single node or "postgres"
+	// if we run more than one instance of the api.
+	lockout struct {
+		Backend     string        `env:"LOCKOUT_BACKEND" env-default:"memory"`
+		MaxAttempts int           `env:"LOCKOUT_MAX_ATTEMPTS" env-default:"5"`
+		BaseDelay   time.Duration `env:"LOCKOUT_BASE_DELAY" env-default:"30s"`
+		MaxDelay    time.Duration `env:"LOCKOUT_MAX_DELAY" env-default:"1h"`
+		Window      time.Duration `env:"LOCKOUT_WINDOW" env-default:"15m"`
+	}
+	// rateLimit configures how many requests a client can make
+	// to a group of routes during a period. Zero requests disables
+	// limiting for that group. Backend works the same way as in lockout.
+	rateLimit struct {
+		Backend string `env:"RATE_LIMIT_BACKEND" env-default:"memory"`
+
+		AuthRequests  int           `env:"RATE_LIMIT_AUTH_REQUESTS" env-default:"20"`
+		AuthPer       time.Duration `env:"RATE_LIMIT_AUTH_PER" env-default:"1m"`
+		UsersRequests int           `env:"RATE_LIMIT_USERS_REQUESTS" env-default:"60"`
+		UsersPer      time.Duration `env:"RATE_LIMIT_USERS_PER" env-default:"1m"`
+		TodosRequests int           `env:"RATE_LIMIT_TODOS_REQUESTS" env-default:"120"`
+		TodosPer      time.Duration `env:"RATE_LIMIT_TODOS_PER" env-default:"1m"`
+	}
+	// export configures where we keep archives with users data
+	// and for how long download links for them are valid.
+	// Expired archives are removed every CleanupInterval.
+	export struct {
+		Dir             string        `env:"EXPORT_DIR" env-default:"/tmp/todo-app/exports"`
+		TTL             time.Duration `env:"EXPORT_TTL" env-default:"24h"`
+		CleanupInterval time.Duration `env:"EXPORT_CLEANUP_INTERVAL" env-default:"10m"`
+	}
+	// events configures delivery of real-time events. Backend "memory"
+	// delivers them only to clients of this instance, with "postgres"
+	// they are broadcasted to every instance with LISTEN/NOTIFY on Channel.
+	events struct {
+		Backend string `env:"EVENTS_BACKEND" env-default:"memory"`
+		Channel string `env:"EVENTS_CHANNEL" env-default:"todo_events"`
+		// ReplayBuffer is how many last events we keep in memory
+		// for clients reconnecting to real-time endpoints
+		ReplayBuffer int `env:"EVENTS_REPLAY_BUFFER" env-default:"1024"`
+	}
+	// webhooks configures delivery of events to urls of our users.
+	// Failed deliveries are retried with exponential backoff, a webhook
+	// is disabled after DisableAfter failed attempts in a row.
+	// AllowPrivate lets webhooks point to private networks, it is
+	// useful only for development.
+	webhooks struct {
+		Interval     time.Duration `env:"WEBHOOKS_INTERVAL" env-default:"5s"`
+		Timeout      time.Duration `env:"WEBHOOKS_TIMEOUT" env-default:"10s"`
+		MaxAttempts  int           `env:"WEBHOOKS_MAX_ATTEMPTS" env-default:"8"`
+		BaseDelay    time.Duration `env:"WEBHOOKS_BASE_DELAY" env-default:"30s"`
+		MaxDelay     time.Duration `env:"WEBHOOKS_MAX_DELAY" env-default:"1h"`
+		DisableAfter int           `env:"WEBHOOKS_DISABLE_AFTER" env-default:"20"`
+		Retention    time.Duration `env:"WEBHOOKS_RETENTION" env-default:"720h"`
+		AllowPrivate bool          `env:"WEBHOOKS_ALLOW_PRIVATE" env-default:"false"`
+	}
+	// outbox configures relaying of domain events to their handlers.
+	// Events whose handlers failed are retried with exponential backoff,
+	// relayed events are deleted after Retention.
+	outbox struct {
+		Interval  time.Duration `env:"OUTBOX_INTERVAL" env-default:"1s"`
+		BatchSize int           `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+		Lease     time.Duration `env:"OUTBOX_LEASE" env-default:"1m"`
+		BaseDelay time.Duration `env:"OUTBOX_BASE_DELAY" env-default:"5s"`
+		MaxDelay  time.Duration `env:"OUTBOX_MAX_DELAY" env-default:"10m"`
+		Retention time.Duration `env:"OUTBOX_RETENTION" env-default:"168h"`
+	}
+	// reminders configures how often we look for reminders that are
+	// due and for todos that became overdue, BatchSize of them at a time
+	reminders struct {
+		Interval  time.Duration `env:"REMINDERS_INTERVAL" env-default:"30s"`
+		BatchSize int           `env:"REMINDERS_BATCH_SIZE" env-default:"100"`
+	}
+	// mail configures how we send emails. Driver "none" sends nothing,
+	// "smtp" sends through an SMTP server and "file" writes .eml files
+	// to Dir, which is handy for development.
+	mail struct {
+		Driver  string        `env:"MAIL_DRIVER" env-default:"none"`
+		From    string        `env:"MAIL_FROM" env-default:"Todo App <no-reply@localhost>"`
+		Host    string        `env:"MAIL_SMTP_HOST" env-default:"localhost"`
+		Port    string        `env:"MAIL_SMTP_PORT" env-default:"587"`
+		User    string        `env:"MAIL_SMTP_USER"`
+		Pass    string        `env:"MAIL_SMTP_PASSWORD"`
+		Timeout time.Duration `env:"MAIL_SMTP_TIMEOUT" env-default:"10s"`
+		Dir     string        `env:"MAIL_DIR" env-default:"/tmp/todo-app/mail"`
+	}
+	// digest configures emails with overdue and upcoming todos. AppURL
+	// is used for links in them. Digests that could not be sent are
+	// retried after Lease.
+	digest struct {
+		AppURL    string        `env:"APP_URL" env-default:"http://localhost:3000"`
+		Interval  time.Duration `env:"DIGEST_INTERVAL" env-default:"1m"`
+		BatchSize int           `env:"DIGEST_BATCH_SIZE" env-default:"50"`
+		Lease     time.Duration `env:"DIGEST_LEASE" env-default:"15m"`
+	}
+	// attachments configures files attached to todos. Store can be
+	// "local" to keep them in Dir or "s3" for AWS and S3 compatible
+	// storages like MinIO. MaxSize limits a single file, Quota limits
+	// all files of a user. Orphaned files of deleted todos are
+	// removed every CleanupInterval. Thumbnails of images are made
+	// every ThumbnailInterval, a worker has ThumbnailLease to make them.
+	attachments struct {
+		Store           string        `env:"ATTACHMENTS_STORE" env-default:"local"`
+		Dir             string        `env:"ATTACHMENTS_DIR" env-default:"/tmp/todo-app/attachments"`
+		MaxSize         int64         `env:"ATTACHMENTS_MAX_SIZE" env-default:"10485760"`
+		Quota           int64         `env:"ATTACHMENTS_QUOTA" env-default:"104857600"`
+		AllowedTypes    []string      `env:"ATTACHMENTS_ALLOWED_TYPES" env-default:"image/png,image/jpeg,image/gif,image/webp,application/pdf,text/plain"`
+		CleanupInterval time.Duration `env:"ATTACHMENTS_CLEANUP_INTERVAL" env-default:"10m"`
+
+		ThumbnailInterval time.Duration `env:"ATTACHMENTS_THUMBNAIL_INTERVAL" env-default:"10s"`
+		ThumbnailLease    time.Duration `env:"ATTACHMENTS_THUMBNAIL_LEASE" env-default:"5m"`
+
+		S3Endpoint  string        `env:"ATTACHMENTS_S3_ENDPOINT" env-default:"http://localhost:9000"`
+		S3Region    string        `env:"ATTACHMENTS_S3_REGION" env-default:"us-east-1"`
+		S3Bucket    string        `env:"ATTACHMENTS_S3_BUCKET" env-default:"attachments"`
+		S3AccessKey string        `env:"ATTACHMENTS_S3_ACCESS_KEY"`
+		S3SecretKey string        `env:"ATTACHMENTS_S3_SECRET_KEY"`
+		S3Timeout   time.Duration `env:"ATTACHMENTS_S3_TIMEOUT" env-default:"1m"`
+	}
+	// ordering configures how often we look for lists where todos
+	// were moved into the same place so many times that there is
+	// almost no room left between them
+	ordering struct {
+		RebalanceInterval time.Duration `env:"ORDERING_REBALANCE_INTERVAL" env-default:"10m"`
+	}
+	// deletion configures how long we wait before actually deleting
+	// an account after user asked us to, and how often we check for that
+	deletion struct {
+		GracePeriod time.Duration `env:"DELETION_GRACE_PERIOD" env-default:"720h"`
+		Interval    time.Duration `env:"DELETION_INTERVAL" env-default:"1h"`
+	}
 )
 
 func LoadConfigs(filename string) (*Config, error) {
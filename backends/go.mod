@@ -6,6 +6,7 @@ require (
 	github.com/go-playground/locales v0.14.0
 	github.com/go-playground/universal-translator v0.18.0
 	github.com/go-playground/validator/v10 v10.11.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/jackc/pgconn v1.12.1
 	github.com/ory/dockertest/v3 v3.9.1
 	go.uber.org/zap v1.21.0
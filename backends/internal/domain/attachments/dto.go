@@ -0,0 +1,14 @@
+package attachments
+
+import "io"
+
+type (
+	UploadInput struct {
+		TodoID   string `validate:"required"`
+		UserID   string `validate:"required"`
+		Filename string `validate:"required,lte=255"`
+		// Size is how many bytes there are in Body
+		Size int64     `validate:"gt=0"`
+		Body io.Reader `validate:"required"`
+	}
+)
@@ -0,0 +1,89 @@
+package attachments
+
+import "time"
+
+type (
+	Attachment struct {
+		ID     string `json:"id"`
+		TodoID string `json:"todoId"`
+		// UserID is who uploaded the file
+		UserID      string    `json:"userId"`
+		Filename    string    `json:"filename"`
+		ContentType string    `json:"contentType"`
+		Size        int64     `json:"size"`
+		CreatedAt   time.Time `json:"createdAt"`
+		// URL is filled by transports
+		URL string `json:"url,omitempty"`
+
+		ThumbnailStatus ThumbnailStatus `json:"thumbnailStatus"`
+		Thumbnails      []Thumbnail     `json:"thumbnails,omitempty"`
+
+		// Key is where the file is kept in a blob store
+		Key string `json:"-"`
+		// Attempts is how many times we tried to make thumbnails
+		Attempts int `json:"-"`
+	}
+
+	// ThumbnailStatus tells if thumbnails of an image are ready.
+	// Files that are not images have no thumbnails.
+	ThumbnailStatus string
+
+	// Thumbnail is a smaller copy of an image without any metadata
+	Thumbnail struct {
+		// Size is a name, see ThumbnailSizes
+		Size        string `json:"size"`
+		Width       int    `json:"width"`
+		Height      int    `json:"height"`
+		ContentType string `json:"contentType"`
+		// URL is filled by transports
+		URL string `json:"url,omitempty"`
+
+		// Key is not saved, it can always be made with thumbnailKey
+		Key string `json:"-"`
+	}
+
+	// Policy limits what users can upload. ContentTypes are
+	// media types like "image/png", we sniff them from contents
+	// of files instead of trusting clients.
+	Policy struct {
+		MaxSize      int64
+		Quota        int64
+		ContentTypes []string
+		// ThumbnailLease is how long a worker has to make thumbnails,
+		// after that somebody else can try again
+		ThumbnailLease time.Duration
+	}
+)
+
+const (
+	ThumbnailNone    ThumbnailStatus = "none"
+	ThumbnailPending ThumbnailStatus = "pending"
+	ThumbnailDone    ThumbnailStatus = "done"
+	ThumbnailFailed  ThumbnailStatus = "failed"
+)
+
+// ThumbnailSizes are names of thumbnails and how many pixels
+// their longest side can take. Images are never upscaled.
+var ThumbnailSizes = []struct {
+	Name string
+	Side int
+}{
+	{Name: "small", Side: 160},
+	{Name: "medium", Side: 640},
+}
+
+// thumbnailKey is where a thumbnail is kept, right next to the original.
+// It doesn't depend on format of the thumbnail so we can delete
+// thumbnails without knowing if they were made.
+func thumbnailKey(key, size string) string {
+	return key + "." + size
+}
+
+// keys are all blobs that belong to an attachment
+func (a Attachment) keys() []string {
+	keys := []string{a.Key}
+	for _, size := range ThumbnailSizes {
+		keys = append(keys, thumbnailKey(a.Key, size.Name))
+	}
+	return keys
+}
@@ -0,0 +1,12 @@
+package attachments
+
+import "errors"
+
+var (
+	ErrNoSuchAttachment = errors.New("attachments: no such attachment")
+	ErrNoSuchThumbnail  = errors.New("attachments: no such thumbnail")
+	ErrNotAllowed       = errors.New("attachments: only uploader or owner of the todo can delete an attachment")
+	ErrTooLarge         = errors.New("attachments: file is too large")
+	ErrQuotaExceeded    = errors.New("attachments: not enough space left")
+	ErrContentType      = errors.New("attachments: this type of files is not allowed")
+)
@@ -0,0 +1,461 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/blobstore"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		// Create should return ErrQuotaExceeded if files of the user
+		// would take more than quota bytes with this one
+		Create(ctx context.Context, a Attachment, quota int64) (id string, err error)
+		// Get should return ErrNoSuchAttachment if there is no attachment with this id
+		Get(ctx context.Context, id string) (Attachment, error)
+		GetAll(ctx context.Context, todoID string) ([]Attachment, error)
+		Delete(ctx context.Context, id string) error
+		// UsedBytes is the size of all files uploaded by the user
+		UsedBytes(ctx context.Context, userID string) (int64, error)
+		// GetOrphaned returns attachments of deleted todos
+		GetOrphaned(ctx context.Context, limit int) ([]Attachment, error)
+
+		// ClaimThumbnails returns pending attachments and hides them from
+		// other workers until lease is over, attempts are incremented
+		ClaimThumbnails(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Attachment, error)
+		// SaveThumbnails should return ErrNoSuchAttachment
+		// if the attachment was deleted meanwhile
+		SaveThumbnails(ctx context.Context, id string, status ThumbnailStatus, thumbnails []Thumbnail) error
+	}
+
+	// Todos tells what a user can do with a todo
+	Todos interface {
+		Authorize(ctx context.Context, userID, id string, need shares.Permission) error
+	}
+
+	Service interface {
+		// Upload needs an editor of the todo
+		Upload(ctx context.Context, inp UploadInput) (Attachment, error)
+		GetAll(ctx context.Context, userID, todoID string) ([]Attachment, error)
+		// Download returns contents of a file, caller has to close them
+		Download(ctx context.Context, userID, todoID, id string) (Attachment, io.ReadCloser, error)
+		// DownloadThumbnail returns ErrNoSuchThumbnail until thumbnails are ready
+		DownloadThumbnail(ctx context.Context, userID, todoID, id, size string) (Thumbnail, io.ReadCloser, error)
+		// Delete is for the uploader and owner of the todo
+		Delete(ctx context.Context, userID, todoID, id string) error
+
+		// Run removes files of deleted todos every interval
+		Run(ctx context.Context, interval time.Duration)
+		// RunThumbnails makes thumbnails of uploaded images every interval
+		// and right after uploads to this instance
+		RunThumbnails(ctx context.Context, interval time.Duration)
+	}
+
+	service struct {
+		repo      Repository
+		todos     Todos
+		store     blobstore.Store
+		log       *logging.Logger
+		validator *validation.Validator
+		policy    Policy
+
+		// uploaded wakes up the thumbnails worker
+		uploaded chan struct{}
+	}
+)
+
+const (
+	// sniffLen is how much http.DetectContentType looks at
+	sniffLen = 512
+	// cleanupBatch is how many orphaned files we delete at a time
+	cleanupBatch = 100
+	// thumbnailsBatch is how many images a worker claims at a time
+	thumbnailsBatch = 10
+	// maxThumbnailAttempts is how many times we try
+	// before giving up on thumbnails of an image
+	maxThumbnailAttempts = 3
+)
+
+func NewService(
+	repo Repository,
+	todos Todos,
+	store blobstore.Store,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	policy Policy,
+) Service {
+	return &service{
+		repo:      repo,
+		todos:     todos,
+		store:     store,
+		log:       logger,
+		validator: validator,
+		policy:    policy,
+		uploaded:  make(chan struct{}, 1),
+	}
+}
+
+func (s *service) Upload(ctx context.Context, inp UploadInput) (Attachment, error) {
+	defer s.log.Sync()
+	s.log.Info("attachments: Upload(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("attachments: Upload(): validation failed", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	if inp.Size > s.policy.MaxSize {
+		return Attachment{}, ErrTooLarge
+	}
+	if err := s.todos.Authorize(ctx, inp.UserID, inp.TodoID, shares.PermissionEditor); err != nil {
+		s.log.Debug("attachments: Upload(): user is not allowed", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	// it is checked again when we save the attachment,
+	// this way we don't upload files that won't fit anyway
+	used, err := s.repo.UsedBytes(ctx, inp.UserID)
+	if err != nil {
+		s.log.Debug("attachments: Upload(): could not get used bytes", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	if used+inp.Size > s.policy.Quota {
+		return Attachment{}, ErrQuotaExceeded
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(inp.Body, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		s.log.Debug("attachments: Upload(): could not read file", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	head = head[:n]
+	contentType, ok := s.allowed(head)
+	if !ok {
+		return Attachment{}, ErrContentType
+	}
+
+	key, err := newKey(inp.TodoID)
+	if err != nil {
+		return Attachment{}, err
+	}
+	a := Attachment{
+		TodoID:      inp.TodoID,
+		UserID:      inp.UserID,
+		Filename:    cleanFilename(inp.Filename),
+		ContentType: contentType,
+		Size:        inp.Size,
+		Key:         key,
+
+		ThumbnailStatus: ThumbnailNone,
+	}
+	if thumbnailTypes[contentType] {
+		a.ThumbnailStatus = ThumbnailPending
+	}
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(head), inp.Body), inp.Size)
+	if err := s.store.Put(ctx, key, body, inp.Size, contentType); err != nil {
+		s.log.Debug("attachments: Upload(): could not store file", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	a.ID, err = s.repo.Create(ctx, a, s.policy.Quota)
+	if err != nil {
+		s.log.Debug("attachments: Upload(): could not create attachment", logging.String("error", err.Error()))
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.log.Error("attachments: Upload(): could not delete file", logging.String("error", err.Error()))
+		}
+		return Attachment{}, err
+	}
+	if a.ThumbnailStatus == ThumbnailPending {
+		select {
+		case s.uploaded <- struct{}{}:
+		default:
+		}
+	}
+	return s.repo.Get(ctx, a.ID)
+}
+
+func (s *service) GetAll(ctx context.Context, userID, todoID string) ([]Attachment, error) {
+	defer s.log.Sync()
+	s.log.Info("attachments: GetAll(): start")
+
+	if err := s.todos.Authorize(ctx, userID, todoID, shares.PermissionViewer); err != nil {
+		s.log.Debug("attachments: GetAll(): user is not allowed", logging.String("error", err.Error()))
+		return nil, err
+	}
+	list, err := s.repo.GetAll(ctx, todoID)
+	if err != nil {
+		s.log.Debug("attachments: GetAll(): could not get attachments", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Download(ctx context.Context, userID, todoID, id string) (Attachment, io.ReadCloser, error) {
+	defer s.log.Sync()
+	s.log.Info("attachments: Download(): start")
+
+	a, err := s.get(ctx, userID, todoID, id, shares.PermissionViewer)
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	body, err := s.store.Get(ctx, a.Key)
+	if err == blobstore.ErrNotFound {
+		s.log.Error("attachments: Download(): file is missing", logging.String("key", a.Key))
+		return Attachment{}, nil, ErrNoSuchAttachment
+	}
+	if err != nil {
+		s.log.Debug("attachments: Download(): could not get file", logging.String("error", err.Error()))
+		return Attachment{}, nil, err
+	}
+	return a, body, nil
+}
+
+func (s *service) DownloadThumbnail(ctx context.Context, userID, todoID, id, size string) (Thumbnail, io.ReadCloser, error) {
+	defer s.log.Sync()
+	s.log.Info("attachments: DownloadThumbnail(): start")
+
+	a, err := s.get(ctx, userID, todoID, id, shares.PermissionViewer)
+	if err != nil {
+		return Thumbnail{}, nil, err
+	}
+	for _, t := range a.Thumbnails {
+		if t.Size != size {
+			continue
+		}
+		t.Key = thumbnailKey(a.Key, t.Size)
+		body, err := s.store.Get(ctx, t.Key)
+		if err == blobstore.ErrNotFound {
+			s.log.Error("attachments: DownloadThumbnail(): file is missing", logging.String("key", t.Key))
+			return Thumbnail{}, nil, ErrNoSuchThumbnail
+		}
+		if err != nil {
+			s.log.Debug("attachments: DownloadThumbnail(): could not get file", logging.String("error", err.Error()))
+			return Thumbnail{}, nil, err
+		}
+		return t, body, nil
+	}
+	return Thumbnail{}, nil, ErrNoSuchThumbnail
+}
+
+func (s *service) Delete(ctx context.Context, userID, todoID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("attachments: Delete(): start")
+
+	a, err := s.get(ctx, userID, todoID, id, shares.PermissionEditor)
+	if err != nil {
+		return err
+	}
+	if a.UserID != userID {
+		if err := s.todos.Authorize(ctx, userID, todoID, shares.PermissionOwner); err != nil {
+			return ErrNotAllowed
+		}
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("attachments: Delete(): could not delete attachment", logging.String("error", err.Error()))
+		return err
+	}
+	// if this fails files just stay in the store, users won't see them anyway
+	if err := s.deleteFiles(ctx, a); err != nil {
+		s.log.Error("attachments: Delete(): could not delete files", logging.String("error", err.Error()))
+	}
+	return nil
+}
+
+func (s *service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.cleanup(ctx)
+		}
+	}
+}
+
+// cleanup deletes files and then attachments of deleted todos,
+// so if we crash in between we will try again next time
+func (s *service) cleanup(ctx context.Context) {
+	defer s.log.Sync()
+
+	list, err := s.repo.GetOrphaned(ctx, cleanupBatch)
+	if err != nil {
+		s.log.Error("attachments: cleanup(): could not get orphaned attachments", logging.String("error", err.Error()))
+		return
+	}
+	for _, a := range list {
+		if err := s.deleteFiles(ctx, a); err != nil {
+			s.log.Error("attachments: cleanup(): could not delete files", logging.String("error", err.Error()))
+			continue
+		}
+		if err := s.repo.Delete(ctx, a.ID); err != nil {
+			s.log.Error("attachments: cleanup(): could not delete attachment", logging.String("error", err.Error()))
+		}
+	}
+}
+
+func (s *service) RunThumbnails(ctx context.Context, interval time.Duration) {
+	defer s.log.Sync()
+	s.log.Info("attachments: RunThumbnails(): start")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("attachments: RunThumbnails(): stopped")
+			return
+		case <-ticker.C:
+		case <-s.uploaded:
+		}
+		s.makeThumbnails(ctx)
+	}
+}
+
+func (s *service) makeThumbnails(ctx context.Context) {
+	for ctx.Err() == nil {
+		list, err := s.repo.ClaimThumbnails(ctx, time.Now().UTC(), s.policy.ThumbnailLease, thumbnailsBatch)
+		if err != nil {
+			s.log.Error("attachments: makeThumbnails(): could not claim attachments", logging.String("error", err.Error()))
+			return
+		}
+		for _, a := range list {
+			s.makeThumbnailsOf(ctx, a)
+		}
+		if len(list) < thumbnailsBatch {
+			return
+		}
+	}
+}
+
+// makeThumbnailsOf gives up right away on files that are not images,
+// other errors are retried after lease until we run out of attempts
+func (s *service) makeThumbnailsOf(ctx context.Context, a Attachment) {
+	thumbnails, err := s.thumbnails(ctx, a)
+	status := ThumbnailDone
+	if err != nil {
+		s.log.Error(
+			"attachments: makeThumbnailsOf(): could not make thumbnails",
+			logging.String("attachmentID", a.ID),
+			logging.String("error", err.Error()),
+		)
+		if err != errNotImage && a.Attempts < maxThumbnailAttempts {
+			return
+		}
+		status = ThumbnailFailed
+	}
+	err = s.repo.SaveThumbnails(ctx, a.ID, status, thumbnails)
+	if err == ErrNoSuchAttachment {
+		// it was deleted while we were busy, nobody else will delete our files
+		err = s.deleteFiles(ctx, a)
+	}
+	if err != nil {
+		s.log.Error(
+			"attachments: makeThumbnailsOf(): could not save thumbnails",
+			logging.String("attachmentID", a.ID),
+			logging.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *service) thumbnails(ctx context.Context, a Attachment) ([]Thumbnail, error) {
+	body, err := s.store.Get(ctx, a.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, a.Size))
+	if err != nil {
+		return nil, err
+	}
+	files, err := makeThumbnails(data, a.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	thumbnails := make([]Thumbnail, 0, len(files))
+	for _, f := range files {
+		f.Key = thumbnailKey(a.Key, f.Size)
+		if err := s.store.Put(ctx, f.Key, bytes.NewReader(f.Data), int64(len(f.Data)), f.ContentType); err != nil {
+			return nil, err
+		}
+		thumbnails = append(thumbnails, f.Thumbnail)
+	}
+	return thumbnails, nil
+}
+
+// deleteFiles deletes the file and its thumbnails, if they were made
+func (s *service) deleteFiles(ctx context.Context, a Attachment) error {
+	for _, key := range a.keys() {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get returns an attachment only if it belongs to the todo
+// and the user has access to that todo
+func (s *service) get(ctx context.Context, userID, todoID, id string, need shares.Permission) (Attachment, error) {
+	if err := s.todos.Authorize(ctx, userID, todoID, need); err != nil {
+		s.log.Debug("attachments: get(): user is not allowed", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	a, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("attachments: get(): could not get attachment", logging.String("error", err.Error()))
+		return Attachment{}, err
+	}
+	if a.TodoID != todoID {
+		return Attachment{}, ErrNoSuchAttachment
+	}
+	return a, nil
+}
+
+// allowed sniffs a content type from first bytes of a file
+func (s *service) allowed(head []byte) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "", false
+	}
+	for _, t := range s.policy.ContentTypes {
+		if strings.EqualFold(strings.TrimSpace(t), mediaType) {
+			return mediaType, true
+		}
+	}
+	return "", false
+}
+
+func newKey(todoID string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "attachments/" + todoID + "/" + hex.EncodeToString(b), nil
+}
+
+// cleanFilename drops directories that some browsers send
+// and characters that would break Content-Disposition
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
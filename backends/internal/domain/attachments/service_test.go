@@ -0,0 +1,128 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/blobstore"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	attachments map[string]Attachment
+}
+
+func (f *fakeRepository) Create(ctx context.Context, a Attachment, quota int64) (string, error) {
+	a.ID = a.Key
+	f.attachments[a.ID] = a
+	return a.ID, nil
+}
+
+func (f *fakeRepository) Get(ctx context.Context, id string) (Attachment, error) {
+	a, ok := f.attachments[id]
+	if !ok {
+		return a, ErrNoSuchAttachment
+	}
+	return a, nil
+}
+
+func (f *fakeRepository) Delete(ctx context.Context, id string) error {
+	delete(f.attachments, id)
+	return nil
+}
+
+func (f *fakeRepository) UsedBytes(ctx context.Context, userID string) (int64, error) {
+	var used int64
+	for _, a := range f.attachments {
+		if a.UserID == userID {
+			used += a.Size
+		}
+	}
+	return used, nil
+}
+
+type fakeTodos map[string]shares.Permission
+
+func (f fakeTodos) Authorize(ctx context.Context, userID, id string, need shares.Permission) error {
+	if !f[userID].Allows(shares.PermissionViewer) {
+		return todos.ErrNoSuchTodo
+	}
+	if !f[userID].Allows(need) {
+		return todos.ErrNotAllowed
+	}
+	return nil
+}
+
+func TestUpload(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{attachments: map[string]Attachment{}}
+	access := fakeTodos{
+		"owner":  shares.PermissionOwner,
+		"editor": shares.PermissionEditor,
+		"viewer": shares.PermissionViewer,
+	}
+	s := NewService(repo, access, blobstore.NewLocal(t.TempDir()), logger, validation.NewValidator(), Policy{
+		MaxSize:      64,
+		Quota:        100,
+		ContentTypes: []string{"text/plain", "image/png"},
+	})
+	ctx := context.Background()
+	upload := func(userID, body string) (Attachment, error) {
+		return s.Upload(ctx, UploadInput{
+			TodoID:   "1",
+			UserID:   userID,
+			Filename: `C:\Users\me\notes.txt`,
+			Size:     int64(len(body)),
+			Body:     strings.NewReader(body),
+		})
+	}
+
+	if _, err := upload("viewer", "hello"); !errors.Is(err, todos.ErrNotAllowed) {
+		t.Fatalf("viewers should not upload files, got %v", err)
+	}
+	if _, err := upload("editor", "%PDF-1.4 pretending to be a text"); !errors.Is(err, ErrContentType) {
+		t.Fatalf("expected ErrContentType, got %v", err)
+	}
+	if _, err := upload("editor", strings.Repeat("a", 65)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+
+	a, err := upload("editor", strings.Repeat("a", 60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ContentType != "text/plain" || a.Filename != "notes.txt" {
+		t.Fatalf("unexpected attachment %+v", a)
+	}
+	if _, err := upload("editor", strings.Repeat("b", 60)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	_, body, err := s.Download(ctx, "viewer", "1", a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, []byte(strings.Repeat("a", 60))) {
+		t.Fatalf("downloaded wrong contents %q", got)
+	}
+
+	if err := s.Delete(ctx, "viewer", "1", a.ID); !errors.Is(err, todos.ErrNotAllowed) {
+		t.Fatalf("viewers should not delete files, got %v", err)
+	}
+	if err := s.Delete(ctx, "owner", "1", a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Download(ctx, "owner", "1", a.ID); !errors.Is(err, ErrNoSuchAttachment) {
+		t.Fatalf("expected ErrNoSuchAttachment, got %v", err)
+	}
+}
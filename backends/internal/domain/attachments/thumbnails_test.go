@@ -0,0 +1,87 @@
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withExif puts an APP1 segment with orientation and GPS-like junk
+// right after SOI of a JPEG file
+func withExif(t *testing.T, data []byte, orientation byte) []byte {
+	t.Helper()
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header, IFD right after it
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, // orientation, SHORT
+		0, 0, 0, 0, // no next IFD
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	segment = append(segment, []byte("secret location")...)
+	length := len(segment) + 2
+	app1 := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, segment...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func TestMakeThumbnails(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for y := 0; y < 400; y++ {
+		for x := 0; x < 800; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	files, err := makeThumbnails(buf.Bytes(), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(ThumbnailSizes) {
+		t.Fatalf("expected %d thumbnails, got %d", len(ThumbnailSizes), len(files))
+	}
+	small := files[0]
+	if small.ContentType != "image/png" || small.Width != 160 || small.Height != 80 {
+		t.Fatalf("unexpected small thumbnail %+v", small.Thumbnail)
+	}
+
+	buf.Reset()
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	photo := withExif(t, buf.Bytes(), 6)
+	if o := jpegOrientation(photo); o != 6 {
+		t.Fatalf("expected orientation 6, got %d", o)
+	}
+	files, err = makeThumbnails(photo, "image/jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	medium := files[1]
+	// rotated by 90 degrees
+	if medium.ContentType != "image/jpeg" || medium.Width != 320 || medium.Height != 640 {
+		t.Fatalf("unexpected medium thumbnail %+v", medium.Thumbnail)
+	}
+	if bytes.Contains(medium.Data, []byte("Exif")) || bytes.Contains(medium.Data, []byte("secret location")) {
+		t.Fatal("thumbnail still has EXIF")
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(medium.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := decoded.Bounds(); b.Dx() != 320 || b.Dy() != 640 {
+		t.Fatalf("thumbnail decodes into %v", b)
+	}
+
+	if _, err := makeThumbnails([]byte("not an image"), "image/png"); err != errNotImage {
+		t.Fatalf("expected errNotImage, got %v", err)
+	}
+}
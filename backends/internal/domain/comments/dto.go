@@ -0,0 +1,21 @@
+package comments
+
+type (
+	CreateInput struct {
+		TodoID   string `validate:"required"`
+		AuthorID string `validate:"required"`
+		Body     string `validate:"required,lt=5000"`
+	}
+
+	UpdateInput struct {
+		ID   string `validate:"required"`
+		Body string `validate:"required,lt=5000"`
+	}
+
+	// GetAllInput returns comments of a todo, oldest first
+	GetAllInput struct {
+		TodoID   string `validate:"required"`
+		PageSize int    `validate:"gte=0,lte=100"`
+		Page     int    `validate:"gte=0"`
+	}
+)
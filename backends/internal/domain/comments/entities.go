@@ -0,0 +1,24 @@
+package comments
+
+import (
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+)
+
+type (
+	Comment struct {
+		ID     string `json:"id"`
+		TodoID string `json:"todoId"`
+		// Author has only id and username
+		Author *users.User `json:"author,omitempty"`
+
+		// Body is Markdown, clients render it
+		Body string `json:"body"`
+		// Mentions are usernames mentioned in the body
+		Mentions []string `json:"mentions,omitempty"`
+
+		CreatedAt time.Time  `json:"createdAt"`
+		EditedAt  *time.Time `json:"editedAt,omitempty"`
+	}
+)
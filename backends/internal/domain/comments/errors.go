@@ -0,0 +1,8 @@
+package comments
+
+import "errors"
+
+var (
+	ErrNoSuchComment = errors.New("comments: no such comment")
+	ErrNotAllowed    = errors.New("comments: only author can change a comment")
+)
@@ -0,0 +1,74 @@
+package comments
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxMentions stops a single comment from notifying half of the users
+const maxMentions = 20
+
+// ParseMentions returns unique usernames mentioned as @username in a
+// Markdown body. Mentions in code spans and code blocks are ignored and
+// so are emails, @ has to start a word.
+func ParseMentions(body string) []string {
+	var (
+		mentions []string
+		seen     = map[string]bool{}
+		fenced   bool
+	)
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		for _, name := range lineMentions(line) {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			mentions = append(mentions, name)
+			if len(mentions) == maxMentions {
+				return mentions
+			}
+		}
+	}
+	return mentions
+}
+
+func lineMentions(line string) []string {
+	var (
+		names []string
+		code  bool
+		prev  rune = ' '
+		runes      = []rune(line)
+	)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '`':
+			code = !code
+		case r == '@' && !code && !isNameRune(prev) && prev != '@':
+			j := i + 1
+			for j < len(runes) && isNameRune(runes[j]) {
+				j++
+			}
+			// names don't end with dots, those are ends of sentences
+			name := strings.TrimRight(string(runes[i+1:j]), ".")
+			if name != "" {
+				names = append(names, name)
+			}
+			i = j - 1
+			r = runes[i]
+		}
+		prev = r
+	}
+	return names
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
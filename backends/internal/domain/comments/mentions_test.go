@@ -0,0 +1,26 @@
+package comments
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"hey @emirlan, take a look", []string{"emirlan"}},
+		{"@alice and @bob_1. Also @alice again", []string{"alice", "bob_1"}},
+		{"mail me at me@example.com", nil},
+		{"not in `@code` spans", nil},
+		{"```\n@nobody here\n```\nbut @somebody here", []string{"somebody"}},
+		{"(@paren) works", []string{"paren"}},
+		{"@@double", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseMentions(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMentions(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package comments
+
+import (
+	"context"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, inp CreateInput) (id string, err error)
+		// Get should return ErrNoSuchComment if there is no comment with this id
+		Get(ctx context.Context, id string) (Comment, error)
+		GetAll(ctx context.Context, inp GetAllInput) ([]Comment, error)
+		Update(ctx context.Context, inp UpdateInput) error
+		Delete(ctx context.Context, id string) error
+		// GetUsers returns users with these usernames,
+		// usernames are not unique so there can be more of them
+		GetUsers(ctx context.Context, usernames []string) ([]users.User, error)
+	}
+
+	// Todos tells if a user can see a todo, it should return
+	// todos.ErrNoSuchTodo if he can't
+	Todos interface {
+		Get(ctx context.Context, userID, id string) (todos.Todo, error)
+	}
+
+	// Inbox keeps in-app notifications of users
+	Inbox interface {
+		Notify(ctx context.Context, n notifications.Notification) error
+	}
+
+	Service interface {
+		// Create needs access to the todo, viewers can comment too
+		Create(ctx context.Context, inp CreateInput) (Comment, error)
+		GetAll(ctx context.Context, userID string, inp GetAllInput) ([]Comment, error)
+		// Update and Delete are only for authors of comments
+		Update(ctx context.Context, userID, todoID string, inp UpdateInput) (Comment, error)
+		Delete(ctx context.Context, userID, todoID, id string) error
+	}
+
+	service struct {
+		repo      Repository
+		todos     Todos
+		inbox     Inbox
+		log       *logging.Logger
+		validator *validation.Validator
+	}
+)
+
+const (
+	defaultPageSize = 20
+	// previewLength is how much of a comment goes to notifications
+	previewLength = 200
+)
+
+func NewService(
+	repo Repository,
+	todos Todos,
+	inbox Inbox,
+	logger *logging.Logger,
+	validator *validation.Validator,
+) Service {
+	return &service{
+		repo:      repo,
+		todos:     todos,
+		inbox:     inbox,
+		log:       logger,
+		validator: validator,
+	}
+}
+
+func (s *service) Create(ctx context.Context, inp CreateInput) (Comment, error) {
+	defer s.log.Sync()
+	s.log.Info("comments: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("comments: Create(): validation failed", logging.String("error", err.Error()))
+		return Comment{}, err
+	}
+	todo, err := s.todos.Get(ctx, inp.AuthorID, inp.TodoID)
+	if err != nil {
+		s.log.Debug("comments: Create(): could not get todo", logging.String("error", err.Error()))
+		return Comment{}, err
+	}
+
+	id, err := s.repo.Create(ctx, inp)
+	if err != nil {
+		s.log.Debug("comments: Create(): could not create comment", logging.String("error", err.Error()))
+		return Comment{}, err
+	}
+	c, err := s.get(ctx, id)
+	if err != nil {
+		return Comment{}, err
+	}
+	s.notifyMentioned(ctx, c, todo)
+	return c, nil
+}
+
+func (s *service) GetAll(ctx context.Context, userID string, inp GetAllInput) ([]Comment, error) {
+	defer s.log.Sync()
+	s.log.Info("comments: GetAll(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("comments: GetAll(): validation failed", logging.String("error", err.Error()))
+		return nil, err
+	}
+	if _, err := s.todos.Get(ctx, userID, inp.TodoID); err != nil {
+		s.log.Debug("comments: GetAll(): could not get todo", logging.String("error", err.Error()))
+		return nil, err
+	}
+	if inp.PageSize == 0 {
+		inp.PageSize = defaultPageSize
+	}
+
+	list, err := s.repo.GetAll(ctx, inp)
+	if err != nil {
+		s.log.Debug("comments: GetAll(): could not get comments", logging.String("error", err.Error()))
+		return nil, err
+	}
+	for i := range list {
+		list[i].Mentions = ParseMentions(list[i].Body)
+	}
+	return list, nil
+}
+
+func (s *service) Update(ctx context.Context, userID, todoID string, inp UpdateInput) (Comment, error) {
+	defer s.log.Sync()
+	s.log.Info("comments: Update(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("comments: Update(): validation failed", logging.String("error", err.Error()))
+		return Comment{}, err
+	}
+	c, todo, err := s.own(ctx, userID, todoID, inp.ID)
+	if err != nil {
+		return Comment{}, err
+	}
+	if err := s.repo.Update(ctx, inp); err != nil {
+		s.log.Debug("comments: Update(): could not update comment", logging.String("error", err.Error()))
+		return Comment{}, err
+	}
+	if c, err = s.get(ctx, c.ID); err != nil {
+		return Comment{}, err
+	}
+	// people who were mentioned before are not notified twice
+	s.notifyMentioned(ctx, c, todo)
+	return c, nil
+}
+
+func (s *service) Delete(ctx context.Context, userID, todoID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("comments: Delete(): start")
+
+	if _, _, err := s.own(ctx, userID, todoID, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("comments: Delete(): could not delete comment", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *service) get(ctx context.Context, id string) (Comment, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("comments: get(): could not get comment", logging.String("error", err.Error()))
+		return Comment{}, err
+	}
+	c.Mentions = ParseMentions(c.Body)
+	return c, nil
+}
+
+// own returns a comment of a todo only if user wrote it
+// and still has access to the todo
+func (s *service) own(ctx context.Context, userID, todoID, id string) (Comment, todos.Todo, error) {
+	todo, err := s.todos.Get(ctx, userID, todoID)
+	if err != nil {
+		s.log.Debug("comments: own(): could not get todo", logging.String("error", err.Error()))
+		return Comment{}, todos.Todo{}, err
+	}
+	c, err := s.get(ctx, id)
+	if err != nil {
+		return Comment{}, todos.Todo{}, err
+	}
+	if c.TodoID != todo.ID {
+		return Comment{}, todos.Todo{}, ErrNoSuchComment
+	}
+	if c.Author == nil || c.Author.ID != userID {
+		return Comment{}, todos.Todo{}, ErrNotAllowed
+	}
+	return c, todo, nil
+}
+
+// notifyMentioned never fails, comment itself is already saved.
+// Only users that can see the todo are notified.
+func (s *service) notifyMentioned(ctx context.Context, c Comment, todo todos.Todo) {
+	if s.inbox == nil || len(c.Mentions) == 0 {
+		return
+	}
+	mentioned, err := s.repo.GetUsers(ctx, c.Mentions)
+	if err != nil {
+		s.log.Error("comments: notifyMentioned(): could not get users", logging.String("error", err.Error()))
+		return
+	}
+
+	author := ""
+	if c.Author != nil {
+		author = c.Author.Username
+	}
+	preview := []rune(c.Body)
+	if len(preview) > previewLength {
+		preview = append(preview[:previewLength], '…')
+	}
+	for _, u := range mentioned {
+		if c.Author != nil && u.ID == c.Author.ID {
+			continue
+		}
+		if _, err := s.todos.Get(ctx, u.ID, todo.ID); err != nil {
+			continue
+		}
+		err := s.inbox.Notify(ctx, notifications.Notification{
+			UserID: u.ID,
+			Type:   notifications.TypeMention,
+			Title:  author + " mentioned you in " + todo.Title,
+			Body:   string(preview),
+			TodoID: todo.ID,
+			Key:    "mention:" + c.ID,
+		})
+		if err != nil {
+			s.log.Error(
+				"comments: notifyMentioned(): could not notify user",
+				logging.String("userID", u.ID),
+				logging.String("error", err.Error()),
+			)
+		}
+	}
+}
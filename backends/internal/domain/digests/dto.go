@@ -0,0 +1,13 @@
+package digests
+
+import "time"
+
+type (
+	UpdateInput struct {
+		UserID    string       `validate:"required"`
+		Frequency Frequency    `validate:"required,oneof=off daily weekly"`
+		Timezone  string       `validate:"required,lt=100"`
+		Hour      int          `validate:"gte=0,lte=23"`
+		Weekday   time.Weekday `validate:"gte=0,lte=6"`
+	}
+)
@@ -0,0 +1,112 @@
+package digests
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/mailer"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+var (
+	htmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templatesFS, "templates/digest.html"))
+	textTemplate = template.Must(template.ParseFS(templatesFS, "templates/digest.txt"))
+)
+
+type (
+	emailData struct {
+		Subject     string
+		Username    string
+		Frequency   Frequency
+		Date        string
+		Sections    []emailSection
+		SettingsURL string
+	}
+
+	emailSection struct {
+		Title string
+		Todos []emailTodo
+	}
+
+	emailTodo struct {
+		Title    string
+		Deadline string
+		URL      string
+	}
+)
+
+// compose renders a digest in the timezone of a user
+func compose(appURL string, due Due, d Digest, now time.Time, loc *time.Location) (mailer.Message, error) {
+	appURL = strings.TrimRight(appURL, "/")
+	section := func(title string, list []todos.Todo) emailSection {
+		s := emailSection{Title: title}
+		for _, t := range list {
+			s.Todos = append(s.Todos, emailTodo{
+				Title:    t.Title,
+				Deadline: t.Deadline.In(loc).Format("Mon, Jan 2 15:04"),
+				URL:      appURL + "/todos/" + t.ID,
+			})
+		}
+		return s
+	}
+
+	data := emailData{
+		Subject:   subject(due.Settings.Frequency, d),
+		Username:  due.Username,
+		Frequency: due.Settings.Frequency,
+		Date:      now.In(loc).Format("Monday, January 2"),
+		Sections: []emailSection{
+			section("Overdue", d.Overdue),
+			section("Due today", d.Today),
+			section("Due this week", d.ThisWeek),
+		},
+		SettingsURL: appURL + "/settings/notifications",
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return mailer.Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return mailer.Message{}, err
+	}
+	return mailer.Message{
+		To:      []string{due.Email},
+		Subject: data.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func subject(f Frequency, d Digest) string {
+	var parts []string
+	if n := len(d.Overdue); n > 0 {
+		parts = append(parts, plural(n, "overdue todo", "overdue todos"))
+	}
+	if n := len(d.Today); n > 0 {
+		parts = append(parts, plural(n, "todo due today", "todos due today"))
+	}
+	if n := len(d.ThisWeek); n > 0 {
+		parts = append(parts, plural(n, "todo due this week", "todos due this week"))
+	}
+	title := "Your daily digest"
+	if f == FrequencyWeekly {
+		title = "Your weekly digest"
+	}
+	return title + ": " + strings.Join(parts, ", ")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return strconv.Itoa(n) + " " + many
+}
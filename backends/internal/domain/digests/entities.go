@@ -0,0 +1,107 @@
+package digests
+
+import (
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+const (
+	FrequencyOff    Frequency = "off"
+	FrequencyDaily  Frequency = "daily"
+	FrequencyWeekly Frequency = "weekly"
+)
+
+type (
+	Frequency string
+
+	// Settings of a digest of a user. Digests are sent at Hour in the
+	// timezone of a user, weekly ones only on Weekday. Users have to
+	// opt in, Frequency is off by default.
+	Settings struct {
+		UserID    string       `json:"-"`
+		Frequency Frequency    `json:"frequency"`
+		Timezone  string       `json:"timezone"`
+		Hour      int          `json:"hour"`
+		Weekday   time.Weekday `json:"weekday"`
+
+		NextSendAt *time.Time `json:"nextSendAt,omitempty"`
+		LastSentAt *time.Time `json:"lastSentAt,omitempty"`
+	}
+
+	// Due is a settings of a digest that has to be sent now
+	// with the user it belongs to
+	Due struct {
+		Settings Settings
+		Email    string
+		Username string
+	}
+
+	// Digest is what we put into an email
+	Digest struct {
+		Overdue  []todos.Todo
+		Today    []todos.Todo
+		ThisWeek []todos.Todo
+	}
+)
+
+// DefaultSettings are used for users who never changed them
+func DefaultSettings(userID string) Settings {
+	return Settings{
+		UserID:    userID,
+		Frequency: FrequencyOff,
+		Timezone:  "UTC",
+		Hour:      8,
+		Weekday:   time.Monday,
+	}
+}
+
+// Next returns the first time after a moment when the digest should be
+// sent. It returns zero time if digest is off or timezone is unknown.
+func (s Settings) Next(after time.Time) time.Time {
+	if s.Frequency != FrequencyDaily && s.Frequency != FrequencyWeekly {
+		return time.Time{}
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}
+	}
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, 0, 0, 0, loc)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, s.Hour, 0, 0, 0, loc)
+	}
+	if s.Frequency == FrequencyWeekly {
+		for next.Weekday() != s.Weekday {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, s.Hour, 0, 0, 0, loc)
+		}
+	}
+	return next.UTC()
+}
+
+// Empty digests are not sent
+func (d Digest) Empty() bool {
+	return len(d.Overdue) == 0 && len(d.Today) == 0 && len(d.ThisWeek) == 0
+}
+
+// split puts todos into sections of a digest. Today and this week
+// end at midnight in loc, this week includes the next 6 days.
+func split(list []todos.Todo, now time.Time, loc *time.Location) Digest {
+	local := now.In(loc)
+	endOfToday := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, loc)
+	endOfWeek := time.Date(local.Year(), local.Month(), local.Day()+7, 0, 0, 0, 0, loc)
+
+	var d Digest
+	for _, t := range list {
+		switch {
+		case t.Completed || t.Deadline.IsZero():
+		case t.Deadline.Before(now):
+			d.Overdue = append(d.Overdue, t)
+		case t.Deadline.Before(endOfToday):
+			d.Today = append(d.Today, t)
+		case t.Deadline.Before(endOfWeek):
+			d.ThisWeek = append(d.ThisWeek, t)
+		}
+	}
+	return d
+}
@@ -0,0 +1,80 @@
+package digests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+func TestSettingsNext(t *testing.T) {
+	// 2022-10-25 is Tuesday
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name     string
+		settings Settings
+		after    string
+		want     string
+	}{
+		{"off", Settings{Frequency: FrequencyOff, Timezone: "UTC"}, "2022-10-25T10:00:00Z", ""},
+		{"unknown timezone", Settings{Frequency: FrequencyDaily, Timezone: "Mars/Olympus"}, "2022-10-25T10:00:00Z", ""},
+		{"later today", Settings{Frequency: FrequencyDaily, Timezone: "UTC", Hour: 18}, "2022-10-25T10:00:00Z", "2022-10-25T18:00:00Z"},
+		{"tomorrow", Settings{Frequency: FrequencyDaily, Timezone: "UTC", Hour: 8}, "2022-10-25T08:00:00Z", "2022-10-26T08:00:00Z"},
+		{"timezone", Settings{Frequency: FrequencyDaily, Timezone: "Asia/Bishkek", Hour: 8}, "2022-10-25T10:00:00Z", "2022-10-26T02:00:00Z"},
+		{"weekly", Settings{Frequency: FrequencyWeekly, Timezone: "UTC", Hour: 8, Weekday: time.Monday}, "2022-10-25T10:00:00Z", "2022-10-31T08:00:00Z"},
+		{"weekly same day", Settings{Frequency: FrequencyWeekly, Timezone: "UTC", Hour: 12, Weekday: time.Tuesday}, "2022-10-25T10:00:00Z", "2022-10-25T12:00:00Z"},
+		// clocks go back on 2022-10-30 in Berlin
+		{"daylight saving", Settings{Frequency: FrequencyDaily, Timezone: "Europe/Berlin", Hour: 8}, "2022-10-29T10:00:00Z", "2022-10-30T07:00:00Z"},
+	}
+	for _, tt := range tests {
+		got := tt.settings.Next(at(tt.after))
+		want := time.Time{}
+		if tt.want != "" {
+			want = at(tt.want)
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: Next() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+func TestSplitAndCompose(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Bishkek") // UTC+6
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2022, 10, 25, 10, 0, 0, 0, time.UTC) // 16:00 local
+	list := []todos.Todo{
+		{ID: "1", Title: "overdue", Deadline: now.Add(-time.Hour)},
+		{ID: "2", Title: "today", Deadline: now.Add(7 * time.Hour)},
+		{ID: "3", Title: "tomorrow <b>", Deadline: now.Add(9 * time.Hour)},
+		{ID: "4", Title: "done", Deadline: now.Add(-time.Hour), Completed: true},
+		{ID: "5", Title: "far", Deadline: now.AddDate(0, 0, 8)},
+	}
+	d := split(list, now, loc)
+	if len(d.Overdue) != 1 || len(d.Today) != 1 || len(d.ThisWeek) != 1 {
+		t.Fatalf("split() = %d overdue, %d today, %d this week, want 1 of each", len(d.Overdue), len(d.Today), len(d.ThisWeek))
+	}
+
+	due := Due{Settings: Settings{Frequency: FrequencyDaily}, Email: "a@example.com", Username: "alice"}
+	msg, err := compose("https://todo.example.com/", due, d, now, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Subject, "1 overdue todo") {
+		t.Errorf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "https://todo.example.com/todos/2") {
+		t.Errorf("text has no link to a todo:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "tomorrow &lt;b&gt;") {
+		t.Errorf("html is not escaped:\n%s", msg.HTML)
+	}
+}
@@ -0,0 +1,8 @@
+package digests
+
+import "errors"
+
+var (
+	ErrInvalidTimezone = errors.New("digests: unknown timezone")
+	ErrNoMailer        = errors.New("digests: sending emails is turned off")
+)
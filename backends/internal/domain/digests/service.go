@@ -0,0 +1,213 @@
+package digests
+
+import (
+	"context"
+	"time"
+
+	// timezones of users should work even if the host has no tzdata
+	_ "time/tzdata"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/mailer"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		// GetSettings should return DefaultSettings if user never saved them
+		GetSettings(ctx context.Context, userID string) (Settings, error)
+		SaveSettings(ctx context.Context, s Settings) error
+
+		// ClaimDue returns up to limit digests that are due at now and
+		// postpones them by lease, so no one else sends them at the same time
+		ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Due, error)
+		// Reschedule sets when the digest is sent next time, zero time means
+		// never. sentAt is nil if nothing was sent because digest was empty.
+		Reschedule(ctx context.Context, userID string, next time.Time, sentAt *time.Time) error
+		// GetTodos returns not completed todos of a user
+		// with a deadline before a moment
+		GetTodos(ctx context.Context, userID string, before time.Time) ([]todos.Todo, error)
+	}
+
+	Service interface {
+		GetSettings(ctx context.Context, userID string) (Settings, error)
+		UpdateSettings(ctx context.Context, inp UpdateInput) (Settings, error)
+		// Location loads a timezone by its IANA name, or the timezone
+		// of the settings of a user if name is empty. It returns
+		// ErrInvalidTimezone for unknown names.
+		Location(ctx context.Context, userID, name string) (*time.Location, error)
+
+		// Run sends due digests until ctx is canceled
+		Run(ctx context.Context, interval time.Duration)
+	}
+
+	// Policy describes how digests are sent. A digest that could not be
+	// sent is retried after Lease, BatchSize digests are claimed at a time.
+	Policy struct {
+		AppURL    string
+		BatchSize int
+		Lease     time.Duration
+	}
+
+	service struct {
+		repo      Repository
+		mailer    mailer.Mailer
+		log       *logging.Logger
+		validator *validation.Validator
+		policy    Policy
+	}
+)
+
+// mailer can be nil if sending emails is turned off,
+// users can still change their settings then
+func NewService(
+	repo Repository,
+	m mailer.Mailer,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	policy Policy,
+) Service {
+	return &service{
+		repo:      repo,
+		mailer:    m,
+		log:       logger,
+		validator: validator,
+		policy:    policy,
+	}
+}
+
+func (s *service) GetSettings(ctx context.Context, userID string) (Settings, error) {
+	defer s.log.Sync()
+	s.log.Info("digests: GetSettings(): start")
+
+	settings, err := s.repo.GetSettings(ctx, userID)
+	if err != nil {
+		s.log.Debug("digests: GetSettings(): could not get settings", logging.String("error", err.Error()))
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+func (s *service) Location(ctx context.Context, userID, name string) (*time.Location, error) {
+	defer s.log.Sync()
+	s.log.Info("digests: Location(): start")
+
+	if name == "" {
+		settings, err := s.repo.GetSettings(ctx, userID)
+		if err != nil {
+			s.log.Debug("digests: Location(): could not get settings", logging.String("error", err.Error()))
+			return nil, err
+		}
+		name = settings.Timezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+func (s *service) UpdateSettings(ctx context.Context, inp UpdateInput) (Settings, error) {
+	defer s.log.Sync()
+	s.log.Info("digests: UpdateSettings(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("digests: UpdateSettings(): validation failed", logging.String("error", err.Error()))
+		return Settings{}, err
+	}
+	if _, err := time.LoadLocation(inp.Timezone); err != nil {
+		return Settings{}, ErrInvalidTimezone
+	}
+	if inp.Frequency != FrequencyOff && s.mailer == nil {
+		return Settings{}, ErrNoMailer
+	}
+
+	settings, err := s.repo.GetSettings(ctx, inp.UserID)
+	if err != nil {
+		s.log.Debug("digests: UpdateSettings(): could not get settings", logging.String("error", err.Error()))
+		return Settings{}, err
+	}
+	settings.Frequency = inp.Frequency
+	settings.Timezone = inp.Timezone
+	settings.Hour = inp.Hour
+	settings.Weekday = inp.Weekday
+	settings.NextSendAt = nil
+	if next := settings.Next(time.Now()); !next.IsZero() {
+		settings.NextSendAt = &next
+	}
+
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		s.log.Debug("digests: UpdateSettings(): could not save settings", logging.String("error", err.Error()))
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
+func (s *service) Run(ctx context.Context, interval time.Duration) {
+	defer s.log.Sync()
+	if s.mailer == nil {
+		s.log.Info("digests: Run(): sending emails is turned off")
+		return
+	}
+	s.log.Info("digests: Run(): start")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("digests: Run(): stopped")
+			return
+		case <-ticker.C:
+			s.sendDue(ctx)
+		}
+	}
+}
+
+func (s *service) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now().UTC()
+		list, err := s.repo.ClaimDue(ctx, now, s.policy.Lease, s.policy.BatchSize)
+		if err != nil {
+			s.log.Error("digests: sendDue(): could not claim digests", logging.String("error", err.Error()))
+			return
+		}
+		for _, due := range list {
+			if err := s.send(ctx, due, now); err != nil {
+				// it is claimed, so we will try again after lease
+				s.log.Error(
+					"digests: sendDue(): could not send digest",
+					logging.String("userID", due.Settings.UserID),
+					logging.String("error", err.Error()),
+				)
+			}
+		}
+		if len(list) < s.policy.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *service) send(ctx context.Context, due Due, now time.Time) error {
+	loc, err := time.LoadLocation(due.Settings.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	list, err := s.repo.GetTodos(ctx, due.Settings.UserID, now.AddDate(0, 0, 8))
+	if err != nil {
+		return err
+	}
+	d := split(list, now, loc)
+	if d.Empty() {
+		return s.repo.Reschedule(ctx, due.Settings.UserID, due.Settings.Next(now), nil)
+	}
+	msg, err := compose(s.policy.AppURL, due, d, now, loc)
+	if err != nil {
+		return err
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return err
+	}
+	return s.repo.Reschedule(ctx, due.Settings.UserID, due.Settings.Next(now), &now)
+}
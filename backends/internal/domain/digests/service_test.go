@@ -0,0 +1,33 @@
+package digests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type fakeRepository struct {
+	Repository
+	timezone string
+}
+
+func (f fakeRepository) GetSettings(ctx context.Context, userID string) (Settings, error) {
+	return Settings{UserID: userID, Timezone: f.timezone}, nil
+}
+
+func TestLocation(t *testing.T) {
+	s := NewService(fakeRepository{timezone: "Asia/Bishkek"}, nil, logging.NewNop(), nil, Policy{})
+	ctx := context.Background()
+
+	if loc, err := s.Location(ctx, "john", ""); err != nil || loc.String() != "Asia/Bishkek" {
+		t.Errorf("expected timezone of the settings, got %v, %v", loc, err)
+	}
+	if loc, err := s.Location(ctx, "john", "Europe/Berlin"); err != nil || loc.String() != "Europe/Berlin" {
+		t.Errorf("expected the given timezone, got %v, %v", loc, err)
+	}
+	if _, err := s.Location(ctx, "john", "Mars/Olympus"); !errors.Is(err, ErrInvalidTimezone) {
+		t.Errorf("expected ErrInvalidTimezone, got %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const subscriptionBuffer = 64
+
+type (
+	// Bus is an in-process publisher of events. It keeps last events
+	// in a bounded buffer so subscribers can catch up after reconnecting.
+	Bus struct {
+		mu     sync.Mutex
+		lastID uint64
+		buffer []Event
+		next   int
+		full   bool
+		subs   map[*Subscription]struct{}
+		closed bool
+		// stale is set by Reset until the next event, events
+		// after lastID could have been missed by then
+		stale bool
+	}
+
+	// Subscription receives events of a single user.
+	// Its channel is closed if subscriber is too slow to read
+	// or when the bus is closed.
+	Subscription struct {
+		bus    *Bus
+		userID string
+		c      chan Event
+	}
+)
+
+func NewBus(bufferSize int) *Bus {
+	return &Bus{
+		// ids from different runs should not overlap, otherwise
+		// a client with an old Last-Event-ID would get wrong events
+		lastID: uint64(time.Now().UnixNano()),
+		buffer: make([]Event, bufferSize),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Bus) Publish(ctx context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+
+	if e.ID == "" {
+		b.lastID++
+		e.ID = strconv.FormatUint(b.lastID, 10)
+	} else if id, err := strconv.ParseUint(e.ID, 10, 64); err == nil {
+		// ids given by a broadcaster are the same on all instances
+		b.lastID = id
+	}
+	b.stale = false
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	if len(e.UserIDs) == 0 {
+		b.deliver(e)
+		return nil
+	}
+	for _, userID := range e.UserIDs {
+		c := e
+		c.UserID, c.UserIDs = userID, nil
+		b.deliver(c)
+	}
+	return nil
+}
+
+// deliver buffers an event and sends it to subscriptions of its user
+func (b *Bus) deliver(e Event) {
+	if len(b.buffer) != 0 {
+		b.buffer[b.next] = e
+		b.next = (b.next + 1) % len(b.buffer)
+		if b.next == 0 {
+			b.full = true
+		}
+	}
+
+	for sub := range b.subs {
+		if sub.userID != e.UserID {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+			// it will reconnect with Last-Event-ID and catch up
+			b.drop(sub)
+		}
+	}
+}
+
+// Subscribe returns a subscription and events for this user that were published
+// after lastID. If lastID is empty nothing is replayed. If lastID is not in
+// the buffer anymore ok is false, and subscriber should refetch everything.
+func (b *Bus) Subscribe(userID, lastID string) (sub *Subscription, replay []Event, ok bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, nil, false, ErrBusClosed
+	}
+
+	sub = &Subscription{
+		bus:    b,
+		userID: userID,
+		c:      make(chan Event, subscriptionBuffer),
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastID == "" {
+		return sub, nil, true, nil
+	}
+	last, err := strconv.ParseUint(lastID, 10, 64)
+	if err != nil {
+		return sub, nil, false, nil
+	}
+	buffered := b.buffered()
+	if len(buffered) == 0 || last > b.lastID {
+		return sub, nil, last == b.lastID && !b.stale, nil
+	}
+	oldest, _ := strconv.ParseUint(buffered[0].ID, 10, 64)
+	// lastID itself has to be in the buffer, or we might have lost something
+	if last+1 < oldest {
+		return sub, nil, false, nil
+	}
+	for _, e := range buffered {
+		id, _ := strconv.ParseUint(e.ID, 10, 64)
+		if id > last && e.UserID == userID {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, true, nil
+}
+
+// Close closes all subscriptions, nothing can be published after that
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+	return nil
+}
+
+// Reset forgets buffered events and closes all subscriptions. It is for
+// the times when some events could have been missed, subscribers will
+// reconnect and learn that they have to refetch everything.
+func (b *Bus) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.next, b.full = 0, false
+	b.stale = true
+	for sub := range b.subs {
+		b.drop(sub)
+	}
+}
+
+// buffered returns events from the oldest to the newest
+func (b *Bus) buffered() []Event {
+	if !b.full {
+		return b.buffer[:b.next]
+	}
+	out := make([]Event, 0, len(b.buffer))
+	out = append(out, b.buffer[b.next:]...)
+	return append(out, b.buffer[:b.next]...)
+}
+
+func (b *Bus) drop(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.c)
+}
+
+func (s *Subscription) C() <-chan Event {
+	return s.c
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.drop(s)
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBusReplay(t *testing.T) {
+	bus := NewBus(3)
+	ctx := context.Background()
+
+	sub, _, _, err := bus.Subscribe("john", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"john", "jane", "john"} {
+		if err := bus.Publish(ctx, Event{Type: TodoCreated, UserID: user}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := <-sub.C()
+	sub.Close()
+
+	_, replay, ok, _ := bus.Subscribe("john", first.ID)
+	if !ok || len(replay) != 1 {
+		t.Fatalf("expected 1 event to be replayed, got %d (ok=%v)", len(replay), ok)
+	}
+
+	// first event is pushed out of the buffer now
+	bus.Publish(ctx, Event{Type: TodoCreated, UserID: "jane"})
+	bus.Publish(ctx, Event{Type: TodoCreated, UserID: "jane"})
+	if _, _, ok, _ := bus.Subscribe("john", first.ID); ok {
+		t.Error("expected replay to be incomplete")
+	}
+}
+
+func TestBusReset(t *testing.T) {
+	bus := NewBus(3)
+	ctx := context.Background()
+
+	// ids that come from a broadcaster are kept
+	bus.Publish(ctx, Event{ID: "7", Type: TodoCreated, UserID: "john"})
+	sub, _, _, _ := bus.Subscribe("john", "7")
+	bus.Reset()
+	if _, open := <-sub.C(); open {
+		t.Fatal("expected subscription to be closed")
+	}
+
+	// event 8 could have been missed while we were resetting
+	if _, _, ok, _ := bus.Subscribe("john", "7"); ok {
+		t.Error("expected replay to be incomplete right after reset")
+	}
+	bus.Publish(ctx, Event{ID: "9", Type: TodoCreated, UserID: "john"})
+	if _, _, ok, _ := bus.Subscribe("john", "7"); ok {
+		t.Error("expected replay to be incomplete after a gap")
+	}
+	_, replay, ok, _ := bus.Subscribe("john", "8")
+	if !ok || len(replay) != 1 || replay[0].ID != "9" {
+		t.Errorf("expected event 9 to be replayed, got %v (ok=%v)", replay, ok)
+	}
+}
+
+func TestBusCopiesForEveryUser(t *testing.T) {
+	bus := NewBus(4)
+	ctx := context.Background()
+
+	john, _, _, _ := bus.Subscribe("john", "")
+	jane, _, _, _ := bus.Subscribe("jane", "")
+	if err := bus.Publish(ctx, Event{Type: TodoUpdated, UserIDs: []string{"john", "jane"}}); err != nil {
+		t.Fatal(err)
+	}
+	a, b := <-john.C(), <-jane.C()
+	if a.ID != b.ID || a.UserID != "john" || b.UserID != "jane" || a.UserIDs != nil {
+		t.Errorf("expected copies with the same id for john and jane, got %+v and %+v", a, b)
+	}
+
+	bus.Publish(ctx, Event{Type: TodoUpdated, UserIDs: []string{"john", "jane"}})
+	_, replay, ok, _ := bus.Subscribe("jane", a.ID)
+	if !ok || len(replay) != 1 || replay[0].UserID != "jane" {
+		t.Errorf("expected the copy of jane to be replayed, got %v (ok=%v)", replay, ok)
+	}
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TodoCreated     Type = "todo.created"
+	TodoUpdated     Type = "todo.updated"
+	TodoCompleted   Type = "todo.completed"
+	TodoUncompleted Type = "todo.uncompleted"
+	TodoDeleted     Type = "todo.deleted"
+	TodoAssigned    Type = "todo.assigned"
+	// TodoStatusChanged comes before todo.completed and todo.uncompleted
+	// when a todo moves into or out of a terminal status
+	TodoStatusChanged Type = "todo.status_changed"
+	// TodoReminder and TodoOverdue are written by the reminders scheduler
+	TodoReminder Type = "todo.reminder"
+	TodoOverdue  Type = "todo.overdue"
+
+	UserCreated           Type = "user.created"
+	UserUpdated           Type = "user.updated"
+	UserDeletionScheduled Type = "user.deletion_scheduled"
+	UserDeletionCanceled  Type = "user.deletion_canceled"
+	UserDeleted           Type = "user.deleted"
+
+	// notification.* events are only pushed to real-time channels
+	NotificationCreated Type = "notification.created"
+	NotificationRead    Type = "notification.read"
+)
+
+type (
+	Type string
+
+	// Event is something that happened in our domain that
+	// other parts of the app (or users) might be interested in.
+	Event struct {
+		// ID is assigned by the bus when event is published, or by the
+		// broadcaster when there are several instances. IDs only grow.
+		ID   string `json:"id"`
+		Type Type   `json:"type"`
+
+		// UserID is the user who should receive this event
+		UserID string `json:"userId"`
+		// UserIDs are set instead of UserID when many users should
+		// receive the same event. It is published once, and every
+		// one of them gets a copy with the same id.
+		UserIDs []string `json:"userIds,omitempty"`
+		// TodoID is set for all todo.* events
+		TodoID string `json:"todoId,omitempty"`
+		// ListID is set for todo.* events of todos in a list
+		ListID string `json:"listId,omitempty"`
+
+		Data json.RawMessage `json:"data,omitempty"`
+
+		At time.Time `json:"at"`
+	}
+
+	// Pending is an event from the outbox that was not relayed yet
+	Pending struct {
+		Event    Event
+		Attempts int
+		// Handled are names of handlers that already handled this event
+		Handled []string
+	}
+)
@@ -0,0 +1,7 @@
+package events
+
+import "errors"
+
+var (
+	ErrBusClosed = errors.New("events: bus is closed")
+)
@@ -0,0 +1,174 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type (
+	// OutboxRepository keeps events that were saved in the same
+	// transaction as changes that caused them
+	OutboxRepository interface {
+		// Claim returns up to limit events that are due at now and postpones
+		// them by lease, so other instances don't relay them at the same time
+		Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Pending, error)
+		MarkHandled(ctx context.Context, id, handler string) error
+		MarkRelayed(ctx context.Context, id string, at time.Time) error
+		// Retry makes an event due again at a given time
+		Retry(ctx context.Context, id string, at time.Time, reason string) error
+		DeleteRelayed(ctx context.Context, before time.Time) error
+	}
+
+	// Handler reacts to events from the outbox. Handlers are called at least
+	// once for every event, so they should be idempotent, Event.ID is the
+	// same for every call.
+	Handler interface {
+		Handle(ctx context.Context, e Event) error
+	}
+
+	// RelayPolicy describes how often we look into the outbox and how we
+	// retry events whose handlers failed. Delay doubles from BaseDelay up to
+	// MaxDelay, we never give up on an event. Relayed events are kept
+	// for Retention.
+	RelayPolicy struct {
+		BatchSize int
+		Lease     time.Duration
+		BaseDelay time.Duration
+		MaxDelay  time.Duration
+		Retention time.Duration
+	}
+
+	// Relay dispatches events from the outbox to registered handlers.
+	// A handler that succeeded is not called again for the same event,
+	// even if others failed and the event is retried.
+	Relay struct {
+		repo     OutboxRepository
+		log      *logging.Logger
+		policy   RelayPolicy
+		handlers []namedHandler
+	}
+
+	namedHandler struct {
+		name string
+		Handler
+	}
+)
+
+const relayCleanupEvery = time.Hour
+
+func NewRelay(repo OutboxRepository, logger *logging.Logger, policy RelayPolicy) *Relay {
+	return &Relay{
+		repo:   repo,
+		log:    logger,
+		policy: policy,
+	}
+}
+
+// Register adds a handler. Name is used to remember which handlers
+// already handled an event, so it should never change.
+// Handlers have to be registered before Run.
+func (r *Relay) Register(name string, h Handler) {
+	r.handlers = append(r.handlers, namedHandler{name: name, Handler: h})
+}
+
+// Run relays events until ctx is canceled
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var cleaned time.Time
+	for {
+		r.relayDue(ctx)
+		if time.Since(cleaned) > relayCleanupEvery {
+			r.cleanup(ctx)
+			cleaned = time.Now()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Relay) relayDue(ctx context.Context) {
+	defer r.log.Sync()
+	for ctx.Err() == nil {
+		batch, err := r.repo.Claim(ctx, time.Now().UTC(), r.policy.Lease, r.policy.BatchSize)
+		if err != nil {
+			r.log.Error("events: relayDue(): could not claim events", logging.String("error", err.Error()))
+			return
+		}
+		for _, p := range batch {
+			r.relay(ctx, p)
+		}
+		if len(batch) < r.policy.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) relay(ctx context.Context, p Pending) {
+	handled := make(map[string]bool, len(p.Handled))
+	for _, name := range p.Handled {
+		handled[name] = true
+	}
+
+	var failures []string
+	for _, h := range r.handlers {
+		if handled[h.name] {
+			continue
+		}
+		if err := h.Handle(ctx, p.Event); err != nil {
+			failures = append(failures, h.name+": "+err.Error())
+			continue
+		}
+		if err := r.repo.MarkHandled(ctx, p.Event.ID, h.name); err != nil {
+			// handler will be called again, that is why it has to be idempotent
+			failures = append(failures, h.name+": "+err.Error())
+		}
+	}
+
+	now := time.Now().UTC()
+	if len(failures) == 0 {
+		if err := r.repo.MarkRelayed(ctx, p.Event.ID, now); err != nil {
+			r.log.Error("events: relay(): could not mark event as relayed", logging.String("error", err.Error()))
+		}
+		return
+	}
+
+	reason := strings.Join(failures, "; ")
+	r.log.Error(
+		"events: relay(): handlers failed",
+		logging.String("id", p.Event.ID),
+		logging.String("type", string(p.Event.Type)),
+		logging.String("error", reason),
+	)
+	if err := r.repo.Retry(ctx, p.Event.ID, now.Add(r.policy.delay(p.Attempts+1)), reason); err != nil {
+		// lease will expire and we will try again anyway
+		r.log.Error("events: relay(): could not postpone event", logging.String("error", err.Error()))
+	}
+}
+
+func (r *Relay) cleanup(ctx context.Context) {
+	if r.policy.Retention <= 0 {
+		return
+	}
+	if err := r.repo.DeleteRelayed(ctx, time.Now().UTC().Add(-r.policy.Retention)); err != nil {
+		defer r.log.Sync()
+		r.log.Error("events: cleanup(): could not delete relayed events", logging.String("error", err.Error()))
+	}
+}
+
+func (p RelayPolicy) delay(attempts int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type fakeOutbox struct {
+	OutboxRepository
+	handled map[string]bool
+	relayed bool
+	retried bool
+}
+
+func (f *fakeOutbox) MarkHandled(ctx context.Context, id, handler string) error {
+	f.handled[handler] = true
+	return nil
+}
+
+func (f *fakeOutbox) MarkRelayed(ctx context.Context, id string, at time.Time) error {
+	f.relayed = true
+	return nil
+}
+
+func (f *fakeOutbox) Retry(ctx context.Context, id string, at time.Time, reason string) error {
+	f.retried = true
+	return nil
+}
+
+type countingHandler struct {
+	calls int
+	err   error
+}
+
+func (h *countingHandler) Handle(ctx context.Context, e Event) error {
+	h.calls++
+	return h.err
+}
+
+func TestRelayRetriesOnlyFailedHandlers(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeOutbox{handled: map[string]bool{}}
+	relay := NewRelay(repo, logger, RelayPolicy{BaseDelay: time.Second, MaxDelay: time.Minute})
+	ok, flaky := &countingHandler{}, &countingHandler{err: errors.New("boom")}
+	relay.Register("ok", ok)
+	relay.Register("flaky", flaky)
+
+	p := Pending{Event: Event{ID: "1", Type: TodoCreated}}
+	relay.relay(context.Background(), p)
+	if !repo.retried || repo.relayed {
+		t.Fatalf("expected event to be retried, got retried=%v relayed=%v", repo.retried, repo.relayed)
+	}
+
+	flaky.err = nil
+	p.Attempts = 1
+	p.Handled = []string{"ok"}
+	relay.relay(context.Background(), p)
+	if !repo.relayed {
+		t.Fatal("expected event to be relayed")
+	}
+	if ok.calls != 1 || flaky.calls != 2 {
+		t.Errorf("expected 1 and 2 calls, got %d and %d", ok.calls, flaky.calls)
+	}
+}
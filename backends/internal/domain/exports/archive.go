@@ -0,0 +1,76 @@
+package exports
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+)
+
+var todosCSVHeader = []string{
+	"id", "title", "body", "completed", "deadline", "createdAt", "updatedAt",
+}
+
+// writeArchive writes a zip with profile.json, todos.json and todos.csv
+func writeArchive(w io.Writer, profile users.User, list []todos.Todo) error {
+	z := zip.NewWriter(w)
+
+	profile.PasswordHash = ""
+	if err := writeJSON(z, "profile.json", profile); err != nil {
+		return err
+	}
+	if err := writeJSON(z, "todos.json", list); err != nil {
+		return err
+	}
+
+	f, err := z.Create("todos.csv")
+	if err != nil {
+		return err
+	}
+	c := csv.NewWriter(f)
+	if err := c.Write(todosCSVHeader); err != nil {
+		return err
+	}
+	for _, t := range list {
+		err := c.Write([]string{
+			t.ID,
+			t.Title,
+			t.Body,
+			strconv.FormatBool(t.Completed),
+			formatTime(t.Deadline),
+			formatTime(t.CreatedAt),
+			formatTime(t.UpdatedAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	c.Flush()
+	if err := c.Error(); err != nil {
+		return err
+	}
+
+	return z.Close()
+}
+
+func writeJSON(z *zip.Writer, name string, v any) error {
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
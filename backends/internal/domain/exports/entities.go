@@ -0,0 +1,42 @@
+package exports
+
+import "time"
+
+const (
+	StatusPending Status = "pending"
+	StatusRunning Status = "running"
+	StatusDone    Status = "done"
+	StatusFailed  Status = "failed"
+)
+
+type (
+	Status string
+
+	// Job is a single export of all the data of a user.
+	// When it is done we keep the archive on disk until ExpiresAt.
+	Job struct {
+		ID     string `json:"id"`
+		UserID string `json:"userId"`
+		Status Status `json:"status"`
+		Error  string `json:"error,omitempty"`
+
+		// Path is a location of the archive on local disk
+		Path string `json:"-"`
+
+		CreatedAt  time.Time `json:"createdAt"`
+		FinishedAt time.Time `json:"finishedAt"`
+		ExpiresAt  time.Time `json:"expiresAt"`
+	}
+
+	// Link is a signed url to download an archive without
+	// any other credentials
+	Link struct {
+		JobID     string
+		ExpiresAt time.Time
+		Signature string
+	}
+)
+
+func (j Job) Active() bool {
+	return j.Status == StatusPending || j.Status == StatusRunning
+}
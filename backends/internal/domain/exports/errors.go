@@ -0,0 +1,10 @@
+package exports
+
+import "errors"
+
+var (
+	ErrNoSuchJob        = errors.New("exports: no such export")
+	ErrNotReady         = errors.New("exports: export is not ready yet")
+	ErrExpired          = errors.New("exports: export has expired")
+	ErrInvalidSignature = errors.New("exports: invalid download link")
+)
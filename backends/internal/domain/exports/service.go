@@ -0,0 +1,296 @@
+package exports
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, userID string) (id string, err error)
+		Get(ctx context.Context, id string) (Job, error)
+		// GetActive should return ErrNoSuchJob if user has no pending or running jobs
+		GetActive(ctx context.Context, userID string) (Job, error)
+		GetExpired(ctx context.Context, now time.Time) ([]Job, error)
+		Start(ctx context.Context, id string) error
+		Finish(ctx context.Context, id, path string, expiresAt time.Time) error
+		Fail(ctx context.Context, id, reason string) error
+		// FailStale fails pending and running jobs created before a moment
+		FailStale(ctx context.Context, before time.Time, reason string) error
+		Delete(ctx context.Context, id string) error
+	}
+
+	UsersService interface {
+		Me(ctx context.Context, id string) (users.User, error)
+	}
+
+	TodosRepository interface {
+		GetAll(ctx context.Context, config todos.GetAllInput) ([]todos.Todo, error)
+	}
+
+	Service interface {
+		// Start will return already running job if there is one
+		Start(ctx context.Context, userID string) (Job, error)
+		Get(ctx context.Context, userID, id string) (Job, error)
+		// Link signs a download url for a finished job
+		Link(job Job) Link
+		// Open checks the signature of a link and returns a job
+		// with a path to its archive
+		Open(ctx context.Context, link Link) (Job, error)
+
+		// Run removes expired archives and fails lost jobs every interval
+		Run(ctx context.Context, interval time.Duration)
+	}
+
+	service struct {
+		repo      Repository
+		uService  UsersService
+		tRepo     TodosRepository
+		log       *logging.Logger
+		secretKey []byte
+
+		dir string
+		ttl time.Duration
+	}
+)
+
+func NewService(
+	repo Repository,
+	uService UsersService,
+	tRepo TodosRepository,
+	logger *logging.Logger,
+	secretKey []byte,
+	dir string,
+	ttl time.Duration,
+) Service {
+	return &service{
+		repo:      repo,
+		uService:  uService,
+		tRepo:     tRepo,
+		log:       logger,
+		secretKey: secretKey,
+		dir:       dir,
+		ttl:       ttl,
+	}
+}
+
+func (s *service) Start(ctx context.Context, userID string) (Job, error) {
+	defer s.log.Sync()
+	s.log.Info("exports: Start(): start")
+
+	s.removeExpired(ctx)
+	s.failStale(ctx)
+
+	job, err := s.repo.GetActive(ctx, userID)
+	if err == nil {
+		return job, nil
+	}
+	if err != ErrNoSuchJob {
+		s.log.Debug("exports: Start(): could not get active job", logging.String("error", err.Error()))
+		return Job{}, err
+	}
+
+	id, err := s.repo.Create(ctx, userID)
+	if err != nil {
+		s.log.Debug("exports: Start(): could not create job", logging.String("error", err.Error()))
+		return Job{}, err
+	}
+
+	// request context will be canceled long before we finish
+	go s.run(context.Background(), id, userID)
+
+	return s.repo.Get(ctx, id)
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (Job, error) {
+	defer s.log.Sync()
+	s.log.Info("exports: Get(): start")
+
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("exports: Get(): could not get job", logging.String("error", err.Error()))
+		return Job{}, err
+	}
+	// we dont want to tell anyone that this export exists
+	if job.UserID != userID {
+		return Job{}, ErrNoSuchJob
+	}
+	return job, nil
+}
+
+func (s *service) Link(job Job) Link {
+	return Link{
+		JobID:     job.ID,
+		ExpiresAt: job.ExpiresAt,
+		Signature: s.sign(job.ID, job.ExpiresAt),
+	}
+}
+
+func (s *service) Open(ctx context.Context, link Link) (Job, error) {
+	defer s.log.Sync()
+	s.log.Info("exports: Open(): start")
+
+	expected := s.sign(link.JobID, link.ExpiresAt)
+	if !hmac.Equal([]byte(expected), []byte(link.Signature)) {
+		s.log.Debug("exports: Open(): invalid signature", logging.String("id", link.JobID))
+		return Job{}, ErrInvalidSignature
+	}
+	if time.Now().UTC().After(link.ExpiresAt) {
+		return Job{}, ErrExpired
+	}
+
+	job, err := s.repo.Get(ctx, link.JobID)
+	if err != nil {
+		s.log.Debug("exports: Open(): could not get job", logging.String("error", err.Error()))
+		return Job{}, err
+	}
+	if job.Status != StatusDone {
+		return Job{}, ErrNotReady
+	}
+	return job, nil
+}
+
+func (s *service) sign(id string, expiresAt time.Time) string {
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte(id + ":" + strconv.FormatInt(expiresAt.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) run(ctx context.Context, id, userID string) {
+	defer s.log.Sync()
+	s.log.Info("exports: run(): start", logging.String("id", id))
+
+	if err := s.repo.Start(ctx, id); err != nil {
+		s.log.Error("exports: run(): could not start job", logging.String("error", err.Error()))
+		return
+	}
+
+	path, err := s.build(ctx, id, userID)
+	if err != nil {
+		s.log.Error(
+			"exports: run(): could not build archive",
+			logging.String("id", id),
+			logging.String("error", err.Error()),
+		)
+		if err := s.repo.Fail(ctx, id, "could not build archive"); err != nil {
+			s.log.Error("exports: run(): could not fail job", logging.String("error", err.Error()))
+		}
+		return
+	}
+
+	// links contain expiration time in seconds, so we truncate it here
+	expiresAt := time.Now().UTC().Add(s.ttl).Truncate(time.Second)
+	if err := s.repo.Finish(ctx, id, path, expiresAt); err != nil {
+		s.log.Error("exports: run(): could not finish job", logging.String("error", err.Error()))
+		return
+	}
+	s.log.Info("exports: run(): done", logging.String("id", id))
+}
+
+func (s *service) build(ctx context.Context, id, userID string) (string, error) {
+	profile, err := s.uService.Me(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	list, err := s.allTodos(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, id+".zip")
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	if err := writeArchive(f, profile, list); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+const (
+	exportPageSize = 100
+	// staleAfter is how long a job can be active. Jobs are run by the
+	// process that created them, so older ones were lost in a restart.
+	staleAfter = time.Hour
+)
+
+func (s *service) allTodos(ctx context.Context, userID string) ([]todos.Todo, error) {
+	all := []todos.Todo{}
+	for page := 0; ; page++ {
+		list, err := s.tRepo.GetAll(ctx, todos.GetAllInput{
+			UserID:   userID,
+			PageSize: exportPageSize,
+			Page:     page,
+		})
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, list...)
+		if len(list) < exportPageSize {
+			return all, nil
+		}
+	}
+}
+
+func (s *service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.removeExpired(ctx)
+			s.failStale(ctx)
+		}
+	}
+}
+
+// removeExpired deletes archives that nobody can download anymore
+func (s *service) removeExpired(ctx context.Context) {
+	expired, err := s.repo.GetExpired(ctx, time.Now().UTC())
+	if err != nil {
+		s.log.Error("exports: removeExpired(): could not get expired jobs", logging.String("error", err.Error()))
+		return
+	}
+	for _, job := range expired {
+		if job.Path != "" {
+			if err := os.Remove(job.Path); err != nil && !os.IsNotExist(err) {
+				s.log.Error("exports: removeExpired(): could not remove archive", logging.String("error", err.Error()))
+				continue
+			}
+		}
+		if err := s.repo.Delete(ctx, job.ID); err != nil {
+			s.log.Error("exports: removeExpired(): could not delete job", logging.String("error", err.Error()))
+		}
+	}
+}
+
+// failStale fails jobs that were lost, otherwise their users
+// would never be able to start a new export
+func (s *service) failStale(ctx context.Context) {
+	if err := s.repo.FailStale(ctx, time.Now().Add(-staleAfter), "export was interrupted, please try again"); err != nil {
+		s.log.Error("exports: failStale(): could not fail stale jobs", logging.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,19 @@
+package links
+
+import (
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+)
+
+type (
+	CreateInput struct {
+		OwnerID      string              `validate:"required"`
+		ResourceType shares.ResourceType `validate:"required,oneof=todo list"`
+		ResourceID   string              `validate:"required"`
+		// Password is optional, bcrypt uses only 72 bytes of it
+		Password string `validate:"omitempty,gte=4,lte=72"`
+		// ExpiresAt is optional, links without it work until revoked
+		ExpiresAt *time.Time
+	}
+)
@@ -0,0 +1,69 @@
+package links
+
+import (
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type (
+	// Link lets anybody who knows its token see a todo or a list
+	// without an account. Links can't change anything.
+	Link struct {
+		ID           string              `json:"id"`
+		OwnerID      string              `json:"ownerId"`
+		ResourceType shares.ResourceType `json:"resourceType"`
+		ResourceID   string              `json:"resourceId"`
+		// Token is the signed id of the link, it goes into urls
+		Token string `json:"token"`
+		// URL and PageURL are filled by transports
+		URL     string `json:"url,omitempty"`
+		PageURL string `json:"pageUrl,omitempty"`
+
+		HasPassword  bool   `json:"hasPassword"`
+		PasswordHash string `json:"-"`
+
+		Views        int64      `json:"views"`
+		LastViewedAt *time.Time `json:"lastViewedAt,omitempty"`
+
+		CreatedAt time.Time  `json:"createdAt"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+		RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	}
+
+	// View is what people see when they open a link. It has
+	// no ids, emails or anything else about owners of todos.
+	View struct {
+		ResourceType shares.ResourceType `json:"resourceType"`
+		// Title is the title of a todo or the name of a list
+		Title string `json:"title"`
+		// Todos has one todo for todo links
+		Todos     []Todo     `json:"todos"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	}
+
+	Todo struct {
+		Title     string     `json:"title"`
+		Body      string     `json:"body"`
+		Completed bool       `json:"completed"`
+		Deadline  *time.Time `json:"deadline,omitempty"`
+	}
+)
+
+// Active tells if a link can be opened at the moment
+func (l Link) Active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func comparePassword(password, hash string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
@@ -0,0 +1,12 @@
+package links
+
+import "errors"
+
+var (
+	ErrNoSuchLink       = errors.New("links: no such link")
+	ErrNotAllowed       = errors.New("links: only owner can share a todo or a list publicly")
+	ErrExpired          = errors.New("links: link expired or was revoked")
+	ErrPasswordRequired = errors.New("links: link is protected with a password")
+	ErrWrongPassword    = errors.New("links: wrong password")
+	ErrExpiresInPast    = errors.New("links: link would expire in the past")
+)
@@ -0,0 +1,276 @@
+package links
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, l Link) (id string, err error)
+		// Get should return ErrNoSuchLink if there is no link with this id
+		Get(ctx context.Context, id string) (Link, error)
+		// GetAll returns links created by a user, newest first
+		GetAll(ctx context.Context, ownerID string) ([]Link, error)
+		Revoke(ctx context.Context, id string, at time.Time) error
+		// RecordView increments the counter of views
+		RecordView(ctx context.Context, id string, at time.Time) error
+	}
+
+	// ResourcesRepository tells who owns a todo or a list
+	ResourcesRepository interface {
+		// Resource should return shares.ErrNoSuchResource
+		// if there is no such todo or list
+		Resource(ctx context.Context, typ shares.ResourceType, id string) (shares.Resource, error)
+	}
+
+	// Todos reads todos the way their owners see them
+	Todos interface {
+		Get(ctx context.Context, userID, id string) (todos.Todo, error)
+		GetAll(ctx context.Context, config todos.GetAllInput) ([]todos.Todo, error)
+	}
+
+	Service interface {
+		// Create is only for owners of todos and lists
+		Create(ctx context.Context, inp CreateInput) (Link, error)
+		GetAll(ctx context.Context, userID string) ([]Link, error)
+		// Revoke is only for the user who created the link
+		Revoke(ctx context.Context, userID, id string) error
+
+		// Open needs no account, only a token and a password if the
+		// link has one. Every successful call is counted as a view.
+		Open(ctx context.Context, token, password string) (View, error)
+	}
+
+	service struct {
+		repo      Repository
+		resources ResourcesRepository
+		todos     Todos
+		log       *logging.Logger
+		validator *validation.Validator
+		secretKey []byte
+	}
+)
+
+// maxListTodos is how many todos of a list we show, that
+// is the biggest page size todos service allows
+const maxListTodos = 100
+
+func NewService(
+	repo Repository,
+	resources ResourcesRepository,
+	todos Todos,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	secretKey []byte,
+) Service {
+	return &service{
+		repo:      repo,
+		resources: resources,
+		todos:     todos,
+		log:       logger,
+		validator: validator,
+		secretKey: secretKey,
+	}
+}
+
+func (s *service) Create(ctx context.Context, inp CreateInput) (Link, error) {
+	defer s.log.Sync()
+	s.log.Info("links: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("links: Create(): validation failed", logging.String("error", err.Error()))
+		return Link{}, err
+	}
+	if inp.ExpiresAt != nil && !inp.ExpiresAt.After(time.Now()) {
+		return Link{}, ErrExpiresInPast
+	}
+	res, err := s.resources.Resource(ctx, inp.ResourceType, inp.ResourceID)
+	if err != nil {
+		s.log.Debug("links: Create(): could not get resource", logging.String("error", err.Error()))
+		return Link{}, err
+	}
+	if res.OwnerID != inp.OwnerID {
+		return Link{}, ErrNotAllowed
+	}
+
+	l := Link{
+		OwnerID:      inp.OwnerID,
+		ResourceType: res.Type,
+		ResourceID:   res.ID,
+		ExpiresAt:    inp.ExpiresAt,
+	}
+	if inp.Password != "" {
+		if l.PasswordHash, err = hashPassword(inp.Password); err != nil {
+			return Link{}, err
+		}
+	}
+	id, err := s.repo.Create(ctx, l)
+	if err != nil {
+		s.log.Debug("links: Create(): could not create link", logging.String("error", err.Error()))
+		return Link{}, err
+	}
+	if l, err = s.repo.Get(ctx, id); err != nil {
+		return Link{}, err
+	}
+	l.Token = s.sign(l.ID)
+	return l, nil
+}
+
+func (s *service) GetAll(ctx context.Context, userID string) ([]Link, error) {
+	defer s.log.Sync()
+	s.log.Info("links: GetAll(): start")
+
+	list, err := s.repo.GetAll(ctx, userID)
+	if err != nil {
+		s.log.Debug("links: GetAll(): could not get links", logging.String("error", err.Error()))
+		return nil, err
+	}
+	for i := range list {
+		list[i].Token = s.sign(list[i].ID)
+	}
+	return list, nil
+}
+
+func (s *service) Revoke(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("links: Revoke(): start")
+
+	l, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("links: Revoke(): could not get link", logging.String("error", err.Error()))
+		return err
+	}
+	// nobody else should know that the link exists
+	if l.OwnerID != userID {
+		return ErrNoSuchLink
+	}
+	if l.RevokedAt != nil {
+		return nil
+	}
+	return s.repo.Revoke(ctx, id, time.Now().UTC())
+}
+
+func (s *service) Open(ctx context.Context, token, password string) (View, error) {
+	defer s.log.Sync()
+	s.log.Info("links: Open(): start")
+
+	id, ok := s.verify(token)
+	if !ok {
+		return View{}, ErrNoSuchLink
+	}
+	l, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("links: Open(): could not get link", logging.String("error", err.Error()))
+		return View{}, err
+	}
+	now := time.Now().UTC()
+	if !l.Active(now) {
+		return View{}, ErrExpired
+	}
+	if l.PasswordHash != "" {
+		if password == "" {
+			return View{}, ErrPasswordRequired
+		}
+		if err := comparePassword(password, l.PasswordHash); err != nil {
+			return View{}, ErrWrongPassword
+		}
+	}
+
+	v, err := s.view(ctx, l)
+	if err != nil {
+		s.log.Debug("links: Open(): could not get contents", logging.String("error", err.Error()))
+		return View{}, err
+	}
+	if err := s.repo.RecordView(ctx, l.ID, now); err != nil {
+		s.log.Error("links: Open(): could not record view", logging.String("error", err.Error()))
+	}
+	return v, nil
+}
+
+// view shows exactly what the owner would see. If the owner lost his
+// todo or list, the link stops working.
+func (s *service) view(ctx context.Context, l Link) (View, error) {
+	res, err := s.resources.Resource(ctx, l.ResourceType, l.ResourceID)
+	if errors.Is(err, shares.ErrNoSuchResource) {
+		return View{}, ErrNoSuchLink
+	}
+	if err != nil {
+		return View{}, err
+	}
+	if res.OwnerID != l.OwnerID {
+		return View{}, ErrNoSuchLink
+	}
+
+	v := View{
+		ResourceType: res.Type,
+		Title:        res.Title,
+		ExpiresAt:    l.ExpiresAt,
+	}
+	var list []todos.Todo
+	switch res.Type {
+	case shares.ResourceTodo:
+		todo, err := s.todos.Get(ctx, l.OwnerID, res.ID)
+		if err != nil {
+			return View{}, hideTodosErrors(err)
+		}
+		list = []todos.Todo{todo}
+	case shares.ResourceList:
+		list, err = s.todos.GetAll(ctx, todos.GetAllInput{
+			UserID:   l.OwnerID,
+			ListID:   res.ID,
+			PageSize: maxListTodos,
+			SortBy:   todos.SortByCreationASC,
+		})
+		if err != nil {
+			return View{}, hideTodosErrors(err)
+		}
+	}
+
+	v.Todos = make([]Todo, 0, len(list))
+	for _, t := range list {
+		todo := Todo{Title: t.Title, Body: t.Body, Completed: t.Completed}
+		if !t.Deadline.IsZero() {
+			deadline := t.Deadline
+			todo.Deadline = &deadline
+		}
+		v.Todos = append(v.Todos, todo)
+	}
+	return v, nil
+}
+
+// hideTodosErrors turns access errors into ErrNoSuchLink
+func hideTodosErrors(err error) error {
+	switch {
+	case errors.Is(err, todos.ErrNoSuchTodo), errors.Is(err, todos.ErrNoSuchList), errors.Is(err, todos.ErrNotAllowed):
+		return ErrNoSuchLink
+	}
+	return err
+}
+
+// sign makes a token that can't be guessed from ids,
+// so we don't even go to the database with forged ones
+func (s *service) sign(id string) string {
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte("link:" + id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) verify(token string) (id string, ok bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i <= 0 {
+		return "", false
+	}
+	id = token[:i]
+	return id, hmac.Equal([]byte(s.sign(id)), []byte(token))
+}
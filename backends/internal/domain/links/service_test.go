@@ -0,0 +1,124 @@
+package links
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	links map[string]Link
+}
+
+func (f *fakeRepository) Create(ctx context.Context, l Link) (string, error) {
+	l.ID = string(rune('a' + len(f.links)))
+	l.HasPassword = l.PasswordHash != ""
+	f.links[l.ID] = l
+	return l.ID, nil
+}
+
+func (f *fakeRepository) Get(ctx context.Context, id string) (Link, error) {
+	l, ok := f.links[id]
+	if !ok {
+		return l, ErrNoSuchLink
+	}
+	return l, nil
+}
+
+func (f *fakeRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	l := f.links[id]
+	l.RevokedAt = &at
+	f.links[id] = l
+	return nil
+}
+
+func (f *fakeRepository) RecordView(ctx context.Context, id string, at time.Time) error {
+	l := f.links[id]
+	l.Views++
+	f.links[id] = l
+	return nil
+}
+
+type fakeResources struct{}
+
+func (fakeResources) Resource(ctx context.Context, typ shares.ResourceType, id string) (shares.Resource, error) {
+	return shares.Resource{Type: typ, ID: id, OwnerID: "owner", Title: "groceries"}, nil
+}
+
+type fakeTodos struct{}
+
+func (fakeTodos) Get(ctx context.Context, userID, id string) (todos.Todo, error) {
+	return todos.Todo{}, todos.ErrNoSuchTodo
+}
+
+func (fakeTodos) GetAll(ctx context.Context, config todos.GetAllInput) ([]todos.Todo, error) {
+	return []todos.Todo{
+		{ID: "1", Title: "buy milk", Author: &users.User{ID: "owner", Email: "owner@example.com"}},
+	}, nil
+}
+
+func TestOpen(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{links: map[string]Link{}}
+	s := NewService(repo, fakeResources{}, fakeTodos{}, logger, validation.NewValidator(), []byte("secret"))
+	ctx := context.Background()
+
+	if _, err := s.Create(ctx, CreateInput{
+		OwnerID: "stranger", ResourceType: shares.ResourceList, ResourceID: "1",
+	}); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("only owners should create links, got %v", err)
+	}
+	l, err := s.Create(ctx, CreateInput{
+		OwnerID: "owner", ResourceType: shares.ResourceList, ResourceID: "1", Password: "open sesame",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Open(ctx, l.ID+".forged", "open sesame"); !errors.Is(err, ErrNoSuchLink) {
+		t.Fatalf("expected ErrNoSuchLink for a forged token, got %v", err)
+	}
+	if _, err := s.Open(ctx, l.Token, ""); !errors.Is(err, ErrPasswordRequired) {
+		t.Fatalf("expected ErrPasswordRequired, got %v", err)
+	}
+	if _, err := s.Open(ctx, l.Token, "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("expected ErrWrongPassword, got %v", err)
+	}
+	v, err := s.Open(ctx, l.Token, "open sesame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Title != "groceries" || len(v.Todos) != 1 || v.Todos[0].Title != "buy milk" {
+		t.Fatalf("unexpected view %+v", v)
+	}
+	if repo.links[l.ID].Views != 1 {
+		t.Fatalf("expected 1 view, got %d", repo.links[l.ID].Views)
+	}
+
+	if err := s.Revoke(ctx, "stranger", l.ID); !errors.Is(err, ErrNoSuchLink) {
+		t.Fatalf("strangers should not revoke links, got %v", err)
+	}
+	if err := s.Revoke(ctx, "owner", l.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, l.Token, "open sesame"); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+
+	todoLink, err := s.Create(ctx, CreateInput{OwnerID: "owner", ResourceType: shares.ResourceTodo, ResourceID: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the owner lost the todo
+	if _, err := s.Open(ctx, todoLink.Token, ""); !errors.Is(err, ErrNoSuchLink) {
+		t.Fatalf("expected ErrNoSuchLink, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package lists
+
+import "github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+
+type (
+	CreateInput struct {
+		UserID string `validate:"required"`
+		Name   string `validate:"required,lt=100"`
+	}
+
+	UpdateInput struct {
+		ID   string `validate:"required"`
+		Name string `validate:"required,lt=100"`
+	}
+
+	SetWorkflowInput struct {
+		ID       string `validate:"required"`
+		Workflow todos.Workflow
+	}
+
+	SetFieldsInput struct {
+		ID     string       `validate:"required"`
+		Fields todos.Fields `validate:"max=50,dive"`
+	}
+)
@@ -0,0 +1,29 @@
+package lists
+
+import (
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+type (
+	// List groups todos. Sharing a list shares all of its todos.
+	List struct {
+		ID      string `json:"id"`
+		OwnerID string `json:"ownerId"`
+		Name    string `json:"name"`
+		// WorkspaceID is empty for lists in the personal space of their owners
+		WorkspaceID string `json:"workspaceId,omitempty"`
+
+		// Permission is what the caller can do with this list
+		Permission shares.Permission `json:"permission,omitempty"`
+		// Workflow is the default one if owner hasn't set their own
+		Workflow todos.Workflow `json:"workflow"`
+		// Fields are custom fields todos of the list can have
+		Fields todos.Fields `json:"fields"`
+
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+)
@@ -0,0 +1,8 @@
+package lists
+
+import "errors"
+
+var (
+	ErrNoSuchList = errors.New("lists: no such list")
+	ErrNotAllowed = errors.New("lists: you are not allowed to change this list")
+)
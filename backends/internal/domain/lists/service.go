@@ -0,0 +1,205 @@
+package lists
+
+import (
+	"context"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, inp CreateInput) (id string, err error)
+		// Get should return ErrNoSuchList if there is no list with this id
+		Get(ctx context.Context, id string) (List, error)
+		// GetAll returns lists of a user and lists shared with him,
+		// with permission of the user for each of them
+		GetAll(ctx context.Context, userID string) ([]List, error)
+		Update(ctx context.Context, inp UpdateInput) error
+		// SetWorkflow saves a workflow and moves todos of the list
+		// in statuses it doesn't have into statuses it has
+		SetWorkflow(ctx context.Context, id string, w todos.Workflow) error
+		// SetFields saves custom fields and removes values of todos of the
+		// list that don't fit them anymore
+		SetFields(ctx context.Context, id string, f todos.Fields) error
+		// Delete removes a list with its shares,
+		// todos of the list stay with their authors
+		Delete(ctx context.Context, id string) error
+	}
+
+	AccessRepository interface {
+		Grant(ctx context.Context, userID string, typ shares.ResourceType, id string) (shares.Permission, error)
+	}
+
+	Service interface {
+		Create(ctx context.Context, inp CreateInput) (List, error)
+		GetAll(ctx context.Context, userID string) ([]List, error)
+		Get(ctx context.Context, userID, id string) (List, error)
+		// Update needs editor permission
+		Update(ctx context.Context, userID string, inp UpdateInput) (List, error)
+		// SetWorkflow is only for the owner. Todos in statuses the new
+		// workflow doesn't have go to its first status, completed ones
+		// go to its first terminal status.
+		SetWorkflow(ctx context.Context, userID string, inp SetWorkflowInput) (List, error)
+		// SetFields is only for the owner. Values of removed fields, fields
+		// that changed their type and removed options are dropped from todos.
+		SetFields(ctx context.Context, userID string, inp SetFieldsInput) (List, error)
+		// Delete is only for the owner
+		Delete(ctx context.Context, userID, id string) error
+	}
+
+	service struct {
+		repo      Repository
+		access    AccessRepository
+		log       *logging.Logger
+		validator *validation.Validator
+	}
+)
+
+func NewService(
+	repo Repository,
+	access AccessRepository,
+	logger *logging.Logger,
+	validator *validation.Validator,
+) Service {
+	return &service{
+		repo:      repo,
+		access:    access,
+		log:       logger,
+		validator: validator,
+	}
+}
+
+func (s *service) Create(ctx context.Context, inp CreateInput) (List, error) {
+	defer s.log.Sync()
+	s.log.Info("lists: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("lists: Create(): validation failed", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	id, err := s.repo.Create(ctx, inp)
+	if err != nil {
+		s.log.Debug("lists: Create(): could not create list", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	return s.Get(ctx, inp.UserID, id)
+}
+
+func (s *service) GetAll(ctx context.Context, userID string) ([]List, error) {
+	defer s.log.Sync()
+	s.log.Info("lists: GetAll(): start")
+
+	list, err := s.repo.GetAll(ctx, userID)
+	if err != nil {
+		s.log.Debug("lists: GetAll(): could not get lists", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (List, error) {
+	defer s.log.Sync()
+	s.log.Info("lists: Get(): start")
+
+	return s.get(ctx, userID, id, shares.PermissionViewer)
+}
+
+func (s *service) Update(ctx context.Context, userID string, inp UpdateInput) (List, error) {
+	defer s.log.Sync()
+	s.log.Info("lists: Update(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("lists: Update(): validation failed", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	if _, err := s.get(ctx, userID, inp.ID, shares.PermissionEditor); err != nil {
+		return List{}, err
+	}
+	if err := s.repo.Update(ctx, inp); err != nil {
+		s.log.Debug("lists: Update(): could not update list", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	return s.Get(ctx, userID, inp.ID)
+}
+
+func (s *service) SetWorkflow(ctx context.Context, userID string, inp SetWorkflowInput) (List, error) {
+	defer s.log.Sync()
+	s.log.Info("lists: SetWorkflow(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("lists: SetWorkflow(): validation failed", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	if err := inp.Workflow.Check(); err != nil {
+		return List{}, err
+	}
+	if _, err := s.get(ctx, userID, inp.ID, shares.PermissionOwner); err != nil {
+		return List{}, err
+	}
+	if err := s.repo.SetWorkflow(ctx, inp.ID, inp.Workflow); err != nil {
+		s.log.Debug("lists: SetWorkflow(): could not set workflow", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	return s.Get(ctx, userID, inp.ID)
+}
+
+func (s *service) SetFields(ctx context.Context, userID string, inp SetFieldsInput) (List, error) {
+	defer s.log.Sync()
+	s.log.Info("lists: SetFields(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("lists: SetFields(): validation failed", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	if err := inp.Fields.Check(); err != nil {
+		return List{}, err
+	}
+	if _, err := s.get(ctx, userID, inp.ID, shares.PermissionOwner); err != nil {
+		return List{}, err
+	}
+	if err := s.repo.SetFields(ctx, inp.ID, inp.Fields); err != nil {
+		s.log.Debug("lists: SetFields(): could not set fields", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	return s.Get(ctx, userID, inp.ID)
+}
+
+func (s *service) Delete(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("lists: Delete(): start")
+
+	if _, err := s.get(ctx, userID, id, shares.PermissionOwner); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("lists: Delete(): could not delete list", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// get returns a list if user has a permission for it. Lists that user
+// can't even see are reported as missing.
+func (s *service) get(ctx context.Context, userID, id string, need shares.Permission) (List, error) {
+	l, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("lists: get(): could not get list", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	p, err := s.access.Grant(ctx, userID, shares.ResourceList, id)
+	if err != nil {
+		s.log.Debug("lists: get(): could not get permission", logging.String("error", err.Error()))
+		return List{}, err
+	}
+	if !p.Allows(shares.PermissionViewer) {
+		return List{}, ErrNoSuchList
+	}
+	if !p.Allows(need) {
+		return List{}, ErrNotAllowed
+	}
+	l.Permission = p
+	return l, nil
+}
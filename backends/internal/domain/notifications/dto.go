@@ -0,0 +1,17 @@
+package notifications
+
+type (
+	GetAllInput struct {
+		UserID     string `validate:"required"`
+		UnreadOnly bool
+		PageSize   int `validate:"gte=0,lte=100"`
+		Page       int `validate:"gte=0"`
+	}
+
+	// Inbox is a page of notifications with the total number
+	// of unread ones, so clients can show a badge
+	Inbox struct {
+		Notifications []Notification `json:"notifications"`
+		Unread        int            `json:"unread"`
+	}
+)
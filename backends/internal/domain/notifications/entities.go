@@ -0,0 +1,71 @@
+package notifications
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TypeReminder Type = "reminder"
+	TypeOverdue  Type = "overdue"
+	TypeShare    Type = "share"
+	// TypeAssignment is sent when somebody assigns a todo to you
+	TypeAssignment Type = "assignment"
+	// TypeMention is sent when somebody mentions you in a comment
+	TypeMention Type = "mention"
+	// TypeWorkspace is sent when somebody invites you to a workspace
+	TypeWorkspace Type = "workspace"
+
+	ChannelInApp Channel = "inApp"
+	ChannelEmail Channel = "email"
+)
+
+// Types are all types of notifications users can configure
+var Types = []Type{TypeReminder, TypeOverdue, TypeShare, TypeAssignment, TypeMention, TypeWorkspace}
+
+type (
+	Type    string
+	Channel string
+
+	Notification struct {
+		ID     string `json:"id"`
+		UserID string `json:"userId"`
+		Type   Type   `json:"type"`
+
+		Title string `json:"title"`
+		Body  string `json:"body,omitempty"`
+		// TodoID is set if notification is about a todo
+		TodoID string          `json:"todoId,omitempty"`
+		Data   json.RawMessage `json:"data,omitempty"`
+
+		// Key makes notifications idempotent, a user gets only one
+		// notification with the same key. Empty keys are never the same.
+		Key string `json:"-"`
+
+		ReadAt    *time.Time `json:"readAt,omitempty"`
+		CreatedAt time.Time  `json:"createdAt"`
+	}
+
+	// Preference says through which channels a user wants to get
+	// notifications of a type. Everything is enabled by default.
+	Preference struct {
+		Type  Type `json:"type"`
+		InApp bool `json:"inApp"`
+		Email bool `json:"email"`
+	}
+)
+
+func (p Preference) Allows(c Channel) bool {
+	switch c {
+	case ChannelInApp:
+		return p.InApp
+	case ChannelEmail:
+		return p.Email
+	}
+	return false
+}
+
+// DefaultPreference is used for types a user did not configure
+func DefaultPreference(t Type) Preference {
+	return Preference{Type: t, InApp: true, Email: true}
+}
@@ -0,0 +1,8 @@
+package notifications
+
+import "errors"
+
+var (
+	ErrNoSuchNotification = errors.New("notifications: no such notification")
+	ErrUnknownType        = errors.New("notifications: unknown type of notifications")
+)
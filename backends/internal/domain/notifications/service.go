@@ -0,0 +1,253 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		// Create should not create a notification if user already has
+		// one with the same non empty key, created is false then
+		Create(ctx context.Context, n Notification) (id string, created bool, err error)
+		// Get should return ErrNoSuchNotification if there is no notification with this id
+		Get(ctx context.Context, id string) (Notification, error)
+		GetAll(ctx context.Context, inp GetAllInput) ([]Notification, error)
+		CountUnread(ctx context.Context, userID string) (int, error)
+		// MarkRead should return ErrNoSuchNotification if user
+		// has no notification with this id
+		MarkRead(ctx context.Context, userID, id string, at time.Time) error
+		MarkAllRead(ctx context.Context, userID string, at time.Time) error
+
+		// GetPreferences returns only preferences that user changed
+		GetPreferences(ctx context.Context, userID string) ([]Preference, error)
+		SetPreferences(ctx context.Context, userID string, prefs []Preference) error
+	}
+
+	// Publisher delivers events to real-time clients of a user
+	Publisher interface {
+		Publish(ctx context.Context, e events.Event) error
+	}
+
+	Service interface {
+		// Notify saves a notification to the inbox of a user and pushes it
+		// to real-time channels, unless user turned in-app notifications
+		// of this type off. Notifications with the same key are sent once.
+		Notify(ctx context.Context, n Notification) error
+		// Allowed tells other subsystems if they should notify a user
+		// through a channel
+		Allowed(ctx context.Context, userID string, typ Type, c Channel) (bool, error)
+
+		GetAll(ctx context.Context, inp GetAllInput) (Inbox, error)
+		MarkRead(ctx context.Context, userID, id string) (Notification, error)
+		MarkAllRead(ctx context.Context, userID string) error
+
+		// GetPreferences returns preferences for every type of notifications
+		GetPreferences(ctx context.Context, userID string) ([]Preference, error)
+		UpdatePreferences(ctx context.Context, userID string, prefs []Preference) ([]Preference, error)
+	}
+
+	service struct {
+		repo      Repository
+		publisher Publisher
+		log       *logging.Logger
+		validator *validation.Validator
+	}
+)
+
+const defaultPageSize = 20
+
+func NewService(
+	repo Repository,
+	publisher Publisher,
+	logger *logging.Logger,
+	validator *validation.Validator,
+) Service {
+	return &service{
+		repo:      repo,
+		publisher: publisher,
+		log:       logger,
+		validator: validator,
+	}
+}
+
+func (s *service) Notify(ctx context.Context, n Notification) error {
+	defer s.log.Sync()
+	s.log.Info("notifications: Notify(): start")
+
+	ok, err := s.Allowed(ctx, n.UserID, n.Type, ChannelInApp)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.log.Debug("notifications: Notify(): turned off by user", logging.String("type", string(n.Type)))
+		return nil
+	}
+
+	id, created, err := s.repo.Create(ctx, n)
+	if err != nil {
+		s.log.Debug("notifications: Notify(): could not create notification", logging.String("error", err.Error()))
+		return err
+	}
+	if !created {
+		return nil
+	}
+	n, err = s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, events.NotificationCreated, n.UserID, n.TodoID, n)
+	return nil
+}
+
+func (s *service) Allowed(ctx context.Context, userID string, typ Type, c Channel) (bool, error) {
+	prefs, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		s.log.Debug("notifications: Allowed(): could not get preferences", logging.String("error", err.Error()))
+		return false, err
+	}
+	for _, p := range prefs {
+		if p.Type == typ {
+			return p.Allows(c), nil
+		}
+	}
+	return DefaultPreference(typ).Allows(c), nil
+}
+
+func (s *service) GetAll(ctx context.Context, inp GetAllInput) (Inbox, error) {
+	defer s.log.Sync()
+	s.log.Info("notifications: GetAll(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("notifications: GetAll(): validation failed", logging.String("error", err.Error()))
+		return Inbox{}, err
+	}
+	if inp.PageSize == 0 {
+		inp.PageSize = defaultPageSize
+	}
+
+	list, err := s.repo.GetAll(ctx, inp)
+	if err != nil {
+		s.log.Debug("notifications: GetAll(): could not get notifications", logging.String("error", err.Error()))
+		return Inbox{}, err
+	}
+	unread, err := s.repo.CountUnread(ctx, inp.UserID)
+	if err != nil {
+		s.log.Debug("notifications: GetAll(): could not count unread", logging.String("error", err.Error()))
+		return Inbox{}, err
+	}
+	return Inbox{Notifications: list, Unread: unread}, nil
+}
+
+func (s *service) MarkRead(ctx context.Context, userID, id string) (Notification, error) {
+	defer s.log.Sync()
+	s.log.Info("notifications: MarkRead(): start")
+
+	if err := s.repo.MarkRead(ctx, userID, id, time.Now().UTC()); err != nil {
+		s.log.Debug("notifications: MarkRead(): could not mark as read", logging.String("error", err.Error()))
+		return Notification{}, err
+	}
+	n, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Notification{}, err
+	}
+	// other tabs and devices should update their badges
+	s.publish(ctx, events.NotificationRead, userID, n.TodoID, n)
+	return n, nil
+}
+
+func (s *service) MarkAllRead(ctx context.Context, userID string) error {
+	defer s.log.Sync()
+	s.log.Info("notifications: MarkAllRead(): start")
+
+	if err := s.repo.MarkAllRead(ctx, userID, time.Now().UTC()); err != nil {
+		s.log.Debug("notifications: MarkAllRead(): could not mark as read", logging.String("error", err.Error()))
+		return err
+	}
+	s.publish(ctx, events.NotificationRead, userID, "", map[string]bool{"all": true})
+	return nil
+}
+
+func (s *service) GetPreferences(ctx context.Context, userID string) ([]Preference, error) {
+	defer s.log.Sync()
+	s.log.Info("notifications: GetPreferences(): start")
+
+	saved, err := s.repo.GetPreferences(ctx, userID)
+	if err != nil {
+		s.log.Debug("notifications: GetPreferences(): could not get preferences", logging.String("error", err.Error()))
+		return nil, err
+	}
+	byType := make(map[Type]Preference, len(saved))
+	for _, p := range saved {
+		byType[p.Type] = p
+	}
+	prefs := make([]Preference, len(Types))
+	for i, t := range Types {
+		p, ok := byType[t]
+		if !ok {
+			p = DefaultPreference(t)
+		}
+		prefs[i] = p
+	}
+	return prefs, nil
+}
+
+func (s *service) UpdatePreferences(ctx context.Context, userID string, prefs []Preference) ([]Preference, error) {
+	defer s.log.Sync()
+	s.log.Info("notifications: UpdatePreferences(): start")
+
+	// the last preference of a type wins
+	byType := make(map[Type]Preference, len(prefs))
+	for _, p := range prefs {
+		if !knownType(p.Type) {
+			return nil, ErrUnknownType
+		}
+		byType[p.Type] = p
+	}
+	unique := make([]Preference, 0, len(byType))
+	for _, t := range Types {
+		if p, ok := byType[t]; ok {
+			unique = append(unique, p)
+		}
+	}
+	if err := s.repo.SetPreferences(ctx, userID, unique); err != nil {
+		s.log.Debug("notifications: UpdatePreferences(): could not set preferences", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return s.GetPreferences(ctx, userID)
+}
+
+// publish never fails, changes are already saved
+func (s *service) publish(ctx context.Context, typ events.Type, userID, todoID string, v interface{}) {
+	if s.publisher == nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.log.Error("notifications: publish(): could not marshal notification", logging.String("error", err.Error()))
+		return
+	}
+	err = s.publisher.Publish(ctx, events.Event{
+		Type:   typ,
+		UserID: userID,
+		TodoID: todoID,
+		Data:   data,
+	})
+	if err != nil {
+		s.log.Error("notifications: publish(): could not publish event", logging.String("error", err.Error()))
+	}
+}
+
+func knownType(t Type) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
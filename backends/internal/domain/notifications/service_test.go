@@ -0,0 +1,52 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type fakeRepository struct {
+	Repository
+	prefs   []Preference
+	created int
+}
+
+func (f *fakeRepository) GetPreferences(ctx context.Context, userID string) ([]Preference, error) {
+	return f.prefs, nil
+}
+
+func (f *fakeRepository) Create(ctx context.Context, n Notification) (string, bool, error) {
+	f.created++
+	return "1", true, nil
+}
+
+func (f *fakeRepository) Get(ctx context.Context, id string) (Notification, error) {
+	return Notification{ID: id}, nil
+}
+
+func TestNotifyRespectsPreferences(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{prefs: []Preference{{Type: TypeOverdue, InApp: false, Email: true}}}
+	s := NewService(repo, nil, logger, nil)
+
+	ctx := context.Background()
+	if err := s.Notify(ctx, Notification{UserID: "u", Type: TypeOverdue}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.created != 0 {
+		t.Fatal("expected overdue notification to be skipped")
+	}
+	if err := s.Notify(ctx, Notification{UserID: "u", Type: TypeReminder}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.created != 1 {
+		t.Fatal("expected reminder notification to be created by default")
+	}
+
+	ok, err := s.Allowed(ctx, "u", TypeOverdue, ChannelEmail)
+	if err != nil || !ok {
+		t.Fatalf("Allowed() = %v, %v, want true", ok, err)
+	}
+}
@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+type (
+	// Limit allows Requests per period of time.
+	// Unused requests are accumulated up to Requests,
+	// so it is also the size of a burst.
+	Limit struct {
+		Requests int
+		Per      time.Duration
+	}
+
+	// Bucket is a state of a token bucket for a single key
+	Bucket struct {
+		Tokens    float64
+		UpdatedAt time.Time
+	}
+
+	Result struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// Reset is the time left until bucket is full again
+		Reset time.Duration
+		// RetryAfter is zero if request was allowed
+		RetryAfter time.Duration
+	}
+)
+
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// rate returns amount of tokens we get per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Take refills the bucket for the time passed since its last update
+// and tries to take a single token from it. It returns the new state
+// of the bucket that has to be saved by the caller.
+func (b Bucket) Take(l Limit, now time.Time) (Bucket, Result) {
+	burst := float64(l.Requests)
+	tokens := burst
+	if !b.UpdatedAt.IsZero() {
+		elapsed := now.Sub(b.UpdatedAt).Seconds()
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		tokens = math.Min(burst, b.Tokens+elapsed*l.rate())
+	}
+
+	res := Result{Limit: l.Requests}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / l.rate())
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((burst - tokens) / l.rate())
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
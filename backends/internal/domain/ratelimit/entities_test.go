@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	l := Limit{Requests: 2, Per: 2 * time.Second}
+	start := time.Now()
+
+	var (
+		b   Bucket
+		res Result
+	)
+	steps := []struct {
+		after   time.Duration
+		allowed bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{500 * time.Millisecond, false},
+		{time.Second, true},
+		{time.Hour, true},
+		{time.Hour, true},
+		{time.Hour, false},
+	}
+	for i, st := range steps {
+		b, res = b.Take(l, start.Add(st.after))
+		if res.Allowed != st.allowed {
+			t.Errorf("step %d: allowed = %v, want %v", i, res.Allowed, st.allowed)
+		}
+		if !res.Allowed && res.RetryAfter <= 0 {
+			t.Errorf("step %d: retry after has to be set when not allowed", i)
+		}
+	}
+}
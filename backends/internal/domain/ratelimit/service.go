@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type (
+	// Repository has to take a token from a bucket atomically,
+	// Bucket.Take does all the math.
+	Repository interface {
+		Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	}
+
+	Service interface {
+		Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	}
+
+	service struct {
+		repo Repository
+		log  *logging.Logger
+	}
+)
+
+func NewService(repo Repository, logger *logging.Logger) Service {
+	return &service{
+		repo: repo,
+		log:  logger,
+	}
+}
+
+func (s *service) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.Disabled() {
+		return Result{Allowed: true}, nil
+	}
+	res, err := s.repo.Take(ctx, key, limit, time.Now())
+	if err != nil {
+		defer s.log.Sync()
+		s.log.Error(
+			"ratelimit: Allow(): could not take a token",
+			logging.String("key", key),
+			logging.String("error", err.Error()),
+		)
+		return res, err
+	}
+	return res, nil
+}
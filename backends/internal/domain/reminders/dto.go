@@ -0,0 +1,12 @@
+package reminders
+
+import "time"
+
+type (
+	// CreateInput needs either At or OffsetMinutes, not both
+	CreateInput struct {
+		TodoID        string `validate:"required"`
+		At            *time.Time
+		OffsetMinutes int `validate:"omitempty,gt=0,lte=525600"`
+	}
+)
@@ -0,0 +1,54 @@
+package reminders
+
+import (
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+type (
+	// Reminder is either at an exact time or some minutes before
+	// the deadline of its todo. Only one of At and OffsetMinutes is set.
+	Reminder struct {
+		ID     string `json:"id"`
+		TodoID string `json:"todoId"`
+		UserID string `json:"userId"`
+
+		At            *time.Time `json:"at,omitempty"`
+		OffsetMinutes int        `json:"offsetMinutes,omitempty"`
+
+		// SentAt is set when the reminder fired, reminders fire only once
+		SentAt    *time.Time `json:"sentAt,omitempty"`
+		CreatedAt time.Time  `json:"createdAt"`
+	}
+
+	// Payload is the data of todo.reminder and todo.overdue events
+	Payload struct {
+		Todo     todos.Todo `json:"todo"`
+		Reminder *Reminder  `json:"reminder,omitempty"`
+	}
+
+	// Notification is what notifiers deliver to a user
+	Notification struct {
+		// EventID is the id of the outbox event, it is the
+		// same if notifier is called again for the same event
+		EventID  string
+		Type     events.Type
+		UserID   string
+		Todo     todos.Todo
+		Reminder *Reminder
+	}
+)
+
+// DueAt returns when the reminder should fire for a todo with
+// this deadline. Offset reminders of todos without a deadline never fire.
+func (r Reminder) DueAt(deadline time.Time) (time.Time, bool) {
+	if r.At != nil {
+		return *r.At, true
+	}
+	if deadline.IsZero() {
+		return time.Time{}, false
+	}
+	return deadline.Add(-time.Duration(r.OffsetMinutes) * time.Minute), true
+}
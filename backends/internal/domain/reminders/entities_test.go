@@ -0,0 +1,30 @@
+package reminders
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminderDueAt(t *testing.T) {
+	deadline := time.Date(2022, 10, 25, 12, 0, 0, 0, time.UTC)
+	at := deadline.Add(-24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		reminder Reminder
+		deadline time.Time
+		want     time.Time
+		ok       bool
+	}{
+		{"exact time", Reminder{At: &at}, deadline, at, true},
+		{"exact time without deadline", Reminder{At: &at}, time.Time{}, at, true},
+		{"offset", Reminder{OffsetMinutes: 90}, deadline, deadline.Add(-90 * time.Minute), true},
+		{"offset without deadline", Reminder{OffsetMinutes: 90}, time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := tt.reminder.DueAt(tt.deadline)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("%s: DueAt() = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
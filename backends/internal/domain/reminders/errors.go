@@ -0,0 +1,13 @@
+package reminders
+
+import "errors"
+
+var (
+	ErrNoSuchReminder = errors.New("reminders: no such reminder")
+	ErrNotAllowed     = errors.New("reminders: only people who can see a todo can set reminders for it")
+
+	ErrInvalidTime    = errors.New("reminders: either at or offsetMinutes should be set")
+	ErrInPast         = errors.New("reminders: reminder can't be in the past")
+	ErrNoDeadline     = errors.New("reminders: todo has no deadline to remind before")
+	ErrTooManyPerTodo = errors.New("reminders: too many reminders for this todo")
+)
@@ -0,0 +1,154 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/mailer"
+)
+
+type (
+	// Notifier delivers notifications to users through some channel.
+	// It may be called more than once for the same notification.
+	Notifier interface {
+		Notify(ctx context.Context, n Notification) error
+	}
+
+	// Inbox keeps in-app notifications of users
+	Inbox interface {
+		Notify(ctx context.Context, n notifications.Notification) error
+	}
+
+	// Preferences tell if a user wants to be notified through a channel
+	Preferences interface {
+		Allowed(ctx context.Context, userID string, typ notifications.Type, c notifications.Channel) (bool, error)
+	}
+
+	UsersRepository interface {
+		Get(ctx context.Context, id string) (users.User, error)
+	}
+
+	handler struct {
+		notifier Notifier
+	}
+
+	inAppNotifier struct {
+		inbox Inbox
+	}
+
+	emailNotifier struct {
+		mailer mailer.Mailer
+		uRepo  UsersRepository
+		prefs  Preferences
+	}
+)
+
+// Handler lets a notifier handle todo.reminder and todo.overdue
+// events of the outbox relay, other events are ignored
+func Handler(n Notifier) events.Handler {
+	return handler{notifier: n}
+}
+
+func (h handler) Handle(ctx context.Context, e events.Event) error {
+	if e.Type != events.TodoReminder && e.Type != events.TodoOverdue {
+		return nil
+	}
+	var p Payload
+	if err := json.Unmarshal(e.Data, &p); err != nil {
+		return err
+	}
+	return h.notifier.Notify(ctx, Notification{
+		EventID:  e.ID,
+		Type:     e.Type,
+		UserID:   e.UserID,
+		Todo:     p.Todo,
+		Reminder: p.Reminder,
+	})
+}
+
+// NewInAppNotifier puts notifications to the inbox of a user,
+// inbox pushes them to real-time channels
+func NewInAppNotifier(inbox Inbox) Notifier {
+	return inAppNotifier{inbox: inbox}
+}
+
+func (n inAppNotifier) Notify(ctx context.Context, nt Notification) error {
+	data, err := json.Marshal(Payload{Todo: nt.Todo, Reminder: nt.Reminder})
+	if err != nil {
+		return err
+	}
+	return n.inbox.Notify(ctx, notifications.Notification{
+		UserID: nt.UserID,
+		Type:   notificationType(nt),
+		Title:  subject(nt),
+		Body:   nt.Todo.Title,
+		TodoID: nt.Todo.ID,
+		Data:   data,
+		Key:    "outbox:" + nt.EventID,
+	})
+}
+
+func NewEmailNotifier(m mailer.Mailer, uRepo UsersRepository, prefs Preferences) Notifier {
+	return emailNotifier{mailer: m, uRepo: uRepo, prefs: prefs}
+}
+
+func (n emailNotifier) Notify(ctx context.Context, nt Notification) error {
+	ok, err := n.prefs.Allowed(ctx, nt.UserID, notificationType(nt), notifications.ChannelEmail)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	u, err := n.uRepo.Get(ctx, nt.UserID)
+	if errors.Is(err, users.ErrNoSuchUser) {
+		// nobody to notify anymore
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return n.mailer.Send(ctx, mailer.Message{
+		To:      []string{u.Email},
+		Subject: subject(nt),
+		Text:    text(u, nt),
+	})
+}
+
+func notificationType(nt Notification) notifications.Type {
+	if nt.Type == events.TodoOverdue {
+		return notifications.TypeOverdue
+	}
+	return notifications.TypeReminder
+}
+
+func subject(nt Notification) string {
+	if nt.Type == events.TodoOverdue {
+		return "Overdue: " + nt.Todo.Title
+	}
+	return "Reminder: " + nt.Todo.Title
+}
+
+func text(u users.User, nt Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Hi %s,\n\n", u.Username)
+	if nt.Type == events.TodoOverdue {
+		fmt.Fprintf(&b, "%q is past its deadline.\n", nt.Todo.Title)
+	} else {
+		fmt.Fprintf(&b, "This is a reminder about %q.\n", nt.Todo.Title)
+	}
+	if !nt.Todo.Deadline.IsZero() {
+		fmt.Fprintf(&b, "Deadline: %s\n", nt.Todo.Deadline.UTC().Format(time.RFC1123))
+	}
+	if nt.Todo.Body != "" {
+		fmt.Fprintf(&b, "\n%s\n", nt.Todo.Body)
+	}
+	return b.String()
+}
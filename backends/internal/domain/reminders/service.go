@@ -0,0 +1,233 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, r Reminder) (id string, err error)
+		// Get should return ErrNoSuchReminder if there is no reminder with this id
+		Get(ctx context.Context, id string) (Reminder, error)
+		GetAll(ctx context.Context, todoID string) ([]Reminder, error)
+		Delete(ctx context.Context, id string) error
+
+		// FireDue marks up to limit reminders that are due at now as sent and
+		// writes a todo.reminder event for each of them. Reminders locked by
+		// another instance are skipped. It returns how many reminders fired.
+		FireDue(ctx context.Context, now time.Time, limit int) (int, error)
+		// FireOverdue does the same for todos whose deadline passed,
+		// every todo is reported as overdue only once per deadline.
+		FireOverdue(ctx context.Context, now time.Time, limit int) (int, error)
+	}
+
+	TodosRepository interface {
+		// Get should return todos.ErrNoSuchTodo if there is no todo with this id
+		Get(ctx context.Context, id string) (todos.Todo, error)
+	}
+
+	// Todos tells what a user can do with a todo
+	Todos interface {
+		Authorize(ctx context.Context, userID, id string, need shares.Permission) error
+	}
+
+	// Service lets anyone who can see a todo keep their own reminders for it
+	Service interface {
+		Create(ctx context.Context, userID string, inp CreateInput) (Reminder, error)
+		GetAll(ctx context.Context, userID, todoID string) ([]Reminder, error)
+		Delete(ctx context.Context, userID, todoID, id string) error
+
+		// Run fires due reminders until ctx is canceled. Notifications
+		// themselves are sent by handlers of the outbox relay.
+		Run(ctx context.Context, interval time.Duration)
+	}
+
+	service struct {
+		repo      Repository
+		tRepo     TodosRepository
+		todos     Todos
+		log       *logging.Logger
+		validator *validation.Validator
+		batchSize int
+	}
+)
+
+const maxPerTodo = 10
+
+func NewService(
+	repo Repository,
+	tRepo TodosRepository,
+	todos Todos,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	batchSize int,
+) Service {
+	return &service{
+		repo:      repo,
+		tRepo:     tRepo,
+		todos:     todos,
+		log:       logger,
+		validator: validator,
+		batchSize: batchSize,
+	}
+}
+
+func (s *service) Create(ctx context.Context, userID string, inp CreateInput) (Reminder, error) {
+	defer s.log.Sync()
+	s.log.Info("reminders: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("reminders: Create(): validation failed", logging.String("error", err.Error()))
+		return Reminder{}, err
+	}
+	if (inp.At == nil) == (inp.OffsetMinutes == 0) {
+		return Reminder{}, ErrInvalidTime
+	}
+
+	todo, err := s.todo(ctx, userID, inp.TodoID)
+	if err != nil {
+		return Reminder{}, err
+	}
+
+	r := Reminder{
+		TodoID:        todo.ID,
+		UserID:        userID,
+		OffsetMinutes: inp.OffsetMinutes,
+	}
+	if inp.At != nil {
+		at := inp.At.UTC()
+		r.At = &at
+	}
+	due, ok := r.DueAt(todo.Deadline)
+	if !ok {
+		return Reminder{}, ErrNoDeadline
+	}
+	if due.Before(time.Now()) {
+		return Reminder{}, ErrInPast
+	}
+
+	existing, err := s.own(ctx, userID, todo.ID)
+	if err != nil {
+		s.log.Debug("reminders: Create(): could not get reminders", logging.String("error", err.Error()))
+		return Reminder{}, err
+	}
+	if len(existing) >= maxPerTodo {
+		return Reminder{}, ErrTooManyPerTodo
+	}
+
+	id, err := s.repo.Create(ctx, r)
+	if err != nil {
+		s.log.Debug("reminders: Create(): could not create reminder", logging.String("error", err.Error()))
+		return Reminder{}, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+func (s *service) GetAll(ctx context.Context, userID, todoID string) ([]Reminder, error) {
+	defer s.log.Sync()
+	s.log.Info("reminders: GetAll(): start")
+
+	if _, err := s.todo(ctx, userID, todoID); err != nil {
+		return nil, err
+	}
+	list, err := s.own(ctx, userID, todoID)
+	if err != nil {
+		s.log.Debug("reminders: GetAll(): could not get reminders", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Delete(ctx context.Context, userID, todoID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("reminders: Delete(): start")
+
+	if _, err := s.todo(ctx, userID, todoID); err != nil {
+		return err
+	}
+	r, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("reminders: Delete(): could not get reminder", logging.String("error", err.Error()))
+		return err
+	}
+	if r.TodoID != todoID || r.UserID != userID {
+		return ErrNoSuchReminder
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("reminders: Delete(): could not delete reminder", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// todo returns a todo only if user can see it
+func (s *service) todo(ctx context.Context, userID, todoID string) (todos.Todo, error) {
+	if err := s.todos.Authorize(ctx, userID, todoID, shares.PermissionViewer); err != nil {
+		s.log.Debug("reminders: todo(): user is not allowed", logging.String("error", err.Error()))
+		if errors.Is(err, todos.ErrNotAllowed) {
+			return todos.Todo{}, ErrNotAllowed
+		}
+		return todos.Todo{}, err
+	}
+	todo, err := s.tRepo.Get(ctx, todoID)
+	if err != nil {
+		s.log.Debug("reminders: todo(): could not get todo", logging.String("error", err.Error()))
+		return todos.Todo{}, err
+	}
+	return todo, nil
+}
+
+// own returns reminders of a todo that the user has set,
+// reminders of other people are none of their business
+func (s *service) own(ctx context.Context, userID, todoID string) ([]Reminder, error) {
+	all, err := s.repo.GetAll(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	list := []Reminder{}
+	for _, r := range all {
+		if r.UserID == userID {
+			list = append(list, r)
+		}
+	}
+	return list, nil
+}
+
+func (s *service) Run(ctx context.Context, interval time.Duration) {
+	defer s.log.Sync()
+	s.log.Info("reminders: Run(): start")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.log.Info("reminders: Run(): stopped")
+			return
+		case <-ticker.C:
+			s.fire(ctx, "FireDue", s.repo.FireDue)
+			s.fire(ctx, "FireOverdue", s.repo.FireOverdue)
+		}
+	}
+}
+
+// fire calls f until everything that is due has fired
+func (s *service) fire(ctx context.Context, name string, f func(context.Context, time.Time, int) (int, error)) {
+	for ctx.Err() == nil {
+		n, err := f(ctx, time.Now().UTC(), s.batchSize)
+		if err != nil {
+			s.log.Error("reminders: fire(): "+name+" failed", logging.String("error", err.Error()))
+			return
+		}
+		if n < s.batchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	reminders []Reminder
+}
+
+func (f *fakeRepository) Create(ctx context.Context, r Reminder) (string, error) {
+	r.ID = string(rune('a' + len(f.reminders)))
+	f.reminders = append(f.reminders, r)
+	return r.ID, nil
+}
+
+func (f *fakeRepository) Get(ctx context.Context, id string) (Reminder, error) {
+	for _, r := range f.reminders {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Reminder{}, ErrNoSuchReminder
+}
+
+func (f *fakeRepository) GetAll(ctx context.Context, todoID string) ([]Reminder, error) {
+	return f.reminders, nil
+}
+
+type fakeTodos map[string]shares.Permission
+
+func (f fakeTodos) Get(ctx context.Context, id string) (todos.Todo, error) {
+	return todos.Todo{ID: id, Author: &users.User{ID: "owner"}}, nil
+}
+
+func (f fakeTodos) Authorize(ctx context.Context, userID, id string, need shares.Permission) error {
+	if !f[userID].Allows(need) {
+		return todos.ErrNotAllowed
+	}
+	return nil
+}
+
+func TestServiceConsultsShares(t *testing.T) {
+	access := fakeTodos{"owner": shares.PermissionOwner, "viewer": shares.PermissionViewer}
+	repo := &fakeRepository{}
+	s := NewService(repo, access, access, logging.NewNop(), validation.NewValidator(), 10)
+	ctx := context.Background()
+	at := time.Now().Add(time.Hour)
+
+	if _, err := s.Create(ctx, "stranger", CreateInput{TodoID: "1", At: &at}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected stranger to be not allowed, got %v", err)
+	}
+	for _, userID := range []string{"owner", "viewer"} {
+		if _, err := s.Create(ctx, userID, CreateInput{TodoID: "1", At: &at}); err != nil {
+			t.Errorf("expected %s to set a reminder, got %v", userID, err)
+		}
+	}
+
+	list, err := s.GetAll(ctx, "viewer", "1")
+	if err != nil || len(list) != 1 || list[0].UserID != "viewer" {
+		t.Errorf("expected viewer to see only their reminder, got %v with %+v", err, list)
+	}
+	if err := s.Delete(ctx, "viewer", "1", repo.reminders[0].ID); !errors.Is(err, ErrNoSuchReminder) {
+		t.Errorf("expected viewer not to delete reminder of the owner, got %v", err)
+	}
+}
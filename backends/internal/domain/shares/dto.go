@@ -0,0 +1,17 @@
+package shares
+
+type (
+	InviteInput struct {
+		OwnerID      string       `validate:"required"`
+		ResourceType ResourceType `validate:"required,oneof=todo list"`
+		ResourceID   string       `validate:"required"`
+		Email        string       `validate:"required,email"`
+		Permission   Permission   `validate:"required,oneof=viewer editor"`
+	}
+
+	RespondInput struct {
+		UserID string `validate:"required"`
+		ID     string `validate:"required"`
+		Accept bool
+	}
+)
@@ -0,0 +1,66 @@
+package shares
+
+import "time"
+
+const (
+	ResourceTodo ResourceType = "todo"
+	ResourceList ResourceType = "list"
+
+	PermissionNone   Permission = ""
+	PermissionViewer Permission = "viewer"
+	PermissionEditor Permission = "editor"
+	// PermissionOwner is never granted, owners of todos and lists
+	// and admins have it
+	PermissionOwner Permission = "owner"
+
+	StatusPending  Status = "pending"
+	StatusAccepted Status = "accepted"
+	StatusDeclined Status = "declined"
+)
+
+type (
+	ResourceType string
+	Permission   string
+	Status       string
+
+	// Share grants a user access to a todo or to a list with all of its
+	// todos. Invitee has to accept it before it grants anything.
+	Share struct {
+		ID           string       `json:"id"`
+		ResourceType ResourceType `json:"resourceType"`
+		ResourceID   string       `json:"resourceId"`
+		// Title is the title of a todo or the name of a list
+		Title string `json:"title"`
+
+		OwnerID string `json:"ownerId"`
+		// InviteeID is empty until somebody with
+		// the email of the share answers it
+		InviteeID    string     `json:"inviteeId,omitempty"`
+		InviteeEmail string     `json:"inviteeEmail"`
+		Permission   Permission `json:"permission"`
+		Status       Status     `json:"status"`
+
+		CreatedAt   time.Time  `json:"createdAt"`
+		RespondedAt *time.Time `json:"respondedAt,omitempty"`
+	}
+
+	// Resource is a todo or a list that can be shared
+	Resource struct {
+		Type    ResourceType
+		ID      string
+		OwnerID string
+		Title   string
+	}
+)
+
+var permissionRanks = map[Permission]int{
+	PermissionNone:   0,
+	PermissionViewer: 1,
+	PermissionEditor: 2,
+	PermissionOwner:  3,
+}
+
+// Allows tells if a permission is enough for something that needs another one
+func (p Permission) Allows(need Permission) bool {
+	return permissionRanks[p] >= permissionRanks[need]
+}
@@ -0,0 +1,12 @@
+package shares
+
+import "errors"
+
+var (
+	ErrNoSuchShare    = errors.New("shares: no such share")
+	ErrNoSuchResource = errors.New("shares: no such todo or list")
+	ErrNotAllowed     = errors.New("shares: only owner can share a todo or a list")
+	ErrSelfShare      = errors.New("shares: you can't share with yourself")
+	ErrAlreadyShared  = errors.New("shares: already shared with this user")
+	ErrNotPending     = errors.New("shares: invitation was already answered")
+)
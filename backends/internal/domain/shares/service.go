@@ -0,0 +1,271 @@
+package shares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		// Create should return ErrAlreadyShared if a resource
+		// is already shared with the email of the invitee
+		Create(ctx context.Context, s Share) (id string, err error)
+		// Get should return ErrNoSuchShare if there is no share with this id
+		Get(ctx context.Context, id string) (Share, error)
+		// GetIncoming returns shares with a user and the ones sent to their
+		// email that nobody has answered yet, all of them if status is empty
+		GetIncoming(ctx context.Context, userID string, status Status) ([]Share, error)
+		GetOutgoing(ctx context.Context, typ ResourceType, resourceID string) ([]Share, error)
+		// Respond binds a share to the user who answers it
+		Respond(ctx context.Context, id, userID string, status Status, at time.Time) error
+		Delete(ctx context.Context, id string) error
+
+		// Resource should return ErrNoSuchResource if there is no such todo or list
+		Resource(ctx context.Context, typ ResourceType, id string) (Resource, error)
+		// Grant returns what a user can do with a todo or a list because he owns
+		// it or because of accepted shares. Sharing a list grants the same
+		// permission to all of its todos, owners of lists can edit their todos.
+		Grant(ctx context.Context, userID string, typ ResourceType, id string) (Permission, error)
+	}
+
+	UsersRepository interface {
+		Get(ctx context.Context, id string) (users.User, error)
+		GetByEmail(ctx context.Context, email string) (users.User, error)
+	}
+
+	// Inbox keeps in-app notifications of users
+	Inbox interface {
+		Notify(ctx context.Context, n notifications.Notification) error
+	}
+
+	Service interface {
+		// Invite shares a resource with an email. Users with that email get
+		// a notification and have to accept it. Emails without an account
+		// get the same answer, so nobody can find out who is signed up.
+		Invite(ctx context.Context, inp InviteInput) (Share, error)
+		Respond(ctx context.Context, inp RespondInput) (Share, error)
+		// GetIncoming returns invitations of a user, all of them if status is empty
+		GetIncoming(ctx context.Context, userID string, status Status) ([]Share, error)
+		// GetOutgoing returns shares of a resource, only its owner can see them
+		GetOutgoing(ctx context.Context, userID string, typ ResourceType, resourceID string) ([]Share, error)
+		// Revoke removes a share, both owner and invitee can do that
+		Revoke(ctx context.Context, userID, id string) error
+	}
+
+	service struct {
+		repo      Repository
+		uRepo     UsersRepository
+		inbox     Inbox
+		log       *logging.Logger
+		validator *validation.Validator
+	}
+)
+
+func NewService(
+	repo Repository,
+	uRepo UsersRepository,
+	inbox Inbox,
+	logger *logging.Logger,
+	validator *validation.Validator,
+) Service {
+	return &service{
+		repo:      repo,
+		uRepo:     uRepo,
+		inbox:     inbox,
+		log:       logger,
+		validator: validator,
+	}
+}
+
+func (s *service) Invite(ctx context.Context, inp InviteInput) (Share, error) {
+	defer s.log.Sync()
+	s.log.Info("shares: Invite(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("shares: Invite(): validation failed", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+
+	res, err := s.repo.Resource(ctx, inp.ResourceType, inp.ResourceID)
+	if err != nil {
+		s.log.Debug("shares: Invite(): could not get resource", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+	if res.OwnerID != inp.OwnerID {
+		return Share{}, ErrNotAllowed
+	}
+	email := strings.ToLower(inp.Email)
+	invitee, err := s.uRepo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, users.ErrNoSuchUser) {
+		s.log.Debug("shares: Invite(): could not get invitee", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+	if invitee.ID == inp.OwnerID {
+		return Share{}, ErrSelfShare
+	}
+
+	id, err := s.repo.Create(ctx, Share{
+		ResourceType: res.Type,
+		ResourceID:   res.ID,
+		OwnerID:      inp.OwnerID,
+		InviteeEmail: email,
+		Permission:   inp.Permission,
+		Status:       StatusPending,
+	})
+	if err != nil {
+		s.log.Debug("shares: Invite(): could not create share", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+	share, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Share{}, err
+	}
+	if invitee.ID != "" {
+		s.notify(ctx, share, invitee.ID)
+	}
+	return share, nil
+}
+
+// notify never fails, share itself is already saved
+func (s *service) notify(ctx context.Context, share Share, inviteeID string) {
+	owner, err := s.uRepo.Get(ctx, share.OwnerID)
+	if err != nil {
+		s.log.Error("shares: notify(): could not get owner", logging.String("error", err.Error()))
+		return
+	}
+	data, err := json.Marshal(share)
+	if err != nil {
+		s.log.Error("shares: notify(): could not marshal share", logging.String("error", err.Error()))
+		return
+	}
+	n := notifications.Notification{
+		UserID: inviteeID,
+		Type:   notifications.TypeShare,
+		Title:  owner.Username + " shared a " + string(share.ResourceType) + " with you",
+		Body:   share.Title,
+		Data:   data,
+		Key:    "share:" + share.ID,
+	}
+	if share.ResourceType == ResourceTodo {
+		n.TodoID = share.ResourceID
+	}
+	if err := s.inbox.Notify(ctx, n); err != nil {
+		s.log.Error("shares: notify(): could not notify invitee", logging.String("error", err.Error()))
+	}
+}
+
+func (s *service) Respond(ctx context.Context, inp RespondInput) (Share, error) {
+	defer s.log.Sync()
+	s.log.Info("shares: Respond(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("shares: Respond(): validation failed", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+
+	share, err := s.repo.Get(ctx, inp.ID)
+	if err != nil {
+		s.log.Debug("shares: Respond(): could not get share", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+	ok, err := s.isInvitee(ctx, share, inp.UserID)
+	if err != nil {
+		s.log.Debug("shares: Respond(): could not get user", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+	if !ok {
+		return Share{}, ErrNoSuchShare
+	}
+	if share.Status != StatusPending {
+		return Share{}, ErrNotPending
+	}
+
+	status := StatusDeclined
+	if inp.Accept {
+		status = StatusAccepted
+	}
+	if err := s.repo.Respond(ctx, share.ID, inp.UserID, status, time.Now().UTC()); err != nil {
+		s.log.Debug("shares: Respond(): could not respond", logging.String("error", err.Error()))
+		return Share{}, err
+	}
+	return s.repo.Get(ctx, share.ID)
+}
+
+func (s *service) GetIncoming(ctx context.Context, userID string, status Status) ([]Share, error) {
+	defer s.log.Sync()
+	s.log.Info("shares: GetIncoming(): start")
+
+	list, err := s.repo.GetIncoming(ctx, userID, status)
+	if err != nil {
+		s.log.Debug("shares: GetIncoming(): could not get shares", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) GetOutgoing(ctx context.Context, userID string, typ ResourceType, resourceID string) ([]Share, error) {
+	defer s.log.Sync()
+	s.log.Info("shares: GetOutgoing(): start")
+
+	res, err := s.repo.Resource(ctx, typ, resourceID)
+	if err != nil {
+		s.log.Debug("shares: GetOutgoing(): could not get resource", logging.String("error", err.Error()))
+		return nil, err
+	}
+	if res.OwnerID != userID {
+		return nil, ErrNotAllowed
+	}
+	list, err := s.repo.GetOutgoing(ctx, typ, resourceID)
+	if err != nil {
+		s.log.Debug("shares: GetOutgoing(): could not get shares", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Revoke(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("shares: Revoke(): start")
+
+	share, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("shares: Revoke(): could not get share", logging.String("error", err.Error()))
+		return err
+	}
+	if share.OwnerID != userID {
+		ok, err := s.isInvitee(ctx, share, userID)
+		if err != nil {
+			s.log.Debug("shares: Revoke(): could not get user", logging.String("error", err.Error()))
+			return err
+		}
+		if !ok {
+			return ErrNoSuchShare
+		}
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("shares: Revoke(): could not delete share", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// isInvitee tells if a share was sent to a user. Shares are bound
+// to invitees when they answer, before that only email is known.
+func (s *service) isInvitee(ctx context.Context, share Share, userID string) (bool, error) {
+	if share.InviteeID != "" {
+		return share.InviteeID == userID, nil
+	}
+	u, err := s.uRepo.Get(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(u.Email, share.InviteeEmail), nil
+}
@@ -0,0 +1,113 @@
+package shares
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	shares map[string]Share
+}
+
+func (f *fakeRepository) Resource(ctx context.Context, typ ResourceType, id string) (Resource, error) {
+	return Resource{Type: typ, ID: id, OwnerID: "owner"}, nil
+}
+
+func (f *fakeRepository) Create(ctx context.Context, s Share) (string, error) {
+	s.ID = strconv.Itoa(len(f.shares) + 1)
+	f.shares[s.ID] = s
+	return s.ID, nil
+}
+
+func (f *fakeRepository) Get(ctx context.Context, id string) (Share, error) {
+	s, ok := f.shares[id]
+	if !ok {
+		return Share{}, ErrNoSuchShare
+	}
+	return s, nil
+}
+
+func (f *fakeRepository) Respond(ctx context.Context, id, userID string, status Status, at time.Time) error {
+	s := f.shares[id]
+	s.InviteeID, s.Status = userID, status
+	f.shares[id] = s
+	return nil
+}
+
+type fakeUsers map[string]users.User
+
+func (f fakeUsers) Get(ctx context.Context, id string) (users.User, error) {
+	u, ok := f[id]
+	if !ok {
+		return users.User{}, users.ErrNoSuchUser
+	}
+	return u, nil
+}
+
+func (f fakeUsers) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	for _, u := range f {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return users.User{}, users.ErrNoSuchUser
+}
+
+type fakeInbox []notifications.Notification
+
+func (f *fakeInbox) Notify(ctx context.Context, n notifications.Notification) error {
+	*f = append(*f, n)
+	return nil
+}
+
+func TestInviteDoesNotRevealAccounts(t *testing.T) {
+	repo := &fakeRepository{shares: map[string]Share{}}
+	uRepo := fakeUsers{
+		"owner":  {ID: "owner", Email: "owner@example.com"},
+		"friend": {ID: "friend", Email: "friend@example.com"},
+	}
+	inbox := &fakeInbox{}
+	s := NewService(repo, uRepo, inbox, logging.NewNop(), validation.NewValidator())
+	ctx := context.Background()
+
+	invite := func(email string) (Share, error) {
+		return s.Invite(ctx, InviteInput{
+			OwnerID:      "owner",
+			ResourceType: ResourceTodo,
+			ResourceID:   "1",
+			Email:        email,
+			Permission:   PermissionViewer,
+		})
+	}
+	known, err := invite("Friend@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := invite("stranger@example.com")
+	if err != nil {
+		t.Fatalf("expected unknown email to be invited too, got %v", err)
+	}
+	if known.InviteeID != "" || unknown.InviteeID != "" {
+		t.Error("expected invitees to stay unknown until they answer")
+	}
+	if len(*inbox) != 1 || (*inbox)[0].UserID != "friend" {
+		t.Errorf("expected only the friend to be notified, got %v", *inbox)
+	}
+
+	if _, err := s.Respond(ctx, RespondInput{UserID: "owner", ID: known.ID, Accept: true}); !errors.Is(err, ErrNoSuchShare) {
+		t.Errorf("expected only invitee to answer, got %v", err)
+	}
+	accepted, err := s.Respond(ctx, RespondInput{UserID: "friend", ID: known.ID, Accept: true})
+	if err != nil || accepted.InviteeID != "friend" || accepted.Status != StatusAccepted {
+		t.Errorf("expected friend to accept the share, got %+v, %v", accepted, err)
+	}
+}
@@ -0,0 +1,26 @@
+package templates
+
+import "time"
+
+type (
+	CreateInput struct {
+		UserID string `validate:"required"`
+		Task
+	}
+
+	// UpdateInput replaces the whole tree of tasks
+	UpdateInput struct {
+		ID string `validate:"required"`
+		Task
+	}
+
+	InstantiateInput struct {
+		ID string `validate:"required"`
+		// ListID is where todos go, empty means no list
+		ListID string
+		// Start is the date deadlines are counted from
+		Start time.Time `validate:"required"`
+		// Variables fill placeholders other than {{date}} and {{deadline}}
+		Variables map[string]string `validate:"max=50"`
+	}
+)
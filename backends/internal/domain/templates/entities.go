@@ -0,0 +1,31 @@
+package templates
+
+import "time"
+
+type (
+	// Task is a todo a template creates, subtasks become
+	// its blockers so the todo is done after all of them
+	Task struct {
+		// Title and Body can have placeholders like {{date}}
+		Title string `json:"title" validate:"required,lt=100"`
+		Body  string `json:"body" validate:"lt=2000"`
+		// DeadlineOffsetDays is the deadline in days after the start date,
+		// it can be negative and nil means no deadline
+		DeadlineOffsetDays *int   `json:"deadlineOffsetDays,omitempty" validate:"omitempty,gte=-3650,lte=3650"`
+		Subtasks           []Task `json:"subtasks,omitempty" validate:"dive"`
+	}
+
+	// Template creates a tree of todos at once
+	Template struct {
+		ID      string `json:"id"`
+		OwnerID string `json:"ownerId"`
+		// WorkspaceID is empty for templates in the personal space of their
+		// owners, members of a workspace can use its templates
+		WorkspaceID string `json:"workspaceId,omitempty"`
+
+		Task
+
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+)
@@ -0,0 +1,10 @@
+package templates
+
+import "errors"
+
+var (
+	ErrNoSuchTemplate     = errors.New("templates: no such template")
+	ErrNotAllowed         = errors.New("templates: only owner can change a template")
+	ErrTooManyTasks       = errors.New("templates: template has too many tasks or they are nested too deep")
+	ErrUnknownPlaceholder = errors.New("templates: unknown placeholder")
+)
@@ -0,0 +1,112 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+const (
+	// maxTasks is how many todos a template can create at once
+	maxTasks = 100
+	// maxDepth counts the top task too
+	maxDepth = 3
+
+	// DateLayout is how {{date}} and {{deadline}} are written
+	DateLayout = "2006-01-02"
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// Check makes sure a tree of tasks is not too big to create at once
+func (t Task) Check() error {
+	if t.depth() > maxDepth || t.count() > maxTasks {
+		return ErrTooManyTasks
+	}
+	return nil
+}
+
+func (t Task) depth() int {
+	d := 0
+	for _, sub := range t.Subtasks {
+		if sd := sub.depth(); sd > d {
+			d = sd
+		}
+	}
+	return d + 1
+}
+
+func (t Task) count() int {
+	n := 1
+	for _, sub := range t.Subtasks {
+		n += sub.count()
+	}
+	return n
+}
+
+// Render replaces placeholders in s. {{date}} is the start date, {{deadline}}
+// is the deadline of the task or nothing if it has none, the rest come from
+// vars. It returns ErrUnknownPlaceholder if some of them are not known.
+func Render(s string, start time.Time, deadline *time.Time, vars map[string]string) (string, error) {
+	var unknown string
+	out := placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		switch name {
+		case "date":
+			return start.Format(DateLayout)
+		case "deadline":
+			if deadline == nil {
+				return ""
+			}
+			return deadline.Format(DateLayout)
+		}
+		v, ok := vars[name]
+		if !ok && unknown == "" {
+			unknown = name
+		}
+		return v
+	})
+	if unknown != "" {
+		return "", fmt.Errorf("%w: %s", ErrUnknownPlaceholder, unknown)
+	}
+	return out, nil
+}
+
+// Todos flattens a tree of tasks into todos, parents go before their
+// subtasks and are blocked by them
+func (t Task) Todos(start time.Time, vars map[string]string) ([]todos.BatchTodo, error) {
+	var all []todos.BatchTodo
+	var add func(t Task) (int, error)
+	add = func(t Task) (int, error) {
+		var deadline *time.Time
+		todo := todos.BatchTodo{}
+		if t.DeadlineOffsetDays != nil {
+			d := start.AddDate(0, 0, *t.DeadlineOffsetDays)
+			deadline = &d
+			todo.Deadline = d
+		}
+		var err error
+		if todo.Title, err = Render(t.Title, start, deadline, vars); err != nil {
+			return 0, err
+		}
+		if todo.Body, err = Render(t.Body, start, deadline, vars); err != nil {
+			return 0, err
+		}
+		i := len(all)
+		all = append(all, todo)
+		for _, sub := range t.Subtasks {
+			j, err := add(sub)
+			if err != nil {
+				return 0, err
+			}
+			all[i].BlockedBy = append(all[i].BlockedBy, j)
+		}
+		return i, nil
+	}
+	if _, err := add(t); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
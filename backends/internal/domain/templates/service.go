@@ -0,0 +1,181 @@
+package templates
+
+import (
+	"context"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, inp CreateInput) (id string, err error)
+		// Get should return ErrNoSuchTemplate if there is no template with this id
+		Get(ctx context.Context, id string) (Template, error)
+		// GetAll returns templates of a user in his personal space
+		// and every template of a workspace in a workspace
+		GetAll(ctx context.Context, userID string) ([]Template, error)
+		Update(ctx context.Context, inp UpdateInput) error
+		Delete(ctx context.Context, id string) error
+	}
+
+	// Todos creates todos of a template, checking
+	// if the user can add todos to the list
+	Todos interface {
+		CreateBatch(ctx context.Context, inp todos.BatchInput) ([]string, error)
+	}
+
+	Service interface {
+		Create(ctx context.Context, inp CreateInput) (Template, error)
+		GetAll(ctx context.Context, userID string) ([]Template, error)
+		// Get is for the owner and members of the workspace of a template
+		Get(ctx context.Context, userID, id string) (Template, error)
+		// Update and Delete are only for the owner
+		Update(ctx context.Context, userID string, inp UpdateInput) (Template, error)
+		Delete(ctx context.Context, userID, id string) error
+		// Instantiate creates todos of a template all at once and returns their
+		// ids, the todo of the template itself goes first. Subtasks block their
+		// parents and deadlines are counted from the start date.
+		Instantiate(ctx context.Context, userID string, inp InstantiateInput) ([]string, error)
+	}
+
+	service struct {
+		repo      Repository
+		todos     Todos
+		log       *logging.Logger
+		validator *validation.Validator
+	}
+)
+
+func NewService(
+	repo Repository,
+	todos Todos,
+	logger *logging.Logger,
+	validator *validation.Validator,
+) Service {
+	return &service{
+		repo:      repo,
+		todos:     todos,
+		log:       logger,
+		validator: validator,
+	}
+}
+
+func (s *service) Create(ctx context.Context, inp CreateInput) (Template, error) {
+	defer s.log.Sync()
+	s.log.Info("templates: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("templates: Create(): validation failed", logging.String("error", err.Error()))
+		return Template{}, err
+	}
+	if err := inp.Task.Check(); err != nil {
+		return Template{}, err
+	}
+	id, err := s.repo.Create(ctx, inp)
+	if err != nil {
+		s.log.Debug("templates: Create(): could not create template", logging.String("error", err.Error()))
+		return Template{}, err
+	}
+	return s.Get(ctx, inp.UserID, id)
+}
+
+func (s *service) GetAll(ctx context.Context, userID string) ([]Template, error) {
+	defer s.log.Sync()
+	s.log.Info("templates: GetAll(): start")
+
+	all, err := s.repo.GetAll(ctx, userID)
+	if err != nil {
+		s.log.Debug("templates: GetAll(): could not get templates", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return all, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (Template, error) {
+	defer s.log.Sync()
+	s.log.Info("templates: Get(): start")
+
+	return s.get(ctx, userID, id, false)
+}
+
+func (s *service) Update(ctx context.Context, userID string, inp UpdateInput) (Template, error) {
+	defer s.log.Sync()
+	s.log.Info("templates: Update(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("templates: Update(): validation failed", logging.String("error", err.Error()))
+		return Template{}, err
+	}
+	if err := inp.Task.Check(); err != nil {
+		return Template{}, err
+	}
+	if _, err := s.get(ctx, userID, inp.ID, true); err != nil {
+		return Template{}, err
+	}
+	if err := s.repo.Update(ctx, inp); err != nil {
+		s.log.Debug("templates: Update(): could not update template", logging.String("error", err.Error()))
+		return Template{}, err
+	}
+	return s.Get(ctx, userID, inp.ID)
+}
+
+func (s *service) Delete(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("templates: Delete(): start")
+
+	if _, err := s.get(ctx, userID, id, true); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("templates: Delete(): could not delete template", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *service) Instantiate(ctx context.Context, userID string, inp InstantiateInput) ([]string, error) {
+	defer s.log.Sync()
+	s.log.Info("templates: Instantiate(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("templates: Instantiate(): validation failed", logging.String("error", err.Error()))
+		return nil, err
+	}
+	t, err := s.get(ctx, userID, inp.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := t.Task.Todos(inp.Start, inp.Variables)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.todos.CreateBatch(ctx, todos.BatchInput{UserID: userID, ListID: inp.ListID, Todos: batch})
+	if err != nil {
+		s.log.Debug("templates: Instantiate(): could not create todos", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return ids, nil
+}
+
+// get returns a template if user can see it. Templates in the personal space
+// of someone else are reported as missing, the repository keeps templates
+// of other workspaces away.
+func (s *service) get(ctx context.Context, userID, id string, owner bool) (Template, error) {
+	t, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("templates: get(): could not get template", logging.String("error", err.Error()))
+		return Template{}, err
+	}
+	if t.OwnerID == userID {
+		return t, nil
+	}
+	if t.WorkspaceID == "" {
+		return Template{}, ErrNoSuchTemplate
+	}
+	if owner {
+		return Template{}, ErrNotAllowed
+	}
+	return t, nil
+}
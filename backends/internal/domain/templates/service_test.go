@@ -0,0 +1,108 @@
+package templates
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	templates map[string]Template
+}
+
+func (f fakeRepository) Get(ctx context.Context, id string) (Template, error) {
+	t, ok := f.templates[id]
+	if !ok {
+		return Template{}, ErrNoSuchTemplate
+	}
+	return t, nil
+}
+
+type fakeTodos struct {
+	batch todos.BatchInput
+}
+
+func (f *fakeTodos) CreateBatch(ctx context.Context, inp todos.BatchInput) ([]string, error) {
+	f.batch = inp
+	ids := make([]string, len(inp.Todos))
+	for i := range ids {
+		ids[i] = string(rune('a' + i))
+	}
+	return ids, nil
+}
+
+func days(n int) *int {
+	return &n
+}
+
+func TestInstantiate(t *testing.T) {
+	logger := logging.NewNop()
+	release := Template{ID: "release", OwnerID: "owner", Task: Task{
+		Title:              "Release {{version}}",
+		DeadlineOffsetDays: days(7),
+		Subtasks: []Task{
+			{Title: "Write changelog", Body: "Due {{ deadline }}", DeadlineOffsetDays: days(-1), Subtasks: []Task{
+				{Title: "Collect merged PRs since {{date}}"},
+			}},
+			{Title: "Tag {{version}}"},
+		},
+	}}
+	private := Template{ID: "private", OwnerID: "owner", Task: Task{Title: "Pay the rent"}}
+	shared := Template{ID: "shared", OwnerID: "owner", WorkspaceID: "w", Task: Task{Title: "Onboard {{name}}"}}
+	repo := fakeRepository{templates: map[string]Template{"release": release, "private": private, "shared": shared}}
+	tds := &fakeTodos{}
+	s := NewService(repo, tds, logger, validation.NewValidator())
+	ctx := context.Background()
+	start := time.Date(2022, 11, 7, 0, 0, 0, 0, time.UTC)
+
+	ids, err := s.Instantiate(ctx, "owner", InstantiateInput{ID: "release", Start: start, Variables: map[string]string{"version": "v1.2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"a", "b", "c", "d"}) {
+		t.Errorf("expected ids of 4 todos, got %v", ids)
+	}
+	want := []todos.BatchTodo{
+		{Title: "Release v1.2", Deadline: start.AddDate(0, 0, 7), BlockedBy: []int{1, 3}},
+		{Title: "Write changelog", Body: "Due 2022-11-06", Deadline: start.AddDate(0, 0, -1), BlockedBy: []int{2}},
+		{Title: "Collect merged PRs since 2022-11-07"},
+		{Title: "Tag v1.2"},
+	}
+	if !reflect.DeepEqual(tds.batch.Todos, want) {
+		t.Errorf("expected todos %+v, got %+v", want, tds.batch.Todos)
+	}
+
+	if _, err := s.Instantiate(ctx, "owner", InstantiateInput{ID: "release", Start: start}); !errors.Is(err, ErrUnknownPlaceholder) {
+		t.Errorf("expected ErrUnknownPlaceholder without variables, got %v", err)
+	}
+	if _, err := s.Instantiate(ctx, "stranger", InstantiateInput{ID: "private", Start: start}); !errors.Is(err, ErrNoSuchTemplate) {
+		t.Errorf("expected stranger to get ErrNoSuchTemplate, got %v", err)
+	}
+	if _, err := s.Instantiate(ctx, "member", InstantiateInput{ID: "shared", Start: start, Variables: map[string]string{"name": "Aibek"}}); err != nil {
+		t.Errorf("expected member of the workspace to use its template, got %v", err)
+	}
+	if err := s.Delete(ctx, "member", "shared"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected only owner to delete the template, got %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	deep := Task{Title: "1", Subtasks: []Task{{Title: "2", Subtasks: []Task{{Title: "3", Subtasks: []Task{{Title: "4"}}}}}}}
+	if err := deep.Check(); !errors.Is(err, ErrTooManyTasks) {
+		t.Errorf("expected 4 levels to be too deep, got %v", err)
+	}
+	wide := Task{Title: "top", Subtasks: make([]Task, maxTasks)}
+	if err := wide.Check(); !errors.Is(err, ErrTooManyTasks) {
+		t.Errorf("expected %d tasks to be too many, got %v", maxTasks+1, err)
+	}
+	if err := deep.Subtasks[0].Check(); err != nil {
+		t.Errorf("expected 3 levels to be fine, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package todos
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+func (s *service) CreateBatch(ctx context.Context, inp BatchInput) ([]string, error) {
+	defer s.log.Sync()
+	s.log.Info("todos: CreateBatch(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: CreateBatch(): validation failed",
+			logging.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	g := Graph{}
+	ids := make([]string, len(inp.Todos))
+	for i, t := range inp.Todos {
+		ids[i] = strconv.Itoa(i)
+		for _, b := range t.BlockedBy {
+			if b < 0 || b >= len(inp.Todos) || b == i {
+				return nil, ErrCycle
+			}
+			g[ids[i]] = append(g[ids[i]], strconv.Itoa(b))
+		}
+	}
+	if _, err := g.Sort(ids); err != nil {
+		return nil, err
+	}
+
+	if inp.ListID != "" {
+		if err := s.authorizeList(ctx, inp.UserID, inp.ListID, shares.PermissionEditor); err != nil {
+			s.log.Debug(
+				"todos: CreateBatch(): user is not allowed to add todos to the list",
+				logging.String("userID", inp.UserID),
+				logging.String("error", err.Error()),
+			)
+			return nil, err
+		}
+	}
+	w, err := s.workflow(ctx, inp.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	todos := make([]CreateInput, len(inp.Todos))
+	blockedBy := make([][]int, len(inp.Todos))
+	for i, t := range inp.Todos {
+		blockedBy[i] = t.BlockedBy
+		todos[i] = CreateInput{
+			UserID:   inp.UserID,
+			Title:    t.Title,
+			Body:     t.Body,
+			Deadline: t.Deadline,
+			ListID:   inp.ListID,
+			Status:   w.Initial(),
+		}
+	}
+	created, err := s.repo.CreateBatch(ctx, todos, blockedBy)
+	if err != nil {
+		s.log.Debug(
+			"todos: CreateBatch(): could not create todos in db",
+			logging.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	for _, id := range created {
+		s.publishTodo(ctx, events.TodoCreated, id)
+	}
+	return created, nil
+}
@@ -0,0 +1,210 @@
+package todos
+
+import (
+	"context"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+// Graph maps ids of todos to ids of todos that block them
+type Graph map[string][]string
+
+// Path returns a chain of blockers that leads from one todo to another,
+// starting with from and ending with to. It is nil if there is no such chain.
+// Making from blocked by to creates a cycle if to already leads to from.
+func (g Graph) Path(from, to string) []string {
+	visited := map[string]bool{}
+	var walk func(id string) []string
+	walk = func(id string) []string {
+		if id == to {
+			return []string{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		for _, b := range g[id] {
+			if path := walk(b); path != nil {
+				return append([]string{id}, path...)
+			}
+		}
+		return nil
+	}
+	return walk(from)
+}
+
+// Sort puts todos after their blockers. Todos that don't depend on each
+// other keep the order they were given in. Blockers that are not in ids
+// don't count. It returns ErrCycle if todos block each other in a circle.
+func (g Graph) Sort(ids []string) ([]string, error) {
+	in := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		in[id] = true
+	}
+	done := make(map[string]bool, len(ids))
+	sorted := make([]string, 0, len(ids))
+	for len(sorted) < len(ids) {
+		progress := false
+		for _, id := range ids {
+			if done[id] || !g.ready(id, in, done) {
+				continue
+			}
+			done[id] = true
+			sorted = append(sorted, id)
+			progress = true
+			// todos unblocked by this one come as early as possible
+			break
+		}
+		if !progress {
+			return nil, ErrCycle
+		}
+	}
+	return sorted, nil
+}
+
+func (g Graph) ready(id string, in, done map[string]bool) bool {
+	for _, b := range g[id] {
+		if in[b] && !done[b] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *service) AddBlocker(ctx context.Context, userID string, inp DependencyInput) error {
+	defer s.log.Sync()
+	s.log.Info("todos: AddBlocker(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: AddBlocker(): validation failed",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	if inp.ID == inp.BlockerID {
+		return ErrCycle
+	}
+
+	if err := s.authorize(ctx, userID, inp.ID, shares.PermissionEditor); err != nil {
+		s.log.Debug(
+			"todos: AddBlocker(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	if err := s.authorize(ctx, userID, inp.BlockerID, shares.PermissionViewer); err != nil {
+		s.log.Debug(
+			"todos: AddBlocker(): user can't see the blocker",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	if err := s.repo.AddBlocker(ctx, inp.ID, inp.BlockerID); err != nil {
+		s.log.Debug(
+			"todos: AddBlocker(): could not add blocker in db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.publishTodo(ctx, events.TodoUpdated, inp.ID)
+	return nil
+}
+
+func (s *service) RemoveBlocker(ctx context.Context, userID string, inp DependencyInput) error {
+	defer s.log.Sync()
+	s.log.Info("todos: RemoveBlocker(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: RemoveBlocker(): validation failed",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	if err := s.authorize(ctx, userID, inp.ID, shares.PermissionEditor); err != nil {
+		s.log.Debug(
+			"todos: RemoveBlocker(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	if err := s.repo.RemoveBlocker(ctx, inp.ID, inp.BlockerID); err != nil {
+		s.log.Debug(
+			"todos: RemoveBlocker(): could not remove blocker in db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.publishTodo(ctx, events.TodoUpdated, inp.ID)
+	return nil
+}
+
+func (s *service) Plan(ctx context.Context, userID, listID string) ([]Todo, error) {
+	defer s.log.Sync()
+	s.log.Info("todos: Plan(): start")
+
+	if err := s.authorizeList(ctx, userID, listID, shares.PermissionViewer); err != nil {
+		s.log.Debug(
+			"todos: Plan(): user is not allowed to see the list",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	open, err := s.repo.GetOpen(ctx, listID)
+	if err != nil {
+		s.log.Debug(
+			"todos: Plan(): could not get todos from db",
+			logging.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	g := Graph{}
+	ids := make([]string, len(open))
+	byID := make(map[string]Todo, len(open))
+	for i, t := range open {
+		g[t.ID] = t.BlockedBy
+		ids[i] = t.ID
+		byID[t.ID] = t
+	}
+	sorted, err := g.Sort(ids)
+	if err != nil {
+		s.log.Error("todos: Plan(): todos of the list block each other", logging.String("listID", listID))
+		return nil, err
+	}
+	plan := make([]Todo, len(sorted))
+	for i, id := range sorted {
+		plan[i] = byID[id]
+	}
+	return plan, nil
+}
+
+// checkBlockers returns ErrBlocked if some blockers
+// of a todo are not completed yet
+func (s *service) checkBlockers(ctx context.Context, id string) error {
+	open, err := s.repo.OpenBlockers(ctx, id)
+	if err != nil {
+		s.log.Debug(
+			"todos: checkBlockers(): could not get blockers from db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	if len(open) != 0 {
+		return ErrBlocked
+	}
+	return nil
+}
@@ -1,12 +1,17 @@
 package todos
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 const (
 	SortByCreationASC SortBy = iota
 	SortByCreationDESC
 	SortByDeadlineASC
 	SortByDeadlineDESC
+	// SortByManual keeps the order users made by moving todos
+	SortByManual
 )
 
 type (
@@ -16,6 +21,30 @@ type (
 		Body   string `json:"body" validate:"lt=2000"`
 		// TODO: dk if i should allow deadlines in past
 		Deadline time.Time `json:"deadline"`
+		// ListID is optional, user has to be an editor of the list
+		ListID string `json:"listId"`
+		// Status is optional, new todos start in the
+		// first status of the workflow of their list
+		Status Status `json:"status"`
+		// Completed is set by the service from the status
+		Completed bool `json:"-"`
+		// Fields are values of custom fields of the list
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+
+	// BatchInput creates todos that can block each other
+	BatchInput struct {
+		UserID string      `json:"userId" validate:"required"`
+		ListID string      `json:"listId"`
+		Todos  []BatchTodo `json:"todos" validate:"required,min=1,max=100,dive"`
+	}
+
+	BatchTodo struct {
+		Title    string    `json:"title" validate:"gt=6,lt=100"`
+		Body     string    `json:"body" validate:"lt=2000"`
+		Deadline time.Time `json:"deadline"`
+		// BlockedBy are indexes of todos of the same batch
+		BlockedBy []int `json:"blockedBy"`
 	}
 
 	UpdateInput struct {
@@ -23,6 +52,9 @@ type (
 		Title    string    `json:"title" validate:"gt=6,lt=100"`
 		Body     string    `json:"body" validate:"lt=2000"`
 		Deadline time.Time `json:"deadline"`
+		// ListID moves a todo to a list if it is set, empty
+		// string takes it out of its list. Only owner can do that.
+		ListID *string `json:"listId"`
 	}
 
 	SortBy uint
@@ -33,5 +65,57 @@ type (
 		Page              int    `json:"page"`
 		ShowOnlyCompleted bool   `json:"showOnlyCompleted"`
 		SortBy            SortBy `json:"sortBy"`
+
+		// ListID returns todos of a list instead of todos of the user
+		ListID string `json:"listId"`
+		// Shared returns todos shared with the user
+		// directly or with their lists
+		Shared bool `json:"shared"`
+		// AssigneeID returns only todos assigned to this user,
+		// todos that the user of UserID can't see are skipped
+		AssigneeID string `json:"assigneeId"`
+		// Statuses returns only todos in these statuses
+		Statuses []Status `json:"statuses"`
+		// FieldFilters returns only todos with these values of custom
+		// fields, values are compared as text: "5", "true", "2022-11-07"
+		FieldFilters map[string]string `json:"fieldFilters"`
+		// SortField sorts by a custom field instead of SortBy,
+		// todos without a value of the field go last
+		SortField     string `json:"sortField"`
+		SortFieldDesc bool   `json:"sortFieldDesc"`
+	}
+
+	AssignInput struct {
+		ID string `json:"id" validate:"required"`
+		// AssigneeID is empty to unassign a todo
+		AssigneeID string `json:"assigneeId"`
+	}
+
+	// MoveInput puts a todo right after AfterID and right before BeforeID.
+	// One anchor is enough, anchors have to be todos of the same list.
+	MoveInput struct {
+		ID       string `json:"id" validate:"required"`
+		BeforeID string `json:"beforeId" validate:"required_without=AfterID"`
+		AfterID  string `json:"afterId" validate:"required_without=BeforeID"`
+	}
+
+	SetStatusInput struct {
+		ID     string `json:"id" validate:"required"`
+		Status Status `json:"status" validate:"required"`
+		// Force completes a todo even if its blockers are still open
+		Force bool `json:"force"`
+	}
+
+	// SetFieldsInput changes values of custom fields by their keys
+	SetFieldsInput struct {
+		ID     string                     `json:"id" validate:"required"`
+		Fields map[string]json.RawMessage `json:"fields" validate:"required,min=1,max=50"`
+	}
+
+	// DependencyInput means that todo of ID can't be
+	// completed before todo of BlockerID
+	DependencyInput struct {
+		ID        string `json:"id" validate:"required"`
+		BlockerID string `json:"blockerId" validate:"required"`
 	}
 )
@@ -1,6 +1,7 @@
 package todos
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
@@ -14,10 +15,71 @@ type (
 		Title string `json:"title"`
 		Body  string `json:"body"`
 
+		// Status is where the todo is in the workflow of its list,
+		// Completed is true when the status is terminal
+		Status    Status    `json:"status"`
 		Completed bool      `json:"completed"`
 		Deadline  time.Time `json:"deadline"`
 
+		// ListID is set if todo belongs to a list
+		ListID string `json:"listId,omitempty"`
+		// WorkspaceID is empty for todos in the personal space of their authors
+		WorkspaceID string `json:"workspaceId,omitempty"`
+		// Position orders todos of a list, or todos of a workspace or
+		// of their author if they have no list, when sorting manually
+		Position float64 `json:"position"`
+		// BlockedBy are ids of todos that have to be completed before this one
+		// and Blocks are ids of todos that wait for it
+		BlockedBy []string `json:"blockedBy"`
+		Blocks    []string `json:"blocks"`
+		// Fields are values of custom fields of the list by their keys
+		Fields map[string]json.RawMessage `json:"fields,omitempty"`
+		// Assignee is responsible for the todo, he always
+		// has access to it at the moment of assignment
+		Assignee *users.User `json:"assignee,omitempty"`
+
+		CommentsCount int `json:"commentsCount"`
+
 		CreatedAt time.Time `json:"createdAt"`
 		UpdatedAt time.Time `json:"updatedAt"`
 	}
+
+	HistoryAction string
+
+	// HistoryEntry records who did what with a todo and when
+	HistoryEntry struct {
+		ID      string        `json:"id"`
+		TodoID  string        `json:"todoId"`
+		ActorID string        `json:"actorId"`
+		Action  HistoryAction `json:"action"`
+		// Data depends on action, see HistoryAssignment
+		Data      json.RawMessage `json:"data,omitempty"`
+		CreatedAt time.Time       `json:"createdAt"`
+	}
+
+	// HistoryAssignment is data of assigned and unassigned entries
+	HistoryAssignment struct {
+		From string `json:"from,omitempty"`
+		To   string `json:"to,omitempty"`
+	}
+
+	// HistoryStatusChange is data of status_changed entries
+	HistoryStatusChange struct {
+		From Status `json:"from,omitempty"`
+		To   Status `json:"to"`
+	}
+)
+
+const (
+	HistoryAssigned      HistoryAction = "assigned"
+	HistoryUnassigned    HistoryAction = "unassigned"
+	HistoryStatusChanged HistoryAction = "status_changed"
 )
+
+// AssigneeID returns an empty string for todos nobody is responsible for
+func (t Todo) AssigneeID() string {
+	if t.Assignee == nil {
+		return ""
+	}
+	return t.Assignee.ID
+}
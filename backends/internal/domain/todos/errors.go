@@ -3,9 +3,29 @@ package todos
 import "errors"
 
 var (
+	ErrNoSuchTodo = errors.New("todos: no such todo")
+	ErrNoSuchList = errors.New("todos: no such list")
+
 	ErrInvalidTitle = errors.New("todos: title can't be less than 6 characters and more than 100 characters")
 	ErrInvalidBody  = errors.New("todos: body can't be more than 2000 characters")
 
 	ErrInvalidDeadline = errors.New("todos: deadline can't be in the past")
-	ErrNotAllowed      = errors.New("todos: only admins are allowed to update todos that dont belong to them")
+	ErrNotAllowed      = errors.New("todos: you are not allowed to do this with the todo")
+
+	ErrAssigneeNoAccess = errors.New("todos: assignee has no access to the todo")
+	ErrReassign         = errors.New("todos: only owner can reassign a todo that is assigned to somebody else")
+
+	ErrUnknownStatus   = errors.New("todos: workflow of the todo has no such status")
+	ErrTransition      = errors.New("todos: workflow of the todo doesn't allow this change of status")
+	ErrInvalidWorkflow = errors.New("todos: workflow needs unique statuses, a non terminal first status, a terminal status and transitions only between its statuses")
+
+	ErrCycle            = errors.New("todos: todos can't block each other in a circle")
+	ErrBlocked          = errors.New("todos: todo has blockers that are not completed yet")
+	ErrNoSuchDependency = errors.New("todos: todo is not blocked by that todo")
+
+	ErrInvalidFields     = errors.New("todos: custom fields need unique keys and only select fields can have options")
+	ErrUnknownField      = errors.New("todos: list of the todo has no such custom field")
+	ErrInvalidFieldValue = errors.New("todos: value doesn't fit its custom field")
+
+	ErrInvalidAnchor = errors.New("todos: anchors have to be other todos of the same list with after going before before")
 )
@@ -0,0 +1,220 @@
+package todos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+const (
+	FieldText     FieldType = "text"
+	FieldNumber   FieldType = "number"
+	FieldDate     FieldType = "date"
+	FieldSelect   FieldType = "select"
+	FieldCheckbox FieldType = "checkbox"
+
+	// FieldDateLayout is how values of date fields are written
+	FieldDateLayout = "2006-01-02"
+
+	// fieldValueTag checks a value against its definition,
+	// it is registered by RegisterFieldValidation
+	fieldValueTag = "fieldvalue"
+)
+
+type (
+	FieldType string
+
+	// FieldDefinition is a custom field that todos of a list can have.
+	// Values are stored as JSON of their type: strings for text, select
+	// and date fields, numbers and booleans for the rest.
+	FieldDefinition struct {
+		Key  string    `json:"key" validate:"required,lte=40"`
+		Name string    `json:"name" validate:"required,lte=100"`
+		Type FieldType `json:"type" validate:"oneof=text number date select checkbox"`
+		// Options are values a select field can have
+		Options []string `json:"options,omitempty" validate:"required_if=Type select,max=50,dive,required,lte=100"`
+	}
+
+	// Fields are definitions of custom fields of a list
+	Fields []FieldDefinition
+
+	// fieldValue is how a value is given to the validator,
+	// check of the tag needs the definition next to it
+	fieldValue struct {
+		Definition FieldDefinition
+		Value      json.RawMessage `validate:"fieldvalue"`
+	}
+)
+
+// fieldChecks tell if a value fits a field of their type
+var fieldChecks = map[FieldType]func(d FieldDefinition, raw json.RawMessage) bool{
+	FieldText: func(_ FieldDefinition, raw json.RawMessage) bool {
+		var s string
+		return json.Unmarshal(raw, &s) == nil && len(s) <= 2000
+	},
+	FieldNumber: func(_ FieldDefinition, raw json.RawMessage) bool {
+		var n float64
+		return json.Unmarshal(raw, &n) == nil
+	},
+	FieldDate: func(_ FieldDefinition, raw json.RawMessage) bool {
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			return false
+		}
+		_, err := time.Parse(FieldDateLayout, s)
+		return err == nil
+	},
+	FieldSelect: func(d FieldDefinition, raw json.RawMessage) bool {
+		var s string
+		return json.Unmarshal(raw, &s) == nil && d.HasOption(s)
+	},
+	FieldCheckbox: func(_ FieldDefinition, raw json.RawMessage) bool {
+		var b bool
+		return json.Unmarshal(raw, &b) == nil
+	},
+}
+
+// RegisterFieldValidation teaches a validator to check values
+// of custom fields, services can't set fields without it
+func RegisterFieldValidation(v *validation.Validator) error {
+	return v.RegisterValidation(fieldValueTag, func(f validation.Field) bool {
+		fv, ok := f.Parent().Interface().(fieldValue)
+		if !ok {
+			return false
+		}
+		check, ok := fieldChecks[fv.Definition.Type]
+		return ok && check(fv.Definition, fv.Value)
+	}, "{0} doesn't fit the type of its custom field")
+}
+
+// HasOption tells if a select field can have this value
+func (d FieldDefinition) HasOption(o string) bool {
+	for _, option := range d.Options {
+		if option == o {
+			return true
+		}
+	}
+	return false
+}
+
+// Check makes sure keys are unique and only select fields have options
+func (f Fields) Check() error {
+	seen := make(map[string]bool, len(f))
+	for _, d := range f {
+		if seen[d.Key] || (d.Type != FieldSelect && len(d.Options) != 0) {
+			return ErrInvalidFields
+		}
+		seen[d.Key] = true
+	}
+	return nil
+}
+
+// Get returns a definition by its key
+func (f Fields) Get(key string) (FieldDefinition, bool) {
+	for _, d := range f {
+		if d.Key == key {
+			return d, true
+		}
+	}
+	return FieldDefinition{}, false
+}
+
+// isNull tells if a value removes a field from a todo
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// checkFields validates values against custom fields of a list,
+// todos without a list can't have any
+func (s *service) checkFields(ctx context.Context, listID string, values map[string]json.RawMessage) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if listID == "" || s.lists == nil {
+		return ErrUnknownField
+	}
+	defs, err := s.lists.Fields(ctx, listID)
+	if err != nil {
+		s.log.Debug(
+			"todos: checkFields(): could not get fields from db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	for key, raw := range values {
+		d, ok := defs.Get(key)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownField, key)
+		}
+		if isNull(raw) {
+			continue
+		}
+		if err := s.validator.ValidateStruct(fieldValue{Definition: d, Value: raw}); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidFieldValue, d.Name,
+				strings.Join(s.validator.UnpackErrors(err), ", "))
+		}
+	}
+	return nil
+}
+
+func (s *service) SetFields(ctx context.Context, userID string, inp SetFieldsInput) error {
+	defer s.log.Sync()
+	s.log.Info("todos: SetFields(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: SetFields(): validation failed",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	if err := s.authorize(ctx, userID, inp.ID, shares.PermissionEditor); err != nil {
+		s.log.Debug(
+			"todos: SetFields(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	todo, err := s.repo.Get(ctx, inp.ID)
+	if err != nil {
+		s.log.Debug(
+			"todos: SetFields(): could not get todo from db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	if err := s.checkFields(ctx, todo.ListID, inp.Fields); err != nil {
+		return err
+	}
+
+	set := make(map[string]json.RawMessage, len(inp.Fields))
+	remove := []string{}
+	for key, raw := range inp.Fields {
+		if isNull(raw) {
+			remove = append(remove, key)
+			continue
+		}
+		set[key] = raw
+	}
+	if err := s.repo.SetFields(ctx, inp.ID, set, remove); err != nil {
+		s.log.Debug(
+			"todos: SetFields(): could not set fields in db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.publishTodo(ctx, events.TodoUpdated, inp.ID)
+	return nil
+}
@@ -0,0 +1,66 @@
+package todos
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+const (
+	// PositionStep is the distance between neighbours after rebalancing,
+	// new todos go PositionStep after the last todo of their list
+	PositionStep = 1024.0
+	// MinPositionGap is the closest two neighbours can get, after
+	// that there is no room between them and we rebalance their list
+	MinPositionGap = 1e-6
+	// crowdedGap is how close neighbours get before
+	// the background job rebalances their list
+	crowdedGap = 1.0
+)
+
+// PositionBetween returns a position in the middle of lo and hi. Nil lo
+// means hi is the first todo of a list and nil hi means lo is the last one.
+// It returns false if lo and hi are too close and have to be rebalanced.
+func PositionBetween(lo, hi *float64) (float64, bool) {
+	switch {
+	case lo == nil && hi == nil:
+		return PositionStep, true
+	case lo == nil:
+		return *hi - PositionStep, true
+	case hi == nil:
+		return *lo + PositionStep, true
+	}
+	if *hi-*lo < MinPositionGap {
+		return 0, false
+	}
+	return *lo + (*hi-*lo)/2, true
+}
+
+// RunRebalancing spreads positions of lists that got
+// too crowded after many moves into the same place
+func (s *service) RunRebalancing(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.rebalance(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) rebalance(ctx context.Context) {
+	defer s.log.Sync()
+	n, err := s.repo.Rebalance(ctx, crowdedGap)
+	if err != nil {
+		s.log.Error("todos: rebalance(): could not rebalance positions", logging.String("error", err.Error()))
+		return
+	}
+	if n != 0 {
+		s.log.Info("todos: rebalance(): lists were rebalanced", logging.String("count", strconv.Itoa(n)))
+	}
+}
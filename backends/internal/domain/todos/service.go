@@ -2,7 +2,12 @@ package todos
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
@@ -11,46 +16,158 @@ import (
 type (
 	Repository interface {
 		Create(ctx context.Context, inp CreateInput) (id string, err error)
+		// CreateBatch creates all todos with their dependencies or none of
+		// them, blockedBy holds indexes of blockers of every todo
+		CreateBatch(ctx context.Context, todos []CreateInput, blockedBy [][]int) (ids []string, err error)
 		Get(ctx context.Context, id string) (todo Todo, err error)
 		GetAll(ctx context.Context, config GetAllInput) (todos []Todo, err error)
 		// Should not update fields that are empty in UpdateInput
 		Update(ctx context.Context, inp UpdateInput) error
-		MarkAsComplete(ctx context.Context, id string) error
-		MarkAsNotComplete(ctx context.Context, id string) error
+		// SetStatus moves a todo to a status and saves the history entry in
+		// the same transaction. Todos are completed in terminal statuses.
+		SetStatus(ctx context.Context, id string, status Status, completed bool, entry HistoryEntry) error
+		// Move puts a todo between its anchors, rebalancing the list if there is
+		// no room left between them. Moves in the same list wait for each other.
+		Move(ctx context.Context, inp MoveInput) error
+		// Rebalance spreads positions of lists where neighbours are closer
+		// than gap and returns how many lists it has rebalanced
+		Rebalance(ctx context.Context, gap float64) (int, error)
+		// AddBlocker returns ErrCycle if the blocker already waits for the todo,
+		// dependencies are added one at a time so cycles can't sneak in
+		AddBlocker(ctx context.Context, id, blockerID string) error
+		RemoveBlocker(ctx context.Context, id, blockerID string) error
+		// OpenBlockers returns ids of blockers of a todo that are not completed
+		OpenBlockers(ctx context.Context, id string) ([]string, error)
+		// GetOpen returns todos of a list that are not completed,
+		// in their manual order
+		GetOpen(ctx context.Context, listID string) ([]Todo, error)
+		// SetFields saves values of custom fields and removes fields of remove,
+		// other fields of the todo stay as they are
+		SetFields(ctx context.Context, id string, set map[string]json.RawMessage, remove []string) error
 		Delete(ctx context.Context, id string) error
+		// Assign changes assignee of a todo and saves
+		// the history entry in the same transaction
+		Assign(ctx context.Context, id, assigneeID string, entry HistoryEntry) (entryID string, err error)
+		GetHistory(ctx context.Context, id string) ([]HistoryEntry, error)
 	}
 
 	UsersRepository interface {
 		Get(ctx context.Context, id string) (users.User, error)
 	}
 
+	// AccessRepository tells what a user can do with a todo or a list,
+	// it knows about authors, list owners, workspace members and accepted
+	// shares. Resources of other workspaces don't exist for it.
+	AccessRepository interface {
+		Grant(ctx context.Context, userID string, typ shares.ResourceType, id string) (shares.Permission, error)
+		Resource(ctx context.Context, typ shares.ResourceType, id string) (shares.Resource, error)
+		// Viewers returns ids of everybody who can see a todo or a list
+		// in any workspace, assignees of todos included
+		Viewers(ctx context.Context, typ shares.ResourceType, id string) ([]string, error)
+	}
+
+	// ListsRepository knows workflows and custom fields of lists,
+	// it returns DefaultWorkflow for lists without their own
+	ListsRepository interface {
+		Workflow(ctx context.Context, listID string) (Workflow, error)
+		Fields(ctx context.Context, listID string) (Fields, error)
+	}
+
+	// Inbox keeps in-app notifications of users
+	Inbox interface {
+		Notify(ctx context.Context, n notifications.Notification) error
+	}
+
+	// Publisher is notified after every successful change of a todo
+	Publisher interface {
+		Publish(ctx context.Context, e events.Event) error
+	}
+
 	Service interface {
 		Create(ctx context.Context, inp CreateInput) (id string, err error)
-		Get(ctx context.Context, id string) (todo Todo, err error)
+		// CreateBatch creates todos of one list at once, they start in the
+		// first status of its workflow. Ids come in the order of BatchInput.
+		CreateBatch(ctx context.Context, inp BatchInput) (ids []string, err error)
+		// Get returns ErrNoSuchTodo if the user is not allowed to see the todo
+		Get(ctx context.Context, userID, id string) (todo Todo, err error)
 		GetAll(ctx context.Context, config GetAllInput) (todos []Todo, err error)
+		// GetBoard groups todos like GetAll returns them by their statuses,
+		// columns follow the workflow of the list or the default one
+		GetBoard(ctx context.Context, config GetAllInput) (Board, error)
 		// Will not update fields that are empty in UpdateInput.
 		// But ID is required
 
 		// userID represents a user that calls this service.
 		// With that id we determine if user is allowed to use this service.
 		Update(ctx context.Context, userID string, inp UpdateInput) error
-		MarkAsComplete(ctx context.Context, userID, id string) error
+		// MarkAsComplete moves a todo to the first terminal status of its
+		// workflow and MarkAsNotComplete moves it back to the first status.
+		// They don't care about transitions, so old clients keep working.
+		// Todos with open blockers are completed only if force is true.
+		MarkAsComplete(ctx context.Context, userID, id string, force bool) error
 		MarkAsNotComplete(ctx context.Context, userID, id string) error
+		// SetStatus moves a todo to a status of the workflow of its list,
+		// if the workflow allows that and it doesn't complete a blocked todo
+		SetStatus(ctx context.Context, userID string, inp SetStatusInput) error
+		// Move changes the place of a todo in its list, anchors have to be
+		// todos of the same list that the user can see
+		Move(ctx context.Context, userID string, inp MoveInput) error
+		// RunRebalancing spreads crowded positions of lists
+		// every interval until ctx is done
+		RunRebalancing(ctx context.Context, interval time.Duration)
+		// AddBlocker makes a todo wait for another one, the user has to be
+		// an editor of the todo and see the blocker. RemoveBlocker undoes it.
+		AddBlocker(ctx context.Context, userID string, inp DependencyInput) error
+		RemoveBlocker(ctx context.Context, userID string, inp DependencyInput) error
+		// Plan returns open todos of a list in an order they can be done
+		// in, blockers go first and the rest keep their manual order
+		Plan(ctx context.Context, userID, listID string) ([]Todo, error)
+		// SetFields changes values of custom fields of a todo, null removes
+		// a value. Values have to fit fields of the list of the todo.
+		SetFields(ctx context.Context, userID string, inp SetFieldsInput) error
 		Delete(ctx context.Context, userID, id string) error
+
+		// Assign makes a user responsible for a todo, the user has to have
+		// access to it. Editors can assign todos that are not assigned to
+		// somebody else, only owner can take a todo from another assignee.
+		Assign(ctx context.Context, userID string, inp AssignInput) error
+		GetHistory(ctx context.Context, userID, id string) ([]HistoryEntry, error)
+
+		// Authorize lets other domains guard things attached to todos.
+		// It returns ErrNoSuchTodo if the user can't see the todo
+		// and ErrNotAllowed if he needs more permissions than he has.
+		Authorize(ctx context.Context, userID, id string, need shares.Permission) error
 	}
 
 	service struct {
 		repo      Repository
 		uRepo     UsersRepository
+		access    AccessRepository
+		lists     ListsRepository
+		inbox     Inbox
+		publisher Publisher
 		log       *logging.Logger
 		validator *validation.Validator
 	}
 )
 
-func NewService(repo Repository, uRepo UsersRepository, logger *logging.Logger, validator *validation.Validator) Service {
+func NewService(
+	repo Repository,
+	uRepo UsersRepository,
+	access AccessRepository,
+	lists ListsRepository,
+	inbox Inbox,
+	publisher Publisher,
+	logger *logging.Logger,
+	validator *validation.Validator,
+) Service {
 	return &service{
 		repo:      repo,
 		uRepo:     uRepo,
+		access:    access,
+		lists:     lists,
+		inbox:     inbox,
+		publisher: publisher,
 		log:       logger,
 		validator: validator,
 	}
@@ -68,6 +185,38 @@ func (s *service) Create(ctx context.Context, inp CreateInput) (id string, err e
 		return "", err
 	}
 
+	if inp.ListID != "" {
+		if err := s.authorizeList(ctx, inp.UserID, inp.ListID, shares.PermissionEditor); err != nil {
+			s.log.Debug(
+				"todos: Create(): user is not allowed to add todos to the list",
+				logging.String("userID", inp.UserID),
+				logging.String("error", err.Error()),
+			)
+			return "", err
+		}
+	}
+
+	if err := s.checkFields(ctx, inp.ListID, inp.Fields); err != nil {
+		return "", err
+	}
+	for key, raw := range inp.Fields {
+		if isNull(raw) {
+			delete(inp.Fields, key)
+		}
+	}
+
+	w, err := s.workflow(ctx, inp.ListID)
+	if err != nil {
+		return "", err
+	}
+	if inp.Status == "" {
+		inp.Status = w.Initial()
+	}
+	if !w.Has(inp.Status) {
+		return "", ErrUnknownStatus
+	}
+	inp.Completed = w.Terminal(inp.Status)
+
 	id, err = s.repo.Create(ctx, inp)
 	if err != nil {
 		s.log.Debug(
@@ -77,13 +226,23 @@ func (s *service) Create(ctx context.Context, inp CreateInput) (id string, err e
 		return "", err
 	}
 
+	s.publishTodo(ctx, events.TodoCreated, id)
 	return id, nil
 }
 
-func (s *service) Get(ctx context.Context, id string) (todo Todo, err error) {
+func (s *service) Get(ctx context.Context, userID, id string) (todo Todo, err error) {
 	defer s.log.Sync()
 	s.log.Info("todos: Get(): start")
 
+	if err := s.authorize(ctx, userID, id, shares.PermissionViewer); err != nil {
+		s.log.Debug(
+			"todos: Get(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return todo, err
+	}
+
 	todo, err = s.repo.Get(ctx, id)
 	if err != nil {
 		s.log.Debug(
@@ -100,6 +259,17 @@ func (s *service) GetAll(ctx context.Context, config GetAllInput) (todos []Todo,
 	defer s.log.Sync()
 	s.log.Info("todos: GetAll(): start")
 
+	if config.ListID != "" {
+		if err := s.authorizeList(ctx, config.UserID, config.ListID, shares.PermissionViewer); err != nil {
+			s.log.Debug(
+				"todos: GetAll(): user is not allowed to see the list",
+				logging.String("userID", config.UserID),
+				logging.String("error", err.Error()),
+			)
+			return todos, err
+		}
+	}
+
 	todos, err = s.repo.GetAll(ctx, config)
 	if err != nil {
 		s.log.Debug(
@@ -112,6 +282,21 @@ func (s *service) GetAll(ctx context.Context, config GetAllInput) (todos []Todo,
 	return todos, nil
 }
 
+func (s *service) GetBoard(ctx context.Context, config GetAllInput) (Board, error) {
+	defer s.log.Sync()
+	s.log.Info("todos: GetBoard(): start")
+
+	todos, err := s.GetAll(ctx, config)
+	if err != nil {
+		return Board{}, err
+	}
+	w, err := s.workflow(ctx, config.ListID)
+	if err != nil {
+		return Board{}, err
+	}
+	return w.group(todos), nil
+}
+
 // TODO: find a better way of sending changesets
 // maybe a map[customTypeForFields]any
 // would be a good solution...or maybe it would be so bad
@@ -127,24 +312,32 @@ func (s *service) Update(ctx context.Context, userID string, inp UpdateInput) er
 		return err
 	}
 
-	ok, err := s.isAllowed(ctx, userID, inp.ID)
-	if err != nil {
+	// only owners decide which list a todo belongs to
+	need := shares.PermissionEditor
+	if inp.ListID != nil {
+		need = shares.PermissionOwner
+	}
+	if err := s.authorize(ctx, userID, inp.ID, need); err != nil {
 		s.log.Debug(
-			"todos: Update(): isAllowed returned error",
+			"todos: Update(): user is not allowed",
+			logging.String("userID", userID),
 			logging.String("error", err.Error()),
 		)
 		return err
 	}
 
-	if !ok {
-		s.log.Debug(
-			"todos: Update(): user is not allowed",
-			logging.String("userID", userID),
-		)
-		return ErrNotAllowed
+	if inp.ListID != nil && *inp.ListID != "" {
+		if err := s.authorizeList(ctx, userID, *inp.ListID, shares.PermissionEditor); err != nil {
+			s.log.Debug(
+				"todos: Update(): user is not allowed to add todos to the list",
+				logging.String("userID", userID),
+				logging.String("error", err.Error()),
+			)
+			return err
+		}
 	}
 
-	err = s.repo.Update(ctx, inp)
+	err := s.repo.Update(ctx, inp)
 	if err != nil {
 		s.log.Debug(
 			"todos: Update(): could not update todo in db",
@@ -153,92 +346,268 @@ func (s *service) Update(ctx context.Context, userID string, inp UpdateInput) er
 		return err
 	}
 
+	s.publishTodo(ctx, events.TodoUpdated, inp.ID)
+	if inp.ListID != nil {
+		return s.fitStatus(ctx, userID, inp.ID, *inp.ListID)
+	}
 	return nil
 }
 
-func (s *service) MarkAsComplete(ctx context.Context, userID, id string) error {
+// fitStatus moves a todo that has moved to another list into a status
+// of the new workflow, if the workflow doesn't have its status.
+// A status it keeps may be terminal in one list and not in the other,
+// then only completion of the todo changes.
+func (s *service) fitStatus(ctx context.Context, userID, id, listID string) error {
+	todo, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	w, err := s.workflow(ctx, listID)
+	if err != nil {
+		return err
+	}
+	status := w.Fit(todo.Status, todo.Completed)
+	if status == todo.Status && w.Terminal(status) == todo.Completed {
+		return nil
+	}
+	return s.setStatus(ctx, userID, todo, w, status)
+}
+
+func (s *service) MarkAsComplete(ctx context.Context, userID, id string, force bool) error {
 	defer s.log.Sync()
 	s.log.Info("todos: MarkAsComplete(): start")
 
-	ok, err := s.isAllowed(ctx, userID, id)
+	if err := s.authorize(ctx, userID, id, shares.PermissionEditor); err != nil {
+		s.log.Debug(
+			"todos: MarkAsComplete(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	todo, w, err := s.todoWorkflow(ctx, id)
 	if err != nil {
 		s.log.Debug(
-			"todos: MarkAsComplete(): isAllowed returned error",
+			"todos: MarkAsComplete(): could not get todo from db",
 			logging.String("error", err.Error()),
 		)
 		return err
 	}
-	if !ok {
+	if w.Terminal(todo.Status) {
+		return nil
+	}
+	if !force {
+		if err := s.checkBlockers(ctx, id); err != nil {
+			return err
+		}
+	}
+	return s.setStatus(ctx, userID, todo, w, w.Done())
+}
+
+func (s *service) MarkAsNotComplete(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("todos: MarkAsNotComplete(): start")
+
+	if err := s.authorize(ctx, userID, id, shares.PermissionEditor); err != nil {
 		s.log.Debug(
-			"todos: MarkAsComplete(): user is not allowed",
+			"todos: MarkAsNotComplete(): user is not allowed",
 			logging.String("userID", userID),
+			logging.String("error", err.Error()),
 		)
-		return ErrNotAllowed
+		return err
 	}
 
-	err = s.repo.MarkAsComplete(ctx, id)
+	todo, w, err := s.todoWorkflow(ctx, id)
 	if err != nil {
 		s.log.Debug(
-			"todos: MarkAsComplete(): could not mark todo as complete in db",
+			"todos: MarkAsNotComplete(): could not get todo from db",
 			logging.String("error", err.Error()),
 		)
 		return err
 	}
-
-	return nil
+	if !w.Terminal(todo.Status) && w.Has(todo.Status) {
+		return nil
+	}
+	return s.setStatus(ctx, userID, todo, w, w.Initial())
 }
 
-func (s *service) MarkAsNotComplete(ctx context.Context, userID, id string) error {
+func (s *service) SetStatus(ctx context.Context, userID string, inp SetStatusInput) error {
 	defer s.log.Sync()
-	s.log.Info("todos: MarkAsNotComplete(): start")
+	s.log.Info("todos: SetStatus(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: SetStatus(): validation failed",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
 
-	ok, err := s.isAllowed(ctx, userID, id)
+	if err := s.authorize(ctx, userID, inp.ID, shares.PermissionEditor); err != nil {
+		s.log.Debug(
+			"todos: SetStatus(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	todo, w, err := s.todoWorkflow(ctx, inp.ID)
 	if err != nil {
 		s.log.Debug(
-			"todos: MarkAsNotComplete(): isAllowed returned error",
+			"todos: SetStatus(): could not get todo from db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	if !w.Has(inp.Status) {
+		return ErrUnknownStatus
+	}
+	if todo.Status == inp.Status {
+		return nil
+	}
+	if !w.Allows(todo.Status, inp.Status) {
+		return ErrTransition
+	}
+	if w.Terminal(inp.Status) && !todo.Completed && !inp.Force {
+		if err := s.checkBlockers(ctx, inp.ID); err != nil {
+			return err
+		}
+	}
+	return s.setStatus(ctx, userID, todo, w, inp.Status)
+}
+
+func (s *service) Move(ctx context.Context, userID string, inp MoveInput) error {
+	defer s.log.Sync()
+	s.log.Info("todos: Move(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: Move(): validation failed",
 			logging.String("error", err.Error()),
 		)
 		return err
 	}
-	if !ok {
+	if inp.BeforeID == inp.ID || inp.AfterID == inp.ID || inp.BeforeID == inp.AfterID {
+		return ErrInvalidAnchor
+	}
+
+	if err := s.authorize(ctx, userID, inp.ID, shares.PermissionEditor); err != nil {
 		s.log.Debug(
-			"todos: MarkAsNotComplete(): user is not allowed",
+			"todos: Move(): user is not allowed",
 			logging.String("userID", userID),
+			logging.String("error", err.Error()),
 		)
-		return ErrNotAllowed
+		return err
+	}
+	for _, anchor := range []string{inp.BeforeID, inp.AfterID} {
+		if anchor == "" {
+			continue
+		}
+		if err := s.authorize(ctx, userID, anchor, shares.PermissionViewer); err != nil {
+			if err == ErrNoSuchTodo {
+				return ErrInvalidAnchor
+			}
+			return err
+		}
+	}
+
+	if err := s.repo.Move(ctx, inp); err != nil {
+		s.log.Debug(
+			"todos: Move(): could not move todo in db",
+			logging.String("error", err.Error()),
+		)
+		return err
 	}
 
-	err = s.repo.MarkAsNotComplete(ctx, id)
+	s.publishTodo(ctx, events.TodoUpdated, inp.ID)
+	return nil
+}
+
+// setStatus saves a new status with a history entry and publishes
+// todo.status_changed, and todo.completed or todo.uncompleted if
+// the todo was completed or reopened by that
+func (s *service) setStatus(ctx context.Context, userID string, todo Todo, w Workflow, status Status) error {
+	data, err := json.Marshal(HistoryStatusChange{From: todo.Status, To: status})
+	if err != nil {
+		return err
+	}
+	completed := w.Terminal(status)
+	err = s.repo.SetStatus(ctx, todo.ID, status, completed, HistoryEntry{
+		TodoID:  todo.ID,
+		ActorID: userID,
+		Action:  HistoryStatusChanged,
+		Data:    data,
+	})
 	if err != nil {
 		s.log.Debug(
-			"todos: MarkAsNotComplete(): could not mark todo as not complete in db",
+			"todos: setStatus(): could not set status in db",
 			logging.String("error", err.Error()),
 		)
 		return err
 	}
 
+	s.publishTodo(ctx, events.TodoStatusChanged, todo.ID)
+	switch {
+	case completed && !todo.Completed:
+		s.publishTodo(ctx, events.TodoCompleted, todo.ID)
+	case !completed && todo.Completed:
+		s.publishTodo(ctx, events.TodoUncompleted, todo.ID)
+	}
 	return nil
 }
 
+// todoWorkflow returns a todo with the workflow of its list
+func (s *service) todoWorkflow(ctx context.Context, id string) (Todo, Workflow, error) {
+	todo, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return Todo{}, Workflow{}, err
+	}
+	w, err := s.workflow(ctx, todo.ListID)
+	return todo, w, err
+}
+
+// workflow returns the workflow of a list, todos
+// without a list follow the default one
+func (s *service) workflow(ctx context.Context, listID string) (Workflow, error) {
+	if listID == "" || s.lists == nil {
+		return DefaultWorkflow, nil
+	}
+	w, err := s.lists.Workflow(ctx, listID)
+	if err != nil {
+		s.log.Debug(
+			"todos: workflow(): could not get workflow from db",
+			logging.String("error", err.Error()),
+		)
+		return Workflow{}, err
+	}
+	return w, nil
+}
+
 func (s *service) Delete(ctx context.Context, userID, id string) error {
 	defer s.log.Sync()
 	s.log.Info("todos: Delete(): start")
 
-	ok, err := s.isAllowed(ctx, userID, id)
-	if err != nil {
+	if err := s.authorize(ctx, userID, id, shares.PermissionOwner); err != nil {
 		s.log.Debug(
-			"todos: Delete(): isAllowed returned error",
+			"todos: Delete(): user is not allowed",
+			logging.String("userID", userID),
 			logging.String("error", err.Error()),
 		)
 		return err
 	}
-	if !ok {
+
+	// we need to know whom to notify after it is gone
+	todo, err := s.repo.Get(ctx, id)
+	if err != nil {
 		s.log.Debug(
-			"todos: Delete(): user is not allowed",
-			logging.String("userID", userID),
+			"todos: Delete(): could not get todo from db",
+			logging.String("error", err.Error()),
 		)
-		return ErrNotAllowed
+		return err
 	}
+	viewers := s.viewers(ctx, todo)
 
 	err = s.repo.Delete(ctx, id)
 	if err != nil {
@@ -249,25 +618,271 @@ func (s *service) Delete(ctx context.Context, userID, id string) error {
 		return err
 	}
 
+	s.publishTo(ctx, events.TodoDeleted, todo, viewers)
 	return nil
 }
 
-// TODO: rewrite this function so we wont use repo calls at all
-// We can get all the info we need from users accessKey so yeah
-func (s *service) isAllowed(ctx context.Context, userID, todoID string) (bool, error) {
+func (s *service) Assign(ctx context.Context, userID string, inp AssignInput) error {
+	defer s.log.Sync()
+	s.log.Info("todos: Assign(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug(
+			"todos: Assign(): validation failed",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	p, err := s.permission(ctx, userID, shares.ResourceTodo, inp.ID)
+	if err != nil {
+		s.log.Debug(
+			"todos: Assign(): could not get permission",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	if !p.Allows(shares.PermissionViewer) {
+		return ErrNoSuchTodo
+	}
+	if !p.Allows(shares.PermissionEditor) {
+		return ErrNotAllowed
+	}
+
+	todo, err := s.repo.Get(ctx, inp.ID)
+	if err != nil {
+		s.log.Debug(
+			"todos: Assign(): could not get todo from db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	current := todo.AssigneeID()
+	if current == inp.AssigneeID {
+		return nil
+	}
+	if current != "" && current != userID && !p.Allows(shares.PermissionOwner) {
+		return ErrReassign
+	}
+
+	action := HistoryUnassigned
+	if inp.AssigneeID != "" {
+		action = HistoryAssigned
+		// admins are not special here, assignee needs a real access
+		ap, err := s.access.Grant(ctx, inp.AssigneeID, shares.ResourceTodo, inp.ID)
+		if err != nil {
+			s.log.Debug(
+				"todos: Assign(): could not get permission of assignee",
+				logging.String("error", err.Error()),
+			)
+			return err
+		}
+		if !ap.Allows(shares.PermissionViewer) {
+			return ErrAssigneeNoAccess
+		}
+	}
+
+	data, err := json.Marshal(HistoryAssignment{From: current, To: inp.AssigneeID})
+	if err != nil {
+		return err
+	}
+	entryID, err := s.repo.Assign(ctx, inp.ID, inp.AssigneeID, HistoryEntry{
+		TodoID:  inp.ID,
+		ActorID: userID,
+		Action:  action,
+		Data:    data,
+	})
+	if err != nil {
+		s.log.Debug(
+			"todos: Assign(): could not assign todo in db",
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+
+	s.publishTodo(ctx, events.TodoAssigned, inp.ID)
+	if inp.AssigneeID != "" && inp.AssigneeID != userID {
+		s.notifyAssignee(ctx, userID, inp.AssigneeID, entryID, todo)
+	}
+	return nil
+}
+
+// notifyAssignee never fails, since the assignment itself is already saved
+func (s *service) notifyAssignee(ctx context.Context, actorID, assigneeID, entryID string, todo Todo) {
+	if s.inbox == nil {
+		return
+	}
+	actor, err := s.uRepo.Get(ctx, actorID)
+	if err != nil {
+		s.log.Error(
+			"todos: notifyAssignee(): could not get user from db",
+			logging.String("error", err.Error()),
+		)
+		return
+	}
+	err = s.inbox.Notify(ctx, notifications.Notification{
+		UserID: assigneeID,
+		Type:   notifications.TypeAssignment,
+		Title:  actor.Username + " assigned a todo to you",
+		Body:   todo.Title,
+		TodoID: todo.ID,
+		Key:    "assignment:" + entryID,
+	})
+	if err != nil {
+		s.log.Error(
+			"todos: notifyAssignee(): could not notify assignee",
+			logging.String("error", err.Error()),
+		)
+	}
+}
+
+func (s *service) GetHistory(ctx context.Context, userID, id string) ([]HistoryEntry, error) {
+	defer s.log.Sync()
+	s.log.Info("todos: GetHistory(): start")
+
+	if err := s.authorize(ctx, userID, id, shares.PermissionViewer); err != nil {
+		s.log.Debug(
+			"todos: GetHistory(): user is not allowed",
+			logging.String("userID", userID),
+			logging.String("error", err.Error()),
+		)
+		return nil, err
+	}
+
+	history, err := s.repo.GetHistory(ctx, id)
+	if err != nil {
+		s.log.Debug(
+			"todos: GetHistory(): could not get history from db",
+			logging.String("error", err.Error()),
+		)
+		return nil, err
+	}
+	return history, nil
+}
+
+func (s *service) Authorize(ctx context.Context, userID, id string, need shares.Permission) error {
+	defer s.log.Sync()
+	s.log.Info("todos: Authorize(): start")
+
+	return s.authorize(ctx, userID, id, need)
+}
+
+// publishTodo gets a fresh copy of a todo and publishes it
+func (s *service) publishTodo(ctx context.Context, typ events.Type, id string) {
+	todo, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Error(
+			"todos: publishTodo(): could not get todo from db",
+			logging.String("id", id),
+			logging.String("error", err.Error()),
+		)
+		return
+	}
+	s.publish(ctx, typ, todo)
+}
+
+// publish never fails, since the change itself is already saved
+func (s *service) publish(ctx context.Context, typ events.Type, todo Todo) {
+	s.publishTo(ctx, typ, todo, s.viewers(ctx, todo))
+}
+
+// publishTo publishes the event once for all viewers,
+// the bus gives each of them a copy with the same id
+func (s *service) publishTo(ctx context.Context, typ events.Type, todo Todo, viewers []string) {
+	if s.publisher == nil || len(viewers) == 0 {
+		return
+	}
+	data, err := json.Marshal(todo)
+	if err != nil {
+		s.log.Error("todos: publish(): could not marshal todo", logging.String("error", err.Error()))
+		return
+	}
+	err = s.publisher.Publish(ctx, events.Event{
+		Type:    typ,
+		UserIDs: viewers,
+		TodoID:  todo.ID,
+		ListID:  todo.ListID,
+		Data:    data,
+	})
+	if err != nil {
+		s.log.Error(
+			"todos: publish(): could not publish event",
+			logging.String("type", string(typ)),
+			logging.String("error", err.Error()),
+		)
+	}
+}
+
+// viewers returns everybody who should hear about changes of a todo,
+// its author still does if we can't find out the rest
+func (s *service) viewers(ctx context.Context, todo Todo) []string {
+	if s.publisher == nil {
+		return nil
+	}
+	viewers, err := s.access.Viewers(ctx, shares.ResourceTodo, todo.ID)
+	if err == nil {
+		return viewers
+	}
+	s.log.Error(
+		"todos: viewers(): could not get viewers of todo",
+		logging.String("id", todo.ID),
+		logging.String("error", err.Error()),
+	)
+	if todo.Author == nil {
+		return nil
+	}
+	return []string{todo.Author.ID}
+}
+
+// permission tells what a user can do with a todo or a list.
+// Admins can do everything.
+func (s *service) permission(ctx context.Context, userID string, typ shares.ResourceType, id string) (shares.Permission, error) {
 	u, err := s.uRepo.Get(ctx, userID)
 	if err != nil {
-		return false, err
+		return shares.PermissionNone, err
 	}
 	if u.Role == users.RoleAdmin {
-		return true, nil
+		// admins own everything, but only in the workspace they work in
+		_, err := s.access.Resource(ctx, typ, id)
+		if err == shares.ErrNoSuchResource {
+			return shares.PermissionNone, nil
+		}
+		if err != nil {
+			return shares.PermissionNone, err
+		}
+		return shares.PermissionOwner, nil
+	}
+	return s.access.Grant(ctx, userID, typ, id)
+}
+
+// authorize returns ErrNoSuchTodo if the user can't even see the todo,
+// so nobody can find out which todos exist, and ErrNotAllowed if the user
+// can see it but needs more than that
+func (s *service) authorize(ctx context.Context, userID, todoID string, need shares.Permission) error {
+	p, err := s.permission(ctx, userID, shares.ResourceTodo, todoID)
+	if err != nil {
+		return err
+	}
+	if !p.Allows(shares.PermissionViewer) {
+		return ErrNoSuchTodo
+	}
+	if !p.Allows(need) {
+		return ErrNotAllowed
 	}
-	t, err := s.repo.Get(ctx, todoID)
+	return nil
+}
+
+// authorizeList does the same as authorize but for lists
+func (s *service) authorizeList(ctx context.Context, userID, listID string, need shares.Permission) error {
+	p, err := s.permission(ctx, userID, shares.ResourceList, listID)
 	if err != nil {
-		return false, err
+		return err
+	}
+	if !p.Allows(shares.PermissionViewer) {
+		return ErrNoSuchList
 	}
-	if t.Author.ID == u.ID {
-		return true, nil
+	if !p.Allows(need) {
+		return ErrNotAllowed
 	}
-	return false, nil
+	return nil
 }
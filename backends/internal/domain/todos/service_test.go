@@ -0,0 +1,389 @@
+package todos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	listID    string
+	status    Status
+	completed bool
+	assignee  *users.User
+	history   []HistoryEntry
+	moves     []MoveInput
+	blockers  []string
+	fields    map[string]json.RawMessage
+}
+
+func (f *fakeRepository) Get(ctx context.Context, id string) (Todo, error) {
+	return Todo{
+		ID:        id,
+		Author:    &users.User{ID: "owner"},
+		ListID:    f.listID,
+		Status:    f.status,
+		Completed: f.completed,
+		Assignee:  f.assignee,
+	}, nil
+}
+
+func (f *fakeRepository) Assign(ctx context.Context, id, assigneeID string, entry HistoryEntry) (string, error) {
+	f.assignee = nil
+	if assigneeID != "" {
+		f.assignee = &users.User{ID: assigneeID}
+	}
+	f.history = append(f.history, entry)
+	return "1", nil
+}
+
+func (f *fakeRepository) SetStatus(ctx context.Context, id string, status Status, completed bool, entry HistoryEntry) error {
+	f.status = status
+	f.completed = completed
+	f.history = append(f.history, entry)
+	return nil
+}
+
+func (f *fakeRepository) Update(ctx context.Context, inp UpdateInput) error {
+	if inp.ListID != nil {
+		f.listID = *inp.ListID
+	}
+	return nil
+}
+
+func (f *fakeRepository) Move(ctx context.Context, inp MoveInput) error {
+	f.moves = append(f.moves, inp)
+	return nil
+}
+
+func (f *fakeRepository) OpenBlockers(ctx context.Context, id string) ([]string, error) {
+	return f.blockers, nil
+}
+
+func (f *fakeRepository) SetFields(ctx context.Context, id string, set map[string]json.RawMessage, remove []string) error {
+	f.fields = set
+	for _, key := range remove {
+		f.fields[key] = nil
+	}
+	return nil
+}
+
+type fakeLists map[string]Workflow
+
+func (f fakeLists) Workflow(ctx context.Context, listID string) (Workflow, error) {
+	return f[listID], nil
+}
+
+func (f fakeLists) Fields(ctx context.Context, listID string) (Fields, error) {
+	return Fields{
+		{Key: "estimate", Name: "Estimate", Type: FieldNumber},
+		{Key: "stage", Name: "Stage", Type: FieldSelect, Options: []string{"alpha", "beta"}},
+	}, nil
+}
+
+type fakeUsers struct{}
+
+func (fakeUsers) Get(ctx context.Context, id string) (users.User, error) {
+	return users.User{ID: id, Role: users.RoleUser}, nil
+}
+
+type fakeAccess map[string]shares.Permission
+
+func (f fakeAccess) Grant(ctx context.Context, userID string, typ shares.ResourceType, id string) (shares.Permission, error) {
+	return f[userID], nil
+}
+
+func (f fakeAccess) Resource(ctx context.Context, typ shares.ResourceType, id string) (shares.Resource, error) {
+	return shares.Resource{Type: typ, ID: id, OwnerID: "owner"}, nil
+}
+
+func (f fakeAccess) Viewers(ctx context.Context, typ shares.ResourceType, id string) ([]string, error) {
+	var ids []string
+	for userID, p := range f {
+		if p.Allows(shares.PermissionViewer) {
+			ids = append(ids, userID)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+type fakePublisher []events.Event
+
+func (f *fakePublisher) Publish(ctx context.Context, e events.Event) error {
+	*f = append(*f, e)
+	return nil
+}
+
+func TestServiceConsultsShares(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{}
+	access := fakeAccess{"owner": shares.PermissionOwner, "viewer": shares.PermissionViewer, "editor": shares.PermissionEditor}
+	s := NewService(repo, fakeUsers{}, access, nil, nil, nil, logger, nil)
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "stranger", "1"); !errors.Is(err, ErrNoSuchTodo) {
+		t.Errorf("expected stranger to get ErrNoSuchTodo, got %v", err)
+	}
+	if _, err := s.Get(ctx, "viewer", "1"); err != nil {
+		t.Errorf("expected viewer to get the todo, got %v", err)
+	}
+	if err := s.MarkAsComplete(ctx, "viewer", "1", false); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected viewer to get ErrNotAllowed, got %v", err)
+	}
+	if err := s.MarkAsComplete(ctx, "editor", "1", false); err != nil || !repo.completed {
+		t.Errorf("expected editor to complete the todo, got %v", err)
+	}
+	if err := s.Delete(ctx, "editor", "1"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected only owner to delete the todo, got %v", err)
+	}
+}
+
+func TestEventsReachEveryViewer(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{listID: "l"}
+	access := fakeAccess{"owner": shares.PermissionOwner, "viewer": shares.PermissionViewer, "editor": shares.PermissionEditor}
+	publisher := &fakePublisher{}
+	s := NewService(repo, fakeUsers{}, access, nil, nil, publisher, logger, nil)
+
+	if err := s.MarkAsComplete(context.Background(), "editor", "1", false); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range *publisher {
+		if e.Type != events.TodoCompleted {
+			continue
+		}
+		if e.TodoID != "1" || e.ListID != "l" {
+			t.Errorf("expected event of todo 1 in list l, got %+v", e)
+		}
+		got = append(got, e.UserIDs...)
+	}
+	if strings.Join(got, ",") != "editor,owner,viewer" {
+		t.Errorf("expected editor, owner and viewer to get events, got %v", got)
+	}
+}
+
+func TestAssignRules(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{}
+	access := fakeAccess{"owner": shares.PermissionOwner, "viewer": shares.PermissionViewer, "editor": shares.PermissionEditor}
+	s := NewService(repo, fakeUsers{}, access, nil, nil, nil, logger, validation.NewValidator())
+	ctx := context.Background()
+
+	if err := s.Assign(ctx, "editor", AssignInput{ID: "1", AssigneeID: "stranger"}); !errors.Is(err, ErrAssigneeNoAccess) {
+		t.Errorf("expected ErrAssigneeNoAccess, got %v", err)
+	}
+	if err := s.Assign(ctx, "editor", AssignInput{ID: "1", AssigneeID: "viewer"}); err != nil {
+		t.Fatalf("expected editor to assign an unassigned todo, got %v", err)
+	}
+	if err := s.Assign(ctx, "editor", AssignInput{ID: "1", AssigneeID: "editor"}); !errors.Is(err, ErrReassign) {
+		t.Errorf("expected editor not to take the todo from viewer, got %v", err)
+	}
+	if err := s.Assign(ctx, "owner", AssignInput{ID: "1"}); err != nil {
+		t.Errorf("expected owner to unassign the todo, got %v", err)
+	}
+	if len(repo.history) != 2 || repo.history[1].Action != HistoryUnassigned {
+		t.Errorf("expected assigned and unassigned entries, got %+v", repo.history)
+	}
+}
+
+func TestStatuses(t *testing.T) {
+	logger := logging.NewNop()
+	review := Workflow{
+		Statuses: []WorkflowStatus{
+			{Key: "todo", Name: "To do"},
+			{Key: "review", Name: "Review"},
+			{Key: "shipped", Name: "Shipped", Terminal: true},
+		},
+		Transitions: map[Status][]Status{"todo": {"review"}},
+	}
+	if err := review.Check(); err != nil {
+		t.Fatalf("expected workflow to be valid, got %v", err)
+	}
+	repo := &fakeRepository{listID: "l", status: "todo"}
+	access := fakeAccess{"editor": shares.PermissionEditor}
+	s := NewService(repo, fakeUsers{}, access, fakeLists{"l": review}, nil, nil, logger, validation.NewValidator())
+	ctx := context.Background()
+
+	if err := s.SetStatus(ctx, "editor", SetStatusInput{ID: "1", Status: StatusInProgress}); !errors.Is(err, ErrUnknownStatus) {
+		t.Errorf("expected ErrUnknownStatus, got %v", err)
+	}
+	if err := s.SetStatus(ctx, "editor", SetStatusInput{ID: "1", Status: "shipped"}); !errors.Is(err, ErrTransition) {
+		t.Errorf("expected ErrTransition, got %v", err)
+	}
+	if err := s.SetStatus(ctx, "editor", SetStatusInput{ID: "1", Status: "review"}); err != nil || repo.status != "review" || repo.completed {
+		t.Errorf("expected todo to go to review, got %v with %+v", err, repo)
+	}
+	if err := s.MarkAsComplete(ctx, "editor", "1", false); err != nil || repo.status != "shipped" || !repo.completed {
+		t.Errorf("expected todo to be shipped and completed, got %v with %+v", err, repo)
+	}
+	if err := s.MarkAsNotComplete(ctx, "editor", "1"); err != nil || repo.status != "todo" || repo.completed {
+		t.Errorf("expected todo to be reopened, got %v with %+v", err, repo)
+	}
+	if len(repo.history) != 3 || repo.history[0].Action != HistoryStatusChanged {
+		t.Errorf("expected three status changes in history, got %+v", repo.history)
+	}
+
+	board := review.group([]Todo{{ID: "1", Status: "review"}, {ID: "2", Status: StatusBacklog}})
+	if len(board.Columns) != 4 || len(board.Columns[1].Todos) != 1 || board.Columns[3].Key != StatusBacklog {
+		t.Errorf("expected todos of unknown statuses in their own column, got %+v", board.Columns)
+	}
+	if err := (Workflow{Statuses: []WorkflowStatus{{Key: "done", Terminal: true}, {Key: "todo"}}}).Check(); !errors.Is(err, ErrInvalidWorkflow) {
+		t.Errorf("expected terminal first status to be invalid, got %v", err)
+	}
+}
+
+func TestMoveToListWhereStatusIsTerminal(t *testing.T) {
+	logger := logging.NewNop()
+	lists := fakeLists{
+		"l":      {Statuses: []WorkflowStatus{{Key: "todo"}, {Key: "review"}, {Key: "shipped", Terminal: true}}},
+		"strict": {Statuses: []WorkflowStatus{{Key: "todo"}, {Key: "review", Terminal: true}}},
+	}
+	repo := &fakeRepository{listID: "l", status: "review"}
+	access := fakeAccess{"owner": shares.PermissionOwner}
+	s := NewService(repo, fakeUsers{}, access, lists, nil, nil, logger, validation.NewValidator())
+	ctx := context.Background()
+
+	listID := "strict"
+	if err := s.Update(ctx, "owner", UpdateInput{ID: "1", Title: "move me please", ListID: &listID}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.status != "review" || !repo.completed {
+		t.Errorf("expected todo to stay in review and be completed, got %+v", repo)
+	}
+
+	listID = "l"
+	if err := s.Update(ctx, "owner", UpdateInput{ID: "1", Title: "move me please", ListID: &listID}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.status != "review" || repo.completed {
+		t.Errorf("expected todo to stay in review and be reopened, got %+v", repo)
+	}
+}
+
+func TestMove(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{}
+	access := fakeAccess{"viewer": shares.PermissionViewer, "editor": shares.PermissionEditor}
+	s := NewService(repo, fakeUsers{}, access, nil, nil, nil, logger, validation.NewValidator())
+	ctx := context.Background()
+
+	if err := s.Move(ctx, "editor", MoveInput{ID: "1"}); err == nil {
+		t.Error("expected move without anchors to fail")
+	}
+	if err := s.Move(ctx, "editor", MoveInput{ID: "1", AfterID: "1"}); !errors.Is(err, ErrInvalidAnchor) {
+		t.Errorf("expected ErrInvalidAnchor, got %v", err)
+	}
+	if err := s.Move(ctx, "viewer", MoveInput{ID: "1", AfterID: "2"}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected ErrNotAllowed, got %v", err)
+	}
+	if err := s.Move(ctx, "editor", MoveInput{ID: "1", AfterID: "2", BeforeID: "3"}); err != nil || len(repo.moves) != 1 {
+		t.Errorf("expected todo to be moved, got %v with %+v", err, repo.moves)
+	}
+
+	lo, hi, close := 1024.0, 2048.0, 1024.0+MinPositionGap/2
+	if p, ok := PositionBetween(&lo, &hi); !ok || p != 1536 {
+		t.Errorf("expected 1536 between neighbours, got %v", p)
+	}
+	if p, ok := PositionBetween(nil, &lo); !ok || p >= lo {
+		t.Errorf("expected a position before the first todo, got %v", p)
+	}
+	if p, ok := PositionBetween(&hi, nil); !ok || p <= hi {
+		t.Errorf("expected a position after the last todo, got %v", p)
+	}
+	if _, ok := PositionBetween(&lo, &close); ok {
+		t.Error("expected crowded neighbours to need rebalancing")
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	logger := logging.NewNop()
+
+	// c waits for b and b waits for a
+	g := Graph{"c": {"b"}, "b": {"a"}}
+	if path := g.Path("c", "a"); len(path) != 3 {
+		t.Errorf("expected c to lead to a through b, got %v", path)
+	}
+	if path := g.Path("a", "c"); path != nil {
+		t.Errorf("expected a to wait for nobody, got %v", path)
+	}
+	sorted, err := g.Sort([]string{"c", "d", "b", "a"})
+	if err != nil || strings.Join(sorted, "") != "dabc" {
+		t.Errorf("expected dabc, got %v with %v", sorted, err)
+	}
+	g["a"] = []string{"c"}
+	if _, err := g.Sort([]string{"a", "b", "c"}); !errors.Is(err, ErrCycle) {
+		t.Errorf("expected ErrCycle, got %v", err)
+	}
+
+	repo := &fakeRepository{status: StatusBacklog, blockers: []string{"2"}}
+	access := fakeAccess{"editor": shares.PermissionEditor}
+	s := NewService(repo, fakeUsers{}, access, nil, nil, nil, logger, validation.NewValidator())
+	ctx := context.Background()
+
+	if err := s.AddBlocker(ctx, "editor", DependencyInput{ID: "1", BlockerID: "1"}); !errors.Is(err, ErrCycle) {
+		t.Errorf("expected todo blocking itself to be a cycle, got %v", err)
+	}
+	if err := s.MarkAsComplete(ctx, "editor", "1", false); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+	if err := s.SetStatus(ctx, "editor", SetStatusInput{ID: "1", Status: StatusDone}); !errors.Is(err, ErrBlocked) {
+		t.Errorf("expected ErrBlocked, got %v", err)
+	}
+	if err := s.SetStatus(ctx, "editor", SetStatusInput{ID: "1", Status: StatusReview}); err != nil {
+		t.Errorf("expected blocked todo to move to review, got %v", err)
+	}
+	if err := s.MarkAsComplete(ctx, "editor", "1", true); err != nil || !repo.completed {
+		t.Errorf("expected forced todo to be completed, got %v with %+v", err, repo)
+	}
+}
+
+func TestFields(t *testing.T) {
+	logger := logging.NewNop()
+	v := validation.NewValidator()
+	if err := RegisterFieldValidation(v); err != nil {
+		t.Fatal(err)
+	}
+	repo := &fakeRepository{listID: "l"}
+	access := fakeAccess{"editor": shares.PermissionEditor}
+	s := NewService(repo, fakeUsers{}, access, fakeLists{}, nil, nil, logger, v)
+	ctx := context.Background()
+
+	set := func(fields string) error {
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(fields), &values); err != nil {
+			t.Fatal(err)
+		}
+		return s.SetFields(ctx, "editor", SetFieldsInput{ID: "1", Fields: values})
+	}
+	if err := set(`{"sprint": "12"}`); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("expected ErrUnknownField, got %v", err)
+	}
+	if err := set(`{"estimate": "five"}`); !errors.Is(err, ErrInvalidFieldValue) {
+		t.Errorf("expected text in a number field to be invalid, got %v", err)
+	}
+	if err := set(`{"stage": "gamma"}`); !errors.Is(err, ErrInvalidFieldValue) {
+		t.Errorf("expected unknown option to be invalid, got %v", err)
+	}
+	if err := set(`{"estimate": 5, "stage": null}`); err != nil || string(repo.fields["estimate"]) != "5" {
+		t.Errorf("expected estimate to be set, got %v with %v", err, repo.fields)
+	}
+	if v, ok := repo.fields["stage"]; !ok || v != nil {
+		t.Errorf("expected stage to be removed, got %v", repo.fields)
+	}
+
+	if err := (Fields{{Key: "a", Type: FieldText}, {Key: "a", Type: FieldDate}}).Check(); !errors.Is(err, ErrInvalidFields) {
+		t.Errorf("expected duplicate keys to be invalid, got %v", err)
+	}
+}
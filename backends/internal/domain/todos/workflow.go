@@ -0,0 +1,168 @@
+package todos
+
+const (
+	StatusBacklog    Status = "backlog"
+	StatusInProgress Status = "in_progress"
+	StatusReview     Status = "review"
+	StatusDone       Status = "done"
+)
+
+type (
+	// Status is a column of a board that a todo is in
+	Status string
+
+	WorkflowStatus struct {
+		Key  Status `json:"key" validate:"required,lte=40"`
+		Name string `json:"name" validate:"required,lte=100"`
+		// Terminal statuses complete todos
+		Terminal bool `json:"terminal"`
+	}
+
+	// Workflow is a set of statuses todos of a list go through. New todos
+	// start in the first status, so it can't be terminal. Todos can go from
+	// a status only to statuses listed in Transitions for it, statuses that
+	// are not listed there allow going anywhere.
+	Workflow struct {
+		Statuses    []WorkflowStatus    `json:"statuses" validate:"required,min=2,max=20,dive"`
+		Transitions map[Status][]Status `json:"transitions,omitempty"`
+	}
+
+	// Board is todos grouped by their statuses
+	Board struct {
+		Workflow Workflow      `json:"workflow"`
+		Columns  []BoardColumn `json:"columns"`
+	}
+
+	BoardColumn struct {
+		WorkflowStatus
+		Todos []Todo `json:"todos"`
+	}
+)
+
+// DefaultWorkflow is used for todos without a list
+// and for lists that don't have their own workflow
+var DefaultWorkflow = Workflow{
+	Statuses: []WorkflowStatus{
+		{Key: StatusBacklog, Name: "Backlog"},
+		{Key: StatusInProgress, Name: "In progress"},
+		{Key: StatusReview, Name: "Review"},
+		{Key: StatusDone, Name: "Done", Terminal: true},
+	},
+}
+
+// Check finds mistakes that struct tags can't find
+func (w Workflow) Check() error {
+	seen := make(map[Status]bool, len(w.Statuses))
+	for _, st := range w.Statuses {
+		if seen[st.Key] {
+			return ErrInvalidWorkflow
+		}
+		seen[st.Key] = true
+	}
+	if len(w.Statuses) == 0 || w.Statuses[0].Terminal || w.Done() == "" {
+		return ErrInvalidWorkflow
+	}
+	for from, to := range w.Transitions {
+		if !seen[from] {
+			return ErrInvalidWorkflow
+		}
+		for _, st := range to {
+			if !seen[st] {
+				return ErrInvalidWorkflow
+			}
+		}
+	}
+	return nil
+}
+
+// Initial is the status new and reopened todos are in
+func (w Workflow) Initial() Status {
+	if len(w.Statuses) == 0 {
+		return ""
+	}
+	return w.Statuses[0].Key
+}
+
+// Done is the first terminal status, todos completed
+// without choosing a status go there
+func (w Workflow) Done() Status {
+	for _, st := range w.Statuses {
+		if st.Terminal {
+			return st.Key
+		}
+	}
+	return ""
+}
+
+func (w Workflow) Has(s Status) bool {
+	for _, st := range w.Statuses {
+		if st.Key == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (w Workflow) Terminal(s Status) bool {
+	for _, st := range w.Statuses {
+		if st.Key == s {
+			return st.Terminal
+		}
+	}
+	return false
+}
+
+// Allows tells if a todo can go from one status to another.
+// Todos in statuses the workflow doesn't know can go anywhere.
+func (w Workflow) Allows(from, to Status) bool {
+	if !w.Has(to) {
+		return false
+	}
+	next, ok := w.Transitions[from]
+	if !ok {
+		return true
+	}
+	for _, st := range next {
+		if st == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Fit finds a status for a todo that moves into this workflow,
+// todos keep their status if the workflow has it
+func (w Workflow) Fit(s Status, completed bool) Status {
+	switch {
+	case w.Has(s):
+		return s
+	case completed:
+		return w.Done()
+	}
+	return w.Initial()
+}
+
+// group puts todos into columns of the workflow. Todos from other
+// lists might be in statuses it doesn't have, they get their own
+// columns at the end.
+func (w Workflow) group(todos []Todo) Board {
+	board := Board{Workflow: w, Columns: []BoardColumn{}}
+	index := map[Status]int{}
+	for _, st := range w.Statuses {
+		index[st.Key] = len(board.Columns)
+		board.Columns = append(board.Columns, BoardColumn{WorkflowStatus: st, Todos: []Todo{}})
+	}
+	for _, t := range todos {
+		i, ok := index[t.Status]
+		if !ok {
+			i = len(board.Columns)
+			index[t.Status] = i
+			board.Columns = append(board.Columns, BoardColumn{
+				WorkflowStatus: WorkflowStatus{Key: t.Status, Name: string(t.Status), Terminal: t.Completed},
+				Todos:          []Todo{},
+			})
+		}
+		board.Columns[i].Todos = append(board.Columns[i].Todos, t)
+	}
+	return board
+}
@@ -0,0 +1,123 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func (s *service) ScheduleDeletion(ctx context.Context, id, password string) (time.Time, error) {
+	defer s.log.Sync()
+	s.log.Info("users: ScheduleDeletion(): start")
+	now := time.Now()
+
+	user, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("users: ScheduleDeletion(): could not get user", logging.String("error", err.Error()))
+		return time.Time{}, err
+	}
+
+	// someone with a stolen access key should not be able
+	// to guess password here either
+	key := accountKey(user.Email)
+	locked, err := s.isLocked(ctx, now, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if locked {
+		return time.Time{}, ErrTooManyAttempts
+	}
+	if err := comparePassword(password, user.PasswordHash); err != nil {
+		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			s.log.Error("users: ScheduleDeletion(): could not compare passwords", logging.String("error", err.Error()))
+		}
+		if err := s.registerFailure(ctx, now, key); err != nil {
+			return time.Time{}, err
+		}
+		return time.Time{}, ErrWrongPassword
+	}
+
+	// workspaces can't be left without an owner
+	owns, err := s.repo.OwnsWorkspaces(ctx, id)
+	if err != nil {
+		s.log.Debug("users: ScheduleDeletion(): could not check workspaces", logging.String("error", err.Error()))
+		return time.Time{}, err
+	}
+	if owns {
+		return time.Time{}, ErrOwnsWorkspace
+	}
+
+	deleteAfter := now.UTC().Add(s.gracePeriod)
+	if err := s.repo.ScheduleDeletion(ctx, id, deleteAfter); err != nil {
+		s.log.Debug("users: ScheduleDeletion(): could not schedule", logging.String("error", err.Error()))
+		return time.Time{}, err
+	}
+	s.log.Info("users: ScheduleDeletion(): scheduled", logging.String("id", id))
+	return deleteAfter, nil
+}
+
+func (s *service) RunDeletions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.deleteScheduled(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) deleteScheduled(ctx context.Context) {
+	defer s.log.Sync()
+	ids, err := s.repo.GetScheduledForDeletion(ctx, time.Now().UTC())
+	if err != nil {
+		s.log.Error("users: deleteScheduled(): could not get users", logging.String("error", err.Error()))
+		return
+	}
+	for _, id := range ids {
+		err := s.purge(ctx, id)
+		if errors.Is(err, ErrOwnsWorkspace) {
+			// a workspace was created during the grace period,
+			// deleting would be tried again and again otherwise
+			if err := s.repo.CancelDeletion(ctx, id); err != nil {
+				s.log.Error("users: deleteScheduled(): could not cancel deletion", logging.String("error", err.Error()))
+				continue
+			}
+			s.log.Info("users: deleteScheduled(): user owns a workspace, deletion was canceled", logging.String("id", id))
+			continue
+		}
+		if err != nil {
+			s.log.Error(
+				"users: deleteScheduled(): could not delete user",
+				logging.String("id", id),
+				logging.String("error", err.Error()),
+			)
+			continue
+		}
+		s.log.Info("users: deleteScheduled(): user was deleted", logging.String("id", id))
+	}
+}
+
+// purge deletes user and everything he owns
+func (s *service) purge(ctx context.Context, id string) error {
+	user, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	files, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			s.log.Error("users: purge(): could not remove file", logging.String("error", err.Error()))
+		}
+	}
+	return s.attempts.Reset(ctx, accountKey(user.Email))
+}
@@ -23,12 +23,15 @@ type (
 	JWTaccess struct {
 		ID   string `json:"userID"`
 		Role Role   `json:"role"`
+		// Version is the token version of the user when the key was issued
+		Version int `json:"ver"`
 
 		jwt.StandardClaims
 	}
 
 	JWTrefresh struct {
-		ID string `json:"userID"`
+		ID      string `json:"userID"`
+		Version int    `json:"ver"`
 
 		jwt.StandardClaims
 	}
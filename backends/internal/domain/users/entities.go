@@ -25,9 +25,23 @@ type (
 
 		Role Role `json:"role"`
 
+		// DeleteAfter is set when user asked to delete his account
+		DeleteAfter *time.Time `json:"deleteAfter,omitempty"`
+		// TokenVersion is bumped to revoke all keys of the user
+		TokenVersion int `json:"-"`
+
 		CreatedAt time.Time `json:"createdAt"`
 		UpdatedAt time.Time `json:"updatedAt"`
 	}
+
+	// LoginAttempts keeps track of failed sign ins for a single key.
+	// Key is either an account (email) or a client ip.
+	LoginAttempts struct {
+		Key           string    `json:"key"`
+		Failures      int       `json:"failures"`
+		LastFailureAt time.Time `json:"lastFailureAt"`
+		LockedUntil   time.Time `json:"lockedUntil"`
+	}
 )
 
 func comparePassword(password, hash string) error {
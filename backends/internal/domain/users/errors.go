@@ -10,4 +10,16 @@ var (
 	ErrInvalidPassword   = errors.New("password has to be longer than 6 and shorter than 60 characters")
 	ErrWrongPassword     = errors.New("wrong password")
 	ErrInvalidRefreshKey = errors.New("invalid refresh key")
+	ErrInvalidAccessKey  = errors.New("invalid access key")
+	// ErrRevokedKey is returned for keys issued before the
+	// token version of their user was bumped
+	ErrRevokedKey = errors.New("key was revoked")
+	// ErrOwnsWorkspace is returned when deleting a user would leave
+	// workspaces without an owner
+	ErrOwnsWorkspace = errors.New("delete your workspaces before deleting the account")
+
+	// ErrInvalidCredentials is returned from SignIn for both unknown emails
+	// and wrong passwords, so nobody can find out which emails are registered.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many failed sign in attempts, try again later")
 )
@@ -0,0 +1,118 @@
+package users
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type (
+	// AttemptsRepository stores failed sign in attempts.
+	// We have an in-memory implementation for a single node
+	// and a postgres one for multi-node deployments.
+	AttemptsRepository interface {
+		// Get should return zero value LoginAttempts if key was never seen
+		Get(ctx context.Context, key string) (LoginAttempts, error)
+		// Fail increments failures counter for a key and returns updated value
+		Fail(ctx context.Context, key string, at time.Time) (failures int, err error)
+		Lock(ctx context.Context, key string, until time.Time) error
+		Reset(ctx context.Context, key string) error
+		GetLocked(ctx context.Context, now time.Time) ([]LoginAttempts, error)
+	}
+
+	// LockoutPolicy describes when and for how long we lock keys.
+	// After MaxAttempts failures a key is locked for BaseDelay and
+	// every next failure doubles it up until MaxDelay.
+	// Failures are forgotten Window after the last of them
+	// or after the lock ends, whichever is later.
+	LockoutPolicy struct {
+		MaxAttempts int
+		BaseDelay   time.Duration
+		MaxDelay    time.Duration
+		Window      time.Duration
+	}
+)
+
+const (
+	attemptsKeyAccount = "account:"
+	attemptsKeyIP      = "ip:"
+)
+
+// dummyHash is compared against when email is not registered,
+// so signing in with unknown email takes as long as with a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password for timing"), bcrypt.DefaultCost)
+
+func accountKey(email string) string {
+	return attemptsKeyAccount + strings.ToLower(email)
+}
+
+func ipKey(ip string) string {
+	return attemptsKeyIP + ip
+}
+
+func (p LockoutPolicy) delay(failures int) time.Duration {
+	if p.MaxAttempts <= 0 || failures < p.MaxAttempts {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := p.MaxAttempts; i < failures; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return d
+}
+
+// forgets tells if failures of a key are old enough to start over.
+// Window counts from the end of a lock, otherwise locks longer than
+// Window would reset the counter and never reach MaxDelay.
+func (p LockoutPolicy) forgets(a LoginAttempts, now time.Time) bool {
+	if a.Failures == 0 {
+		return false
+	}
+	last := a.LastFailureAt
+	if a.LockedUntil.After(last) {
+		last = a.LockedUntil
+	}
+	return now.Sub(last) > p.Window
+}
+
+func (s *service) isLocked(ctx context.Context, now time.Time, keys ...string) (bool, error) {
+	for _, key := range keys {
+		a, err := s.attempts.Get(ctx, key)
+		if err != nil {
+			return false, err
+		}
+		if a.LockedUntil.After(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *service) registerFailure(ctx context.Context, now time.Time, keys ...string) error {
+	for _, key := range keys {
+		a, err := s.attempts.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		if s.lockout.forgets(a, now) {
+			if err := s.attempts.Reset(ctx, key); err != nil {
+				return err
+			}
+		}
+		failures, err := s.attempts.Fail(ctx, key, now)
+		if err != nil {
+			return err
+		}
+		if d := s.lockout.delay(failures); d != 0 {
+			if err := s.attempts.Lock(ctx, key, now.Add(d)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package users
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicyDelay(t *testing.T) {
+	p := LockoutPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Second,
+		MaxDelay:    10 * time.Second,
+	}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockoutPolicyForgets(t *testing.T) {
+	p := LockoutPolicy{Window: 15 * time.Minute}
+	now := time.Date(2022, 11, 9, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		a    LoginAttempts
+		want bool
+	}{
+		{"no failures", LoginAttempts{}, false},
+		{"recent failure", LoginAttempts{Failures: 2, LastFailureAt: now.Add(-time.Minute)}, false},
+		{"old failure", LoginAttempts{Failures: 2, LastFailureAt: now.Add(-16 * time.Minute)}, true},
+		{
+			"lock longer than window just ended",
+			LoginAttempts{Failures: 10, LastFailureAt: now.Add(-17 * time.Minute), LockedUntil: now.Add(-time.Minute)},
+			false,
+		},
+		{
+			"window passed after the lock",
+			LoginAttempts{Failures: 10, LastFailureAt: now.Add(-40 * time.Minute), LockedUntil: now.Add(-16 * time.Minute)},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		if got := p.forgets(tt.a, now); got != tt.want {
+			t.Errorf("%s: forgets() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -21,38 +21,80 @@ type (
 		Create(ctx context.Context, email, hashedPassword, username string) (id string, err error)
 		Get(ctx context.Context, id string) (user User, err error)
 		GetByEmail(ctx context.Context, email string) (user User, err error)
+		// TokenVersion should return ErrNoSuchUser if user is gone
+		TokenVersion(ctx context.Context, id string) (int, error)
 		Update(ctx context.Context, inp UpdateInput) error
-		Delete(ctx context.Context, id string) error
+		// Delete removes a user with everything that belongs to him
+		// in one transaction and bumps his token version in it. It returns
+		// paths of files that have to be removed from disk after that.
+		// Todos and lists the user made in workspaces and in lists of others
+		// are given to their owners. It should return ErrOwnsWorkspace
+		// if the user owns a workspace.
+		Delete(ctx context.Context, id string) (files []string, err error)
+		// OwnsWorkspaces tells if a user owns a workspace
+		OwnsWorkspaces(ctx context.Context, id string) (bool, error)
+
+		ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+		CancelDeletion(ctx context.Context, id string) error
+		GetScheduledForDeletion(ctx context.Context, before time.Time) (ids []string, err error)
 	}
 
 	Service interface {
 		SignUp(ctx context.Context, inp SignUpInput) (SignInOutput, error)
-		SignIn(ctx context.Context, email, password string) (SignInOutput, error)
+		// ip is used for tracking failed attempts per client
+		SignIn(ctx context.Context, email, password, ip string) (SignInOutput, error)
 
 		Me(ctx context.Context, id string) (User, error)
 
+		// UnpackAccessKey checks the signature of a key and that its
+		// user still exists and has not revoked it
 		UnpackAccessKey(ctx context.Context, accessKey string) (JWTaccess, error)
 		Refresh(ctx context.Context, refreshKey string) (SignInOutput, error)
 
 		Update(ctx context.Context, inp UpdateInput) error
 		Delete(ctx context.Context, id string) error
+
+		// ScheduleDeletion deletes users account after a grace period.
+		// Signing in during that period cancels deletion.
+		ScheduleDeletion(ctx context.Context, id, password string) (deleteAfter time.Time, err error)
+		// RunDeletions deletes accounts which grace period is over
+		// every interval until ctx is done
+		RunDeletions(ctx context.Context, interval time.Duration)
+
+		// These are for admins
+		GetLocked(ctx context.Context) ([]LoginAttempts, error)
+		Unlock(ctx context.Context, key string) error
 	}
 
 	service struct {
 		repo       Repository
+		attempts   AttemptsRepository
 		validation *validation.Validator
 		log        *logging.Logger
 
-		secretKey []byte
+		secretKey   []byte
+		lockout     LockoutPolicy
+		gracePeriod time.Duration
 	}
 )
 
-func NewService(repo Repository, logger *logging.Logger, validator *validation.Validator, secretKey []byte) (Service, error) {
+func NewService(
+	repo Repository,
+	attempts AttemptsRepository,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	secretKey []byte,
+	lockout LockoutPolicy,
+	gracePeriod time.Duration,
+) (Service, error) {
 	return &service{
-		repo:       repo,
-		log:        logger,
-		validation: validator,
-		secretKey:  secretKey,
+		repo:        repo,
+		attempts:    attempts,
+		log:         logger,
+		validation:  validator,
+		secretKey:   secretKey,
+		lockout:     lockout,
+		gracePeriod: gracePeriod,
 	}, nil
 }
 
@@ -78,34 +120,70 @@ func (s *service) SignUp(ctx context.Context, inp SignUpInput) (SignInOutput, er
 		s.log.Error("users: SignUp(): could not hash password", logging.String("error", err.Error()))
 		return SignInOutput{}, err
 	}
-	_, err = s.repo.Create(ctx, inp.Email, passwordHash, inp.Username)
+	id, err := s.repo.Create(ctx, inp.Email, passwordHash, inp.Username)
 	if err != nil {
 		s.log.Debug("users: SignUp(): could not create user in database", logging.String("error", err.Error()))
 		return SignInOutput{}, err
 	}
 
-	return s.SignIn(ctx, inp.Email, inp.Password)
+	return generateKeys(User{ID: id, Role: RoleUser}, s.secretKey, accessLifeTime, refreshLifeTime)
 }
 
 // TODO: add a remember me option
-func (s *service) SignIn(ctx context.Context, email, password string) (SignInOutput, error) {
+func (s *service) SignIn(ctx context.Context, email, password, ip string) (SignInOutput, error) {
 	defer s.log.Sync()
 	s.log.Info("users: SignIn(): start")
-	user, err := s.repo.GetByEmail(ctx, email)
+	now := time.Now()
+	keys := []string{accountKey(email), ipKey(ip)}
+
+	locked, err := s.isLocked(ctx, now, keys...)
+	if err != nil {
+		s.log.Error("users: SignIn(): could not check lockout", logging.String("error", err.Error()))
+		return SignInOutput{}, err
+	}
+	if locked {
+		s.log.Debug("users: SignIn(): locked out", logging.String("ip", ip))
+		return SignInOutput{}, ErrTooManyAttempts
+	}
+
+	user, err := s.repo.GetByEmail(ctx, strings.ToLower(email))
+	if err != nil && !errors.Is(err, ErrNoSuchUser) {
+		return SignInOutput{}, err
+	}
+	hash := user.PasswordHash
 	if err != nil {
+		// we still compare passwords so that response time
+		// does not tell if this email is registered
+		hash = string(dummyHash)
+	}
+	if cmpErr := comparePassword(password, hash); cmpErr != nil || err != nil {
+		if cmpErr != nil && !errors.Is(cmpErr, bcrypt.ErrMismatchedHashAndPassword) {
+			s.log.Error(
+				"users: SignIn(): could not compare passwords",
+				logging.String("error", cmpErr.Error()),
+			)
+		}
+		s.log.Debug("users: SignIn(): invalid credentials")
+		if err := s.registerFailure(ctx, now, keys...); err != nil {
+			s.log.Error("users: SignIn(): could not register failure", logging.String("error", err.Error()))
+			return SignInOutput{}, err
+		}
+		return SignInOutput{}, ErrInvalidCredentials
+	}
+
+	// we dont reset ip key on purpose, otherwise anyone
+	// with a valid account could keep guessing other passwords
+	if err := s.attempts.Reset(ctx, keys[0]); err != nil {
+		s.log.Error("users: SignIn(): could not reset attempts", logging.String("error", err.Error()))
 		return SignInOutput{}, err
 	}
-	if err := comparePassword(password, user.PasswordHash); err != nil {
-		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
-			s.log.Debug("users: SignIn(): wrong password")
-			return SignInOutput{}, ErrWrongPassword
+
+	if user.DeleteAfter != nil {
+		if err := s.repo.CancelDeletion(ctx, user.ID); err != nil {
+			s.log.Error("users: SignIn(): could not cancel deletion", logging.String("error", err.Error()))
+			return SignInOutput{}, err
 		}
-		// TODO: not sure what to do here. is it ok to return err from bcrypt?
-		s.log.Error(
-			"users: SignIn(): could not compare passwords",
-			logging.String("error", err.Error()),
-		)
-		return SignInOutput{}, ErrWrongPassword
+		s.log.Info("users: SignIn(): deletion was canceled", logging.String("id", user.ID))
 	}
 
 	return generateKeys(user, s.secretKey, accessLifeTime, refreshLifeTime)
@@ -152,6 +230,9 @@ func (s *service) Refresh(ctx context.Context, refreshKey string) (SignInOutput,
 		)
 		return SignInOutput{}, err
 	}
+	if claims.Version != user.TokenVersion {
+		return SignInOutput{}, ErrRevokedKey
+	}
 
 	// TODO: add a remember me option or at least think about it
 	return generateKeys(user, s.secretKey, accessLifeTime, refreshLifeTime)
@@ -166,8 +247,7 @@ func (s *service) Update(ctx context.Context, inp UpdateInput) error {
 func (s *service) Delete(ctx context.Context, id string) error {
 	defer s.log.Sync()
 	s.log.Info("users: Delete(): start")
-	err := s.repo.Delete(ctx, id)
-	if err != nil {
+	if err := s.purge(ctx, id); err != nil {
 		s.log.Debug(
 			"users: Delete(): could not delete user",
 			logging.String("id", id),
@@ -179,19 +259,46 @@ func (s *service) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (s *service) GetLocked(ctx context.Context) ([]LoginAttempts, error) {
+	defer s.log.Sync()
+	s.log.Info("users: GetLocked(): start")
+	locked, err := s.attempts.GetLocked(ctx, time.Now())
+	if err != nil {
+		s.log.Debug("users: GetLocked(): could not get locked keys", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return locked, nil
+}
+
+func (s *service) Unlock(ctx context.Context, key string) error {
+	defer s.log.Sync()
+	s.log.Info("users: Unlock(): start")
+	if err := s.attempts.Reset(ctx, key); err != nil {
+		s.log.Debug(
+			"users: Unlock(): could not reset attempts",
+			logging.String("key", key),
+			logging.String("error", err.Error()),
+		)
+		return err
+	}
+	return nil
+}
+
 func generateKeys(user User, secretKey []byte, accessEXP, refreshEXP time.Duration) (SignInOutput, error) {
 	expAccess := time.Now().Add(accessEXP)
 	expRefresh := time.Now().Add(refreshEXP)
 
 	claimsAccess := JWTaccess{
-		ID:   user.ID,
-		Role: user.Role,
+		ID:      user.ID,
+		Role:    user.Role,
+		Version: user.TokenVersion,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expAccess.Unix(),
 		},
 	}
 	claimsRefresh := JWTrefresh{
-		ID: user.ID,
+		ID:      user.ID,
+		Version: user.TokenVersion,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expRefresh.Unix(),
 		},
@@ -227,7 +334,15 @@ func (s *service) UnpackAccessKey(ctx context.Context, accessKey string) (JWTacc
 	}
 	claims, ok := token.Claims.(*JWTaccess)
 	if !ok || !token.Valid {
+		return JWTaccess{}, ErrInvalidAccessKey
+	}
+	// keys live for minutes, but deleted users should not wait for that
+	version, err := s.repo.TokenVersion(ctx, claims.ID)
+	if err != nil {
 		return JWTaccess{}, err
 	}
+	if version != claims.Version {
+		return JWTaccess{}, ErrRevokedKey
+	}
 	return *claims, nil
 }
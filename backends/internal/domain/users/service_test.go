@@ -0,0 +1,84 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type fakeRepository struct {
+	Repository
+	versions map[string]int
+	// owners own workspaces, scheduled are waiting for deletion
+	owners    map[string]bool
+	scheduled map[string]bool
+}
+
+func (f fakeRepository) Get(ctx context.Context, id string) (User, error) {
+	return User{ID: id, Email: id + "@example.com"}, nil
+}
+
+func (f fakeRepository) GetScheduledForDeletion(ctx context.Context, before time.Time) ([]string, error) {
+	var ids []string
+	for id := range f.scheduled {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (f fakeRepository) Delete(ctx context.Context, id string) ([]string, error) {
+	if f.owners[id] {
+		return nil, ErrOwnsWorkspace
+	}
+	delete(f.scheduled, id)
+	return nil, nil
+}
+
+func (f fakeRepository) CancelDeletion(ctx context.Context, id string) error {
+	delete(f.scheduled, id)
+	return nil
+}
+
+func (f fakeRepository) TokenVersion(ctx context.Context, id string) (int, error) {
+	v, ok := f.versions[id]
+	if !ok {
+		return 0, ErrNoSuchUser
+	}
+	return v, nil
+}
+
+func TestUnpackAccessKeyChecksVersion(t *testing.T) {
+	repo := fakeRepository{versions: map[string]int{"active": 0, "revoked": 1}}
+	s := &service{repo: repo, log: logging.NewNop(), secretKey: []byte("secret")}
+	ctx := context.Background()
+
+	key := func(id string) string {
+		keys, err := generateKeys(User{ID: id}, s.secretKey, time.Minute, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return keys.AccessKey
+	}
+	if _, err := s.UnpackAccessKey(ctx, key("active")); err != nil {
+		t.Errorf("expected key of an active user to work, got %v", err)
+	}
+	if _, err := s.UnpackAccessKey(ctx, key("revoked")); !errors.Is(err, ErrRevokedKey) {
+		t.Errorf("expected ErrRevokedKey after version was bumped, got %v", err)
+	}
+	if _, err := s.UnpackAccessKey(ctx, key("deleted")); !errors.Is(err, ErrNoSuchUser) {
+		t.Errorf("expected ErrNoSuchUser for a deleted user, got %v", err)
+	}
+}
+
+func TestDeleteScheduledSparesWorkspaceOwners(t *testing.T) {
+	repo := fakeRepository{owners: map[string]bool{"owner": true}, scheduled: map[string]bool{"owner": true}}
+	s := &service{repo: repo, log: logging.NewNop()}
+
+	s.deleteScheduled(context.Background())
+	if repo.scheduled["owner"] {
+		t.Error("expected deletion of a workspace owner to be canceled")
+	}
+}
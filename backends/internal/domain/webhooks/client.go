@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+)
+
+const (
+	HeaderWebhook   = "X-Webhook-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+	userAgent       = "todo-app-webhooks/1.0"
+)
+
+type (
+	// Client posts deliveries to webhooks. Unless private hosts are allowed
+	// it refuses to connect to loopback and private networks, so nobody
+	// can use webhooks to reach our internal services.
+	Client struct {
+		http         *http.Client
+		timeout      time.Duration
+		allowPrivate bool
+	}
+
+	// body is what receivers get. ID is the same for all attempts
+	// of a delivery, so they can ignore duplicates.
+	body struct {
+		ID        string          `json:"id"`
+		Type      events.Type     `json:"type"`
+		CreatedAt time.Time       `json:"createdAt"`
+		Data      json.RawMessage `json:"data"`
+	}
+)
+
+func NewClient(timeout time.Duration, allowPrivate bool) *Client {
+	c := &Client{
+		timeout:      timeout,
+		allowPrivate: allowPrivate,
+	}
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: c.control,
+	}
+	c.http = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		// redirects could lead anywhere, receivers should give us the final url
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return c
+}
+
+// Sign returns a signature of a body sent at timestamp. Receivers should
+// compute it with their secret and compare with X-Webhook-Signature.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Client) post(ctx context.Context, due Due) Attempt {
+	d := due.Delivery
+	start := time.Now().UTC()
+	a := Attempt{DeliveryID: d.ID, CreatedAt: start}
+
+	payload, err := json.Marshal(body{
+		ID:        d.ID,
+		Type:      d.Type,
+		CreatedAt: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, due.URL, bytes.NewReader(payload))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderWebhook, d.WebhookID)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderEvent, string(d.Type))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(due.Secret, start.Unix(), payload))
+
+	resp, err := c.http.Do(req)
+	a.Duration = time.Since(start)
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	defer resp.Body.Close()
+	// we don't care about the body, but connection can be reused only if it is read
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	a.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		a.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+	return a
+}
+
+// control is called with a resolved address right before connecting,
+// so it also catches hostnames that resolve to private addresses
+func (c *Client) control(network, address string, _ syscall.RawConn) error {
+	if c.allowPrivate {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if isPrivate(net.ParseIP(host)) {
+		return ErrForbiddenHost
+	}
+	return nil
+}
+
+// checkHost rejects obviously private hosts when a webhook is saved,
+// the real check happens on every connect
+func (c *Client) checkHost(host string) error {
+	if c.allowPrivate {
+		return nil
+	}
+	if host == "localhost" {
+		return ErrForbiddenHost
+	}
+	if ip := net.ParseIP(host); ip != nil && isPrivate(ip) {
+		return ErrForbiddenHost
+	}
+	return nil
+}
+
+// reservedNets are not private by the standard library, but still
+// lead into someone's network: carrier-grade NAT, "this network"
+// and benchmarking ranges
+var reservedNets = []*net.IPNet{
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+func isPrivate(ip net.IP) bool {
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() {
+		return true
+	}
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+const deliverWorkers = 4
+
+func (p RetryPolicy) delay(attempts int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return d
+}
+
+func (s *service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.deliverDue(ctx)
+		s.cleanup(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) Handle(ctx context.Context, e events.Event) error {
+	if err := s.repo.EnqueueEvent(ctx, e); err != nil {
+		defer s.log.Sync()
+		s.log.Debug("webhooks: Handle(): could not enqueue event", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *service) deliverDue(ctx context.Context) {
+	// nobody else should take a delivery while we are sending it
+	lease := 2 * s.client.timeout
+
+	var wg sync.WaitGroup
+	for i := 0; i < deliverWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				due, err := s.repo.ClaimDue(ctx, time.Now().UTC(), lease)
+				if errors.Is(err, ErrNoSuchDelivery) {
+					return
+				}
+				if err != nil {
+					defer s.log.Sync()
+					s.log.Error("webhooks: deliverDue(): could not claim delivery", logging.String("error", err.Error()))
+					return
+				}
+				s.deliver(ctx, due)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *service) deliver(ctx context.Context, due Due) {
+	defer s.log.Sync()
+
+	a := s.client.post(ctx, due)
+	d := due.Delivery
+	d.Attempts++
+	switch {
+	case a.Error == "":
+		d.Status = StatusDelivered
+		d.DeliveredAt = a.CreatedAt
+	case d.Attempts >= s.policy.MaxAttempts:
+		d.Status = StatusFailed
+	default:
+		d.NextAttemptAt = a.CreatedAt.Add(s.policy.delay(d.Attempts))
+	}
+
+	disabled, err := s.repo.Record(ctx, d, a, s.policy.DisableAfter)
+	if err != nil {
+		// lease will expire and we will try again
+		s.log.Error("webhooks: deliver(): could not record attempt", logging.String("error", err.Error()))
+		return
+	}
+	if a.Error != "" {
+		s.log.Debug(
+			"webhooks: deliver(): attempt failed",
+			logging.String("delivery", d.ID),
+			logging.String("error", a.Error),
+		)
+	}
+	if disabled {
+		s.log.Info("webhooks: deliver(): webhook disabled after too many failures", logging.String("id", d.WebhookID))
+	}
+}
+
+func (s *service) cleanup(ctx context.Context) {
+	if s.policy.Retention <= 0 {
+		return
+	}
+	if err := s.repo.DeleteDeliveries(ctx, time.Now().UTC().Add(-s.policy.Retention)); err != nil {
+		defer s.log.Sync()
+		s.log.Error("webhooks: cleanup(): could not delete old deliveries", logging.String("error", err.Error()))
+	}
+}
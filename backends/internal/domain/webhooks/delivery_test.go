@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Second,
+	}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.attempts); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestClientCheckHost(t *testing.T) {
+	c := NewClient(time.Second, false)
+	tests := []struct {
+		host string
+		want error
+	}{
+		{"example.com", nil},
+		{"93.184.216.34", nil},
+		{"localhost", ErrForbiddenHost},
+		{"127.0.0.1", ErrForbiddenHost},
+		{"10.1.2.3", ErrForbiddenHost},
+		{"192.168.0.10", ErrForbiddenHost},
+		{"169.254.169.254", ErrForbiddenHost},
+		{"::1", ErrForbiddenHost},
+		{"0.0.0.0", ErrForbiddenHost},
+		{"0.1.2.3", ErrForbiddenHost},
+		{"100.64.0.1", ErrForbiddenHost},
+		{"100.127.255.254", ErrForbiddenHost},
+		{"100.128.0.1", nil},
+		{"198.18.0.1", ErrForbiddenHost},
+		{"198.19.255.254", ErrForbiddenHost},
+		{"198.20.0.1", nil},
+		{"::ffff:100.64.0.1", ErrForbiddenHost},
+	}
+	for _, tt := range tests {
+		if got := c.checkHost(tt.host); got != tt.want {
+			t.Errorf("checkHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if err := NewClient(time.Second, true).checkHost("localhost"); err != nil {
+		t.Errorf("checkHost() with private hosts allowed = %v, want nil", err)
+	}
+}
@@ -0,0 +1,22 @@
+package webhooks
+
+import "github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+
+type (
+	CreateInput struct {
+		UserID string        `validate:"required"`
+		URL    string        `validate:"required,url,lt=2000"`
+		Events []events.Type `validate:"required,min=1,dive,oneof=todo.created todo.updated todo.completed todo.uncompleted todo.deleted todo.assigned todo.reminder todo.overdue todo.status_changed"`
+		// Secret is generated if empty
+		Secret string `validate:"omitempty,gte=16,lt=200"`
+	}
+
+	// UpdateInput changes only fields that are set.
+	// Enabling a webhook resets its failures.
+	UpdateInput struct {
+		ID      string        `validate:"required"`
+		URL     *string       `validate:"omitempty,url,lt=2000"`
+		Events  []events.Type `validate:"omitempty,min=1,dive,oneof=todo.created todo.updated todo.completed todo.uncompleted todo.deleted todo.assigned todo.reminder todo.overdue todo.status_changed"`
+		Enabled *bool
+	}
+)
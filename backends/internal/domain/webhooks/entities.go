@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+)
+
+const (
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed"
+
+	// EventTest is sent by "send test event", it can't be subscribed to
+	EventTest events.Type = "webhook.test"
+)
+
+type (
+	Status string
+
+	// Webhook is a url of a user that we POST events to
+	Webhook struct {
+		ID     string        `json:"id"`
+		UserID string        `json:"userId"`
+		URL    string        `json:"url"`
+		Events []events.Type `json:"events"`
+
+		// Secret is used to sign payloads. We show it only once
+		// when the webhook is created
+		Secret string `json:"-"`
+
+		// Enabled is false when user disabled the webhook or when it
+		// failed too many times in a row. Failures are reset by a
+		// successful delivery.
+		Enabled    bool      `json:"enabled"`
+		Failures   int       `json:"failures"`
+		DisabledAt time.Time `json:"disabledAt"`
+
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	// Delivery is a single event that has to be sent to a webhook.
+	// It is retried until it is delivered or we run out of attempts.
+	Delivery struct {
+		ID        string          `json:"id"`
+		WebhookID string          `json:"webhookId"`
+		Type      events.Type     `json:"type"`
+		Payload   json.RawMessage `json:"payload"`
+
+		Status        Status    `json:"status"`
+		Attempts      int       `json:"attempts"`
+		NextAttemptAt time.Time `json:"nextAttemptAt"`
+
+		CreatedAt   time.Time `json:"createdAt"`
+		DeliveredAt time.Time `json:"deliveredAt"`
+	}
+
+	// Attempt is a result of a single POST of a delivery
+	Attempt struct {
+		DeliveryID string        `json:"deliveryId"`
+		StatusCode int           `json:"statusCode"`
+		Error      string        `json:"error,omitempty"`
+		Duration   time.Duration `json:"duration"`
+		CreatedAt  time.Time     `json:"createdAt"`
+	}
+
+	// Due is a delivery that has to be sent right now,
+	// with everything we need to send it
+	Due struct {
+		Delivery Delivery
+		URL      string
+		Secret   string
+	}
+)
+
+func (w Webhook) Subscribed(typ events.Type) bool {
+	for _, t := range w.Events {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
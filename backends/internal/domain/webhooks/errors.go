@@ -0,0 +1,11 @@
+package webhooks
+
+import "errors"
+
+var (
+	ErrNoSuchWebhook  = errors.New("webhooks: no such webhook")
+	ErrNoSuchDelivery = errors.New("webhooks: no such delivery")
+	ErrInvalidURL     = errors.New("webhooks: url must be an absolute http or https url")
+	ErrForbiddenHost  = errors.New("webhooks: url points to a private network")
+	ErrDisabled       = errors.New("webhooks: webhook is disabled")
+)
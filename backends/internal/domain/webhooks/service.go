@@ -0,0 +1,331 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, w Webhook) (id string, err error)
+		// Get should return ErrNoSuchWebhook if there is no webhook with this id
+		Get(ctx context.Context, id string) (Webhook, error)
+		GetAll(ctx context.Context, userID string) ([]Webhook, error)
+		Update(ctx context.Context, w Webhook) error
+		Delete(ctx context.Context, id string) error
+
+		// EnqueueEvent creates a delivery for every enabled webhook subscribed
+		// to an event. It should do nothing for webhooks that already have it.
+		EnqueueEvent(ctx context.Context, e events.Event) error
+		Enqueue(ctx context.Context, webhookID string, typ events.Type, payload json.RawMessage) (id string, err error)
+		// GetDelivery should return ErrNoSuchDelivery if there is no delivery with this id
+		GetDelivery(ctx context.Context, id string) (Delivery, error)
+		GetDeliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error)
+		GetAttempts(ctx context.Context, deliveryID string) ([]Attempt, error)
+		// Redeliver makes a delivery pending again with a fresh number
+		// of attempts, previous attempts are kept
+		Redeliver(ctx context.Context, id string, at time.Time) error
+		// ClaimDue returns a pending delivery of an enabled webhook that is due at now,
+		// and postpones it by lease so no one else sends it at the same time.
+		// It should return ErrNoSuchDelivery if nothing is due.
+		ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (Due, error)
+		// Record saves an attempt along with the new state of its delivery.
+		// Failures of the webhook are reset if the attempt succeeded and
+		// incremented otherwise, webhook is disabled when they reach disableAfter.
+		Record(ctx context.Context, d Delivery, a Attempt, disableAfter int) (disabled bool, err error)
+		DeleteDeliveries(ctx context.Context, before time.Time) error
+	}
+
+	Service interface {
+		// Create returns a webhook with its secret, this is the only
+		// time when user can see it
+		Create(ctx context.Context, inp CreateInput) (Webhook, error)
+		GetAll(ctx context.Context, userID string) ([]Webhook, error)
+		Get(ctx context.Context, userID, id string) (Webhook, error)
+		Update(ctx context.Context, userID string, inp UpdateInput) (Webhook, error)
+		Delete(ctx context.Context, userID, id string) error
+
+		// Test sends a webhook.test event to a webhook
+		Test(ctx context.Context, userID, id string) (Delivery, error)
+		GetDeliveries(ctx context.Context, userID, id string) ([]Delivery, error)
+		GetDelivery(ctx context.Context, userID, webhookID, id string) (Delivery, []Attempt, error)
+		Redeliver(ctx context.Context, userID, webhookID, id string) (Delivery, error)
+
+		// Handle enqueues an event from the outbox, it is safe to call it
+		// more than once for the same event
+		Handle(ctx context.Context, e events.Event) error
+		// Run delivers events until ctx is canceled
+		Run(ctx context.Context, interval time.Duration)
+	}
+
+	// RetryPolicy describes how deliveries are retried. After a failed
+	// attempt we wait BaseDelay and double it every time up to MaxDelay.
+	// After MaxAttempts the delivery is failed, after DisableAfter failed
+	// attempts in a row the whole webhook is disabled.
+	RetryPolicy struct {
+		MaxAttempts  int
+		BaseDelay    time.Duration
+		MaxDelay     time.Duration
+		DisableAfter int
+		// Retention is how long we keep finished deliveries
+		Retention time.Duration
+	}
+
+	service struct {
+		repo      Repository
+		log       *logging.Logger
+		validator *validation.Validator
+		client    *Client
+		policy    RetryPolicy
+	}
+)
+
+const (
+	deliveriesShown = 50
+	secretPrefix    = "whsec_"
+)
+
+func NewService(
+	repo Repository,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	client *Client,
+	policy RetryPolicy,
+) Service {
+	return &service{
+		repo:      repo,
+		log:       logger,
+		validator: validator,
+		client:    client,
+		policy:    policy,
+	}
+}
+
+func (s *service) Create(ctx context.Context, inp CreateInput) (Webhook, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("webhooks: Create(): validation failed", logging.String("error", err.Error()))
+		return Webhook{}, err
+	}
+	if err := s.checkURL(inp.URL); err != nil {
+		return Webhook{}, err
+	}
+
+	secret := inp.Secret
+	if secret == "" {
+		var err error
+		if secret, err = generateSecret(); err != nil {
+			return Webhook{}, err
+		}
+	}
+
+	id, err := s.repo.Create(ctx, Webhook{
+		UserID:  inp.UserID,
+		URL:     inp.URL,
+		Events:  inp.Events,
+		Secret:  secret,
+		Enabled: true,
+	})
+	if err != nil {
+		s.log.Debug("webhooks: Create(): could not create webhook", logging.String("error", err.Error()))
+		return Webhook{}, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+func (s *service) GetAll(ctx context.Context, userID string) ([]Webhook, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: GetAll(): start")
+
+	list, err := s.repo.GetAll(ctx, userID)
+	if err != nil {
+		s.log.Debug("webhooks: GetAll(): could not get webhooks", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (Webhook, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: Get(): start")
+
+	w, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("webhooks: Get(): could not get webhook", logging.String("error", err.Error()))
+		return Webhook{}, err
+	}
+	// others should not even know that it exists
+	if w.UserID != userID {
+		return Webhook{}, ErrNoSuchWebhook
+	}
+	return w, nil
+}
+
+func (s *service) Update(ctx context.Context, userID string, inp UpdateInput) (Webhook, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: Update(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("webhooks: Update(): validation failed", logging.String("error", err.Error()))
+		return Webhook{}, err
+	}
+
+	w, err := s.Get(ctx, userID, inp.ID)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if inp.URL != nil {
+		if err := s.checkURL(*inp.URL); err != nil {
+			return Webhook{}, err
+		}
+		w.URL = *inp.URL
+	}
+	if inp.Events != nil {
+		w.Events = inp.Events
+	}
+	if inp.Enabled != nil {
+		if *inp.Enabled && !w.Enabled {
+			w.Failures = 0
+			w.DisabledAt = time.Time{}
+		}
+		if !*inp.Enabled && w.Enabled {
+			w.DisabledAt = time.Now().UTC()
+		}
+		w.Enabled = *inp.Enabled
+	}
+
+	if err := s.repo.Update(ctx, w); err != nil {
+		s.log.Debug("webhooks: Update(): could not update webhook", logging.String("error", err.Error()))
+		return Webhook{}, err
+	}
+	return s.repo.Get(ctx, w.ID)
+}
+
+func (s *service) Delete(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("webhooks: Delete(): start")
+
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("webhooks: Delete(): could not delete webhook", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *service) Test(ctx context.Context, userID, id string) (Delivery, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: Test(): start")
+
+	w, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if !w.Enabled {
+		return Delivery{}, ErrDisabled
+	}
+
+	payload, err := json.Marshal(map[string]string{"webhookId": w.ID})
+	if err != nil {
+		return Delivery{}, err
+	}
+	deliveryID, err := s.repo.Enqueue(ctx, w.ID, EventTest, payload)
+	if err != nil {
+		s.log.Debug("webhooks: Test(): could not enqueue", logging.String("error", err.Error()))
+		return Delivery{}, err
+	}
+	return s.repo.GetDelivery(ctx, deliveryID)
+}
+
+func (s *service) GetDeliveries(ctx context.Context, userID, id string) ([]Delivery, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: GetDeliveries(): start")
+
+	if _, err := s.Get(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.GetDeliveries(ctx, id, deliveriesShown)
+	if err != nil {
+		s.log.Debug("webhooks: GetDeliveries(): could not get deliveries", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) GetDelivery(ctx context.Context, userID, webhookID, id string) (Delivery, []Attempt, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: GetDelivery(): start")
+
+	d, err := s.delivery(ctx, userID, webhookID, id)
+	if err != nil {
+		return Delivery{}, nil, err
+	}
+	attempts, err := s.repo.GetAttempts(ctx, d.ID)
+	if err != nil {
+		s.log.Debug("webhooks: GetDelivery(): could not get attempts", logging.String("error", err.Error()))
+		return Delivery{}, nil, err
+	}
+	return d, attempts, nil
+}
+
+func (s *service) Redeliver(ctx context.Context, userID, webhookID, id string) (Delivery, error) {
+	defer s.log.Sync()
+	s.log.Info("webhooks: Redeliver(): start")
+
+	d, err := s.delivery(ctx, userID, webhookID, id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if err := s.repo.Redeliver(ctx, d.ID, time.Now().UTC()); err != nil {
+		s.log.Debug("webhooks: Redeliver(): could not redeliver", logging.String("error", err.Error()))
+		return Delivery{}, err
+	}
+	return s.repo.GetDelivery(ctx, d.ID)
+}
+
+// delivery returns a delivery only if it belongs to a webhook of this user
+func (s *service) delivery(ctx context.Context, userID, webhookID, id string) (Delivery, error) {
+	if _, err := s.Get(ctx, userID, webhookID); err != nil {
+		return Delivery{}, err
+	}
+	d, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		s.log.Debug("webhooks: delivery(): could not get delivery", logging.String("error", err.Error()))
+		return Delivery{}, err
+	}
+	if d.WebhookID != webhookID {
+		return Delivery{}, ErrNoSuchDelivery
+	}
+	return d, nil
+}
+
+func (s *service) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrInvalidURL
+	}
+	return s.client.checkHost(u.Hostname())
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return secretPrefix + hex.EncodeToString(b), nil
+}
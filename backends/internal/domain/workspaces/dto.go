@@ -0,0 +1,33 @@
+package workspaces
+
+type (
+	CreateInput struct {
+		UserID string `validate:"required"`
+		Name   string `validate:"required,lte=100"`
+	}
+
+	UpdateInput struct {
+		ID   string `validate:"required"`
+		Name string `validate:"required,lte=100"`
+	}
+
+	SetRoleInput struct {
+		WorkspaceID string `validate:"required"`
+		UserID      string `validate:"required"`
+		// there is only one owner, nobody can become one
+		Role Role `validate:"required,oneof=admin member"`
+	}
+
+	InviteInput struct {
+		WorkspaceID string `validate:"required"`
+		InviterID   string `validate:"required"`
+		Email       string `validate:"required,email"`
+		Role        Role   `validate:"required,oneof=admin member"`
+	}
+
+	RespondInput struct {
+		UserID string `validate:"required"`
+		ID     string `validate:"required"`
+		Accept bool
+	}
+)
@@ -0,0 +1,67 @@
+package workspaces
+
+import "time"
+
+const (
+	// RoleOwner is the one who created a workspace,
+	// there is always exactly one owner
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+
+	StatusPending  Status = "pending"
+	StatusAccepted Status = "accepted"
+	StatusDeclined Status = "declined"
+)
+
+type (
+	Role   string
+	Status string
+
+	// Workspace is a team that shares todos and lists. Members can see
+	// and edit everything in it, admins manage members and invitations.
+	Workspace struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		OwnerID string `json:"ownerId"`
+		// Role is what the caller is in this workspace
+		Role Role `json:"role,omitempty"`
+
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	Member struct {
+		WorkspaceID string    `json:"workspaceId"`
+		UserID      string    `json:"userId"`
+		Username    string    `json:"username"`
+		Email       string    `json:"email"`
+		Role        Role      `json:"role"`
+		JoinedAt    time.Time `json:"joinedAt"`
+	}
+
+	// Invitation is sent by email, people without accounts
+	// can accept it after they sign up with that email
+	Invitation struct {
+		ID            string `json:"id"`
+		WorkspaceID   string `json:"workspaceId"`
+		WorkspaceName string `json:"workspaceName"`
+		Email         string `json:"email"`
+		Role          Role   `json:"role"`
+		InvitedBy     string `json:"invitedBy"`
+		Status        Status `json:"status"`
+
+		CreatedAt   time.Time  `json:"createdAt"`
+		RespondedAt *time.Time `json:"respondedAt,omitempty"`
+	}
+)
+
+var roleRanks = map[Role]int{
+	RoleMember: 1,
+	RoleAdmin:  2,
+	RoleOwner:  3,
+}
+
+// Allows tells if a role is enough to do what needs another one
+func (r Role) Allows(need Role) bool {
+	return roleRanks[r] >= roleRanks[need]
+}
@@ -0,0 +1,16 @@
+package workspaces
+
+import "errors"
+
+var (
+	// ErrNoSuchWorkspace is also returned to people who are not members,
+	// so nobody can find out which workspaces exist
+	ErrNoSuchWorkspace  = errors.New("workspaces: no such workspace")
+	ErrNoSuchMember     = errors.New("workspaces: no such member")
+	ErrNoSuchInvitation = errors.New("workspaces: no such invitation")
+	ErrNotAllowed       = errors.New("workspaces: your role does not allow that")
+	ErrOwner            = errors.New("workspaces: owner can't be removed or demoted")
+	ErrAlreadyMember    = errors.New("workspaces: user is already a member")
+	ErrAlreadyInvited   = errors.New("workspaces: user is already invited")
+	ErrNotPending       = errors.New("workspaces: invitation was already answered")
+)
@@ -0,0 +1,26 @@
+package workspaces
+
+import "context"
+
+type scopeKey struct{}
+
+// WithScope tells repositories which workspace a request works in.
+// Empty id is the personal space of a user, which is where todos
+// and lists that don't belong to any workspace live.
+func WithScope(ctx context.Context, workspaceID string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, workspaceID)
+}
+
+// ScopeFrom returns the workspace of a request. Contexts without
+// a scope come from background jobs, they can see every workspace.
+func ScopeFrom(ctx context.Context) (workspaceID string, ok bool) {
+	workspaceID, ok = ctx.Value(scopeKey{}).(string)
+	return workspaceID, ok
+}
+
+// InScope tells if something that belongs to a workspace can be seen
+// by a request. Empty workspaceID is the personal space.
+func InScope(ctx context.Context, workspaceID string) bool {
+	scope, ok := ScopeFrom(ctx)
+	return !ok || scope == workspaceID
+}
@@ -0,0 +1,460 @@
+package workspaces
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/mailer"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type (
+	Repository interface {
+		// Create saves a workspace and makes its owner a member
+		Create(ctx context.Context, w Workspace) (id string, err error)
+		// Get should return ErrNoSuchWorkspace if there is no workspace with this id
+		Get(ctx context.Context, id string) (Workspace, error)
+		// GetAll returns workspaces of a user with his roles in them
+		GetAll(ctx context.Context, userID string) ([]Workspace, error)
+		Update(ctx context.Context, inp UpdateInput) error
+		// Delete removes a workspace with all of its todos and lists
+		Delete(ctx context.Context, id string) error
+
+		// GetMember should return ErrNoSuchMember if the user is not a member
+		GetMember(ctx context.Context, workspaceID, userID string) (Member, error)
+		GetMembers(ctx context.Context, workspaceID string) ([]Member, error)
+		SetRole(ctx context.Context, workspaceID, userID string, role Role) error
+		RemoveMember(ctx context.Context, workspaceID, userID string) error
+
+		// CreateInvitation should return ErrAlreadyInvited if the email
+		// already has a pending invitation to the workspace
+		CreateInvitation(ctx context.Context, inv Invitation) (id string, err error)
+		// GetInvitation should return ErrNoSuchInvitation if there is no invitation with this id
+		GetInvitation(ctx context.Context, id string) (Invitation, error)
+		// GetInvitations and GetInvitationsByEmail return only pending invitations
+		GetInvitations(ctx context.Context, workspaceID string) ([]Invitation, error)
+		GetInvitationsByEmail(ctx context.Context, email string) ([]Invitation, error)
+		// Respond answers an invitation, if it is accepted the user becomes
+		// a member in the same transaction
+		Respond(ctx context.Context, inv Invitation, userID string, status Status, at time.Time) error
+		DeleteInvitation(ctx context.Context, id string) error
+	}
+
+	UsersRepository interface {
+		Get(ctx context.Context, id string) (users.User, error)
+		GetByEmail(ctx context.Context, email string) (users.User, error)
+	}
+
+	// Inbox keeps in-app notifications of users
+	Inbox interface {
+		Notify(ctx context.Context, n notifications.Notification) error
+	}
+
+	Service interface {
+		// Create makes the user the owner of a new workspace
+		Create(ctx context.Context, inp CreateInput) (Workspace, error)
+		GetAll(ctx context.Context, userID string) ([]Workspace, error)
+		Get(ctx context.Context, userID, id string) (Workspace, error)
+		// Update is for admins
+		Update(ctx context.Context, userID string, inp UpdateInput) (Workspace, error)
+		// Delete is only for the owner, everything in the workspace is deleted
+		Delete(ctx context.Context, userID, id string) error
+
+		// Member returns ErrNoSuchWorkspace if the user is not a member,
+		// transports use it to check workspaces requests want to work in
+		Member(ctx context.Context, workspaceID, userID string) (Member, error)
+		GetMembers(ctx context.Context, userID, workspaceID string) ([]Member, error)
+		// SetRole is for admins, nobody can change the role of the owner
+		SetRole(ctx context.Context, userID string, inp SetRoleInput) (Member, error)
+		// RemoveMember is for admins, members can remove themselves
+		// to leave a workspace. The owner can't leave.
+		RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error
+
+		// Invite is for admins. People get an email, users who
+		// already have an account get a notification as well.
+		Invite(ctx context.Context, inp InviteInput) (Invitation, error)
+		GetInvitations(ctx context.Context, userID, workspaceID string) ([]Invitation, error)
+		RevokeInvitation(ctx context.Context, userID, workspaceID, id string) error
+		// GetMyInvitations returns invitations sent to the email of the user
+		GetMyInvitations(ctx context.Context, userID string) ([]Invitation, error)
+		Respond(ctx context.Context, inp RespondInput) (Invitation, error)
+	}
+
+	service struct {
+		repo      Repository
+		uRepo     UsersRepository
+		inbox     Inbox
+		mailer    mailer.Mailer
+		log       *logging.Logger
+		validator *validation.Validator
+		appURL    string
+	}
+)
+
+// NewService works without a mailer, invitations are not emailed then
+func NewService(
+	repo Repository,
+	uRepo UsersRepository,
+	inbox Inbox,
+	m mailer.Mailer,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	appURL string,
+) Service {
+	return &service{
+		repo:      repo,
+		uRepo:     uRepo,
+		inbox:     inbox,
+		mailer:    m,
+		log:       logger,
+		validator: validator,
+		appURL:    strings.TrimRight(appURL, "/"),
+	}
+}
+
+func (s *service) Create(ctx context.Context, inp CreateInput) (Workspace, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Create(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("workspaces: Create(): validation failed", logging.String("error", err.Error()))
+		return Workspace{}, err
+	}
+	id, err := s.repo.Create(ctx, Workspace{Name: inp.Name, OwnerID: inp.UserID})
+	if err != nil {
+		s.log.Debug("workspaces: Create(): could not create workspace", logging.String("error", err.Error()))
+		return Workspace{}, err
+	}
+	return s.Get(ctx, inp.UserID, id)
+}
+
+func (s *service) GetAll(ctx context.Context, userID string) ([]Workspace, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: GetAll(): start")
+
+	list, err := s.repo.GetAll(ctx, userID)
+	if err != nil {
+		s.log.Debug("workspaces: GetAll(): could not get workspaces", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, id string) (Workspace, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Get(): start")
+
+	m, err := s.authorize(ctx, userID, id, RoleMember)
+	if err != nil {
+		return Workspace{}, err
+	}
+	w, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.Debug("workspaces: Get(): could not get workspace", logging.String("error", err.Error()))
+		return Workspace{}, err
+	}
+	w.Role = m.Role
+	return w, nil
+}
+
+func (s *service) Update(ctx context.Context, userID string, inp UpdateInput) (Workspace, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Update(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("workspaces: Update(): validation failed", logging.String("error", err.Error()))
+		return Workspace{}, err
+	}
+	if _, err := s.authorize(ctx, userID, inp.ID, RoleAdmin); err != nil {
+		return Workspace{}, err
+	}
+	if err := s.repo.Update(ctx, inp); err != nil {
+		s.log.Debug("workspaces: Update(): could not update workspace", logging.String("error", err.Error()))
+		return Workspace{}, err
+	}
+	return s.Get(ctx, userID, inp.ID)
+}
+
+func (s *service) Delete(ctx context.Context, userID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Delete(): start")
+
+	if _, err := s.authorize(ctx, userID, id, RoleOwner); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		s.log.Debug("workspaces: Delete(): could not delete workspace", logging.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+func (s *service) Member(ctx context.Context, workspaceID, userID string) (Member, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Member(): start")
+
+	return s.authorize(ctx, userID, workspaceID, RoleMember)
+}
+
+func (s *service) GetMembers(ctx context.Context, userID, workspaceID string) ([]Member, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: GetMembers(): start")
+
+	if _, err := s.authorize(ctx, userID, workspaceID, RoleMember); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.GetMembers(ctx, workspaceID)
+	if err != nil {
+		s.log.Debug("workspaces: GetMembers(): could not get members", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) SetRole(ctx context.Context, userID string, inp SetRoleInput) (Member, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: SetRole(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("workspaces: SetRole(): validation failed", logging.String("error", err.Error()))
+		return Member{}, err
+	}
+	if _, err := s.authorize(ctx, userID, inp.WorkspaceID, RoleAdmin); err != nil {
+		return Member{}, err
+	}
+	m, err := s.repo.GetMember(ctx, inp.WorkspaceID, inp.UserID)
+	if err != nil {
+		s.log.Debug("workspaces: SetRole(): could not get member", logging.String("error", err.Error()))
+		return Member{}, err
+	}
+	if m.Role == RoleOwner {
+		return Member{}, ErrOwner
+	}
+	if err := s.repo.SetRole(ctx, inp.WorkspaceID, inp.UserID, inp.Role); err != nil {
+		s.log.Debug("workspaces: SetRole(): could not set role", logging.String("error", err.Error()))
+		return Member{}, err
+	}
+	m.Role = inp.Role
+	return m, nil
+}
+
+func (s *service) RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error {
+	defer s.log.Sync()
+	s.log.Info("workspaces: RemoveMember(): start")
+
+	need := RoleAdmin
+	if userID == memberID {
+		need = RoleMember
+	}
+	if _, err := s.authorize(ctx, userID, workspaceID, need); err != nil {
+		return err
+	}
+	m, err := s.repo.GetMember(ctx, workspaceID, memberID)
+	if err != nil {
+		s.log.Debug("workspaces: RemoveMember(): could not get member", logging.String("error", err.Error()))
+		return err
+	}
+	if m.Role == RoleOwner {
+		return ErrOwner
+	}
+	return s.repo.RemoveMember(ctx, workspaceID, memberID)
+}
+
+func (s *service) Invite(ctx context.Context, inp InviteInput) (Invitation, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Invite(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("workspaces: Invite(): validation failed", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+	email := strings.ToLower(inp.Email)
+	if _, err := s.authorize(ctx, inp.InviterID, inp.WorkspaceID, RoleAdmin); err != nil {
+		return Invitation{}, err
+	}
+	invitee, err := s.uRepo.GetByEmail(ctx, email)
+	switch err {
+	case nil:
+		if _, err := s.repo.GetMember(ctx, inp.WorkspaceID, invitee.ID); err == nil {
+			return Invitation{}, ErrAlreadyMember
+		}
+	case users.ErrNoSuchUser:
+		// they will accept it after signing up
+	default:
+		s.log.Debug("workspaces: Invite(): could not get invitee", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+
+	id, err := s.repo.CreateInvitation(ctx, Invitation{
+		WorkspaceID: inp.WorkspaceID,
+		Email:       email,
+		Role:        inp.Role,
+		InvitedBy:   inp.InviterID,
+		Status:      StatusPending,
+	})
+	if err != nil {
+		s.log.Debug("workspaces: Invite(): could not create invitation", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+	inv, err := s.repo.GetInvitation(ctx, id)
+	if err != nil {
+		return Invitation{}, err
+	}
+	s.notify(ctx, inv, invitee.ID)
+	return inv, nil
+}
+
+// notify never fails, the invitation is already saved and
+// people can find it in their list of invitations
+func (s *service) notify(ctx context.Context, inv Invitation, inviteeID string) {
+	inviter, err := s.uRepo.Get(ctx, inv.InvitedBy)
+	if err != nil {
+		s.log.Error("workspaces: notify(): could not get inviter", logging.String("error", err.Error()))
+		return
+	}
+	title := inviter.Username + " invited you to " + inv.WorkspaceName
+
+	if inviteeID != "" {
+		data, err := json.Marshal(inv)
+		if err != nil {
+			s.log.Error("workspaces: notify(): could not marshal invitation", logging.String("error", err.Error()))
+			return
+		}
+		err = s.inbox.Notify(ctx, notifications.Notification{
+			UserID: inviteeID,
+			Type:   notifications.TypeWorkspace,
+			Title:  title,
+			Data:   data,
+			Key:    "workspace:" + inv.ID,
+		})
+		if err != nil {
+			s.log.Error("workspaces: notify(): could not notify invitee", logging.String("error", err.Error()))
+		}
+	}
+
+	if s.mailer == nil {
+		return
+	}
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      []string{inv.Email},
+		Subject: title,
+		Text: title + " as " + string(inv.Role) + ".\n\n" +
+			"Sign in or sign up with this email to accept the invitation:\n" +
+			s.appURL + "/workspaces/invitations\n",
+	})
+	if err != nil {
+		s.log.Error("workspaces: notify(): could not email invitee", logging.String("error", err.Error()))
+	}
+}
+
+func (s *service) GetInvitations(ctx context.Context, userID, workspaceID string) ([]Invitation, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: GetInvitations(): start")
+
+	if _, err := s.authorize(ctx, userID, workspaceID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.GetInvitations(ctx, workspaceID)
+	if err != nil {
+		s.log.Debug("workspaces: GetInvitations(): could not get invitations", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) RevokeInvitation(ctx context.Context, userID, workspaceID, id string) error {
+	defer s.log.Sync()
+	s.log.Info("workspaces: RevokeInvitation(): start")
+
+	if _, err := s.authorize(ctx, userID, workspaceID, RoleAdmin); err != nil {
+		return err
+	}
+	inv, err := s.repo.GetInvitation(ctx, id)
+	if err != nil {
+		s.log.Debug("workspaces: RevokeInvitation(): could not get invitation", logging.String("error", err.Error()))
+		return err
+	}
+	if inv.WorkspaceID != workspaceID {
+		return ErrNoSuchInvitation
+	}
+	return s.repo.DeleteInvitation(ctx, id)
+}
+
+func (s *service) GetMyInvitations(ctx context.Context, userID string) ([]Invitation, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: GetMyInvitations(): start")
+
+	u, err := s.uRepo.Get(ctx, userID)
+	if err != nil {
+		s.log.Debug("workspaces: GetMyInvitations(): could not get user", logging.String("error", err.Error()))
+		return nil, err
+	}
+	list, err := s.repo.GetInvitationsByEmail(ctx, strings.ToLower(u.Email))
+	if err != nil {
+		s.log.Debug("workspaces: GetMyInvitations(): could not get invitations", logging.String("error", err.Error()))
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *service) Respond(ctx context.Context, inp RespondInput) (Invitation, error) {
+	defer s.log.Sync()
+	s.log.Info("workspaces: Respond(): start")
+
+	if err := s.validator.ValidateStruct(inp); err != nil {
+		s.log.Debug("workspaces: Respond(): validation failed", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+	u, err := s.uRepo.Get(ctx, inp.UserID)
+	if err != nil {
+		s.log.Debug("workspaces: Respond(): could not get user", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+	inv, err := s.repo.GetInvitation(ctx, inp.ID)
+	if err != nil {
+		s.log.Debug("workspaces: Respond(): could not get invitation", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+	// invitations of other people don't exist for this user
+	if !strings.EqualFold(inv.Email, u.Email) {
+		return Invitation{}, ErrNoSuchInvitation
+	}
+	if inv.Status != StatusPending {
+		return Invitation{}, ErrNotPending
+	}
+
+	status := StatusDeclined
+	if inp.Accept {
+		status = StatusAccepted
+	}
+	now := time.Now().UTC()
+	if err := s.repo.Respond(ctx, inv, u.ID, status, now); err != nil {
+		s.log.Debug("workspaces: Respond(): could not respond", logging.String("error", err.Error()))
+		return Invitation{}, err
+	}
+	inv.Status = status
+	inv.RespondedAt = &now
+	return inv, nil
+}
+
+// authorize returns ErrNoSuchWorkspace to people who are not members
+// and ErrNotAllowed to members whose role is not enough
+func (s *service) authorize(ctx context.Context, userID, workspaceID string, need Role) (Member, error) {
+	m, err := s.repo.GetMember(ctx, workspaceID, userID)
+	if err == ErrNoSuchMember {
+		return Member{}, ErrNoSuchWorkspace
+	}
+	if err != nil {
+		s.log.Debug("workspaces: authorize(): could not get member", logging.String("error", err.Error()))
+		return Member{}, err
+	}
+	if !m.Role.Allows(need) {
+		return Member{}, ErrNotAllowed
+	}
+	return m, nil
+}
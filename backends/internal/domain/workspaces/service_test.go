@@ -0,0 +1,145 @@
+package workspaces
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
+)
+
+type fakeRepository struct {
+	Repository
+	members     map[string]Role
+	invitations map[string]Invitation
+}
+
+func (f *fakeRepository) GetMember(ctx context.Context, workspaceID, userID string) (Member, error) {
+	role, ok := f.members[userID]
+	if !ok || workspaceID != "w" {
+		return Member{}, ErrNoSuchMember
+	}
+	return Member{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
+
+func (f *fakeRepository) SetRole(ctx context.Context, workspaceID, userID string, role Role) error {
+	f.members[userID] = role
+	return nil
+}
+
+func (f *fakeRepository) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	delete(f.members, userID)
+	return nil
+}
+
+func (f *fakeRepository) CreateInvitation(ctx context.Context, inv Invitation) (string, error) {
+	inv.ID = string(rune('a' + len(f.invitations)))
+	f.invitations[inv.ID] = inv
+	return inv.ID, nil
+}
+
+func (f *fakeRepository) GetInvitation(ctx context.Context, id string) (Invitation, error) {
+	inv, ok := f.invitations[id]
+	if !ok {
+		return inv, ErrNoSuchInvitation
+	}
+	return inv, nil
+}
+
+func (f *fakeRepository) Respond(ctx context.Context, inv Invitation, userID string, status Status, at time.Time) error {
+	inv.Status = status
+	f.invitations[inv.ID] = inv
+	if status == StatusAccepted {
+		f.members[userID] = inv.Role
+	}
+	return nil
+}
+
+type fakeUsers struct{}
+
+func (fakeUsers) Get(ctx context.Context, id string) (users.User, error) {
+	return users.User{ID: id, Username: id, Email: id + "@example.com"}, nil
+}
+
+func (fakeUsers) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	if email == "newbie@example.com" {
+		return users.User{ID: "newbie", Email: email}, nil
+	}
+	return users.User{}, users.ErrNoSuchUser
+}
+
+type fakeInbox struct{ sent []notifications.Notification }
+
+func (f *fakeInbox) Notify(ctx context.Context, n notifications.Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+func TestRolesAndInvitations(t *testing.T) {
+	logger := logging.NewNop()
+	repo := &fakeRepository{
+		members:     map[string]Role{"owner": RoleOwner, "admin": RoleAdmin, "member": RoleMember},
+		invitations: map[string]Invitation{},
+	}
+	inbox := &fakeInbox{}
+	s := NewService(repo, fakeUsers{}, inbox, nil, logger, validation.NewValidator(), "")
+	ctx := context.Background()
+
+	if _, err := s.Member(ctx, "w", "stranger"); !errors.Is(err, ErrNoSuchWorkspace) {
+		t.Errorf("expected stranger to get ErrNoSuchWorkspace, got %v", err)
+	}
+	if _, err := s.SetRole(ctx, "member", SetRoleInput{WorkspaceID: "w", UserID: "admin", Role: RoleMember}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected member not to change roles, got %v", err)
+	}
+	if _, err := s.SetRole(ctx, "admin", SetRoleInput{WorkspaceID: "w", UserID: "owner", Role: RoleMember}); !errors.Is(err, ErrOwner) {
+		t.Errorf("expected role of the owner to stay, got %v", err)
+	}
+	if err := s.RemoveMember(ctx, "admin", "w", "owner"); !errors.Is(err, ErrOwner) {
+		t.Errorf("expected owner not to be removed, got %v", err)
+	}
+
+	if _, err := s.Invite(ctx, InviteInput{WorkspaceID: "w", InviterID: "member", Email: "newbie@example.com", Role: RoleMember}); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected member not to invite, got %v", err)
+	}
+	inv, err := s.Invite(ctx, InviteInput{WorkspaceID: "w", InviterID: "admin", Email: "newbie@example.com", Role: RoleMember})
+	if err != nil {
+		t.Fatalf("expected admin to invite, got %v", err)
+	}
+	if len(inbox.sent) != 1 || inbox.sent[0].UserID != "newbie" {
+		t.Errorf("expected newbie to be notified, got %+v", inbox.sent)
+	}
+	if _, err := s.Respond(ctx, RespondInput{UserID: "member", ID: inv.ID, Accept: true}); !errors.Is(err, ErrNoSuchInvitation) {
+		t.Errorf("expected invitations of others to be hidden, got %v", err)
+	}
+	if _, err := s.Respond(ctx, RespondInput{UserID: "newbie", ID: inv.ID, Accept: true}); err != nil {
+		t.Fatalf("expected newbie to accept, got %v", err)
+	}
+	if _, err := s.Member(ctx, "w", "newbie"); err != nil {
+		t.Errorf("expected newbie to become a member, got %v", err)
+	}
+	if _, err := s.Respond(ctx, RespondInput{UserID: "newbie", ID: inv.ID}); !errors.Is(err, ErrNotPending) {
+		t.Errorf("expected ErrNotPending, got %v", err)
+	}
+	if err := s.RemoveMember(ctx, "newbie", "w", "newbie"); err != nil {
+		t.Errorf("expected member to leave, got %v", err)
+	}
+}
+
+func TestScope(t *testing.T) {
+	ctx := context.Background()
+	if !InScope(ctx, "w") || !InScope(ctx, "") {
+		t.Error("expected contexts without a scope to see everything")
+	}
+	personal := WithScope(ctx, "")
+	if InScope(personal, "w") || !InScope(personal, "") {
+		t.Error("expected personal space to see only personal things")
+	}
+	team := WithScope(ctx, "w")
+	if InScope(team, "other") || InScope(team, "") || !InScope(team, "w") {
+		t.Error("expected workspace to see only its own things")
+	}
+}
@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+)
+
+type attemptsRepository struct {
+	mu       sync.Mutex
+	attempts map[string]users.LoginAttempts
+}
+
+func newAttemptsRepository() *attemptsRepository {
+	return &attemptsRepository{
+		attempts: make(map[string]users.LoginAttempts),
+	}
+}
+
+func (r *attemptsRepository) Get(ctx context.Context, key string) (users.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a, ok := r.attempts[key]
+	if !ok {
+		return users.LoginAttempts{Key: key}, nil
+	}
+	return a, nil
+}
+
+func (r *attemptsRepository) Fail(ctx context.Context, key string, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := r.attempts[key]
+	a.Key = key
+	a.Failures++
+	a.LastFailureAt = at
+	r.attempts[key] = a
+	return a.Failures, nil
+}
+
+func (r *attemptsRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	a := r.attempts[key]
+	a.Key = key
+	a.LockedUntil = until
+	r.attempts[key] = a
+	return nil
+}
+
+func (r *attemptsRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *attemptsRepository) GetLocked(ctx context.Context, now time.Time) ([]users.LoginAttempts, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	locked := []users.LoginAttempts{}
+	for _, a := range r.attempts {
+		if a.LockedUntil.After(now) {
+			locked = append(locked, a)
+		}
+	}
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].LockedUntil.After(locked[j].LockedUntil)
+	})
+	return locked, nil
+}
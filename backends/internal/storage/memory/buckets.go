@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+)
+
+const (
+	// buckets that were not touched for this long are full anyway
+	// for any sane limit, so we can forget about them
+	bucketsIdleTTL       = time.Hour
+	bucketsPruneInterval = 1000
+)
+
+type bucketsRepository struct {
+	mu      sync.Mutex
+	buckets map[string]ratelimit.Bucket
+	takes   int
+}
+
+func newBucketsRepository() *bucketsRepository {
+	return &bucketsRepository{
+		buckets: make(map[string]ratelimit.Bucket),
+	}
+}
+
+func (r *bucketsRepository) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.takes++
+	if r.takes%bucketsPruneInterval == 0 {
+		for k, b := range r.buckets {
+			if now.Sub(b.UpdatedAt) > bucketsIdleTTL {
+				delete(r.buckets, k)
+			}
+		}
+	}
+
+	b, res := r.buckets[key].Take(limit, now)
+	r.buckets[key] = b
+	return res, nil
+}
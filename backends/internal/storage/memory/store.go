@@ -0,0 +1,24 @@
+// Package memory contains in-memory implementations of our repositories.
+// They are only good for a single instance of the api, since nothing
+// is shared between processes and everything is lost on restart.
+package memory
+
+type Store struct {
+	attemptsRepository *attemptsRepository
+	bucketsRepository  *bucketsRepository
+}
+
+func NewStore() *Store {
+	return &Store{
+		attemptsRepository: newAttemptsRepository(),
+		bucketsRepository:  newBucketsRepository(),
+	}
+}
+
+func (s *Store) Attempts() *attemptsRepository {
+	return s.attemptsRepository
+}
+
+func (s *Store) Buckets() *bucketsRepository {
+	return s.bucketsRepository
+}
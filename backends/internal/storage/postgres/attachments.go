@@ -0,0 +1,279 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/attachments"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type attachmentsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+const attachmentColumns = `id, COALESCE(todo_id::text, ''), COALESCE(user_id::text, ''),
+	filename, content_type, size, storage_key, created_at,
+	thumbnail_status, thumbnails, thumbnail_attempts`
+
+func selectAttachments() sq.SelectBuilder {
+	return sq.
+		Select(attachmentColumns).
+		From("attachments").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanAttachment(row pgx.Row) (a attachments.Attachment, err error) {
+	var thumbnails []byte
+	err = row.Scan(
+		&a.ID, &a.TodoID, &a.UserID,
+		&a.Filename, &a.ContentType, &a.Size, &a.Key, &a.CreatedAt,
+		&a.ThumbnailStatus, &thumbnails, &a.Attempts,
+	)
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(thumbnails, &a.Thumbnails); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+func (r *attachmentsRepository) Create(ctx context.Context, a attachments.Attachment, quota int64) (id string, err error) {
+	// the user row is locked, so concurrent uploads can't exceed the quota together
+	lockSQL, lockArgs, err := sq.
+		Select("1").
+		From("users").
+		Where(sq.Eq{"id::text": a.UserID}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+	usedSQL, usedArgs, err := sq.
+		Select("COALESCE(SUM(size), 0)").
+		From("attachments").
+		Where(sq.Eq{"user_id::text": a.UserID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+	insertSQL, insertArgs, err := sq.
+		Insert("attachments").
+		Columns("todo_id", "user_id", "filename", "content_type", "size", "storage_key", "created_at", "thumbnail_status").
+		Values(a.TodoID, a.UserID, a.Filename, a.ContentType, a.Size, a.Key, time.Now().UTC(), a.ThumbnailStatus).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: Create()", logging.String("sql", insertSQL))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, lockSQL, lockArgs...); err != nil {
+		return "", err
+	}
+	var used int64
+	if err := tx.QueryRow(ctx, usedSQL, usedArgs...).Scan(&used); err != nil {
+		return "", err
+	}
+	if used+a.Size > quota {
+		return "", attachments.ErrQuotaExceeded
+	}
+	if err := tx.QueryRow(ctx, insertSQL, insertArgs...).Scan(&id); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+func (r *attachmentsRepository) Get(ctx context.Context, id string) (attachments.Attachment, error) {
+	sql, args, err := selectAttachments().Where(sq.Eq{"id::text": id}).ToSql()
+	if err != nil {
+		return attachments.Attachment{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return attachments.Attachment{}, err
+	}
+	defer conn.Release()
+
+	a, err := scanAttachment(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return a, attachments.ErrNoSuchAttachment
+	}
+	return a, err
+}
+
+func (r *attachmentsRepository) GetAll(ctx context.Context, todoID string) ([]attachments.Attachment, error) {
+	sql, args, err := selectAttachments().
+		Where(sq.Eq{"todo_id::text": todoID}).
+		OrderBy("created_at ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: GetAll()", logging.String("sql", sql))
+
+	return r.query(ctx, sql, args...)
+}
+
+func (r *attachmentsRepository) GetOrphaned(ctx context.Context, limit int) ([]attachments.Attachment, error) {
+	sql, args, err := selectAttachments().
+		Where(sq.Eq{"todo_id": nil}).
+		OrderBy("created_at ASC").
+		Limit(uint64(limit)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: GetOrphaned()", logging.String("sql", sql))
+
+	return r.query(ctx, sql, args...)
+}
+
+// claimThumbnailsSQL skips attachments of deleted todos, they are about to go
+const claimThumbnailsSQL = `UPDATE attachments
+	SET thumbnail_claimed_until = $2, thumbnail_attempts = thumbnail_attempts + 1
+	WHERE id IN (
+		SELECT id FROM attachments
+		WHERE thumbnail_status = 'pending' AND todo_id IS NOT NULL
+			AND (thumbnail_claimed_until IS NULL OR thumbnail_claimed_until <= $1)
+		ORDER BY created_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + attachmentColumns
+
+func (r *attachmentsRepository) ClaimThumbnails(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]attachments.Attachment, error) {
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: ClaimThumbnails()", logging.String("sql", claimThumbnailsSQL))
+
+	return r.query(ctx, claimThumbnailsSQL, now, now.Add(lease), limit)
+}
+
+func (r *attachmentsRepository) SaveThumbnails(ctx context.Context, id string, status attachments.ThumbnailStatus, thumbnails []attachments.Thumbnail) error {
+	if thumbnails == nil {
+		thumbnails = []attachments.Thumbnail{}
+	}
+	data, err := json.Marshal(thumbnails)
+	if err != nil {
+		return err
+	}
+	sql, args, err := sq.
+		Update("attachments").
+		Set("thumbnail_status", status).
+		Set("thumbnails", data).
+		Set("thumbnail_claimed_until", nil).
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: SaveThumbnails()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return attachments.ErrNoSuchAttachment
+	}
+	return nil
+}
+
+func (r *attachmentsRepository) query(ctx context.Context, sql string, args ...interface{}) ([]attachments.Attachment, error) {
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []attachments.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func (r *attachmentsRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("attachments").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *attachmentsRepository) UsedBytes(ctx context.Context, userID string) (used int64, err error) {
+	sql, args, err := sq.
+		Select("COALESCE(SUM(size), 0)").
+		From("attachments").
+		Where(sq.Eq{"user_id::text": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attachmentsRepository: UsedBytes()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&used)
+	return used, err
+}
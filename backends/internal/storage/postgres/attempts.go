@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type attemptsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+func (r *attemptsRepository) Get(ctx context.Context, key string) (a users.LoginAttempts, err error) {
+	sql, args, err := sq.
+		Select("key, failures, last_failure_at, locked_until").
+		From("login_attempts").
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return a, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attemptsRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return a, err
+	}
+	defer conn.Release()
+
+	var lockedUntil pq.NullTime
+	err = conn.QueryRow(ctx, sql, args...).Scan(&a.Key, &a.Failures, &a.LastFailureAt, &lockedUntil)
+	if err == pgx.ErrNoRows {
+		return users.LoginAttempts{Key: key}, nil
+	}
+	if lockedUntil.Valid {
+		a.LockedUntil = lockedUntil.Time
+	}
+	return a, err
+}
+
+func (r *attemptsRepository) Fail(ctx context.Context, key string, at time.Time) (failures int, err error) {
+	sql, args, err := sq.
+		Insert("login_attempts").
+		Columns("key", "failures", "last_failure_at").
+		Values(key, 1, at).
+		Suffix(`ON CONFLICT (key) DO UPDATE
+			SET failures = login_attempts.failures + 1,
+			last_failure_at = EXCLUDED.last_failure_at
+			RETURNING failures`).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attemptsRepository: Fail()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&failures)
+	return failures, err
+}
+
+func (r *attemptsRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	sql, args, err := sq.
+		Update("login_attempts").
+		Set("locked_until", until).
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attemptsRepository: Lock()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *attemptsRepository) Reset(ctx context.Context, key string) error {
+	sql, args, err := sq.
+		Delete("login_attempts").
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attemptsRepository: Reset()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *attemptsRepository) GetLocked(ctx context.Context, now time.Time) ([]users.LoginAttempts, error) {
+	sql, args, err := sq.
+		Select("key, failures, last_failure_at, locked_until").
+		From("login_attempts").
+		Where(sq.Gt{"locked_until": now}).
+		OrderBy("locked_until DESC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("attemptsRepository: GetLocked()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locked := []users.LoginAttempts{}
+	for rows.Next() {
+		var a users.LoginAttempts
+		if err := rows.Scan(&a.Key, &a.Failures, &a.LastFailureAt, &a.LockedUntil); err != nil {
+			return nil, err
+		}
+		locked = append(locked, a)
+	}
+	return locked, rows.Err()
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+const (
+	// postgres refuses payloads of 8000 bytes and more
+	notifyPayloadLimit = 7999
+
+	listenMinBackoff = time.Second
+	listenMaxBackoff = 30 * time.Second
+)
+
+// LocalPublisher delivers events to clients connected to this instance
+type LocalPublisher interface {
+	Publish(ctx context.Context, e events.Event) error
+	// Reset tells local clients to refetch everything,
+	// because they could have missed some events
+	Reset()
+}
+
+// broadcaster publishes events to every instance of the api with
+// NOTIFY, and delivers events it receives with LISTEN to the local
+// publisher. Events of this instance go the same way, so all
+// instances see them in the same order and with the same ids.
+type broadcaster struct {
+	conn    *pgxpool.Pool
+	log     *logging.Logger
+	channel string
+	local   LocalPublisher
+}
+
+func (r *Repository) Broadcaster(channel string, local LocalPublisher) *broadcaster {
+	return &broadcaster{
+		conn:    r.conn,
+		log:     r.log,
+		channel: channel,
+		local:   local,
+	}
+}
+
+func (b *broadcaster) Publish(ctx context.Context, e events.Event) error {
+	defer b.log.Sync()
+	b.log.Debug("broadcaster: Publish()", logging.String("type", string(e.Type)))
+
+	if err := b.notify(ctx, e); err != nil {
+		b.log.Error("broadcaster: Publish(): could not notify", logging.String("error", err.Error()))
+		// local clients would silently miss this event otherwise
+		b.local.Reset()
+		return err
+	}
+	return nil
+}
+
+func (b *broadcaster) notify(ctx context.Context, e events.Event) error {
+	tx, err := b.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the counter row stays locked until commit and notifications are
+	// queued before it, so ids come to listeners in ascending order
+	var id int64
+	if err := tx.QueryRow(ctx, "UPDATE event_ids SET last_id = last_id + 1 RETURNING last_id").Scan(&id); err != nil {
+		return err
+	}
+	e.ID = strconv.FormatInt(id, 10)
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if len(payload) > notifyPayloadLimit {
+		// clients will still know that something changed
+		// and can fetch the todo themselves
+		e.Data = nil
+		if payload, err = json.Marshal(e); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", b.channel, string(payload)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Listen blocks until ctx is canceled. It holds one connection of the
+// pool and gets a new one with backoff if that connection breaks.
+func (b *broadcaster) Listen(ctx context.Context) {
+	defer b.log.Sync()
+	b.log.Info("broadcaster: Listen(): start", logging.String("channel", b.channel))
+
+	backoff := listenMinBackoff
+	reconnecting := false
+	for {
+		err := b.listen(ctx, func() {
+			backoff = listenMinBackoff
+			if reconnecting {
+				// notifications sent while we were away are lost
+				b.local.Reset()
+			}
+			reconnecting = true
+		})
+		if ctx.Err() != nil {
+			b.log.Info("broadcaster: Listen(): stopped")
+			return
+		}
+		b.log.Error(
+			"broadcaster: Listen(): connection lost, reconnecting",
+			logging.String("error", err.Error()),
+			logging.String("backoff", backoff.String()),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > listenMaxBackoff {
+			backoff = listenMaxBackoff
+		}
+	}
+}
+
+func (b *broadcaster) listen(ctx context.Context, listening func()) error {
+	conn, err := b.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// connection in LISTEN state should not go back to the pool,
+		// release destroys closed connections
+		conn.Conn().Close(context.Background())
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+	listening()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var e events.Event
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			b.log.Error("broadcaster: listen(): invalid payload", logging.String("error", err.Error()))
+			continue
+		}
+		if err := b.local.Publish(ctx, e); err != nil {
+			b.log.Error("broadcaster: listen(): could not publish locally", logging.String("error", err.Error()))
+		}
+	}
+}
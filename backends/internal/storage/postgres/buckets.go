@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type bucketsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+// Take locks the row of a bucket, so concurrent requests
+// from different instances of the api are serialized.
+// The very first request for a key has nothing to lock, but
+// its bucket is full anyway so it does not really matter.
+func (r *bucketsRepository) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (res ratelimit.Result, err error) {
+	selectSQL, selectArgs, err := sq.
+		Select("tokens, updated_at").
+		From("rate_limits").
+		Where(sq.Eq{"key": key}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return res, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("bucketsRepository: Take()", logging.String("sql", selectSQL))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback(ctx)
+
+	var b ratelimit.Bucket
+	err = tx.QueryRow(ctx, selectSQL, selectArgs...).Scan(&b.Tokens, &b.UpdatedAt)
+	if err != nil && err != pgx.ErrNoRows {
+		return res, err
+	}
+
+	b, res = b.Take(limit, now)
+
+	upsertSQL, upsertArgs, err := sq.
+		Insert("rate_limits").
+		Columns("key", "tokens", "updated_at").
+		Values(key, b.Tokens, b.UpdatedAt).
+		Suffix(`ON CONFLICT (key) DO UPDATE
+			SET tokens = EXCLUDED.tokens,
+			updated_at = EXCLUDED.updated_at`).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return res, err
+	}
+	if _, err := tx.Exec(ctx, upsertSQL, upsertArgs...); err != nil {
+		return res, err
+	}
+
+	return res, tx.Commit(ctx)
+}
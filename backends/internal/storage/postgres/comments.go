@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/comments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type commentsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+func selectComments() sq.SelectBuilder {
+	return sq.
+		Select("c.id, c.todo_id, c.user_id, u.username, c.body, c.created_at, c.edited_at").
+		From("comments AS c").
+		Join("users AS u ON u.id = c.user_id").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanComment(row pgx.Row) (c comments.Comment, err error) {
+	var (
+		author   users.User
+		editedAt pq.NullTime
+	)
+	err = row.Scan(&c.ID, &c.TodoID, &author.ID, &author.Username, &c.Body, &c.CreatedAt, &editedAt)
+	if err != nil {
+		return c, err
+	}
+	if editedAt.Valid {
+		c.EditedAt = &editedAt.Time
+	}
+	c.Author = &author
+	return c, nil
+}
+
+func (r *commentsRepository) Create(ctx context.Context, inp comments.CreateInput) (id string, err error) {
+	sql, args, err := sq.
+		Insert("comments").
+		Columns("todo_id", "user_id", "body", "created_at").
+		Values(inp.TodoID, inp.AuthorID, inp.Body, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("commentsRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *commentsRepository) Get(ctx context.Context, id string) (comments.Comment, error) {
+	sql, args, err := selectComments().Where(sq.Eq{"c.id::text": id}).ToSql()
+	if err != nil {
+		return comments.Comment{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("commentsRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return comments.Comment{}, err
+	}
+	defer conn.Release()
+
+	c, err := scanComment(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return c, comments.ErrNoSuchComment
+	}
+	return c, err
+}
+
+func (r *commentsRepository) GetAll(ctx context.Context, inp comments.GetAllInput) ([]comments.Comment, error) {
+	sql, args, err := selectComments().
+		Where(sq.Eq{"c.todo_id::text": inp.TodoID}).
+		OrderBy("c.created_at ASC").
+		Limit(uint64(inp.PageSize)).
+		Offset(uint64(inp.PageSize * inp.Page)).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("commentsRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []comments.Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (r *commentsRepository) Update(ctx context.Context, inp comments.UpdateInput) error {
+	sql, args, err := sq.
+		Update("comments").
+		Set("body", inp.Body).
+		Set("edited_at", time.Now().UTC()).
+		Where(sq.Eq{"id::text": inp.ID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("commentsRepository: Update()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *commentsRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("comments").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("commentsRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *commentsRepository) GetUsers(ctx context.Context, usernames []string) ([]users.User, error) {
+	sql, args, err := sq.
+		Select("id, username").
+		From("users").
+		Where(sq.Eq{"username": usernames}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("commentsRepository: GetUsers()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []users.User{}
+	for rows.Next() {
+		var u users.User
+		if err := rows.Scan(&u.ID, &u.Username); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+// blockersGraphSQL returns every dependency that a todo waits for,
+// directly or through other todos
+const blockersGraphSQL = `WITH RECURSIVE reach(todo_id, blocker_id) AS (
+		SELECT todo_id, blocker_id FROM todo_dependencies WHERE todo_id::text = $1
+		UNION
+		SELECT d.todo_id, d.blocker_id FROM todo_dependencies AS d
+		INNER JOIN reach AS r ON d.todo_id = r.blocker_id
+	)
+	SELECT todo_id::text, blocker_id::text FROM reach`
+
+func (r *todosRepository) AddBlocker(ctx context.Context, id, blockerID string) error {
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: AddBlocker()", logging.String("sql", blockersGraphSQL))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// a cycle can go through any todo, so two dependencies
+	// can't be checked at the same time without missing it
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('todos.dependencies'))"); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, blockersGraphSQL, blockerID)
+	if err != nil {
+		return err
+	}
+	g := todos.Graph{}
+	for rows.Next() {
+		var todoID, b string
+		if err := rows.Scan(&todoID, &b); err != nil {
+			rows.Close()
+			return err
+		}
+		g[todoID] = append(g[todoID], b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if g.Path(blockerID, id) != nil {
+		return todos.ErrCycle
+	}
+
+	sql, args, err := sq.
+		Insert("todo_dependencies").
+		Columns("todo_id", "blocker_id").
+		Values(id, blockerID).
+		Suffix("ON CONFLICT DO NOTHING").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+	return writeDependencyEvent(ctx, tx, id)
+}
+
+func (r *todosRepository) RemoveBlocker(ctx context.Context, id, blockerID string) error {
+	sql, args, err := sq.
+		Delete("todo_dependencies").
+		Where(sq.Eq{"todo_id::text": id, "blocker_id::text": blockerID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: RemoveBlocker()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return todos.ErrNoSuchDependency
+	}
+	return writeDependencyEvent(ctx, tx, id)
+}
+
+// writeDependencyEvent tells subscribers of a todo that its
+// blockers have changed and commits the transaction
+func writeDependencyEvent(ctx context.Context, tx pgx.Tx, id string) error {
+	todo, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := writeTodoEvent(ctx, tx, events.TodoUpdated, todo); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *todosRepository) OpenBlockers(ctx context.Context, id string) ([]string, error) {
+	sql, args, err := sq.
+		Select("t.id::text").
+		From("todo_dependencies AS d").
+		InnerJoin("todos AS t ON t.id = d.blocker_id").
+		Where(sq.Eq{"d.todo_id::text": id, "t.completed": false}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: OpenBlockers()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *todosRepository) GetOpen(ctx context.Context, listID string) ([]todos.Todo, error) {
+	sql, args, err := sq.
+		Select(todoColumns).
+		From("todos").
+		Where(sq.Eq{"list_id::text": listID, "completed": false}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		OrderBy(sortingVariants[todos.SortByManual]).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: GetOpen()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTodos(rows)
+}
@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type digestsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+func scanDigestSettings(row pgx.Row, extra ...interface{}) (s digests.Settings, err error) {
+	var (
+		weekday    int
+		nextSendAt pq.NullTime
+		lastSentAt pq.NullTime
+	)
+	dest := append([]interface{}{
+		&s.UserID, &s.Frequency, &s.Timezone, &s.Hour, &weekday, &nextSendAt, &lastSentAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return s, err
+	}
+	s.Weekday = time.Weekday(weekday)
+	if nextSendAt.Valid {
+		s.NextSendAt = &nextSendAt.Time
+	}
+	if lastSentAt.Valid {
+		s.LastSentAt = &lastSentAt.Time
+	}
+	return s, nil
+}
+
+func (r *digestsRepository) GetSettings(ctx context.Context, userID string) (digests.Settings, error) {
+	sql, args, err := sq.
+		Select("user_id, frequency, timezone, hour, weekday, next_send_at, last_sent_at").
+		From("digest_settings").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return digests.Settings{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("digestsRepository: GetSettings()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return digests.Settings{}, err
+	}
+	defer conn.Release()
+
+	s, err := scanDigestSettings(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return digests.DefaultSettings(userID), nil
+	}
+	return s, err
+}
+
+func (r *digestsRepository) SaveSettings(ctx context.Context, s digests.Settings) error {
+	sql, args, err := sq.
+		Insert("digest_settings").
+		Columns("user_id", "frequency", "timezone", "hour", "weekday", "next_send_at", "updated_at").
+		Values(s.UserID, s.Frequency, s.Timezone, s.Hour, int(s.Weekday), s.NextSendAt, time.Now().UTC()).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE SET
+			frequency = EXCLUDED.frequency, timezone = EXCLUDED.timezone,
+			hour = EXCLUDED.hour, weekday = EXCLUDED.weekday,
+			next_send_at = EXCLUDED.next_send_at, updated_at = EXCLUDED.updated_at`).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("digestsRepository: SaveSettings()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+const claimDigestsSQL = `UPDATE digest_settings AS d SET next_send_at = $2
+	FROM users AS u
+	WHERE d.user_id = u.id AND d.user_id IN (
+		SELECT user_id FROM digest_settings
+		WHERE frequency <> 'off' AND next_send_at <= $1
+		ORDER BY next_send_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING d.user_id, d.frequency, d.timezone, d.hour, d.weekday, d.next_send_at, d.last_sent_at,
+		u.email, u.username`
+
+func (r *digestsRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]digests.Due, error) {
+	defer r.log.Sync()
+	r.log.Debug("digestsRepository: ClaimDue()", logging.String("sql", claimDigestsSQL))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, claimDigestsSQL, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []digests.Due{}
+	for rows.Next() {
+		var due digests.Due
+		due.Settings, err = scanDigestSettings(rows, &due.Email, &due.Username)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, due)
+	}
+	return list, rows.Err()
+}
+
+func (r *digestsRepository) Reschedule(ctx context.Context, userID string, next time.Time, sentAt *time.Time) error {
+	var nextSendAt *time.Time
+	if !next.IsZero() {
+		nextSendAt = &next
+	}
+	sql, args, err := sq.
+		Update("digest_settings").
+		Set("next_send_at", nextSendAt).
+		Set("last_sent_at", sq.Expr("COALESCE(?, last_sent_at)", sentAt)).
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("digestsRepository: Reschedule()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *digestsRepository) GetTodos(ctx context.Context, userID string, before time.Time) ([]todos.Todo, error) {
+	sql, args, err := sq.
+		Select("id, title, description, completed, deadline, created_at, updated_at").
+		From("todos").
+		Where(sq.Eq{"user_id": userID, "completed": false}).
+		Where("deadline > 'epoch'").
+		Where(sq.Lt{"deadline": before}).
+		OrderBy("deadline ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("digestsRepository: GetTodos()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []todos.Todo{}
+	for rows.Next() {
+		var (
+			todo      todos.Todo
+			updatedAt pq.NullTime
+		)
+		err := rows.Scan(&todo.ID, &todo.Title, &todo.Body, &todo.Completed, &todo.Deadline, &todo.CreatedAt, &updatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			todo.UpdatedAt = updatedAt.Time
+		}
+		todo.Author = &users.User{ID: userID}
+		list = append(list, todo)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/exports"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type exportsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+const exportsColumns = `id, user_id, status, error, path,
+	created_at, finished_at, expires_at`
+
+func scanExport(row pgx.Row) (job exports.Job, err error) {
+	var (
+		reason     *string
+		path       *string
+		finishedAt pq.NullTime
+		expiresAt  pq.NullTime
+	)
+	err = row.Scan(
+		&job.ID, &job.UserID, &job.Status, &reason, &path,
+		&job.CreatedAt, &finishedAt, &expiresAt,
+	)
+	if err != nil {
+		return job, err
+	}
+	if reason != nil {
+		job.Error = *reason
+	}
+	if path != nil {
+		job.Path = *path
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = finishedAt.Time
+	}
+	if expiresAt.Valid {
+		job.ExpiresAt = expiresAt.Time
+	}
+	return job, nil
+}
+
+func (r *exportsRepository) Create(ctx context.Context, userID string) (id string, err error) {
+	sql, args, err := sq.
+		Insert("exports").
+		Columns("user_id", "status", "created_at").
+		Values(userID, exports.StatusPending, time.Now()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *exportsRepository) Get(ctx context.Context, id string) (exports.Job, error) {
+	sql, args, err := sq.
+		Select(exportsColumns).
+		From("exports").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return exports.Job{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return exports.Job{}, err
+	}
+	defer conn.Release()
+
+	job, err := scanExport(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return job, exports.ErrNoSuchJob
+	}
+	return job, err
+}
+
+func (r *exportsRepository) GetActive(ctx context.Context, userID string) (exports.Job, error) {
+	sql, args, err := sq.
+		Select(exportsColumns).
+		From("exports").
+		Where(sq.Eq{
+			"user_id": userID,
+			"status":  []exports.Status{exports.StatusPending, exports.StatusRunning},
+		}).
+		OrderBy("created_at DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return exports.Job{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: GetActive()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return exports.Job{}, err
+	}
+	defer conn.Release()
+
+	job, err := scanExport(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return job, exports.ErrNoSuchJob
+	}
+	return job, err
+}
+
+func (r *exportsRepository) GetExpired(ctx context.Context, now time.Time) ([]exports.Job, error) {
+	sql, args, err := sq.
+		Select(exportsColumns).
+		From("exports").
+		Where(sq.Lt{"expires_at": now}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: GetExpired()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []exports.Job{}
+	for rows.Next() {
+		job, err := scanExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *exportsRepository) Start(ctx context.Context, id string) error {
+	return r.update(ctx, "Start()", id, map[string]any{
+		"status": exports.StatusRunning,
+	})
+}
+
+func (r *exportsRepository) Finish(ctx context.Context, id, path string, expiresAt time.Time) error {
+	return r.update(ctx, "Finish()", id, map[string]any{
+		"status":      exports.StatusDone,
+		"path":        path,
+		"finished_at": time.Now(),
+		"expires_at":  expiresAt,
+	})
+}
+
+func (r *exportsRepository) Fail(ctx context.Context, id, reason string) error {
+	return r.update(ctx, "Fail()", id, map[string]any{
+		"status":      exports.StatusFailed,
+		"error":       reason,
+		"finished_at": time.Now(),
+	})
+}
+
+func (r *exportsRepository) FailStale(ctx context.Context, before time.Time, reason string) error {
+	sql, args, err := sq.
+		Update("exports").
+		Set("status", exports.StatusFailed).
+		Set("error", reason).
+		Set("finished_at", time.Now()).
+		Where(sq.Eq{"status": []exports.Status{exports.StatusPending, exports.StatusRunning}}).
+		Where(sq.Lt{"created_at": before}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: FailStale()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *exportsRepository) update(ctx context.Context, method, id string, set map[string]any) error {
+	sql, args, err := sq.
+		Update("exports").
+		SetMap(set).
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: "+method, logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *exportsRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("exports").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("exportsRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
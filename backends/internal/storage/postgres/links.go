@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/links"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type linksRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+func selectLinks() sq.SelectBuilder {
+	return sq.
+		Select(`id, resource_type, resource_id, owner_id, COALESCE(password_hash, ''),
+			views, last_viewed_at, created_at, expires_at, revoked_at`).
+		From("public_links").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanLink(row pgx.Row) (l links.Link, err error) {
+	var lastViewedAt, expiresAt, revokedAt pq.NullTime
+	err = row.Scan(
+		&l.ID, &l.ResourceType, &l.ResourceID, &l.OwnerID, &l.PasswordHash,
+		&l.Views, &lastViewedAt, &l.CreatedAt, &expiresAt, &revokedAt,
+	)
+	if err != nil {
+		return l, err
+	}
+	l.HasPassword = l.PasswordHash != ""
+	if lastViewedAt.Valid {
+		l.LastViewedAt = &lastViewedAt.Time
+	}
+	if expiresAt.Valid {
+		l.ExpiresAt = &expiresAt.Time
+	}
+	if revokedAt.Valid {
+		l.RevokedAt = &revokedAt.Time
+	}
+	return l, nil
+}
+
+func (r *linksRepository) Create(ctx context.Context, l links.Link) (id string, err error) {
+	var expiresAt *time.Time
+	if l.ExpiresAt != nil {
+		t := l.ExpiresAt.UTC()
+		expiresAt = &t
+	}
+	sql, args, err := sq.
+		Insert("public_links").
+		Columns("resource_type", "resource_id", "owner_id", "password_hash", "created_at", "expires_at").
+		Values(l.ResourceType, l.ResourceID, l.OwnerID, nullString(l.PasswordHash), time.Now().UTC(), expiresAt).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("linksRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *linksRepository) Get(ctx context.Context, id string) (links.Link, error) {
+	sql, args, err := selectLinks().Where(sq.Eq{"id::text": id}).ToSql()
+	if err != nil {
+		return links.Link{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("linksRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return links.Link{}, err
+	}
+	defer conn.Release()
+
+	l, err := scanLink(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return l, links.ErrNoSuchLink
+	}
+	return l, err
+}
+
+func (r *linksRepository) GetAll(ctx context.Context, ownerID string) ([]links.Link, error) {
+	sql, args, err := selectLinks().
+		Where(sq.Eq{"owner_id::text": ownerID}).
+		OrderBy("created_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("linksRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []links.Link{}
+	for rows.Next() {
+		l, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func (r *linksRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	sql, args, err := sq.
+		Update("public_links").
+		Set("revoked_at", at).
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("linksRepository: Revoke()", logging.String("sql", sql))
+
+	return r.exec(ctx, sql, args...)
+}
+
+func (r *linksRepository) RecordView(ctx context.Context, id string, at time.Time) error {
+	sql, args, err := sq.
+		Update("public_links").
+		Set("views", sq.Expr("views + 1")).
+		Set("last_viewed_at", at).
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("linksRepository: RecordView()", logging.String("sql", sql))
+
+	return r.exec(ctx, sql, args...)
+}
+
+func (r *linksRepository) exec(ctx context.Context, sql string, args ...interface{}) error {
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,416 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/lists"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type listsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+func scanList(row pgx.Row, extra ...interface{}) (l lists.List, err error) {
+	var (
+		workspaceID *string
+		workflow    []byte
+		fields      []byte
+		updatedAt   pq.NullTime
+	)
+	dest := append([]interface{}{
+		&l.ID, &l.OwnerID, &l.Name, &workspaceID, &workflow, &fields, &l.CreatedAt, &updatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return l, err
+	}
+	if workspaceID != nil {
+		l.WorkspaceID = *workspaceID
+	}
+	if l.Workflow, err = unmarshalWorkflow(workflow); err != nil {
+		return l, err
+	}
+	if l.Fields, err = unmarshalFields(fields); err != nil {
+		return l, err
+	}
+	if updatedAt.Valid {
+		l.UpdatedAt = updatedAt.Time
+	}
+	return l, nil
+}
+
+func (r *listsRepository) Create(ctx context.Context, inp lists.CreateInput) (id string, err error) {
+	sql, args, err := sq.
+		Insert("lists").
+		Columns("user_id", "name", "workspace_id", "created_at").
+		Values(inp.UserID, inp.Name, scopeValue(ctx), time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *listsRepository) Get(ctx context.Context, id string) (lists.List, error) {
+	sql, args, err := sq.
+		Select("id, user_id, name, workspace_id, workflow, fields, created_at, updated_at").
+		From("lists").
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return lists.List{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return lists.List{}, err
+	}
+	defer conn.Release()
+
+	l, err := scanList(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return l, lists.ErrNoSuchList
+	}
+	return l, err
+}
+
+func (r *listsRepository) GetAll(ctx context.Context, userID string) ([]lists.List, error) {
+	query := sq.
+		Select(`l.id, l.user_id, l.name, l.workspace_id, l.workflow, l.fields, l.created_at, l.updated_at,
+			CASE WHEN s.id IS NULL THEN 'owner' ELSE s.permission END`).
+		From("lists AS l").
+		LeftJoin(`shares AS s ON s.resource_type = ? AND s.resource_id = l.id
+			AND s.invitee_id = ? AND s.status = ?`,
+			shares.ResourceList, userID, shares.StatusAccepted).
+		Where(sq.Or{sq.Eq{"l.user_id": userID}, sq.NotEq{"s.id": nil}})
+	if inWorkspace(ctx) {
+		// members see every list of their workspace, admins own all of them
+		query = sq.
+			Select(`l.id, l.user_id, l.name, l.workspace_id, l.workflow, l.fields, l.created_at, l.updated_at,
+				CASE WHEN l.user_id = m.user_id OR m.role IN ('owner', 'admin') THEN 'owner' ELSE 'editor' END`).
+			From("lists AS l").
+			Join("workspace_members AS m ON m.workspace_id = l.workspace_id AND m.user_id = ?", userID)
+	}
+	sql, args, err := query.
+		Where(scopeFilter(ctx, "l.workspace_id")).
+		OrderBy("l.created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := []lists.List{}
+	for rows.Next() {
+		var p shares.Permission
+		l, err := scanList(rows, &p)
+		if err != nil {
+			return nil, err
+		}
+		l.Permission = p
+		all = append(all, l)
+	}
+	return all, rows.Err()
+}
+
+func (r *listsRepository) Update(ctx context.Context, inp lists.UpdateInput) error {
+	sql, args, err := sq.
+		Update("lists").
+		Set("name", inp.Name).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id::text": inp.ID}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: Update()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+// unmarshalWorkflow turns nulls into the default workflow
+func unmarshalWorkflow(data []byte) (todos.Workflow, error) {
+	if data == nil {
+		return todos.DefaultWorkflow, nil
+	}
+	var w todos.Workflow
+	err := json.Unmarshal(data, &w)
+	return w, err
+}
+
+func (r *listsRepository) Workflow(ctx context.Context, listID string) (todos.Workflow, error) {
+	sql, args, err := sq.
+		Select("workflow").
+		From("lists").
+		Where(sq.Eq{"id::text": listID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return todos.Workflow{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: Workflow()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return todos.Workflow{}, err
+	}
+	defer conn.Release()
+
+	var data []byte
+	err = conn.QueryRow(ctx, sql, args...).Scan(&data)
+	if err == pgx.ErrNoRows {
+		return todos.Workflow{}, todos.ErrNoSuchList
+	}
+	if err != nil {
+		return todos.Workflow{}, err
+	}
+	return unmarshalWorkflow(data)
+}
+
+func (r *listsRepository) SetWorkflow(ctx context.Context, id string, w todos.Workflow) error {
+	data, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	sql, args, err := sq.
+		Update("lists").
+		Set("workflow", data).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: SetWorkflow()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return lists.ErrNoSuchList
+	}
+
+	var known, terminal []string
+	for _, st := range w.Statuses {
+		known = append(known, string(st.Key))
+		if st.Terminal {
+			terminal = append(terminal, string(st.Key))
+		}
+	}
+	// it is done in bulk, so these moves don't make it into history
+	_, err = tx.Exec(ctx, `UPDATE todos
+		SET status = CASE WHEN completed THEN $2 ELSE $3 END
+		WHERE list_id::text = $1 AND NOT (status = ANY($4))`,
+		id, string(w.Done()), string(w.Initial()), known)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, `UPDATE todos SET completed = (status = ANY($2)) WHERE list_id::text = $1`, id, terminal)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// unmarshalFields turns nulls into no fields
+func unmarshalFields(data []byte) (todos.Fields, error) {
+	f := todos.Fields{}
+	if data == nil {
+		return f, nil
+	}
+	err := json.Unmarshal(data, &f)
+	return f, err
+}
+
+func (r *listsRepository) Fields(ctx context.Context, listID string) (todos.Fields, error) {
+	sql, args, err := sq.
+		Select("fields").
+		From("lists").
+		Where(sq.Eq{"id::text": listID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: Fields()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var data []byte
+	err = conn.QueryRow(ctx, sql, args...).Scan(&data)
+	if err == pgx.ErrNoRows {
+		return nil, todos.ErrNoSuchList
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalFields(data)
+}
+
+func (r *listsRepository) SetFields(ctx context.Context, id string, f todos.Fields) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	sql, args, err := sq.
+		Update("lists").
+		Set("fields", data).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: SetFields()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var old []byte
+	err = tx.QueryRow(ctx, "SELECT fields FROM lists WHERE id::text = $1 FOR UPDATE", id).Scan(&old)
+	if err == pgx.ErrNoRows {
+		return lists.ErrNoSuchList
+	}
+	if err != nil {
+		return err
+	}
+	before, err := unmarshalFields(old)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return lists.ErrNoSuchList
+	}
+
+	// values are dropped in bulk, so it doesn't make it into events
+	dropped := []string{}
+	for _, d := range before {
+		now, ok := f.Get(d.Key)
+		switch {
+		case !ok || now.Type != d.Type:
+			dropped = append(dropped, d.Key)
+		case now.Type == todos.FieldSelect:
+			_, err = tx.Exec(ctx, `UPDATE todos SET fields = fields - $2::text
+				WHERE list_id::text = $1 AND fields ->> $2::text IS NOT NULL AND NOT (fields ->> $2::text = ANY($3))`,
+				id, d.Key, now.Options)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if len(dropped) != 0 {
+		_, err = tx.Exec(ctx, `UPDATE todos SET fields = fields - $2::text[] WHERE list_id::text = $1`, id, dropped)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *listsRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("lists").
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("listsRepository: Delete()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// todos of the list are taken out of it by the foreign key
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return lists.ErrNoSuchList
+	}
+	if err := deleteShares(ctx, tx, shares.ResourceList, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
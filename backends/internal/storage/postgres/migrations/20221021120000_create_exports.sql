@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS exports (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    error text,
+    path text,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    finished_at timestamp,
+    expires_at timestamp,
+    CONSTRAINT fk_exports_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_exports_user_id ON exports(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS exports CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS delete_after timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_users_delete_after ON users(delete_after);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS delete_after;
+-- +goose StatementEnd
@@ -0,0 +1,73 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS outbox (
+    id bigserial PRIMARY KEY,
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    todo_id uuid,
+    payload jsonb NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_outbox_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL,
+    enabled boolean NOT NULL DEFAULT true,
+    failures int NOT NULL DEFAULT 0,
+    disabled_at timestamp,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    updated_at timestamp,
+    CONSTRAINT fk_webhooks_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id uuid NOT NULL,
+    event_id bigint,
+    type text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    attempts int NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL DEFAULT NOW(),
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    delivered_at timestamp,
+    CONSTRAINT fk_webhook_deliveries_webhooks_id FOREIGN KEY(webhook_id)
+        REFERENCES webhooks(id) ON DELETE CASCADE,
+    -- an event is delivered to a webhook only once
+    CONSTRAINT uq_webhook_deliveries_event UNIQUE(webhook_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id
+    ON webhook_deliveries(webhook_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    id bigserial PRIMARY KEY,
+    delivery_id uuid NOT NULL,
+    status_code int NOT NULL DEFAULT 0,
+    error text,
+    duration_ms int NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_webhook_attempts_deliveries_id FOREIGN KEY(delivery_id)
+        REFERENCES webhook_deliveries(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_attempts_delivery_id ON webhook_attempts(delivery_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_attempts CASCADE;
+DROP TABLE IF EXISTS webhook_deliveries CASCADE;
+DROP TABLE IF EXISTS webhooks CASCADE;
+DROP TABLE IF EXISTS outbox CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+-- user.deleted has to outlive the user
+ALTER TABLE outbox DROP CONSTRAINT IF EXISTS fk_outbox_users_id;
+
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS attempts int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamp NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS relayed_at timestamp,
+    ADD COLUMN IF NOT EXISTS error text;
+
+CREATE INDEX IF NOT EXISTS idx_outbox_due
+    ON outbox(next_attempt_at) WHERE relayed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_relayed_at
+    ON outbox(relayed_at) WHERE relayed_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS outbox_handled (
+    event_id bigint NOT NULL,
+    handler text NOT NULL,
+    handled_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(event_id, handler),
+    CONSTRAINT fk_outbox_handled_outbox_id FOREIGN KEY(event_id)
+        REFERENCES outbox(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox_handled CASCADE;
+
+DROP INDEX IF EXISTS idx_outbox_relayed_at;
+DROP INDEX IF EXISTS idx_outbox_due;
+
+ALTER TABLE outbox
+    DROP COLUMN IF EXISTS error,
+    DROP COLUMN IF EXISTS relayed_at,
+    DROP COLUMN IF EXISTS next_attempt_at,
+    DROP COLUMN IF EXISTS attempts;
+
+DELETE FROM outbox WHERE user_id NOT IN (SELECT id FROM users);
+ALTER TABLE outbox ADD CONSTRAINT fk_outbox_users_id FOREIGN KEY(user_id)
+    REFERENCES users(id) ON DELETE CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS reminders (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    remind_at timestamp,
+    offset_minutes int,
+    sent_at timestamp,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_reminders_todos_id FOREIGN KEY(todo_id)
+        REFERENCES todos(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reminders_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    -- a reminder is either at an exact time or relative to the deadline
+    CONSTRAINT ck_reminders_time CHECK ((remind_at IS NULL) <> (offset_minutes IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_todo_id ON reminders(todo_id);
+CREATE INDEX IF NOT EXISTS idx_reminders_pending
+    ON reminders(remind_at) WHERE sent_at IS NULL;
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS overdue_notified_at timestamp;
+
+-- todos that are already overdue should not all notify right after deploy
+UPDATE todos SET overdue_notified_at = NOW()
+    WHERE NOT completed AND deadline > 'epoch' AND deadline <= NOW();
+
+CREATE INDEX IF NOT EXISTS idx_todos_overdue
+    ON todos(deadline) WHERE overdue_notified_at IS NULL AND NOT completed;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_overdue;
+ALTER TABLE todos DROP COLUMN IF EXISTS overdue_notified_at;
+DROP TABLE IF EXISTS reminders CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notifications (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    title text NOT NULL,
+    body text NOT NULL DEFAULT '',
+    -- notifications outlive todos they are about
+    todo_id uuid,
+    data jsonb,
+    key text,
+    read_at timestamp,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_notifications_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_notifications_key UNIQUE(user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id
+    ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread
+    ON notifications(user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id uuid NOT NULL,
+    type text NOT NULL,
+    in_app boolean NOT NULL DEFAULT true,
+    email boolean NOT NULL DEFAULT true,
+    PRIMARY KEY(user_id, type),
+    CONSTRAINT fk_notification_preferences_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_preferences CASCADE;
+DROP TABLE IF EXISTS notifications CASCADE;
+-- +goose StatementEnd
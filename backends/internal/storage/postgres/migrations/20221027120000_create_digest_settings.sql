@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS digest_settings (
+    user_id uuid PRIMARY KEY,
+    frequency text NOT NULL DEFAULT 'off',
+    timezone text NOT NULL DEFAULT 'UTC',
+    hour int NOT NULL DEFAULT 8,
+    weekday int NOT NULL DEFAULT 1,
+    next_send_at timestamp,
+    last_sent_at timestamp,
+    updated_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_digest_settings_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_settings_due
+    ON digest_settings(next_send_at) WHERE frequency <> 'off';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS digest_settings CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS lists (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    name text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    updated_at timestamp,
+    CONSTRAINT fk_lists_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_lists_user_id ON lists(user_id);
+
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS list_id uuid
+    CONSTRAINT fk_todos_lists_id REFERENCES lists(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_list_id ON todos(list_id);
+
+-- resource_id points either to a todo or to a list,
+-- so shares are deleted together with them by hand
+CREATE TABLE IF NOT EXISTS shares (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    resource_type text NOT NULL,
+    resource_id uuid NOT NULL,
+    owner_id uuid NOT NULL,
+    invitee_id uuid NOT NULL,
+    permission text NOT NULL,
+    status text NOT NULL DEFAULT 'pending',
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    responded_at timestamp,
+    CONSTRAINT fk_shares_owner_id FOREIGN KEY(owner_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_shares_invitee_id FOREIGN KEY(invitee_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_shares_invitee UNIQUE(resource_type, resource_id, invitee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shares_invitee_id ON shares(invitee_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS shares CASCADE;
+DROP INDEX IF EXISTS idx_todos_list_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS list_id;
+DROP TABLE IF EXISTS lists CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS assignee_id uuid
+    CONSTRAINT fk_todos_assignee_id REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_todos_assignee_id ON todos(assignee_id);
+
+CREATE TABLE IF NOT EXISTS todo_history (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id uuid NOT NULL,
+    -- history stays when its actor deletes his account
+    actor_id uuid,
+    action text NOT NULL,
+    data jsonb,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_todo_history_todos_id FOREIGN KEY(todo_id)
+        REFERENCES todos(id) ON DELETE CASCADE,
+    CONSTRAINT fk_todo_history_actor_id FOREIGN KEY(actor_id)
+        REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_history_todo_id
+    ON todo_history(todo_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS todo_history CASCADE;
+DROP INDEX IF EXISTS idx_todos_assignee_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS assignee_id;
+-- +goose StatementEnd
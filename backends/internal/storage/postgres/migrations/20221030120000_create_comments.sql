@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS comments (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    body text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    edited_at timestamp,
+    CONSTRAINT fk_comments_todos_id FOREIGN KEY(todo_id)
+        REFERENCES todos(id) ON DELETE CASCADE,
+    CONSTRAINT fk_comments_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_todo_id ON comments(todo_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS comments CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- todo_id becomes NULL when a todo is deleted, such attachments
+-- are deleted together with their files by a background job
+CREATE TABLE IF NOT EXISTS attachments (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    todo_id uuid,
+    user_id uuid,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    storage_key text NOT NULL UNIQUE,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_attachments_todos_id FOREIGN KEY(todo_id)
+        REFERENCES todos(id) ON DELETE SET NULL,
+    CONSTRAINT fk_attachments_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_todo_id ON attachments(todo_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments(user_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_orphaned ON attachments(created_at) WHERE todo_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS attachments CASCADE;
+-- +goose StatementEnd
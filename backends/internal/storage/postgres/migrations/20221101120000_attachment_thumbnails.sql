@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE attachments
+    ADD COLUMN IF NOT EXISTS thumbnail_status text NOT NULL DEFAULT 'none',
+    ADD COLUMN IF NOT EXISTS thumbnails jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN IF NOT EXISTS thumbnail_attempts int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS thumbnail_claimed_until timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_thumbnails_pending
+    ON attachments(created_at) WHERE thumbnail_status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_attachments_thumbnails_pending;
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS thumbnail_status,
+    DROP COLUMN IF EXISTS thumbnails,
+    DROP COLUMN IF EXISTS thumbnail_attempts,
+    DROP COLUMN IF EXISTS thumbnail_claimed_until;
+-- +goose StatementEnd
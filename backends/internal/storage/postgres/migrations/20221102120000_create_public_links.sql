@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- like shares, links are deleted together with their todos and lists by hand
+CREATE TABLE IF NOT EXISTS public_links (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    resource_type text NOT NULL,
+    resource_id uuid NOT NULL,
+    owner_id uuid NOT NULL,
+    password_hash text,
+    views bigint NOT NULL DEFAULT 0,
+    last_viewed_at timestamp,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    expires_at timestamp,
+    revoked_at timestamp,
+    CONSTRAINT fk_public_links_owner_id FOREIGN KEY(owner_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_public_links_owner_id ON public_links(owner_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_public_links_resource ON public_links(resource_type, resource_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS public_links CASCADE;
+-- +goose StatementEnd
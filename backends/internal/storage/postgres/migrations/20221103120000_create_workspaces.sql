@@ -0,0 +1,66 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workspaces (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name text NOT NULL,
+    owner_id uuid NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    updated_at timestamp,
+    CONSTRAINT fk_workspaces_owner_id FOREIGN KEY(owner_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    role text NOT NULL,
+    joined_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(workspace_id, user_id),
+    CONSTRAINT fk_workspace_members_workspace_id FOREIGN KEY(workspace_id)
+        REFERENCES workspaces(id) ON DELETE CASCADE,
+    CONSTRAINT fk_workspace_members_user_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user_id ON workspace_members(user_id);
+
+CREATE TABLE IF NOT EXISTS workspace_invitations (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    workspace_id uuid NOT NULL,
+    email text NOT NULL,
+    role text NOT NULL,
+    invited_by uuid,
+    status text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    responded_at timestamp,
+    CONSTRAINT fk_workspace_invitations_workspace_id FOREIGN KEY(workspace_id)
+        REFERENCES workspaces(id) ON DELETE CASCADE,
+    CONSTRAINT fk_workspace_invitations_invited_by FOREIGN KEY(invited_by)
+        REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- only one pending invitation per email, answered ones are kept as history
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workspace_invitations_pending
+    ON workspace_invitations(workspace_id, email) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_workspace_invitations_email ON workspace_invitations(email);
+
+-- todos and lists without a workspace live in the personal space of their owners
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS workspace_id uuid
+    CONSTRAINT fk_todos_workspace_id REFERENCES workspaces(id) ON DELETE CASCADE;
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS workspace_id uuid
+    CONSTRAINT fk_lists_workspace_id REFERENCES workspaces(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_todos_workspace_id ON todos(workspace_id);
+CREATE INDEX IF NOT EXISTS idx_lists_workspace_id ON lists(workspace_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_workspace_id;
+DROP INDEX IF EXISTS idx_lists_workspace_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS workspace_id;
+ALTER TABLE lists DROP COLUMN IF EXISTS workspace_id;
+DROP TABLE IF EXISTS workspace_invitations CASCADE;
+DROP TABLE IF EXISTS workspace_members CASCADE;
+DROP TABLE IF EXISTS workspaces CASCADE;
+-- +goose StatementEnd
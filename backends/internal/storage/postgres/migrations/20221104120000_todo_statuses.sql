@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- completed stays, it is true for todos in terminal statuses
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'backlog';
+UPDATE todos SET status = 'done' WHERE completed;
+
+CREATE INDEX IF NOT EXISTS idx_todos_status ON todos(status);
+
+-- lists without a workflow use the default one
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS workflow jsonb;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_status;
+ALTER TABLE todos DROP COLUMN IF EXISTS status;
+ALTER TABLE lists DROP COLUMN IF EXISTS workflow;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- todos are ordered within their list, their workspace
+-- if they have no list, or their author otherwise
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS position double precision NOT NULL DEFAULT 0;
+UPDATE todos SET position = o.n * 1024
+    FROM (SELECT id, row_number() OVER (
+        PARTITION BY COALESCE(list_id, workspace_id, user_id) ORDER BY created_at, id) AS n
+        FROM todos) AS o
+    WHERE todos.id = o.id;
+
+CREATE INDEX IF NOT EXISTS idx_todos_position ON todos((COALESCE(list_id, workspace_id, user_id)), position);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_todos_position;
+ALTER TABLE todos DROP COLUMN IF EXISTS position;
+-- +goose StatementEnd
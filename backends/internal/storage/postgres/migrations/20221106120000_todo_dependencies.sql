@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- todo_id can't be completed before blocker_id
+CREATE TABLE IF NOT EXISTS todo_dependencies (
+    todo_id uuid NOT NULL,
+    blocker_id uuid NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY(todo_id, blocker_id),
+    CONSTRAINT fk_todo_dependencies_todo_id FOREIGN KEY(todo_id)
+        REFERENCES todos(id) ON DELETE CASCADE,
+    CONSTRAINT fk_todo_dependencies_blocker_id FOREIGN KEY(blocker_id)
+        REFERENCES todos(id) ON DELETE CASCADE,
+    CONSTRAINT chk_todo_dependencies_self CHECK (todo_id <> blocker_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependencies_blocker_id ON todo_dependencies(blocker_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS todo_dependencies;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- definitions of custom fields of a list, null means there are none
+ALTER TABLE lists ADD COLUMN IF NOT EXISTS fields jsonb;
+-- values of custom fields by their keys
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS fields jsonb NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE todos DROP COLUMN IF EXISTS fields;
+ALTER TABLE lists DROP COLUMN IF EXISTS fields;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS templates (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    workspace_id uuid,
+    -- the top task with its subtasks, they are only read and written together
+    task jsonb NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    updated_at timestamp,
+    CONSTRAINT fk_templates_users_id FOREIGN KEY(user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_templates_workspaces_id FOREIGN KEY(workspace_id)
+        REFERENCES workspaces(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_templates_user_id ON templates(user_id);
+CREATE INDEX IF NOT EXISTS idx_templates_workspace_id ON templates(workspace_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS templates CASCADE;
+-- +goose StatementEnd
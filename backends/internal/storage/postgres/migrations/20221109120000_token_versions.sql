@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- access and refresh keys carry the version they were issued with,
+-- bumping it revokes all of them
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version int NOT NULL DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS token_version;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a single counter gives ids to events of all instances, unlike
+-- a sequence it has no gaps and its row lock keeps ids in order
+CREATE TABLE IF NOT EXISTS event_ids (
+    last_id bigint NOT NULL
+);
+INSERT INTO event_ids (last_id) VALUES (0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS event_ids;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- shares are sent to emails, so nobody can find out who has an account
+-- by sharing with them. Invitees are bound to shares when they answer.
+ALTER TABLE shares ADD COLUMN IF NOT EXISTS invitee_email varchar;
+UPDATE shares AS s SET invitee_email = LOWER(u.email) FROM users AS u WHERE u.id = s.invitee_id;
+ALTER TABLE shares ALTER COLUMN invitee_email SET NOT NULL;
+ALTER TABLE shares ALTER COLUMN invitee_id DROP NOT NULL;
+ALTER TABLE shares ADD CONSTRAINT uq_shares_invitee_email UNIQUE(resource_type, resource_id, invitee_email);
+
+CREATE INDEX IF NOT EXISTS idx_shares_invitee_email ON shares(invitee_email, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM shares WHERE invitee_id IS NULL;
+DROP INDEX IF EXISTS idx_shares_invitee_email;
+ALTER TABLE shares DROP CONSTRAINT IF EXISTS uq_shares_invitee_email;
+ALTER TABLE shares ALTER COLUMN invitee_id SET NOT NULL;
+ALTER TABLE shares DROP COLUMN IF EXISTS invitee_email;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- deleting an account should never take a whole team workspace with it
+ALTER TABLE workspaces DROP CONSTRAINT IF EXISTS fk_workspaces_owner_id;
+ALTER TABLE workspaces ADD CONSTRAINT fk_workspaces_owner_id FOREIGN KEY(owner_id)
+    REFERENCES users(id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workspaces DROP CONSTRAINT IF EXISTS fk_workspaces_owner_id;
+ALTER TABLE workspaces ADD CONSTRAINT fk_workspaces_owner_id FOREIGN KEY(owner_id)
+    REFERENCES users(id) ON DELETE CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type notificationsRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+const notificationsColumns = `id, user_id, type, title, body, todo_id, data, read_at, created_at`
+
+func scanNotification(row pgx.Row) (n notifications.Notification, err error) {
+	var (
+		todoID *string
+		data   []byte
+		readAt pq.NullTime
+	)
+	err = row.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &todoID, &data, &readAt, &n.CreatedAt)
+	if err != nil {
+		return n, err
+	}
+	if todoID != nil {
+		n.TodoID = *todoID
+	}
+	if data != nil {
+		n.Data = json.RawMessage(data)
+	}
+	if readAt.Valid {
+		n.ReadAt = &readAt.Time
+	}
+	return n, nil
+}
+
+func (r *notificationsRepository) Create(ctx context.Context, n notifications.Notification) (id string, created bool, err error) {
+	var (
+		todoID *string
+		key    *string
+		data   []byte
+	)
+	if n.TodoID != "" {
+		todoID = &n.TodoID
+	}
+	// nulls are never equal, so notifications without a key are never skipped
+	if n.Key != "" {
+		key = &n.Key
+	}
+	if n.Data != nil {
+		data = []byte(n.Data)
+	}
+	sql, args, err := sq.
+		Insert("notifications").
+		Columns("user_id", "type", "title", "body", "todo_id", "data", "key", "created_at").
+		Values(n.UserID, n.Type, n.Title, n.Body, todoID, data, key, time.Now().UTC()).
+		Suffix("ON CONFLICT (user_id, key) DO NOTHING RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", false, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+func (r *notificationsRepository) Get(ctx context.Context, id string) (notifications.Notification, error) {
+	sql, args, err := sq.
+		Select(notificationsColumns).
+		From("notifications").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return notifications.Notification{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return notifications.Notification{}, err
+	}
+	defer conn.Release()
+
+	n, err := scanNotification(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return n, notifications.ErrNoSuchNotification
+	}
+	return n, err
+}
+
+func (r *notificationsRepository) GetAll(ctx context.Context, inp notifications.GetAllInput) ([]notifications.Notification, error) {
+	query := sq.
+		Select(notificationsColumns).
+		From("notifications").
+		Where(sq.Eq{"user_id": inp.UserID}).
+		OrderBy("created_at DESC").
+		Limit(uint64(inp.PageSize)).
+		Offset(uint64(inp.PageSize * inp.Page))
+	if inp.UnreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	sql, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []notifications.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+func (r *notificationsRepository) CountUnread(ctx context.Context, userID string) (count int, err error) {
+	sql, args, err := sq.
+		Select("count(*)").
+		From("notifications").
+		Where(sq.Eq{"user_id": userID}).
+		Where("read_at IS NULL").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: CountUnread()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&count)
+	return count, err
+}
+
+func (r *notificationsRepository) MarkRead(ctx context.Context, userID, id string, at time.Time) error {
+	sql, args, err := sq.
+		Update("notifications").
+		Set("read_at", sq.Expr("COALESCE(read_at, ?)", at)).
+		Where(sq.Eq{"id::text": id, "user_id": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: MarkRead()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return notifications.ErrNoSuchNotification
+	}
+	return nil
+}
+
+func (r *notificationsRepository) MarkAllRead(ctx context.Context, userID string, at time.Time) error {
+	sql, args, err := sq.
+		Update("notifications").
+		Set("read_at", at).
+		Where(sq.Eq{"user_id": userID}).
+		Where("read_at IS NULL").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: MarkAllRead()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *notificationsRepository) GetPreferences(ctx context.Context, userID string) ([]notifications.Preference, error) {
+	sql, args, err := sq.
+		Select("type, in_app, email").
+		From("notification_preferences").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: GetPreferences()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := []notifications.Preference{}
+	for rows.Next() {
+		var p notifications.Preference
+		if err := rows.Scan(&p.Type, &p.InApp, &p.Email); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, rows.Err()
+}
+
+func (r *notificationsRepository) SetPreferences(ctx context.Context, userID string, prefs []notifications.Preference) error {
+	if len(prefs) == 0 {
+		return nil
+	}
+	query := sq.
+		Insert("notification_preferences").
+		Columns("user_id", "type", "in_app", "email")
+	for _, p := range prefs {
+		query = query.Values(userID, p.Type, p.InApp, p.Email)
+	}
+	sql, args, err := query.
+		Suffix("ON CONFLICT (user_id, type) DO UPDATE SET in_app = EXCLUDED.in_app, email = EXCLUDED.email").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("notificationsRepository: SetPreferences()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type outboxRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+// writeEvent saves an event to the outbox in the same transaction
+// as the change that caused it. If we crash right after commit the
+// event is still there and will be relayed later.
+func writeEvent(ctx context.Context, tx pgx.Tx, e events.Event) error {
+	var todoID *string
+	if e.TodoID != "" {
+		todoID = &e.TodoID
+	}
+	sql, args, err := sq.
+		Insert("outbox").
+		Columns("user_id", "type", "todo_id", "payload", "created_at", "next_attempt_at").
+		Values(e.UserID, e.Type, todoID, []byte(e.Data), e.At, e.At).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, sql, args...)
+	return err
+}
+
+func writeTodoEvent(ctx context.Context, tx pgx.Tx, typ events.Type, todo todos.Todo) error {
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return err
+	}
+	return writeEvent(ctx, tx, events.Event{
+		Type:   typ,
+		UserID: todo.Author.ID,
+		TodoID: todo.ID,
+		Data:   data,
+		At:     time.Now().UTC(),
+	})
+}
+
+func writeUserEvent(ctx context.Context, tx pgx.Tx, typ events.Type, user users.User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	return writeEvent(ctx, tx, events.Event{
+		Type:   typ,
+		UserID: user.ID,
+		Data:   data,
+		At:     time.Now().UTC(),
+	})
+}
+
+const claimOutboxSQL = `UPDATE outbox AS o SET next_attempt_at = $2
+WHERE o.id IN (
+	SELECT id FROM outbox
+	WHERE relayed_at IS NULL AND next_attempt_at <= $1
+	ORDER BY id
+	LIMIT $3
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING o.id, o.type, o.user_id, o.todo_id, o.payload, o.created_at, o.attempts,
+	array(SELECT handler FROM outbox_handled WHERE event_id = o.id)`
+
+func (r *outboxRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]events.Pending, error) {
+	defer r.log.Sync()
+	r.log.Debug("outboxRepository: Claim()", logging.String("sql", claimOutboxSQL))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, claimOutboxSQL, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []events.Pending{}
+	for rows.Next() {
+		var (
+			p       events.Pending
+			id      int64
+			todoID  *string
+			payload []byte
+		)
+		err := rows.Scan(
+			&id, &p.Event.Type, &p.Event.UserID, &todoID, &payload, &p.Event.At,
+			&p.Attempts, &p.Handled,
+		)
+		if err != nil {
+			return nil, err
+		}
+		p.Event.ID = strconv.FormatInt(id, 10)
+		if todoID != nil {
+			p.Event.TodoID = *todoID
+		}
+		p.Event.Data = json.RawMessage(payload)
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+func (r *outboxRepository) MarkHandled(ctx context.Context, id, handler string) error {
+	sql, args, err := sq.
+		Insert("outbox_handled").
+		Columns("event_id", "handler", "handled_at").
+		Values(id, handler, time.Now().UTC()).
+		Suffix("ON CONFLICT (event_id, handler) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("outboxRepository: MarkHandled()", logging.String("sql", sql))
+
+	return r.exec(ctx, sql, args)
+}
+
+func (r *outboxRepository) MarkRelayed(ctx context.Context, id string, at time.Time) error {
+	sql, args, err := sq.
+		Update("outbox").
+		Set("relayed_at", at).
+		Set("error", nil).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("outboxRepository: MarkRelayed()", logging.String("sql", sql))
+
+	return r.exec(ctx, sql, args)
+}
+
+func (r *outboxRepository) Retry(ctx context.Context, id string, at time.Time, reason string) error {
+	sql, args, err := sq.
+		Update("outbox").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("next_attempt_at", at).
+		Set("error", reason).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("outboxRepository: Retry()", logging.String("sql", sql))
+
+	return r.exec(ctx, sql, args)
+}
+
+func (r *outboxRepository) DeleteRelayed(ctx context.Context, before time.Time) error {
+	sql, args, err := sq.
+		Delete("outbox").
+		Where(sq.Lt{"relayed_at": before}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("outboxRepository: DeleteRelayed()", logging.String("sql", sql))
+
+	return r.exec(ctx, sql, args)
+}
+
+func (r *outboxRepository) exec(ctx context.Context, sql string, args []interface{}) error {
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
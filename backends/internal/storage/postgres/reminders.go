@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/reminders"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type remindersRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+const remindersColumns = `id, todo_id, user_id, remind_at, offset_minutes, sent_at, created_at`
+
+func scanReminder(row pgx.Row) (r reminders.Reminder, err error) {
+	var (
+		remindAt pq.NullTime
+		offset   *int
+		sentAt   pq.NullTime
+	)
+	err = row.Scan(&r.ID, &r.TodoID, &r.UserID, &remindAt, &offset, &sentAt, &r.CreatedAt)
+	if err != nil {
+		return r, err
+	}
+	if remindAt.Valid {
+		r.At = &remindAt.Time
+	}
+	if offset != nil {
+		r.OffsetMinutes = *offset
+	}
+	if sentAt.Valid {
+		r.SentAt = &sentAt.Time
+	}
+	return r, nil
+}
+
+func (r *remindersRepository) Create(ctx context.Context, rm reminders.Reminder) (id string, err error) {
+	var offset *int
+	if rm.At == nil {
+		offset = &rm.OffsetMinutes
+	}
+	sql, args, err := sq.
+		Insert("reminders").
+		Columns("todo_id", "user_id", "remind_at", "offset_minutes", "created_at").
+		Values(rm.TodoID, rm.UserID, rm.At, offset, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("remindersRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *remindersRepository) Get(ctx context.Context, id string) (reminders.Reminder, error) {
+	sql, args, err := sq.
+		Select(remindersColumns).
+		From("reminders").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return reminders.Reminder{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("remindersRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return reminders.Reminder{}, err
+	}
+	defer conn.Release()
+
+	rm, err := scanReminder(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return rm, reminders.ErrNoSuchReminder
+	}
+	return rm, err
+}
+
+func (r *remindersRepository) GetAll(ctx context.Context, todoID string) ([]reminders.Reminder, error) {
+	sql, args, err := sq.
+		Select(remindersColumns).
+		From("reminders").
+		Where(sq.Eq{"todo_id::text": todoID}).
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("remindersRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []reminders.Reminder{}
+	for rows.Next() {
+		rm, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rm)
+	}
+	return list, rows.Err()
+}
+
+func (r *remindersRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("reminders").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("remindersRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+// todos without a deadline keep zero time in it, see todos.CreateInput
+const dueRemindersSQL = `SELECT r.id, r.todo_id, r.user_id, r.remind_at, r.offset_minutes, r.sent_at, r.created_at
+	FROM reminders AS r INNER JOIN todos AS t ON r.todo_id = t.id
+	WHERE r.sent_at IS NULL AND NOT t.completed
+		AND (r.remind_at IS NOT NULL OR t.deadline > 'epoch')
+		AND COALESCE(r.remind_at, t.deadline - make_interval(mins => r.offset_minutes)) <= $1
+	ORDER BY r.created_at
+	LIMIT $2
+	FOR UPDATE OF r SKIP LOCKED`
+
+func (r *remindersRepository) FireDue(ctx context.Context, now time.Time, limit int) (int, error) {
+	defer r.log.Sync()
+	r.log.Debug("remindersRepository: FireDue()", logging.String("sql", dueRemindersSQL))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, dueRemindersSQL, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	due := []reminders.Reminder{}
+	for rows.Next() {
+		rm, err := scanReminder(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, rm)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, rm := range due {
+		if _, err := tx.Exec(ctx, "UPDATE reminders SET sent_at = $1 WHERE id = $2", now, rm.ID); err != nil {
+			return 0, err
+		}
+		rm.SentAt = &now
+		todo, err := getTodo(ctx, tx, rm.TodoID)
+		if err != nil {
+			return 0, err
+		}
+		if err := writeReminderEvent(ctx, tx, events.TodoReminder, reminders.Payload{Todo: todo, Reminder: &rm}, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), tx.Commit(ctx)
+}
+
+const overdueTodosSQL = `SELECT id FROM todos
+	WHERE overdue_notified_at IS NULL AND NOT completed
+		AND deadline > 'epoch' AND deadline <= $1
+	ORDER BY deadline
+	LIMIT $2
+	FOR UPDATE SKIP LOCKED`
+
+func (r *remindersRepository) FireOverdue(ctx context.Context, now time.Time, limit int) (int, error) {
+	defer r.log.Sync()
+	r.log.Debug("remindersRepository: FireOverdue()", logging.String("sql", overdueTodosSQL))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, overdueTodosSQL, now, limit)
+	if err != nil {
+		return 0, err
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec(ctx, "UPDATE todos SET overdue_notified_at = $1 WHERE id = $2", now, id); err != nil {
+			return 0, err
+		}
+		todo, err := getTodo(ctx, tx, id)
+		if err != nil {
+			return 0, err
+		}
+		if err := writeReminderEvent(ctx, tx, events.TodoOverdue, reminders.Payload{Todo: todo}, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), tx.Commit(ctx)
+}
+
+func writeReminderEvent(ctx context.Context, tx pgx.Tx, typ events.Type, p reminders.Payload, at time.Time) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return writeEvent(ctx, tx, events.Event{
+		Type:   typ,
+		UserID: p.Todo.Author.ID,
+		TodoID: p.Todo.ID,
+		Data:   data,
+		At:     at,
+	})
+}
@@ -13,9 +13,31 @@ import (
 
 type Repository struct {
 	conn *pgxpool.Pool
+	log  *logging.Logger
 
 	usersRepository *usersRepository
 	todosRepository *todosRepository
+
+	attemptsRepository *attemptsRepository
+	bucketsRepository  *bucketsRepository
+
+	exportsRepository   *exportsRepository
+	webhooksRepository  *webhooksRepository
+	outboxRepository    *outboxRepository
+	remindersRepository *remindersRepository
+
+	notificationsRepository *notificationsRepository
+	digestsRepository       *digestsRepository
+
+	listsRepository  *listsRepository
+	sharesRepository *sharesRepository
+	linksRepository  *linksRepository
+
+	commentsRepository    *commentsRepository
+	attachmentsRepository *attachmentsRepository
+
+	workspacesRepository *workspacesRepository
+	templatesRepository  *templatesRepository
 }
 
 func NewRepository(cfg config.Config, logger *logging.Logger) (*Repository, error) {
@@ -44,8 +66,30 @@ func NewRepository(cfg config.Config, logger *logging.Logger) (*Repository, erro
 	}
 	return &Repository{
 		conn:            conn,
+		log:             logger,
 		usersRepository: &usersRepository{conn: conn, log: logger},
 		todosRepository: &todosRepository{conn: conn, log: logger},
+
+		attemptsRepository: &attemptsRepository{conn: conn, log: logger},
+		bucketsRepository:  &bucketsRepository{conn: conn, log: logger},
+
+		exportsRepository:   &exportsRepository{conn: conn, log: logger},
+		webhooksRepository:  &webhooksRepository{conn: conn, log: logger},
+		outboxRepository:    &outboxRepository{conn: conn, log: logger},
+		remindersRepository: &remindersRepository{conn: conn, log: logger},
+
+		notificationsRepository: &notificationsRepository{conn: conn, log: logger},
+		digestsRepository:       &digestsRepository{conn: conn, log: logger},
+
+		listsRepository:  &listsRepository{conn: conn, log: logger},
+		sharesRepository: &sharesRepository{conn: conn, log: logger},
+		linksRepository:  &linksRepository{conn: conn, log: logger},
+
+		commentsRepository:    &commentsRepository{conn: conn, log: logger},
+		attachmentsRepository: &attachmentsRepository{conn: conn, log: logger},
+
+		workspacesRepository: &workspacesRepository{conn: conn, log: logger},
+		templatesRepository:  &templatesRepository{conn: conn, log: logger},
 	}, nil
 }
 
@@ -62,6 +106,66 @@ func (r *Repository) Todos() *todosRepository {
 	return r.todosRepository
 }
 
+func (r *Repository) Attempts() *attemptsRepository {
+	return r.attemptsRepository
+}
+
+func (r *Repository) Buckets() *bucketsRepository {
+	return r.bucketsRepository
+}
+
+func (r *Repository) Exports() *exportsRepository {
+	return r.exportsRepository
+}
+
+func (r *Repository) Webhooks() *webhooksRepository {
+	return r.webhooksRepository
+}
+
+func (r *Repository) Outbox() *outboxRepository {
+	return r.outboxRepository
+}
+
+func (r *Repository) Reminders() *remindersRepository {
+	return r.remindersRepository
+}
+
+func (r *Repository) Notifications() *notificationsRepository {
+	return r.notificationsRepository
+}
+
+func (r *Repository) Digests() *digestsRepository {
+	return r.digestsRepository
+}
+
+func (r *Repository) Lists() *listsRepository {
+	return r.listsRepository
+}
+
+func (r *Repository) Shares() *sharesRepository {
+	return r.sharesRepository
+}
+
+func (r *Repository) Links() *linksRepository {
+	return r.linksRepository
+}
+
+func (r *Repository) Comments() *commentsRepository {
+	return r.commentsRepository
+}
+
+func (r *Repository) Attachments() *attachmentsRepository {
+	return r.attachmentsRepository
+}
+
+func (r *Repository) Workspaces() *workspacesRepository {
+	return r.workspacesRepository
+}
+
+func (r *Repository) Templates() *templatesRepository {
+	return r.templatesRepository
+}
+
 func (r *Repository) Ping() error {
 	return r.conn.Ping(context.Background())
 }
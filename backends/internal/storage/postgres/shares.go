@@ -0,0 +1,374 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type sharesRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+func selectShares() sq.SelectBuilder {
+	return sq.
+		Select(`s.id, s.resource_type, s.resource_id, COALESCE(t.title, l.name, ''),
+			s.owner_id, s.invitee_id, s.invitee_email, s.permission, s.status,
+			s.created_at, s.responded_at`).
+		From("shares AS s").
+		LeftJoin("todos AS t ON s.resource_type = 'todo' AND t.id = s.resource_id").
+		LeftJoin("lists AS l ON s.resource_type = 'list' AND l.id = s.resource_id").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanShare(row pgx.Row) (s shares.Share, err error) {
+	var (
+		respondedAt pq.NullTime
+		inviteeID   *string
+	)
+	err = row.Scan(
+		&s.ID, &s.ResourceType, &s.ResourceID, &s.Title,
+		&s.OwnerID, &inviteeID, &s.InviteeEmail, &s.Permission, &s.Status,
+		&s.CreatedAt, &respondedAt,
+	)
+	if err != nil {
+		return s, err
+	}
+	s.InviteeID = deref(inviteeID)
+	if respondedAt.Valid {
+		s.RespondedAt = &respondedAt.Time
+	}
+	return s, nil
+}
+
+func (r *sharesRepository) Create(ctx context.Context, s shares.Share) (id string, err error) {
+	sql, args, err := sq.
+		Insert("shares").
+		Columns("resource_type", "resource_id", "owner_id", "invitee_email", "permission", "status", "created_at").
+		Values(s.ResourceType, s.ResourceID, s.OwnerID, s.InviteeEmail, s.Permission, s.Status, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return "", shares.ErrAlreadyShared
+	}
+	return id, err
+}
+
+func (r *sharesRepository) Get(ctx context.Context, id string) (shares.Share, error) {
+	sql, args, err := selectShares().Where(sq.Eq{"s.id::text": id}).ToSql()
+	if err != nil {
+		return shares.Share{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return shares.Share{}, err
+	}
+	defer conn.Release()
+
+	s, err := scanShare(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return s, shares.ErrNoSuchShare
+	}
+	return s, err
+}
+
+func (r *sharesRepository) GetIncoming(ctx context.Context, userID string, status shares.Status) ([]shares.Share, error) {
+	// shares nobody has answered yet are known only by email
+	query := selectShares().
+		Where(sq.Or{
+			sq.Eq{"s.invitee_id": userID},
+			sq.And{
+				sq.Eq{"s.invitee_id": nil},
+				sq.Expr("s.invitee_email = (SELECT LOWER(email) FROM users WHERE id::text = ?)", userID),
+			},
+		}).
+		OrderBy("s.created_at DESC")
+	if status != "" {
+		query = query.Where(sq.Eq{"s.status": status})
+	}
+	return r.getAll(ctx, "GetIncoming", query)
+}
+
+func (r *sharesRepository) GetOutgoing(ctx context.Context, typ shares.ResourceType, resourceID string) ([]shares.Share, error) {
+	query := selectShares().
+		Where(sq.Eq{"s.resource_type": typ, "s.resource_id::text": resourceID}).
+		OrderBy("s.created_at DESC")
+	return r.getAll(ctx, "GetOutgoing", query)
+}
+
+func (r *sharesRepository) getAll(ctx context.Context, method string, query sq.SelectBuilder) ([]shares.Share, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: "+method+"()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []shares.Share{}
+	for rows.Next() {
+		s, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (r *sharesRepository) Respond(ctx context.Context, id, userID string, status shares.Status, at time.Time) error {
+	sql, args, err := sq.
+		Update("shares").
+		Set("invitee_id", userID).
+		Set("status", status).
+		Set("responded_at", at).
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Respond()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *sharesRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("shares").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+// deleteShares removes shares and public links
+// of a todo or a list that is being deleted
+func deleteShares(ctx context.Context, tx pgx.Tx, typ shares.ResourceType, id string) error {
+	for _, table := range []string{"shares", "public_links"} {
+		sql, args, err := sq.
+			Delete(table).
+			Where(sq.Eq{"resource_type": typ, "resource_id::text": id}).
+			PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var resourceSQL = map[shares.ResourceType]string{
+	shares.ResourceTodo: `SELECT id, user_id, title, workspace_id FROM todos WHERE id::text = $1`,
+	shares.ResourceList: `SELECT id, user_id, name, workspace_id FROM lists WHERE id::text = $1`,
+}
+
+func (r *sharesRepository) Resource(ctx context.Context, typ shares.ResourceType, id string) (shares.Resource, error) {
+	sql, ok := resourceSQL[typ]
+	if !ok {
+		return shares.Resource{}, shares.ErrNoSuchResource
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Resource()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return shares.Resource{}, err
+	}
+	defer conn.Release()
+
+	var workspaceID *string
+	res := shares.Resource{Type: typ}
+	err = conn.QueryRow(ctx, sql, id).Scan(&res.ID, &res.OwnerID, &res.Title, &workspaceID)
+	if err == pgx.ErrNoRows || (err == nil && !workspaces.InScope(ctx, deref(workspaceID))) {
+		return shares.Resource{}, shares.ErrNoSuchResource
+	}
+	return res, err
+}
+
+// editors go first, so the best of shares is picked. Members of
+// a workspace can edit everything in it, its admins own everything.
+var grantSQL = map[shares.ResourceType]string{
+	shares.ResourceTodo: `SELECT CASE
+		WHEN t.user_id = $1 THEN 'owner'
+		WHEN m.role IN ('owner', 'admin') THEN 'owner'
+		WHEN m.role IS NOT NULL THEN 'editor'
+		WHEN l.user_id = $1 THEN 'editor'
+		ELSE COALESCE((SELECT s.permission FROM shares AS s
+			WHERE s.invitee_id = $1 AND s.status = 'accepted' AND (
+				(s.resource_type = 'todo' AND s.resource_id = t.id) OR
+				(s.resource_type = 'list' AND s.resource_id = t.list_id))
+			ORDER BY s.permission = 'editor' DESC LIMIT 1), '')
+		END, t.workspace_id
+		FROM todos AS t LEFT JOIN lists AS l ON l.id = t.list_id
+		LEFT JOIN workspace_members AS m ON m.workspace_id = t.workspace_id AND m.user_id = $1
+		WHERE t.id::text = $2`,
+	shares.ResourceList: `SELECT CASE
+		WHEN l.user_id = $1 THEN 'owner'
+		WHEN m.role IN ('owner', 'admin') THEN 'owner'
+		WHEN m.role IS NOT NULL THEN 'editor'
+		ELSE COALESCE((SELECT s.permission FROM shares AS s
+			WHERE s.invitee_id = $1 AND s.status = 'accepted'
+			AND s.resource_type = 'list' AND s.resource_id = l.id), '')
+		END, l.workspace_id
+		FROM lists AS l
+		LEFT JOIN workspace_members AS m ON m.workspace_id = l.workspace_id AND m.user_id = $1
+		WHERE l.id::text = $2`,
+}
+
+func (r *sharesRepository) Grant(ctx context.Context, userID string, typ shares.ResourceType, id string) (shares.Permission, error) {
+	sql, ok := grantSQL[typ]
+	if !ok {
+		return shares.PermissionNone, nil
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Grant()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return shares.PermissionNone, err
+	}
+	defer conn.Release()
+
+	var (
+		p           shares.Permission
+		workspaceID *string
+	)
+	err = conn.QueryRow(ctx, sql, userID, id).Scan(&p, &workspaceID)
+	// resources of other workspaces are invisible even to their owners
+	if err == pgx.ErrNoRows || (err == nil && !workspaces.InScope(ctx, deref(workspaceID))) {
+		return shares.PermissionNone, nil
+	}
+	return p, err
+}
+
+// viewers are everybody who would get a permission from grantSQL
+// in any workspace, and assignees of todos
+var viewersSQL = map[shares.ResourceType]string{
+	shares.ResourceTodo: `SELECT t.user_id FROM todos AS t WHERE t.id::text = $1
+		UNION SELECT t.assignee_id FROM todos AS t
+			WHERE t.id::text = $1 AND t.assignee_id IS NOT NULL
+		UNION SELECT l.user_id FROM todos AS t JOIN lists AS l ON l.id = t.list_id
+			WHERE t.id::text = $1
+		UNION SELECT m.user_id FROM todos AS t
+			JOIN workspace_members AS m ON m.workspace_id = t.workspace_id
+			WHERE t.id::text = $1
+		UNION SELECT s.invitee_id FROM todos AS t
+			JOIN shares AS s ON s.status = 'accepted' AND (
+				(s.resource_type = 'todo' AND s.resource_id = t.id) OR
+				(s.resource_type = 'list' AND s.resource_id = t.list_id))
+			WHERE t.id::text = $1`,
+	shares.ResourceList: `SELECT l.user_id FROM lists AS l WHERE l.id::text = $1
+		UNION SELECT m.user_id FROM lists AS l
+			JOIN workspace_members AS m ON m.workspace_id = l.workspace_id
+			WHERE l.id::text = $1
+		UNION SELECT s.invitee_id FROM shares AS s
+			WHERE s.status = 'accepted' AND s.resource_type = 'list'
+			AND s.resource_id::text = $1`,
+}
+
+func (r *sharesRepository) Viewers(ctx context.Context, typ shares.ResourceType, id string) ([]string, error) {
+	sql, ok := viewersSQL[typ]
+	if !ok {
+		return nil, nil
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("sharesRepository: Viewers()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// deref turns nulls of nullable columns into empty strings
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
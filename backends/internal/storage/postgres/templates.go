@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/templates"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type templatesRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+const templateColumns = "id, user_id, workspace_id, task, created_at, updated_at"
+
+func scanTemplate(row pgx.Row) (t templates.Template, err error) {
+	var (
+		workspaceID *string
+		task        []byte
+		updatedAt   pq.NullTime
+	)
+	if err := row.Scan(&t.ID, &t.OwnerID, &workspaceID, &task, &t.CreatedAt, &updatedAt); err != nil {
+		return t, err
+	}
+	if workspaceID != nil {
+		t.WorkspaceID = *workspaceID
+	}
+	if err := json.Unmarshal(task, &t.Task); err != nil {
+		return t, err
+	}
+	if updatedAt.Valid {
+		t.UpdatedAt = updatedAt.Time
+	}
+	return t, nil
+}
+
+func (r *templatesRepository) Create(ctx context.Context, inp templates.CreateInput) (id string, err error) {
+	task, err := json.Marshal(inp.Task)
+	if err != nil {
+		return "", err
+	}
+	sql, args, err := sq.
+		Insert("templates").
+		Columns("user_id", "workspace_id", "task", "created_at").
+		Values(inp.UserID, scopeValue(ctx), task, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("templatesRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *templatesRepository) Get(ctx context.Context, id string) (templates.Template, error) {
+	sql, args, err := sq.
+		Select(templateColumns).
+		From("templates").
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return templates.Template{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("templatesRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return templates.Template{}, err
+	}
+	defer conn.Release()
+
+	t, err := scanTemplate(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return t, templates.ErrNoSuchTemplate
+	}
+	return t, err
+}
+
+func (r *templatesRepository) GetAll(ctx context.Context, userID string) ([]templates.Template, error) {
+	query := sq.
+		Select(templateColumns).
+		From("templates").
+		Where(scopeFilter(ctx, "workspace_id"))
+	if !inWorkspace(ctx) {
+		query = query.Where(sq.Eq{"user_id": userID})
+	}
+	sql, args, err := query.
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("templatesRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := []templates.Template{}
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, t)
+	}
+	return all, rows.Err()
+}
+
+func (r *templatesRepository) Update(ctx context.Context, inp templates.UpdateInput) error {
+	task, err := json.Marshal(inp.Task)
+	if err != nil {
+		return err
+	}
+	sql, args, err := sq.
+		Update("templates").
+		Set("task", task).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id::text": inp.ID}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("templatesRepository: Update()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return templates.ErrNoSuchTemplate
+	}
+	return nil
+}
+
+func (r *templatesRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("templates").
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("templatesRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	tag, err := conn.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return templates.ErrNoSuchTemplate
+	}
+	return nil
+}
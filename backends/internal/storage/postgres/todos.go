@@ -2,13 +2,20 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"sort"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
 )
 
@@ -18,46 +25,112 @@ type todosRepository struct {
 }
 
 func (r *todosRepository) Create(ctx context.Context, inp todos.CreateInput) (id string, err error) {
-	sql, args, err := sq.
-		Insert("todos").
-		Columns("user_id, title, description, deadline, created_at, updated_at").
-		Values(inp.UserID, inp.Title, inp.Body, inp.Deadline, time.Now(), nil).
-		Suffix("RETURNING id").
-		PlaceholderFormat(sq.Dollar).ToSql()
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: Create()")
+
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
 		return "", err
 	}
+	defer tx.Rollback(ctx)
 
+	if id, err = r.insertTodo(ctx, tx, inp); err != nil {
+		return "", err
+	}
+	todo, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return "", err
+	}
+	if err := writeTodoEvent(ctx, tx, events.TodoCreated, todo); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+func (r *todosRepository) CreateBatch(ctx context.Context, inp []todos.CreateInput, blockedBy [][]int) ([]string, error) {
 	defer r.log.Sync()
-	r.log.Debug("todosRepository: Create()", logging.String("sql", sql))
+	r.log.Debug("todosRepository: CreateBatch()")
 
-	conn, err := r.conn.Acquire(ctx)
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer conn.Release()
+	defer tx.Rollback(ctx)
 
-	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
-	return id, err
+	ids := make([]string, len(inp))
+	for i, t := range inp {
+		if ids[i], err = r.insertTodo(ctx, tx, t); err != nil {
+			return nil, err
+		}
+	}
+	// new todos can't be in a cycle with old ones,
+	// so dependencies don't need the global lock here
+	deps := sq.Insert("todo_dependencies").Columns("todo_id, blocker_id")
+	n := 0
+	for i, blockers := range blockedBy {
+		for _, b := range blockers {
+			deps = deps.Values(ids[i], ids[b])
+			n++
+		}
+	}
+	if n != 0 {
+		sql, args, err := deps.PlaceholderFormat(sq.Dollar).ToSql()
+		if err != nil {
+			return nil, err
+		}
+		r.log.Debug("todosRepository: CreateBatch()", logging.String("sql", sql))
+		if _, err := tx.Exec(ctx, sql, args...); err != nil {
+			return nil, err
+		}
+	}
+	// events go after dependencies, so they have both sides of them
+	for _, id := range ids {
+		todo, err := getTodo(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTodoEvent(ctx, tx, events.TodoCreated, todo); err != nil {
+			return nil, err
+		}
+	}
+	return ids, tx.Commit(ctx)
 }
 
-func (r *todosRepository) Get(ctx context.Context, id string) (todo todos.Todo, err error) {
+// insertTodo puts a new todo to the end of its list
+func (r *todosRepository) insertTodo(ctx context.Context, tx pgx.Tx, inp todos.CreateInput) (id string, err error) {
+	fields, err := marshalFields(inp.Fields)
+	if err != nil {
+		return "", err
+	}
 	sql, args, err := sq.
-		Select(
-			`t.id, user_id, username, 
-			email, role_id, u.created_at,
-			title, description, deadline, 
-			t.created_at, t.updated_at`,
-		).
-		From("todos AS t").Where(sq.Eq{"t.id::text": id}).
-		InnerJoin("users AS u ON t.user_id = u.id").
+		Insert("todos").
+		Columns("user_id, title, description, deadline, list_id, workspace_id, status, completed, fields, created_at, updated_at").
+		Values(inp.UserID, inp.Title, inp.Body, inp.Deadline, nullString(inp.ListID), scopeValue(ctx),
+			inp.Status, inp.Completed, fields, time.Now(), nil).
+		Suffix("RETURNING id, " + positionGroup + "::text").
 		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
-		return todo, err
+		return "", err
 	}
 
+	r.log.Debug("todosRepository: insertTodo()", logging.String("sql", sql))
+
+	var group string
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&id, &group); err != nil {
+		return "", err
+	}
+	if err := lockPositions(ctx, tx, group); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, placeLastSQL, id, group, todos.PositionStep); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (r *todosRepository) Get(ctx context.Context, id string) (todo todos.Todo, err error) {
 	defer r.log.Sync()
-	r.log.Debug("todosRepository: Get()", logging.String("sql", sql))
+	r.log.Debug("todosRepository: Get()", logging.String("sql", getTodoSQL))
 
 	conn, err := r.conn.Acquire(ctx)
 	if err != nil {
@@ -65,19 +138,61 @@ func (r *todosRepository) Get(ctx context.Context, id string) (todo todos.Todo,
 	}
 	defer conn.Release()
 
+	todo, err = getTodo(ctx, conn, id)
+	if err != nil {
+		return todo, err
+	}
+	if !workspaces.InScope(ctx, todo.WorkspaceID) {
+		return todos.Todo{}, todos.ErrNoSuchTodo
+	}
+	return todo, nil
+}
+
+const getTodoSQL = `SELECT t.id, t.user_id, u.username,
+	u.email, u.role_id, u.created_at,
+	t.title, t.description, t.status, t.completed, t.deadline, t.list_id, t.workspace_id, t.position,
+	ARRAY(SELECT d.blocker_id::text FROM todo_dependencies AS d WHERE d.todo_id = t.id),
+	ARRAY(SELECT d.todo_id::text FROM todo_dependencies AS d WHERE d.blocker_id = t.id),
+	t.fields,
+	a.id, a.username, a.email,
+	(SELECT COUNT(*) FROM comments AS c WHERE c.todo_id = t.id),
+	t.created_at, t.updated_at
+	FROM todos AS t INNER JOIN users AS u ON t.user_id = u.id
+	LEFT JOIN users AS a ON t.assignee_id = a.id
+	WHERE t.id::text = $1`
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// getTodo works both with connections and transactions,
+// so mutations can see the todo they have just changed
+func getTodo(ctx context.Context, q queryRower, id string) (todo todos.Todo, err error) {
 	var (
-		author    users.User
-		roleID    int
-		deadline  pq.NullTime
-		updatedAt pq.NullTime
+		author      users.User
+		roleID      int
+		deadline    pq.NullTime
+		listID      *string
+		workspaceID *string
+		fields      []byte
+		updatedAt   pq.NullTime
+
+		assigneeID, assigneeUsername, assigneeEmail *string
 	)
 
-	err = conn.QueryRow(ctx, sql, args...).Scan(
+	err = q.QueryRow(ctx, getTodoSQL, id).Scan(
 		&todo.ID, &author.ID, &author.Username,
 		&author.Email, &roleID, &author.CreatedAt,
-		&todo.Title, &todo.Body, &deadline,
+		&todo.Title, &todo.Body, &todo.Status, &todo.Completed, &deadline, &listID, &workspaceID, &todo.Position,
+		&todo.BlockedBy, &todo.Blocks,
+		&fields,
+		&assigneeID, &assigneeUsername, &assigneeEmail,
+		&todo.CommentsCount,
 		&todo.CreatedAt, &updatedAt,
 	)
+	if err == pgx.ErrNoRows {
+		return todo, todos.ErrNoSuchTodo
+	}
 	if err != nil {
 		return todo, err
 	}
@@ -88,6 +203,18 @@ func (r *todosRepository) Get(ctx context.Context, id string) (todo todos.Todo,
 	if deadline.Valid {
 		todo.Deadline = deadline.Time
 	}
+	if listID != nil {
+		todo.ListID = *listID
+	}
+	if workspaceID != nil {
+		todo.WorkspaceID = *workspaceID
+	}
+	if err := json.Unmarshal(fields, &todo.Fields); err != nil {
+		return todo, err
+	}
+	if assigneeID != nil {
+		todo.Assignee = &users.User{ID: *assigneeID, Username: *assigneeUsername, Email: *assigneeEmail}
+	}
 	author.Role = roleIds[roleID]
 	todo.Author = &author
 	return todo, nil
@@ -98,6 +225,7 @@ var sortingVariants = map[todos.SortBy]string{
 	todos.SortByCreationDESC: "created_at DESC",
 	todos.SortByDeadlineASC:  "deadline ASC",
 	todos.SortByDeadlineDESC: "deadline DESC",
+	todos.SortByManual:       "position ASC, created_at ASC",
 }
 
 func (r *todosRepository) GetAll(ctx context.Context, config todos.GetAllInput) ([]todos.Todo, error) {
@@ -106,14 +234,52 @@ func (r *todosRepository) GetAll(ctx context.Context, config todos.GetAllInput)
 		sorting = sortingVariants[todos.SortByCreationASC]
 	}
 	query := sq.
-		Select(`id, user_id, title, description, deadline, created_at, updated_at`).
+		Select(todoColumns).
 		From("todos").
+		Where(scopeFilter(ctx, "workspace_id")).
 		Limit(uint64(config.PageSize)).
-		Offset(uint64(config.PageSize * config.Page)).
-		OrderBy(sorting)
-
-	if len(config.UserID) != 0 {
-		query.Where(sq.Eq{"user_id": config.UserID})
+		Offset(uint64(config.PageSize * config.Page))
+	if len(config.SortField) != 0 {
+		direction := "ASC"
+		if config.SortFieldDesc {
+			direction = "DESC"
+		}
+		sorting = "created_at ASC"
+		query = query.OrderByClause("fields -> ?::text "+direction+" NULLS LAST", config.SortField)
+	}
+	query = query.OrderBy(sorting)
+
+	switch {
+	case len(config.ListID) != 0:
+		query = query.Where(sq.Eq{"list_id": config.ListID})
+	case config.Shared:
+		query = query.Where(sharedWith(config.UserID))
+	case inWorkspace(ctx):
+		// members see every todo of their workspace
+	case len(config.AssigneeID) != 0:
+		// assignees might have lost their access since then
+		query = query.Where(sq.Or{
+			sq.Eq{"user_id": config.UserID},
+			sq.Expr("list_id IN (SELECT id FROM lists WHERE user_id = ?)", config.UserID),
+			sharedWith(config.UserID),
+		})
+	case len(config.UserID) != 0:
+		query = query.Where(sq.Eq{"user_id": config.UserID})
+	}
+	if len(config.AssigneeID) != 0 {
+		query = query.Where(sq.Eq{"assignee_id": config.AssigneeID})
+	}
+	if len(config.Statuses) != 0 {
+		query = query.Where(sq.Eq{"status": config.Statuses})
+	}
+	keys := make([]string, 0, len(config.FieldFilters))
+	for key := range config.FieldFilters {
+		keys = append(keys, key)
+	}
+	// the same filters make the same sql
+	sort.Strings(keys)
+	for _, key := range keys {
+		query = query.Where("fields ->> ?::text = ?", key, config.FieldFilters[key])
 	}
 
 	sql, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
@@ -136,21 +302,48 @@ func (r *todosRepository) GetAll(ctx context.Context, config todos.GetAllInput)
 	}
 	defer rows.Close()
 
-	var (
-		authorId  string
-		deadline  pq.NullTime
-		updatedAt pq.NullTime
-		todolist  = []todos.Todo{}
-		todo      = todos.Todo{}
-	)
+	return scanTodos(rows)
+}
 
+// todoColumns are what scanTodos expects, they are
+// selected from todos without joining anything
+const todoColumns = `id, user_id, title, description, status, completed, deadline, list_id, workspace_id, position,
+	ARRAY(SELECT d.blocker_id::text FROM todo_dependencies AS d WHERE d.todo_id = todos.id),
+	ARRAY(SELECT d.todo_id::text FROM todo_dependencies AS d WHERE d.blocker_id = todos.id),
+	fields, assignee_id,
+	(SELECT COUNT(*) FROM comments AS c WHERE c.todo_id = todos.id),
+	created_at, updated_at`
+
+// scanTodos sets only ids of authors and assignees
+func scanTodos(rows pgx.Rows) ([]todos.Todo, error) {
+	todolist := []todos.Todo{}
 	for rows.Next() {
-		err = rows.Scan(
+		var (
+			authorId    string
+			deadline    pq.NullTime
+			listID      *string
+			workspaceID *string
+			fields      []byte
+			assigneeID  *string
+			updatedAt   pq.NullTime
+			todo        = todos.Todo{}
+		)
+		err := rows.Scan(
 			&todo.ID,
 			&authorId,
 			&todo.Title,
 			&todo.Body,
+			&todo.Status,
+			&todo.Completed,
 			&deadline,
+			&listID,
+			&workspaceID,
+			&todo.Position,
+			&todo.BlockedBy,
+			&todo.Blocks,
+			&fields,
+			&assigneeID,
+			&todo.CommentsCount,
 			&todo.CreatedAt,
 			&updatedAt)
 		if err != nil {
@@ -162,19 +355,61 @@ func (r *todosRepository) GetAll(ctx context.Context, config todos.GetAllInput)
 		if deadline.Valid {
 			todo.Deadline = deadline.Time
 		}
+		if listID != nil {
+			todo.ListID = *listID
+		}
+		if workspaceID != nil {
+			todo.WorkspaceID = *workspaceID
+		}
+		if err := json.Unmarshal(fields, &todo.Fields); err != nil {
+			return nil, err
+		}
+		if assigneeID != nil {
+			todo.Assignee = &users.User{ID: *assigneeID}
+		}
 		todo.Author = &users.User{ID: authorId}
 		todolist = append(todolist, todo)
 	}
 
-	return todolist, nil
+	return todolist, rows.Err()
+}
+
+// sharedWith matches todos shared with a user directly or with their lists
+func sharedWith(userID string) sq.Sqlizer {
+	return sq.Or{
+		sq.Expr(`id IN (SELECT resource_id FROM shares
+			WHERE resource_type = ? AND invitee_id = ? AND status = ?)`,
+			shares.ResourceTodo, userID, shares.StatusAccepted),
+		sq.Expr(`list_id IN (SELECT resource_id FROM shares
+			WHERE resource_type = ? AND invitee_id = ? AND status = ?)`,
+			shares.ResourceList, userID, shares.StatusAccepted),
+	}
 }
 
 func (r *todosRepository) Update(ctx context.Context, inp todos.UpdateInput) error {
-	sql, args, err := sq.Update("todos").
+	query := sq.Update("todos").
 		Set("title", inp.Title).
 		Set("description", inp.Body).
-		Set("deadline", inp.Deadline).Where(sq.Eq{"id::text": inp.ID}).
-		PlaceholderFormat(sq.Dollar).ToSql()
+		Set("deadline", inp.Deadline).
+		// a todo with a new deadline can become overdue again
+		Set("overdue_notified_at", sq.Expr(
+			"CASE WHEN deadline IS DISTINCT FROM ? THEN NULL ELSE overdue_notified_at END", inp.Deadline,
+		)).
+		Where(sq.Eq{"id::text": inp.ID}).
+		Where(scopeFilter(ctx, "workspace_id"))
+	if inp.ListID != nil {
+		// fields of the old list mean nothing in the new one
+		query = query.Set("fields", sq.Expr(
+			"CASE WHEN list_id IS NOT DISTINCT FROM ?::uuid THEN fields ELSE '{}'::jsonb END", nullString(*inp.ListID),
+		))
+		// it goes to the end of the new list, without waiting for moves
+		// in it, ties are sorted by creation and rebalanced later
+		query = query.Set("list_id", nullString(*inp.ListID)).
+			Set("position", sq.Expr(`COALESCE((SELECT MAX(t.position) FROM todos AS t
+				WHERE COALESCE(t.list_id, t.workspace_id, t.user_id) = COALESCE(?::uuid, todos.workspace_id, todos.user_id)), 0) + ?`,
+				nullString(*inp.ListID), todos.PositionStep))
+	}
+	sql, args, err := query.PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return err
 	}
@@ -182,66 +417,255 @@ func (r *todosRepository) Update(ctx context.Context, inp todos.UpdateInput) err
 	defer r.log.Sync()
 	r.log.Debug("todosRepository: Update()", logging.String("sql", sql))
 
-	conn, err := r.conn.Acquire(ctx)
-	if err != nil {
-		return err
-	}
-	defer conn.Release()
-
-	_, err = conn.Exec(ctx, sql, args...)
-	return err
+	return r.change(ctx, events.TodoUpdated, inp.ID, sql, args)
 }
 
-func (r *todosRepository) MarkAsComplete(ctx context.Context, id string) error {
+func (r *todosRepository) SetStatus(ctx context.Context, id string, status todos.Status, completed bool, entry todos.HistoryEntry) error {
 	sql, args, err := sq.
 		Update("todos").
-		Set("completed", true).
+		Set("status", status).
+		Set("completed", completed).
 		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
 		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return err
 	}
 
 	defer r.log.Sync()
-	r.log.Debug("todosRepository: MarkAsComplete()", logging.String("sql", sql))
+	r.log.Debug("todosRepository: SetStatus()", logging.String("sql", sql))
 
-	conn, err := r.conn.Acquire(ctx)
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	defer conn.Release()
+	defer tx.Rollback(ctx)
+
+	before, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return todos.ErrNoSuchTodo
+	}
+	if _, err := writeHistory(ctx, tx, entry); err != nil {
+		return err
+	}
+	todo, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := writeTodoEvent(ctx, tx, events.TodoStatusChanged, todo); err != nil {
+		return err
+	}
+	// webhooks that only know about completion keep getting it
+	switch {
+	case todo.Completed && !before.Completed:
+		err = writeTodoEvent(ctx, tx, events.TodoCompleted, todo)
+	case !todo.Completed && before.Completed:
+		err = writeTodoEvent(ctx, tx, events.TodoUncompleted, todo)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-	_, err = conn.Exec(ctx, sql, args...)
+// positionGroup is what todos are ordered within: their list,
+// their workspace if they have no list, or their author
+const positionGroup = "COALESCE(list_id, workspace_id, user_id)"
+
+const placeLastSQL = `UPDATE todos SET position = COALESCE((SELECT MAX(position) FROM todos
+	WHERE COALESCE(list_id, workspace_id, user_id)::text = $2 AND id::text <> $1), 0) + $3
+	WHERE id::text = $1`
+
+const rebalanceSQL = `UPDATE todos SET position = o.n * $2
+	FROM (SELECT id, row_number() OVER (ORDER BY position, created_at, id) AS n
+		FROM todos WHERE COALESCE(list_id, workspace_id, user_id)::text = $1) AS o
+	WHERE todos.id = o.id`
+
+// lockPositions makes moves and inserts into the same
+// group wait for each other until the transaction ends
+func lockPositions(ctx context.Context, tx pgx.Tx, group string) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "todos.position:"+group)
 	return err
 }
 
-func (r *todosRepository) MarkAsNotComplete(ctx context.Context, id string) error {
+func (r *todosRepository) Move(ctx context.Context, inp todos.MoveInput) error {
 	sql, args, err := sq.
-		Update("todos").
-		Set("completed", false).
-		Where(sq.Eq{"id::text": id}).
+		Select(positionGroup + "::text").
+		From("todos").
+		Where(sq.Eq{"id::text": inp.ID}).
+		Where(scopeFilter(ctx, "workspace_id")).
 		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return err
 	}
 
 	defer r.log.Sync()
-	r.log.Debug("todosRepository: MarkAsNotComplete()", logging.String("sql", sql))
+	r.log.Debug("todosRepository: Move()", logging.String("sql", sql))
 
-	conn, err := r.conn.Acquire(ctx)
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var group string
+	err = tx.QueryRow(ctx, sql, args...).Scan(&group)
+	if err == pgx.ErrNoRows {
+		return todos.ErrNoSuchTodo
+	}
+	if err != nil {
+		return err
+	}
+	if err := lockPositions(ctx, tx, group); err != nil {
+		return err
+	}
+
+	// the second try always has room after rebalancing
+	position, ok := 0.0, false
+	for try := 0; try < 2; try++ {
+		lo, hi, err := neighbours(ctx, tx, group, inp)
+		if err != nil {
+			return err
+		}
+		if position, ok = todos.PositionBetween(lo, hi); ok {
+			break
+		}
+		if _, err := tx.Exec(ctx, rebalanceSQL, group, todos.PositionStep); err != nil {
+			return err
+		}
+	}
+	if !ok {
+		return errors.New("postgres: no room between anchors after rebalancing")
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE todos SET position = $2 WHERE id::text = $1", inp.ID, position); err != nil {
+		return err
+	}
+	todo, err := getTodo(ctx, tx, inp.ID)
 	if err != nil {
 		return err
 	}
+	if err := writeTodoEvent(ctx, tx, events.TodoUpdated, todo); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// neighbours returns positions a moved todo goes between,
+// lo is nil for the first place and hi is nil for the last one
+func neighbours(ctx context.Context, tx pgx.Tx, group string, inp todos.MoveInput) (lo, hi *float64, err error) {
+	anchor := func(id string) (*float64, error) {
+		var p float64
+		err := tx.QueryRow(ctx, `SELECT position FROM todos
+			WHERE id::text = $1 AND `+positionGroup+`::text = $2`, id, group).Scan(&p)
+		if err == pgx.ErrNoRows {
+			return nil, todos.ErrInvalidAnchor
+		}
+		return &p, err
+	}
+	// ties with an anchor leave no room and make us rebalance
+	closest := func(agg, cmp string, p float64, anchorID string) (*float64, error) {
+		var n *float64
+		err := tx.QueryRow(ctx, `SELECT `+agg+`(position) FROM todos
+			WHERE `+positionGroup+`::text = $1 AND position `+cmp+` $2
+			AND id::text <> $3 AND id::text <> $4`, group, p, inp.ID, anchorID).Scan(&n)
+		return n, err
+	}
+
+	if inp.AfterID != "" {
+		if lo, err = anchor(inp.AfterID); err != nil {
+			return nil, nil, err
+		}
+	}
+	if inp.BeforeID != "" {
+		if hi, err = anchor(inp.BeforeID); err != nil {
+			return nil, nil, err
+		}
+	}
+	switch {
+	case lo != nil && hi != nil:
+		if *lo > *hi {
+			return nil, nil, todos.ErrInvalidAnchor
+		}
+	case lo != nil:
+		hi, err = closest("MIN", ">=", *lo, inp.AfterID)
+	case hi != nil:
+		lo, err = closest("MAX", "<=", *hi, inp.BeforeID)
+	}
+	return lo, hi, err
+}
+
+// Rebalance doesn't write events, order of todos stays the same
+func (r *todosRepository) Rebalance(ctx context.Context, gap float64) (int, error) {
+	const sql = `SELECT g FROM (
+		SELECT ` + positionGroup + `::text AS g, position - LAG(position) OVER (
+			PARTITION BY ` + positionGroup + ` ORDER BY position) AS gap
+		FROM todos) AS p
+	WHERE gap < $1 GROUP BY g LIMIT 100`
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: Rebalance()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, sql, args...)
-	return err
+	rows, err := conn.Query(ctx, sql, gap)
+	if err != nil {
+		return 0, err
+	}
+	groups := []string{}
+	for rows.Next() {
+		var g string
+		if err := rows.Scan(&g); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		groups = append(groups, g)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, g := range groups {
+		if err := r.rebalance(ctx, conn, g); err != nil {
+			return i, err
+		}
+	}
+	return len(groups), nil
+}
+
+func (r *todosRepository) rebalance(ctx context.Context, conn *pgxpool.Conn, group string) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockPositions(ctx, tx, group); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, rebalanceSQL, group, todos.PositionStep); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *todosRepository) Delete(ctx context.Context, id string) error {
 	sql, args, err := sq.
 		Delete("todos").
 		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
 		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return err
@@ -250,12 +674,202 @@ func (r *todosRepository) Delete(ctx context.Context, id string) error {
 	defer r.log.Sync()
 	r.log.Debug("todosRepository: Delete()", logging.String("sql", sql))
 
-	conn, err := r.conn.Acquire(ctx)
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
+
+	todo, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	// todos of other workspaces don't exist for this request
+	if tag.RowsAffected() == 0 {
+		return todos.ErrNoSuchTodo
+	}
+	if err := deleteShares(ctx, tx, shares.ResourceTodo, id); err != nil {
+		return err
+	}
+	if err := writeTodoEvent(ctx, tx, events.TodoDeleted, todo); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *todosRepository) Assign(ctx context.Context, id, assigneeID string, entry todos.HistoryEntry) (entryID string, err error) {
+	sql, args, err := sq.
+		Update("todos").
+		Set("assignee_id", nullString(assigneeID)).
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: Assign()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return "", err
+	}
+	if tag.RowsAffected() == 0 {
+		return "", todos.ErrNoSuchTodo
+	}
+	if entryID, err = writeHistory(ctx, tx, entry); err != nil {
+		return "", err
+	}
+	todo, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return "", err
+	}
+	if err := writeTodoEvent(ctx, tx, events.TodoAssigned, todo); err != nil {
+		return "", err
+	}
+	return entryID, tx.Commit(ctx)
+}
+
+// writeHistory saves a history entry of a todo in a transaction of the change itself
+func writeHistory(ctx context.Context, tx pgx.Tx, e todos.HistoryEntry) (id string, err error) {
+	var data []byte
+	if e.Data != nil {
+		data = []byte(e.Data)
+	}
+	sql, args, err := sq.
+		Insert("todo_history").
+		Columns("todo_id", "actor_id", "action", "data", "created_at").
+		Values(e.TodoID, nullString(e.ActorID), e.Action, data, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+	err = tx.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *todosRepository) GetHistory(ctx context.Context, id string) ([]todos.HistoryEntry, error) {
+	sql, args, err := sq.
+		Select("id, todo_id, actor_id, action, data, created_at").
+		From("todo_history").
+		Where(sq.Eq{"todo_id::text": id}).
+		Where(sq.Expr("todo_id IN (?)", sq.Select("id").From("todos").Where(scopeFilter(ctx, "workspace_id")))).
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: GetHistory()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, sql, args...)
-	return err
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []todos.HistoryEntry{}
+	for rows.Next() {
+		var (
+			e       todos.HistoryEntry
+			actorID *string
+			data    []byte
+		)
+		if err := rows.Scan(&e.ID, &e.TodoID, &actorID, &e.Action, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorID != nil {
+			e.ActorID = *actorID
+		}
+		if data != nil {
+			e.Data = json.RawMessage(data)
+		}
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
+
+// change executes an update of a todo and writes an event
+// with the updated todo in the same transaction
+func (r *todosRepository) change(ctx context.Context, typ events.Type, id, sql string, args []interface{}) error {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return todos.ErrNoSuchTodo
+	}
+	todo, err := getTodo(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := writeTodoEvent(ctx, tx, typ, todo); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *todosRepository) SetFields(ctx context.Context, id string, set map[string]json.RawMessage, remove []string) error {
+	fields, err := marshalFields(set)
+	if err != nil {
+		return err
+	}
+	if remove == nil {
+		remove = []string{}
+	}
+	sql, args, err := sq.
+		Update("todos").
+		Set("fields", sq.Expr("(fields - ?::text[]) || ?::jsonb", remove, fields)).
+		Where(sq.Eq{"id::text": id}).
+		Where(scopeFilter(ctx, "workspace_id")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("todosRepository: SetFields()", logging.String("sql", sql))
+
+	return r.change(ctx, events.TodoUpdated, id, sql, args)
+}
+
+// marshalFields keeps todos without fields as an empty object
+func marshalFields(fields map[string]json.RawMessage) ([]byte, error) {
+	if fields == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(fields)
+}
+
+// nullString turns empty strings into nulls for nullable columns
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
 )
@@ -31,13 +32,13 @@ func (r *usersRepository) Create(ctx context.Context, email, hashedPassword, use
 	defer r.log.Sync()
 	r.log.Debug("usersRepository: Create()", logging.String("sql", sql))
 
-	conn, err := r.conn.Acquire(ctx)
+	tx, err := r.conn.Begin(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer conn.Release()
+	defer tx.Rollback(ctx)
 
-	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	err = tx.QueryRow(ctx, sql, args...).Scan(&id)
 	if err != nil {
 		r.log.Debug("usersRepository: Create()", logging.String("error", err.Error()))
 		var pgErr *pgconn.PgError
@@ -46,12 +47,16 @@ func (r *usersRepository) Create(ctx context.Context, email, hashedPassword, use
 				return id, users.ErrEmailIsTaken
 			}
 		}
+		return id, err
+	}
+	if err := r.writeEvent(ctx, tx, events.UserCreated, id); err != nil {
+		return "", err
 	}
-	return id, err
+	return id, tx.Commit(ctx)
 }
 
 func (r *usersRepository) Get(ctx context.Context, id string) (user users.User, err error) {
-	sql, args, err := sq.Select(`id, email, password, role_id, username, created_at, updated_at`).
+	sql, args, err := sq.Select(`id, email, password, role_id, username, delete_after, token_version, created_at, updated_at`).
 		From("users").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return user, err
@@ -68,14 +73,14 @@ func (r *usersRepository) Get(ctx context.Context, id string) (user users.User,
 
 	err = conn.QueryRow(ctx, sql, args...).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Role,
-		&user.Username, &user.CreatedAt, &user.UpdatedAt,
+		&user.Username, &user.DeleteAfter, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	return user, err
 }
 
 func (r *usersRepository) GetByEmail(ctx context.Context, email string) (user users.User, err error) {
-	sql, args, err := sq.Select(`id, email, password, role_id, username, created_at, updated_at`).
+	sql, args, err := sq.Select(`id, email, password, role_id, username, delete_after, token_version, created_at, updated_at`).
 		From("users").Where(sq.Eq{"email": email}).PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return user, err
@@ -92,7 +97,7 @@ func (r *usersRepository) GetByEmail(ctx context.Context, email string) (user us
 
 	err = conn.QueryRow(ctx, sql, args...).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.Role,
-		&user.Username, &user.CreatedAt, &user.UpdatedAt,
+		&user.Username, &user.DeleteAfter, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
@@ -103,6 +108,29 @@ func (r *usersRepository) GetByEmail(ctx context.Context, email string) (user us
 	return user, err
 }
 
+func (r *usersRepository) TokenVersion(ctx context.Context, id string) (version int, err error) {
+	sql, args, err := sq.Select("token_version").
+		From("users").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("usersRepository: TokenVersion()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&version)
+	if err == pgx.ErrNoRows {
+		return 0, users.ErrNoSuchUser
+	}
+	return version, err
+}
+
 func (r *usersRepository) Update(ctx context.Context, inp users.UpdateInput) (err error) {
 	sql, args, err := sq.Update("users").
 		Set("password", inp.Password).
@@ -117,35 +145,191 @@ func (r *usersRepository) Update(ctx context.Context, inp users.UpdateInput) (er
 	defer r.log.Sync()
 	r.log.Debug("usersRepository: Update()", logging.String("sql", sql))
 
+	return r.change(ctx, events.UserUpdated, inp.ID, sql, args)
+}
+
+func (r *usersRepository) Delete(ctx context.Context, id string) (files []string, err error) {
+	defer r.log.Sync()
+	r.log.Debug("usersRepository: Delete()", logging.String("id", id))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// we need to tell everyone who it was
+	if err := r.writeEvent(ctx, tx, events.UserDeleted, id); err != nil {
+		return nil, err
+	}
+
+	// keys of the user stop working together with everything else
+	if _, err := tx.Exec(ctx, "UPDATE users SET token_version = token_version + 1 WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+
+	var owns bool
+	if err := tx.QueryRow(ctx, ownsWorkspacesSQL, id).Scan(&owns); err != nil {
+		return nil, err
+	}
+	if owns {
+		return nil, users.ErrOwnsWorkspace
+	}
+
+	// things made for others stay with them, only personal ones are deleted
+	for _, sql := range handOverSQL {
+		if _, err := tx.Exec(ctx, sql, id); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM todos WHERE user_id = $1", id); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "DELETE FROM exports WHERE user_id = $1 RETURNING path", id)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var path *string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if path != nil {
+			files = append(files, *path)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM users WHERE id = $1", id); err != nil {
+		return nil, err
+	}
+
+	return files, tx.Commit(ctx)
+}
+
+const ownsWorkspacesSQL = `SELECT EXISTS (SELECT 1 FROM workspaces WHERE owner_id = $1)`
+
+// handOverSQL gives lists and todos of a user in workspaces to owners
+// of the workspaces, and todos of the user in lists of others to owners of the lists
+var handOverSQL = []string{
+	`UPDATE lists AS l SET user_id = w.owner_id FROM workspaces AS w
+		WHERE l.workspace_id = w.id AND l.user_id = $1`,
+	`UPDATE todos AS t SET user_id = w.owner_id FROM workspaces AS w
+		WHERE t.workspace_id = w.id AND t.user_id = $1`,
+	`UPDATE todos AS t SET user_id = l.user_id FROM lists AS l
+		WHERE t.list_id = l.id AND l.user_id <> $1 AND t.user_id = $1`,
+}
+
+func (r *usersRepository) OwnsWorkspaces(ctx context.Context, id string) (bool, error) {
+	defer r.log.Sync()
+	r.log.Debug("usersRepository: OwnsWorkspaces()", logging.String("sql", ownsWorkspacesSQL))
+
 	conn, err := r.conn.Acquire(ctx)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, sql, args...)
+	var owns bool
+	err = conn.QueryRow(ctx, ownsWorkspacesSQL, id).Scan(&owns)
+	return owns, err
+}
+
+func (r *usersRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	return r.setDeleteAfter(ctx, "ScheduleDeletion()", events.UserDeletionScheduled, id, &at)
+}
 
-	return err
+func (r *usersRepository) CancelDeletion(ctx context.Context, id string) error {
+	return r.setDeleteAfter(ctx, "CancelDeletion()", events.UserDeletionCanceled, id, nil)
 }
 
-func (r *usersRepository) Delete(ctx context.Context, id string) (err error) {
-	sql, args, err := sq.Delete("users").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar).ToSql()
+func (r *usersRepository) setDeleteAfter(ctx context.Context, method string, typ events.Type, id string, at *time.Time) error {
+	sql, args, err := sq.Update("users").
+		Set("delete_after", at).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
 	if err != nil {
 		return err
 	}
 
 	defer r.log.Sync()
-	r.log.Debug("usersRepository: Delete()", logging.String("sql", sql))
+	r.log.Debug("usersRepository: "+method, logging.String("sql", sql))
 
+	return r.change(ctx, typ, id, sql, args)
+}
 
-	conn, err := r.conn.Acquire(ctx)
+// change executes an update of a user and writes an event
+// with the updated user in the same transaction
+func (r *usersRepository) change(ctx context.Context, typ events.Type, id, sql string, args []interface{}) error {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+	if err := r.writeEvent(ctx, tx, typ, id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// writeEvent writes an event with a user as it is in the transaction right now
+func (r *usersRepository) writeEvent(ctx context.Context, tx pgx.Tx, typ events.Type, id string) error {
+	var user users.User
+	err := tx.QueryRow(ctx,
+		`SELECT id, email, role_id, username, delete_after, created_at, updated_at
+		FROM users WHERE id = $1`, id,
+	).Scan(
+		&user.ID, &user.Email, &user.Role,
+		&user.Username, &user.DeleteAfter, &user.CreatedAt, &user.UpdatedAt,
+	)
 	if err != nil {
 		return err
 	}
+	return writeUserEvent(ctx, tx, typ, user)
+}
+
+func (r *usersRepository) GetScheduledForDeletion(ctx context.Context, before time.Time) ([]string, error) {
+	sql, args, err := sq.Select("id").
+		From("users").
+		Where(sq.Lt{"delete_after": before}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("usersRepository: GetScheduledForDeletion()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer conn.Release()
 
-	_, err = conn.Exec(ctx, sql, args...)
-	return err
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
 var roleIds = map[int]users.Role{
@@ -0,0 +1,562 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/webhooks"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type webhooksRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+const (
+	webhooksColumns = `id, user_id, url, secret, events,
+	enabled, failures, disabled_at, created_at, updated_at`
+
+	deliveriesColumns = `id, webhook_id, type, payload,
+	status, attempts, next_attempt_at, created_at, delivered_at`
+)
+
+func scanWebhook(row pgx.Row) (w webhooks.Webhook, err error) {
+	var (
+		types      []string
+		disabledAt pq.NullTime
+		updatedAt  pq.NullTime
+	)
+	err = row.Scan(
+		&w.ID, &w.UserID, &w.URL, &w.Secret, &types,
+		&w.Enabled, &w.Failures, &disabledAt, &w.CreatedAt, &updatedAt,
+	)
+	if err != nil {
+		return w, err
+	}
+	w.Events = make([]events.Type, len(types))
+	for i, t := range types {
+		w.Events[i] = events.Type(t)
+	}
+	if disabledAt.Valid {
+		w.DisabledAt = disabledAt.Time
+	}
+	if updatedAt.Valid {
+		w.UpdatedAt = updatedAt.Time
+	}
+	return w, nil
+}
+
+func scanDelivery(row pgx.Row) (d webhooks.Delivery, err error) {
+	var (
+		payload     []byte
+		deliveredAt pq.NullTime
+	)
+	err = row.Scan(
+		&d.ID, &d.WebhookID, &d.Type, &payload,
+		&d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt, &deliveredAt,
+	)
+	if err != nil {
+		return d, err
+	}
+	d.Payload = json.RawMessage(payload)
+	if deliveredAt.Valid {
+		d.DeliveredAt = deliveredAt.Time
+	}
+	return d, nil
+}
+
+func eventTypes(types []events.Type) []string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = string(t)
+	}
+	return out
+}
+
+func (r *webhooksRepository) Create(ctx context.Context, w webhooks.Webhook) (id string, err error) {
+	sql, args, err := sq.
+		Insert("webhooks").
+		Columns("user_id", "url", "secret", "events", "enabled", "created_at").
+		Values(w.UserID, w.URL, w.Secret, eventTypes(w.Events), w.Enabled, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Create()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *webhooksRepository) Get(ctx context.Context, id string) (webhooks.Webhook, error) {
+	sql, args, err := sq.
+		Select(webhooksColumns).
+		From("webhooks").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return webhooks.Webhook{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return webhooks.Webhook{}, err
+	}
+	defer conn.Release()
+
+	w, err := scanWebhook(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return w, webhooks.ErrNoSuchWebhook
+	}
+	return w, err
+}
+
+func (r *webhooksRepository) GetAll(ctx context.Context, userID string) ([]webhooks.Webhook, error) {
+	sql, args, err := sq.
+		Select(webhooksColumns).
+		From("webhooks").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []webhooks.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+func (r *webhooksRepository) Update(ctx context.Context, w webhooks.Webhook) error {
+	var disabledAt *time.Time
+	if !w.DisabledAt.IsZero() {
+		disabledAt = &w.DisabledAt
+	}
+	sql, args, err := sq.
+		Update("webhooks").
+		SetMap(map[string]any{
+			"url":         w.URL,
+			"events":      eventTypes(w.Events),
+			"enabled":     w.Enabled,
+			"failures":    w.Failures,
+			"disabled_at": disabledAt,
+			"updated_at":  time.Now().UTC(),
+		}).
+		Where(sq.Eq{"id::text": w.ID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Update()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *webhooksRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("webhooks").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Delete()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+// enqueueEventSQL creates a delivery for every enabled webhook subscribed
+// to an event. Unique constraint makes sure that an event is delivered
+// to a webhook only once, even if it is relayed again.
+const enqueueEventSQL = `INSERT INTO webhook_deliveries
+	(webhook_id, event_id, type, payload, created_at, next_attempt_at)
+SELECT id, $1, $2, $3, $4, $4 FROM webhooks
+WHERE user_id::text = $5 AND enabled AND $2 = ANY(events)
+ON CONFLICT (webhook_id, event_id) DO NOTHING`
+
+func (r *webhooksRepository) EnqueueEvent(ctx context.Context, e events.Event) error {
+	eventID, err := strconv.ParseInt(e.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: EnqueueEvent()", logging.String("sql", enqueueEventSQL))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, enqueueEventSQL, eventID, string(e.Type), []byte(e.Data), time.Now().UTC(), e.UserID)
+	return err
+}
+
+func (r *webhooksRepository) Enqueue(ctx context.Context, webhookID string, typ events.Type, payload json.RawMessage) (id string, err error) {
+	now := time.Now().UTC()
+	sql, args, err := sq.
+		Insert("webhook_deliveries").
+		Columns("webhook_id", "type", "payload", "created_at", "next_attempt_at").
+		Values(webhookID, typ, []byte(payload), now, now).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Enqueue()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	return id, err
+}
+
+func (r *webhooksRepository) GetDelivery(ctx context.Context, id string) (webhooks.Delivery, error) {
+	sql, args, err := sq.
+		Select(deliveriesColumns).
+		From("webhook_deliveries").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return webhooks.Delivery{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: GetDelivery()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return webhooks.Delivery{}, err
+	}
+	defer conn.Release()
+
+	d, err := scanDelivery(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return d, webhooks.ErrNoSuchDelivery
+	}
+	return d, err
+}
+
+func (r *webhooksRepository) GetDeliveries(ctx context.Context, webhookID string, limit int) ([]webhooks.Delivery, error) {
+	sql, args, err := sq.
+		Select(deliveriesColumns).
+		From("webhook_deliveries").
+		Where(sq.Eq{"webhook_id": webhookID}).
+		OrderBy("created_at DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: GetDeliveries()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []webhooks.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+func (r *webhooksRepository) GetAttempts(ctx context.Context, deliveryID string) ([]webhooks.Attempt, error) {
+	sql, args, err := sq.
+		Select("delivery_id, status_code, error, duration_ms, created_at").
+		From("webhook_attempts").
+		Where(sq.Eq{"delivery_id": deliveryID}).
+		OrderBy("created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: GetAttempts()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []webhooks.Attempt{}
+	for rows.Next() {
+		var (
+			a          webhooks.Attempt
+			reason     *string
+			durationMs int64
+		)
+		if err := rows.Scan(&a.DeliveryID, &a.StatusCode, &reason, &durationMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		if reason != nil {
+			a.Error = *reason
+		}
+		a.Duration = time.Duration(durationMs) * time.Millisecond
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func (r *webhooksRepository) Redeliver(ctx context.Context, id string, at time.Time) error {
+	sql, args, err := sq.
+		Update("webhook_deliveries").
+		SetMap(map[string]any{
+			"status":          webhooks.StatusPending,
+			"attempts":        0,
+			"next_attempt_at": at,
+			"delivered_at":    nil,
+		}).
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Redeliver()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+const claimDueSQL = `UPDATE webhook_deliveries AS d SET next_attempt_at = $2
+FROM webhooks AS w
+WHERE w.id = d.webhook_id AND d.id = (
+	SELECT dd.id FROM webhook_deliveries AS dd
+	INNER JOIN webhooks AS ww ON ww.id = dd.webhook_id
+	WHERE dd.status = 'pending' AND dd.next_attempt_at <= $1 AND ww.enabled
+	ORDER BY dd.next_attempt_at
+	LIMIT 1
+	FOR UPDATE OF dd SKIP LOCKED
+)
+RETURNING d.id, d.webhook_id, d.type, d.payload,
+	d.status, d.attempts, d.next_attempt_at, d.created_at, d.delivered_at,
+	w.url, w.secret`
+
+func (r *webhooksRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (webhooks.Due, error) {
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: ClaimDue()", logging.String("sql", claimDueSQL))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return webhooks.Due{}, err
+	}
+	defer conn.Release()
+
+	var (
+		due         webhooks.Due
+		payload     []byte
+		deliveredAt pq.NullTime
+	)
+	d := &due.Delivery
+	err = conn.QueryRow(ctx, claimDueSQL, now, now.Add(lease)).Scan(
+		&d.ID, &d.WebhookID, &d.Type, &payload,
+		&d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt, &deliveredAt,
+		&due.URL, &due.Secret,
+	)
+	if err == pgx.ErrNoRows {
+		return due, webhooks.ErrNoSuchDelivery
+	}
+	if err != nil {
+		return due, err
+	}
+	d.Payload = json.RawMessage(payload)
+	if deliveredAt.Valid {
+		d.DeliveredAt = deliveredAt.Time
+	}
+	return due, nil
+}
+
+func (r *webhooksRepository) Record(ctx context.Context, d webhooks.Delivery, a webhooks.Attempt, disableAfter int) (disabled bool, err error) {
+	var reason *string
+	if a.Error != "" {
+		reason = &a.Error
+	}
+	attemptSQL, attemptArgs, err := sq.
+		Insert("webhook_attempts").
+		Columns("delivery_id", "status_code", "error", "duration_ms", "created_at").
+		Values(d.ID, a.StatusCode, reason, a.Duration.Milliseconds(), a.CreatedAt).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var deliveredAt *time.Time
+	if !d.DeliveredAt.IsZero() {
+		deliveredAt = &d.DeliveredAt
+	}
+	deliverySQL, deliveryArgs, err := sq.
+		Update("webhook_deliveries").
+		SetMap(map[string]any{
+			"status":          d.Status,
+			"attempts":        d.Attempts,
+			"next_attempt_at": d.NextAttemptAt,
+			"delivered_at":    deliveredAt,
+		}).
+		Where(sq.Eq{"id::text": d.ID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: Record()", logging.String("sql", deliverySQL))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, attemptSQL, attemptArgs...); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, deliverySQL, deliveryArgs...); err != nil {
+		return false, err
+	}
+
+	if a.Error == "" {
+		if _, err := tx.Exec(ctx, "UPDATE webhooks SET failures = 0 WHERE id = $1", d.WebhookID); err != nil {
+			return false, err
+		}
+		return false, tx.Commit(ctx)
+	}
+
+	var failures int
+	err = tx.QueryRow(ctx,
+		"UPDATE webhooks SET failures = failures + 1 WHERE id = $1 RETURNING failures",
+		d.WebhookID,
+	).Scan(&failures)
+	if err != nil {
+		return false, err
+	}
+	if disableAfter > 0 && failures >= disableAfter {
+		tag, err := tx.Exec(ctx,
+			"UPDATE webhooks SET enabled = false, disabled_at = $2 WHERE id = $1 AND enabled",
+			d.WebhookID, a.CreatedAt,
+		)
+		if err != nil {
+			return false, err
+		}
+		disabled = tag.RowsAffected() == 1
+	}
+	return disabled, tx.Commit(ctx)
+}
+
+func (r *webhooksRepository) DeleteDeliveries(ctx context.Context, before time.Time) error {
+	sql, args, err := sq.
+		Delete("webhook_deliveries").
+		Where(sq.NotEq{"status": webhooks.StatusPending}).
+		Where(sq.Lt{"created_at": before}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("webhooksRepository: DeleteDeliveries()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
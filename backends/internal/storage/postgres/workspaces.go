@@ -0,0 +1,515 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/lib/pq"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type workspacesRepository struct {
+	conn *pgxpool.Pool
+	log  *logging.Logger
+}
+
+// scopeFilter keeps rows of the workspace a request works in.
+// Contexts without a scope come from background jobs, they see everything.
+func scopeFilter(ctx context.Context, column string) sq.Sqlizer {
+	workspaceID, ok := workspaces.ScopeFrom(ctx)
+	if !ok {
+		return sq.Expr("TRUE")
+	}
+	if workspaceID == "" {
+		return sq.Eq{column: nil}
+	}
+	return sq.Eq{column + "::text": workspaceID}
+}
+
+// scopeValue is the workspace new todos and lists are created in
+func scopeValue(ctx context.Context) *string {
+	workspaceID, _ := workspaces.ScopeFrom(ctx)
+	return nullString(workspaceID)
+}
+
+// inWorkspace tells if a request works in a workspace
+// and not in the personal space of a user
+func inWorkspace(ctx context.Context) bool {
+	workspaceID, _ := workspaces.ScopeFrom(ctx)
+	return workspaceID != ""
+}
+
+func (r *workspacesRepository) Create(ctx context.Context, w workspaces.Workspace) (id string, err error) {
+	sql, args, err := sq.
+		Insert("workspaces").
+		Columns("name", "owner_id", "created_at").
+		Values(w.Name, w.OwnerID, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: Create()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		return "", err
+	}
+	if err := addMember(ctx, tx, id, w.OwnerID, workspaces.RoleOwner); err != nil {
+		return "", err
+	}
+	return id, tx.Commit(ctx)
+}
+
+func addMember(ctx context.Context, tx pgx.Tx, workspaceID, userID string, role workspaces.Role) error {
+	sql, args, err := sq.
+		Insert("workspace_members").
+		Columns("workspace_id", "user_id", "role", "joined_at").
+		Values(workspaceID, userID, role, time.Now().UTC()).
+		Suffix("ON CONFLICT (workspace_id, user_id) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *workspacesRepository) Get(ctx context.Context, id string) (w workspaces.Workspace, err error) {
+	sql, args, err := sq.
+		Select("id, name, owner_id, created_at").
+		From("workspaces").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return w, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: Get()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return w, err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&w.ID, &w.Name, &w.OwnerID, &w.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return w, workspaces.ErrNoSuchWorkspace
+	}
+	return w, err
+}
+
+func (r *workspacesRepository) GetAll(ctx context.Context, userID string) ([]workspaces.Workspace, error) {
+	sql, args, err := sq.
+		Select("w.id, w.name, w.owner_id, w.created_at, m.role").
+		From("workspaces AS w").
+		Join("workspace_members AS m ON m.workspace_id = w.id").
+		Where(sq.Eq{"m.user_id": userID}).
+		OrderBy("w.created_at ASC").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: GetAll()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	all := []workspaces.Workspace{}
+	for rows.Next() {
+		var w workspaces.Workspace
+		if err := rows.Scan(&w.ID, &w.Name, &w.OwnerID, &w.CreatedAt, &w.Role); err != nil {
+			return nil, err
+		}
+		all = append(all, w)
+	}
+	return all, rows.Err()
+}
+
+func (r *workspacesRepository) Update(ctx context.Context, inp workspaces.UpdateInput) error {
+	sql, args, err := sq.
+		Update("workspaces").
+		Set("name", inp.Name).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"id::text": inp.ID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: Update()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *workspacesRepository) Delete(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("workspaces").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: Delete()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// todos and lists go away with the workspace by foreign keys,
+	// but their shares and links have to be deleted by hand
+	for _, table := range []string{"shares", "public_links"} {
+		_, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE
+			(resource_type = $1 AND resource_id IN (SELECT id FROM todos WHERE workspace_id::text = $3)) OR
+			(resource_type = $2 AND resource_id IN (SELECT id FROM lists WHERE workspace_id::text = $3))`,
+			shares.ResourceTodo, shares.ResourceList, id)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(ctx, sql, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func selectMembers() sq.SelectBuilder {
+	return sq.
+		Select("m.workspace_id, m.user_id, u.username, u.email, m.role, m.joined_at").
+		From("workspace_members AS m").
+		Join("users AS u ON u.id = m.user_id").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanMember(row pgx.Row) (m workspaces.Member, err error) {
+	err = row.Scan(&m.WorkspaceID, &m.UserID, &m.Username, &m.Email, &m.Role, &m.JoinedAt)
+	return m, err
+}
+
+func (r *workspacesRepository) GetMember(ctx context.Context, workspaceID, userID string) (workspaces.Member, error) {
+	sql, args, err := selectMembers().
+		Where(sq.Eq{"m.workspace_id::text": workspaceID, "m.user_id::text": userID}).
+		ToSql()
+	if err != nil {
+		return workspaces.Member{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: GetMember()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return workspaces.Member{}, err
+	}
+	defer conn.Release()
+
+	m, err := scanMember(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return m, workspaces.ErrNoSuchMember
+	}
+	return m, err
+}
+
+func (r *workspacesRepository) GetMembers(ctx context.Context, workspaceID string) ([]workspaces.Member, error) {
+	sql, args, err := selectMembers().
+		Where(sq.Eq{"m.workspace_id::text": workspaceID}).
+		OrderBy("m.joined_at ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: GetMembers()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []workspaces.Member{}
+	for rows.Next() {
+		m, err := scanMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (r *workspacesRepository) SetRole(ctx context.Context, workspaceID, userID string, role workspaces.Role) error {
+	sql, args, err := sq.
+		Update("workspace_members").
+		Set("role", role).
+		Where(sq.Eq{"workspace_id::text": workspaceID, "user_id::text": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: SetRole()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *workspacesRepository) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	sql, args, err := sq.
+		Delete("workspace_members").
+		Where(sq.Eq{"workspace_id::text": workspaceID, "user_id::text": userID}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: RemoveMember()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	// todos stay in the workspace, the member just can't see them anymore
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
+
+func (r *workspacesRepository) CreateInvitation(ctx context.Context, inv workspaces.Invitation) (id string, err error) {
+	sql, args, err := sq.
+		Insert("workspace_invitations").
+		Columns("workspace_id", "email", "role", "invited_by", "status", "created_at").
+		Values(inv.WorkspaceID, inv.Email, inv.Role, inv.InvitedBy, inv.Status, time.Now().UTC()).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return "", err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: CreateInvitation()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Release()
+
+	err = conn.QueryRow(ctx, sql, args...).Scan(&id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return "", workspaces.ErrAlreadyInvited
+	}
+	return id, err
+}
+
+func selectInvitations() sq.SelectBuilder {
+	return sq.
+		Select(`i.id, i.workspace_id, w.name, i.email, i.role, i.invited_by,
+			i.status, i.created_at, i.responded_at`).
+		From("workspace_invitations AS i").
+		Join("workspaces AS w ON w.id = i.workspace_id").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func scanInvitation(row pgx.Row) (inv workspaces.Invitation, err error) {
+	var (
+		invitedBy   *string
+		respondedAt pq.NullTime
+	)
+	err = row.Scan(
+		&inv.ID, &inv.WorkspaceID, &inv.WorkspaceName, &inv.Email, &inv.Role, &invitedBy,
+		&inv.Status, &inv.CreatedAt, &respondedAt,
+	)
+	if err != nil {
+		return inv, err
+	}
+	if invitedBy != nil {
+		inv.InvitedBy = *invitedBy
+	}
+	if respondedAt.Valid {
+		inv.RespondedAt = &respondedAt.Time
+	}
+	return inv, nil
+}
+
+func (r *workspacesRepository) GetInvitation(ctx context.Context, id string) (workspaces.Invitation, error) {
+	sql, args, err := selectInvitations().Where(sq.Eq{"i.id::text": id}).ToSql()
+	if err != nil {
+		return workspaces.Invitation{}, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: GetInvitation()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return workspaces.Invitation{}, err
+	}
+	defer conn.Release()
+
+	inv, err := scanInvitation(conn.QueryRow(ctx, sql, args...))
+	if err == pgx.ErrNoRows {
+		return inv, workspaces.ErrNoSuchInvitation
+	}
+	return inv, err
+}
+
+func (r *workspacesRepository) GetInvitations(ctx context.Context, workspaceID string) ([]workspaces.Invitation, error) {
+	query := selectInvitations().
+		Where(sq.Eq{"i.workspace_id::text": workspaceID, "i.status": workspaces.StatusPending}).
+		OrderBy("i.created_at DESC")
+	return r.getInvitations(ctx, "GetInvitations", query)
+}
+
+func (r *workspacesRepository) GetInvitationsByEmail(ctx context.Context, email string) ([]workspaces.Invitation, error) {
+	query := selectInvitations().
+		Where(sq.Eq{"i.email": email, "i.status": workspaces.StatusPending}).
+		OrderBy("i.created_at DESC")
+	return r.getInvitations(ctx, "GetInvitationsByEmail", query)
+}
+
+func (r *workspacesRepository) getInvitations(ctx context.Context, method string, query sq.SelectBuilder) ([]workspaces.Invitation, error) {
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: "+method+"()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	rows, err := conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []workspaces.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, inv)
+	}
+	return list, rows.Err()
+}
+
+func (r *workspacesRepository) Respond(ctx context.Context, inv workspaces.Invitation, userID string, status workspaces.Status, at time.Time) error {
+	sql, args, err := sq.
+		Update("workspace_invitations").
+		Set("status", status).
+		Set("responded_at", at).
+		Where(sq.Eq{"id::text": inv.ID, "status": workspaces.StatusPending}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: Respond()", logging.String("sql", sql))
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	// somebody answered it in the meantime
+	if tag.RowsAffected() == 0 {
+		return workspaces.ErrNotPending
+	}
+	if status == workspaces.StatusAccepted {
+		if err := addMember(ctx, tx, inv.WorkspaceID, userID, inv.Role); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *workspacesRepository) DeleteInvitation(ctx context.Context, id string) error {
+	sql, args, err := sq.
+		Delete("workspace_invitations").
+		Where(sq.Eq{"id::text": id}).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return err
+	}
+
+	defer r.log.Sync()
+	r.log.Debug("workspacesRepository: DeleteInvitation()", logging.String("sql", sql))
+
+	conn, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, sql, args...)
+	return err
+}
@@ -0,0 +1,327 @@
+package resthttp
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/attachments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+// multipartOverhead is room for boundaries and headers of a multipart body
+const multipartOverhead = 1 << 20
+
+var ErrFileNotProvided = errors.New("file was not provided in form field \"file\"")
+
+func (s *Server) attachmentsError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, attachments.ErrNoSuchAttachment), errors.Is(err, attachments.ErrNoSuchThumbnail),
+		errors.Is(err, todos.ErrNoSuchTodo):
+		status = http.StatusNotFound
+	case errors.Is(err, attachments.ErrNotAllowed), errors.Is(err, todos.ErrNotAllowed):
+		status = http.StatusForbidden
+	case errors.Is(err, attachments.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, attachments.ErrQuotaExceeded):
+		status = http.StatusInsufficientStorage
+	case errors.Is(err, attachments.ErrContentType):
+		status = http.StatusUnsupportedMediaType
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+// withURLs tells clients where to download a file and its thumbnails
+func withURLs(a attachments.Attachment) attachments.Attachment {
+	a.URL = "/api/todos/" + a.TodoID + "/attachments/" + a.ID
+	thumbnails := make([]attachments.Thumbnail, len(a.Thumbnails))
+	for i, t := range a.Thumbnails {
+		t.URL = a.URL + "/thumbnails/" + t.Size
+		thumbnails[i] = t
+	}
+	a.Thumbnails = thumbnails
+	return a
+}
+
+// swagger:route POST /todos/{id}/attachments attachments AttachmentsUpload
+//
+// Attach a file to a todo
+//
+// Editors of a todo can attach files to it. Type of a file is
+// detected from its contents, only some types are allowed.
+// Files of a user can't take more space than his quota.
+// Thumbnails of images are made in background, until then
+// thumbnailStatus is "pending".
+//
+//     Consumes:
+//     - multipart/form-data
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: file
+//         in: formData
+//         required: true
+//         type: file
+//
+//     Responses:
+//       201: description: attachment
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       413: stdResponse
+//       415: stdResponse
+//       507: stdResponse
+func (s *Server) AttachmentsUpload(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	limit := s.maxUpload + multipartOverhead
+	if ctx.Request.ContentLength > limit {
+		s.attachmentsError(ctx, attachments.ErrTooLarge)
+		return
+	}
+	// bodies without Content-Length are cut here
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrFileNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		return
+	}
+	defer file.Close()
+
+	a, err := s.attachmentsService.Upload(ctx, attachments.UploadInput{
+		TodoID:   ctx.Param("id"),
+		UserID:   u.ID,
+		Filename: header.Filename,
+		Size:     header.Size,
+		Body:     file,
+	})
+	if err != nil {
+		s.attachmentsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, withURLs(a), nil)
+}
+
+// swagger:route GET /todos/{id}/attachments attachments AttachmentsGetAll
+//
+// Get files attached to a todo
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: attachments
+//       404: stdResponse
+func (s *Server) AttachmentsGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.attachmentsService.GetAll(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.attachmentsError(ctx, err)
+		return
+	}
+
+	for i := range list {
+		list[i] = withURLs(list[i])
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route GET /todos/{id}/attachments/{attachmentId} attachments AttachmentsDownload
+//
+// Download an attached file
+//
+//     Produces:
+//     - application/octet-stream
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: attachmentId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: contents of the file
+//       404: stdResponse
+func (s *Server) AttachmentsDownload(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	a, body, err := s.attachmentsService.Download(ctx, u.ID, ctx.Param("id"), ctx.Param("attachmentId"))
+	if err != nil {
+		s.attachmentsError(ctx, err)
+		return
+	}
+	defer body.Close()
+
+	ctx.DataFromReader(http.StatusOK, a.Size, a.ContentType, body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// swagger:route GET /todos/{id}/attachments/{attachmentId}/thumbnails/{size} attachments AttachmentsDownloadThumbnail
+//
+// Download a thumbnail of an image
+//
+// Thumbnails have no EXIF or any other metadata.
+//
+//     Produces:
+//     - image/jpeg
+//     - image/png
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: attachmentId
+//         in: params
+//         required: true
+//         type: string
+//       + name: size
+//         in: params
+//         required: true
+//         type: string
+//         enum: small, medium
+//
+//     Responses:
+//       200: description: the thumbnail
+//       404: stdResponse
+func (s *Server) AttachmentsDownloadThumbnail(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	t, body, err := s.attachmentsService.DownloadThumbnail(ctx, u.ID, ctx.Param("id"), ctx.Param("attachmentId"), ctx.Param("size"))
+	if err != nil {
+		s.attachmentsError(ctx, err)
+		return
+	}
+	defer body.Close()
+
+	// thumbnails never change, a new upload gets a new id
+	ctx.DataFromReader(http.StatusOK, -1, t.ContentType, body, map[string]string{
+		"Cache-Control":          "private, max-age=86400",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// swagger:route DELETE /todos/{id}/attachments/{attachmentId} attachments AttachmentsDelete
+//
+// Delete an attached file
+//
+// Files can be deleted by those who uploaded them and by the owner of the todo.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: attachmentId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) AttachmentsDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.attachmentsService.Delete(ctx, u.ID, ctx.Param("id"), ctx.Param("attachmentId")); err != nil {
+		s.attachmentsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
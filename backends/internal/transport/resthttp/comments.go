@@ -0,0 +1,282 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/comments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+type (
+	// reqCommentsSave
+	// This is a model used for writing and editing comments
+	// swagger:model
+	reqCommentsSave struct {
+		// Markdown, @username mentions notify users who can see the todo
+		// required: true
+		// example: "@emirlan can you take a look?"
+		// max length: 5000
+		Body string `json:"body"`
+	}
+)
+
+func (s *Server) commentsError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, comments.ErrNoSuchComment), errors.Is(err, todos.ErrNoSuchTodo):
+		status = http.StatusNotFound
+	case errors.Is(err, comments.ErrNotAllowed):
+		status = http.StatusForbidden
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+func bindCommentsSave(ctx *gin.Context) (reqCommentsSave, bool) {
+	req := reqCommentsSave{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return req, false
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// swagger:route POST /todos/{id}/comments comments CommentsCreate
+//
+// Comment a todo
+//
+// Everybody who can see a todo can comment it.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: comment
+//         in: body
+//         required: true
+//         type: reqCommentsSave
+//
+//     Responses:
+//       201: description: comment
+//       400: stdResponse
+//       404: stdResponse
+func (s *Server) CommentsCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req, ok := bindCommentsSave(ctx)
+	if !ok {
+		return
+	}
+
+	c, err := s.commentsService.Create(ctx, comments.CreateInput{
+		TodoID:   ctx.Param("id"),
+		AuthorID: u.ID,
+		Body:     req.Body,
+	})
+	if err != nil {
+		s.commentsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, c, nil)
+}
+
+// swagger:route GET /todos/{id}/comments comments CommentsGetAll
+//
+// Get comments of a todo
+//
+// Oldest comments go first.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: pageSize
+//         in: query
+//         required: false
+//         type: integer
+//         example: 20
+//       + name: page
+//         in: query
+//         required: false
+//         type: integer
+//         example: 0
+//
+//     Responses:
+//       200: description: comments
+//       400: stdResponse
+//       404: stdResponse
+func (s *Server) CommentsGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	inp := comments.GetAllInput{TodoID: ctx.Param("id")}
+	if v := ctx.Query("pageSize"); v != "" {
+		if inp.PageSize, err = strconv.Atoi(v); err != nil {
+			respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+			return
+		}
+	}
+	if v := ctx.Query("page"); v != "" {
+		if inp.Page, err = strconv.Atoi(v); err != nil {
+			respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+			return
+		}
+	}
+
+	list, err := s.commentsService.GetAll(ctx, u.ID, inp)
+	if err != nil {
+		s.commentsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route PATCH /todos/{id}/comments/{commentId} comments CommentsUpdate
+//
+// Edit a comment
+//
+// Only the author can edit a comment. Newly mentioned users are notified.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: commentId
+//         in: params
+//         required: true
+//         type: string
+//       + name: comment
+//         in: body
+//         required: true
+//         type: reqCommentsSave
+//
+//     Responses:
+//       200: description: comment
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) CommentsUpdate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req, ok := bindCommentsSave(ctx)
+	if !ok {
+		return
+	}
+
+	c, err := s.commentsService.Update(ctx, u.ID, ctx.Param("id"), comments.UpdateInput{
+		ID:   ctx.Param("commentId"),
+		Body: req.Body,
+	})
+	if err != nil {
+		s.commentsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, c, nil)
+}
+
+// swagger:route DELETE /todos/{id}/comments/{commentId} comments CommentsDelete
+//
+// Delete a comment
+//
+// Only the author can delete a comment.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: commentId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) CommentsDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.commentsService.Delete(ctx, u.ID, ctx.Param("id"), ctx.Param("commentId")); err != nil {
+		s.commentsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
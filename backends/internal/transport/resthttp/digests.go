@@ -0,0 +1,146 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
+)
+
+type (
+	// reqDigestsUpdate
+	// This is a model used for changing email digest settings
+	// swagger:model
+	reqDigestsUpdate struct {
+		// Variations: [off, daily, weekly]
+		// required: true
+		// example: daily
+		Frequency digests.Frequency `json:"frequency"`
+
+		// IANA name of your timezone
+		// required: true
+		// example: Asia/Bishkek
+		Timezone string `json:"timezone"`
+
+		// Hour in your timezone when we send the digest, from 0 to 23
+		// example: 8
+		Hour int `json:"hour"`
+
+		// Day of the week for weekly digests, 0 is Sunday
+		// example: 1
+		Weekday time.Weekday `json:"weekday"`
+	}
+)
+
+func (s *Server) digestsError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, digests.ErrInvalidTimezone):
+		status = http.StatusBadRequest
+	case errors.Is(err, digests.ErrNoMailer):
+		status = http.StatusServiceUnavailable
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+// swagger:route GET /users/me/digest users DigestsGet
+//
+// Get my email digest settings
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: digest settings
+//       401: stdResponse
+func (s *Server) DigestsGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	settings, err := s.digestsService.GetSettings(ctx, u.ID)
+	if err != nil {
+		s.digestsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, settings, nil)
+}
+
+// swagger:route PUT /users/me/digest users DigestsUpdate
+//
+// Change my email digest settings
+//
+// Digest lists your overdue todos and todos due today and this week.
+// It is not sent if there is nothing to list.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: settings
+//         in: body
+//         required: true
+//         type: reqDigestsUpdate
+//
+//     Responses:
+//       200: description: digest settings
+//       400: stdResponse
+//       401: stdResponse
+//       503: stdResponse
+func (s *Server) DigestsUpdate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	var req reqDigestsUpdate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrRequestBodyNotProvided
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	settings, err := s.digestsService.UpdateSettings(ctx, digests.UpdateInput{
+		UserID:    u.ID,
+		Frequency: req.Frequency,
+		Timezone:  req.Timezone,
+		Hour:      req.Hour,
+		Weekday:   req.Weekday,
+	})
+	if err != nil {
+		s.digestsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, settings, nil)
+}
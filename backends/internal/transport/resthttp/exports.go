@@ -0,0 +1,211 @@
+package resthttp
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/exports"
+)
+
+type (
+	// respExportsJob
+	// This is a status of an export of all your data.
+	// DownloadURL is set only when status is "done"
+	// swagger:model
+	respExportsJob struct {
+		// type: string
+		// format: uuid
+		ID string `json:"id"`
+
+		// Variations: [pending, running, done, failed]
+		Status exports.Status `json:"status"`
+		Error  string         `json:"error,omitempty"`
+
+		// Signed link that works without Bearer token until expiresAt
+		DownloadURL string `json:"downloadUrl,omitempty"`
+
+		CreatedAt  time.Time `json:"createdAt"`
+		FinishedAt time.Time `json:"finishedAt"`
+		ExpiresAt  time.Time `json:"expiresAt"`
+	}
+)
+
+func (s *Server) exportsJobResponse(job exports.Job) respExportsJob {
+	resp := respExportsJob{
+		ID:         job.ID,
+		Status:     job.Status,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		FinishedAt: job.FinishedAt,
+		ExpiresAt:  job.ExpiresAt,
+	}
+	if job.Status == exports.StatusDone {
+		link := s.exportsService.Link(job)
+		query := url.Values{}
+		query.Set("expires", strconv.FormatInt(link.ExpiresAt.Unix(), 10))
+		query.Set("signature", link.Signature)
+		resp.DownloadURL = "/api/exports/" + job.ID + "/download?" + query.Encode()
+	}
+	return resp
+}
+
+// swagger:route POST /users/me/export users ExportsStart
+//
+// Export my data
+//
+// This will start collecting all the data we have about you into a zip archive.
+// If an export is already running it will be returned instead of starting a new one.
+// Poll GET /users/me/export/{id} to know when it is ready.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       202: respExportsJob
+//       401: stdResponse
+func (s *Server) ExportsStart(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	job, err := s.exportsService.Start(ctx, u.ID)
+	if err != nil {
+		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, s.exportsJobResponse(job), nil)
+}
+
+// swagger:route GET /users/me/export/{id} users ExportsGet
+//
+// Get status of an export
+//
+// This will return status of an export and a download link when it is done
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id of the export
+//         type: string
+//
+//     Responses:
+//       200: respExportsJob
+//       404: stdResponse
+func (s *Server) ExportsGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	id := ctx.Param("id")
+	if len(id) == 0 {
+		respond(ctx, http.StatusBadRequest, nil, []string{ErrParamNotProvided.Error()})
+		return
+	}
+
+	job, err := s.exportsService.Get(ctx, u.ID, id)
+	if err != nil {
+		if errors.Is(err, exports.ErrNoSuchJob) {
+			respond(ctx, http.StatusNotFound, nil, []string{err.Error()})
+			return
+		}
+		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		return
+	}
+
+	respond(ctx, http.StatusOK, s.exportsJobResponse(job), nil)
+}
+
+// swagger:route GET /exports/{id}/download users ExportsDownload
+//
+// Download an export
+//
+// This will return a zip archive with profile.json, todos.json and todos.csv.
+// Use downloadUrl from the export status, it is already signed.
+//
+//     Produces:
+//     - application/zip
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: expires
+//         in: query
+//         required: true
+//         type: integer
+//       + name: signature
+//         in: query
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: zip archive
+//       403: stdResponse
+//       410: stdResponse
+func (s *Server) ExportsDownload(ctx *gin.Context) {
+	expires, err := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	if err != nil {
+		respond(ctx, http.StatusForbidden, nil, []string{exports.ErrInvalidSignature.Error()})
+		return
+	}
+
+	job, err := s.exportsService.Open(ctx, exports.Link{
+		JobID:     ctx.Param("id"),
+		ExpiresAt: time.Unix(expires, 0),
+		Signature: ctx.Query("signature"),
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, exports.ErrInvalidSignature):
+			status = http.StatusForbidden
+		case errors.Is(err, exports.ErrExpired):
+			status = http.StatusGone
+		case errors.Is(err, exports.ErrNoSuchJob):
+			status = http.StatusNotFound
+		case errors.Is(err, exports.ErrNotReady):
+			status = http.StatusConflict
+		}
+		respond(ctx, status, nil, []string{err.Error()})
+		return
+	}
+
+	ctx.FileAttachment(job.Path, "todo-app-export.zip")
+}
@@ -57,7 +57,12 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
-	server, err := wire.InitializeRestApi(*config, logger, validator, repo)
+	services, err := wire.InitializeServices(*config, logger, validator, repo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server, err := wire.InitializeRestApi(*config, logger, validator, services)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,330 @@
+package resthttp
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/links"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type (
+	// reqLinksCreate
+	// This is a model used for sharing a todo or a list with a public link
+	// swagger:model
+	reqLinksCreate struct {
+		// Variations: [todo, list]
+		// required: true
+		// example: list
+		ResourceType shares.ResourceType `json:"resourceType"`
+
+		// required: true
+		// format: uuid
+		ResourceID string `json:"resourceId"`
+
+		// People will have to enter it to open the link
+		// required: false
+		// min length: 4
+		// max length: 72
+		Password string `json:"password"`
+
+		// The link stops working after that
+		// required: false
+		// format: date-time
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+
+	// publicPage is data of templates/public.html
+	publicPage struct {
+		View        *links.View
+		AskPassword bool
+		Error       string
+	}
+)
+
+// linkPasswordHeader carries passwords of links for the json endpoint
+const linkPasswordHeader = "X-Link-Password"
+
+//go:embed templates/public.html
+var pages embed.FS
+
+var publicTemplate = template.Must(template.ParseFS(pages, "templates/public.html"))
+
+func (s *Server) linksError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	respond(ctx, linksStatus(err), nil, []string{err.Error()})
+}
+
+func linksStatus(err error) int {
+	switch {
+	case errors.Is(err, links.ErrNoSuchLink), errors.Is(err, shares.ErrNoSuchResource):
+		return http.StatusNotFound
+	case errors.Is(err, links.ErrNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, links.ErrExpired):
+		return http.StatusGone
+	case errors.Is(err, links.ErrPasswordRequired), errors.Is(err, links.ErrWrongPassword):
+		return http.StatusUnauthorized
+	case errors.Is(err, links.ErrExpiresInPast):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// withLinkURLs tells owners where their links lead
+func withLinkURLs(l links.Link) links.Link {
+	l.URL = "/api/public/" + l.Token
+	l.PageURL = l.URL + "/page"
+	return l
+}
+
+// swagger:route POST /links links LinksCreate
+//
+// Share a todo or a list with a public link
+//
+// Anybody who has the link can see the todo or the list without an account,
+// but can't change anything. Only the owner can create links. Links can have
+// a password and an expiration date, they work until they are revoked.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: link
+//         in: body
+//         required: true
+//         type: reqLinksCreate
+//
+//     Responses:
+//       201: description: link
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) LinksCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := reqLinksCreate{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	l, err := s.linksService.Create(ctx, links.CreateInput{
+		OwnerID:      u.ID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Password:     req.Password,
+		ExpiresAt:    req.ExpiresAt,
+	})
+	if err != nil {
+		s.linksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, withLinkURLs(l), nil)
+}
+
+// swagger:route GET /links links LinksGetAll
+//
+// Get my public links
+//
+// Revoked and expired links are here too, with how many times they were opened.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: links
+func (s *Server) LinksGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.linksService.GetAll(ctx, u.ID)
+	if err != nil {
+		s.linksError(ctx, err)
+		return
+	}
+	for i := range list {
+		list[i] = withLinkURLs(list[i])
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route DELETE /links/{id} links LinksRevoke
+//
+// Revoke a public link
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       404: stdResponse
+func (s *Server) LinksRevoke(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.linksService.Revoke(ctx, u.ID, ctx.Param("id")); err != nil {
+		s.linksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route GET /public/{token} public PublicGet
+//
+// Open a public link
+//
+// No account is needed. If the link has a password send it in X-Link-Password header.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Parameters:
+//       + name: token
+//         in: params
+//         required: true
+//         type: string
+//       + name: X-Link-Password
+//         in: header
+//         required: false
+//         type: string
+//
+//     Responses:
+//       200: description: todos
+//       401: stdResponse
+//       404: stdResponse
+//       410: stdResponse
+func (s *Server) PublicGet(ctx *gin.Context) {
+	v, err := s.linksService.Open(ctx, ctx.Param("token"), ctx.GetHeader(linkPasswordHeader))
+	setPublicHeaders(ctx)
+	if err != nil {
+		s.linksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, v, nil)
+}
+
+// swagger:route GET /public/{token}/page public PublicPage
+//
+// Open a public link in a browser
+//
+// A simple html page with the todos. Password protected links show a form
+// that posts the password back to the same url.
+//
+//     Produces:
+//     - text/html
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Parameters:
+//       + name: token
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: html page
+//       401: description: html page with a password form
+//       404: description: html page
+//       410: description: html page
+func (s *Server) PublicPage(ctx *gin.Context) {
+	password := ""
+	if ctx.Request.Method == http.MethodPost {
+		password = ctx.PostForm("password")
+	}
+	v, err := s.linksService.Open(ctx, ctx.Param("token"), password)
+	setPublicHeaders(ctx)
+
+	page := publicPage{}
+	status := http.StatusOK
+	switch {
+	case err == nil:
+		page.View = &v
+	case errors.Is(err, links.ErrPasswordRequired):
+		status, page.AskPassword = http.StatusUnauthorized, true
+	case errors.Is(err, links.ErrWrongPassword):
+		status, page.AskPassword, page.Error = http.StatusUnauthorized, true, "Wrong password, try again."
+	case errors.Is(err, links.ErrExpired):
+		status, page.Error = http.StatusGone, "This link has expired."
+	case errors.Is(err, links.ErrNoSuchLink):
+		status, page.Error = http.StatusNotFound, "This link does not exist."
+	default:
+		s.logger.Error("resthttp: PublicPage(): could not open link", logging.String("error", err.Error()))
+		status, page.Error = http.StatusInternalServerError, "Something went wrong, try again later."
+	}
+
+	ctx.Status(status)
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	if err := publicTemplate.Execute(ctx.Writer, page); err != nil {
+		s.logger.Error("resthttp: PublicPage(): could not render page", logging.String("error", err.Error()))
+	}
+}
+
+// setPublicHeaders keeps tokens out of caches, search engines and referrers
+func setPublicHeaders(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Referrer-Policy", "no-referrer")
+	ctx.Header("X-Robots-Tag", "noindex")
+}
@@ -0,0 +1,442 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/lists"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+type (
+	// reqListsSave
+	// This is a model used for creating and renaming lists
+	// swagger:model
+	reqListsSave struct {
+		// required: true
+		// example: Groceries
+		// max length: 100
+		Name string `json:"name"`
+	}
+)
+
+func (s *Server) listsError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, lists.ErrNoSuchList):
+		status = http.StatusNotFound
+	case errors.Is(err, lists.ErrNotAllowed):
+		status = http.StatusForbidden
+	case errors.Is(err, todos.ErrInvalidWorkflow), errors.Is(err, todos.ErrInvalidFields):
+		status = http.StatusBadRequest
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+func bindListsSave(ctx *gin.Context) (reqListsSave, bool) {
+	req := reqListsSave{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return req, false
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// swagger:route POST /lists lists ListsCreate
+//
+// Create a list
+//
+// Lists group todos. Sharing a list with someone
+// shares all of its todos with them.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: list
+//         in: body
+//         required: true
+//         type: reqListsSave
+//
+//     Responses:
+//       201: description: list
+//       400: stdResponse
+//       401: stdResponse
+func (s *Server) ListsCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req, ok := bindListsSave(ctx)
+	if !ok {
+		return
+	}
+
+	l, err := s.listsService.Create(ctx, lists.CreateInput{UserID: u.ID, Name: req.Name})
+	if err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, l, nil)
+}
+
+// swagger:route GET /lists lists ListsGetAll
+//
+// Get my lists
+//
+// Returns your lists and lists shared with you.
+// Permission of each list says what you can do with it.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: lists
+//       401: stdResponse
+func (s *Server) ListsGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	all, err := s.listsService.GetAll(ctx, u.ID)
+	if err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, all, nil)
+}
+
+// swagger:route GET /lists/{id} lists ListsGet
+//
+// Get a list
+//
+// Todos of the list are returned by GET /todos?listId={id}
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: list
+//       404: stdResponse
+func (s *Server) ListsGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	l, err := s.listsService.Get(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, l, nil)
+}
+
+// swagger:route PATCH /lists/{id} lists ListsUpdate
+//
+// Rename a list
+//
+// Owner and editors of a list can rename it.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: list
+//         in: body
+//         required: true
+//         type: reqListsSave
+//
+//     Responses:
+//       200: description: list
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) ListsUpdate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req, ok := bindListsSave(ctx)
+	if !ok {
+		return
+	}
+
+	l, err := s.listsService.Update(ctx, u.ID, lists.UpdateInput{ID: ctx.Param("id"), Name: req.Name})
+	if err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, l, nil)
+}
+
+// swagger:route PUT /lists/{id}/workflow lists ListsSetWorkflow
+//
+// Set workflow of a list
+//
+// Only the owner can change statuses of a list and transitions between them.
+// Empty transitions allow any move. Todos in statuses the new workflow
+// doesn't have go to its first status, completed ones to its first terminal one.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: workflow
+//         in: body
+//         required: true
+//         description: {"statuses": [{"key": "todo", "name": "To do"}, {"key": "done", "name": "Done", "terminal": true}], "transitions": {"todo": ["done"]}}
+//
+//     Responses:
+//       200: description: list
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) ListsSetWorkflow(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := todos.Workflow{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	l, err := s.listsService.SetWorkflow(ctx, u.ID, lists.SetWorkflowInput{ID: ctx.Param("id"), Workflow: req})
+	if err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, l, nil)
+}
+
+// swagger:route PUT /lists/{id}/fields lists ListsSetFields
+//
+// Set custom fields of a list
+//
+// Only the owner can change custom fields todos of a list can have. Types are
+// text, number, date, select and checkbox, only select fields have options.
+// Values of removed fields, of fields with a new type and of removed options
+// are dropped from todos.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: fields
+//         in: body
+//         required: true
+//         description: [{"key": "estimate", "name": "Estimate", "type": "number"}, {"key": "sprint", "name": "Sprint", "type": "select", "options": ["11", "12"]}]
+//
+//     Responses:
+//       200: description: list
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) ListsSetFields(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := todos.Fields{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	l, err := s.listsService.SetFields(ctx, u.ID, lists.SetFieldsInput{ID: ctx.Param("id"), Fields: req})
+	if err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, l, nil)
+}
+
+// swagger:route GET /lists/{id}/plan lists ListsPlan
+//
+// Plan of a list
+//
+// Returns todos of a list that are not completed yet in an order they can
+// be done in. Blockers go before todos waiting for them, other todos keep
+// their manual order.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: []todo
+//       404: stdResponse
+func (s *Server) ListsPlan(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	plan, err := s.todosService.Plan(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, plan, nil)
+}
+
+// swagger:route DELETE /lists/{id} lists ListsDelete
+//
+// Delete a list
+//
+// Only the owner can delete a list. Its todos are not
+// deleted, they just don't belong to any list anymore.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) ListsDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.listsService.Delete(ctx, u.ID, ctx.Param("id")); err != nil {
+		s.listsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
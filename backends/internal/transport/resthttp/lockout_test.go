@@ -0,0 +1,59 @@
+package resthttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/memory"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type noUsersRepo struct{ users.Repository }
+
+func (noUsersRepo) GetByEmail(ctx context.Context, email string) (users.User, error) {
+	return users.User{}, users.ErrNoSuchUser
+}
+
+func TestSignInLockoutIgnoresForwardedFor(t *testing.T) {
+	s, router := newTestRouter(t, config.Config{})
+	attempts := memory.NewStore().Attempts()
+	policy := users.LockoutPolicy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	usersService, err := users.NewService(noUsersRepo{}, attempts, logging.NewNop(), nil, []byte("secret"), policy, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.usersService = usersService
+	router.POST("/signin", s.UsersSignIn)
+
+	// every attempt uses another email, so only the ip key can lock
+	want := []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests}
+	for i := range want {
+		forwarded := fmt.Sprintf("198.51.100.%d", i+1)
+		body := fmt.Sprintf(`{"email":"user%d@example.com","password":"wrong password"}`, i)
+		req := httptest.NewRequest(http.MethodPost, "/signin", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113.7:4321"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.Header.Set("X-Real-IP", forwarded)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want[i] {
+			t.Errorf("attempt %d: expected %d, got %d", i+1, want[i], rec.Code)
+		}
+	}
+
+	locked, err := attempts.GetLocked(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != 1 || locked[0].Key != "ip:203.0.113.7" {
+		t.Errorf("expected only the real address to be locked, got %+v", locked)
+	}
+}
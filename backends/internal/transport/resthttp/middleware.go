@@ -7,10 +7,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
 )
 
 const (
 	usersInfoInContext = "userinfo"
+
+	// workspaceHeader selects a workspace the request works in,
+	// without it requests work in the personal space of the user
+	workspaceHeader = "X-Workspace-ID"
 )
 
 var (
@@ -56,6 +61,16 @@ func (s *Server) requireAuth(ctx *gin.Context) {
 		return
 	}
 	ctx.Set(usersInfoInContext, &claims)
+
+	workspaceID := ctx.Request.Header.Get(workspaceHeader)
+	if workspaceID != "" {
+		if _, err := s.workspacesService.Member(ctx, workspaceID, claims.ID); err != nil {
+			ctx.AbortWithStatusJSON(workspacesStatus(err), stdResponse{Errors: []string{err.Error()}})
+			return
+		}
+	}
+	// repositories read the scope from the context of the request
+	ctx.Request = ctx.Request.WithContext(workspaces.WithScope(ctx.Request.Context(), workspaceID))
 	ctx.Next()
 }
 
@@ -78,7 +93,7 @@ func CORSMiddleware() gin.HandlerFunc {
 			ctx.Header("Access-Control-Max-Age", "1728000")
 			ctx.Header("Access-Control-Allow-Credentials", "true")
 			ctx.Header("Access-Control-Allow-Methods", "GET,PUT,POST,DELETE,PATCH,OPTIONS")
-			ctx.Header("Access-Control-Allow-Headers", "Content-Type,Cookie,Authorization,Access-Control-Request-Headers,Access-Control-Request-Method,Origin,Referer,Sec-Fetch-Dest,Accept-Language,Accept-Encoding,Sec-Fetch-Mode,Sec-Fetch-Site,User-Agent,Pragma,Host,Connection,Cache-Control,Accept-Language,Accept-Encoding,X-Requested-With,X-Forwarded-For,X-Forwarded-Host,X-Forwarded-Proto,X-Forwarded-Port,X-Forwarded-Prefix,X-Real-IP,Accept")
+			ctx.Header("Access-Control-Allow-Headers", "Content-Type,Cookie,Authorization,Access-Control-Request-Headers,Access-Control-Request-Method,Origin,Referer,Sec-Fetch-Dest,Accept-Language,Accept-Encoding,Sec-Fetch-Mode,Sec-Fetch-Site,User-Agent,Pragma,Host,Connection,Cache-Control,Accept-Language,Accept-Encoding,X-Workspace-ID,X-Requested-With,X-Forwarded-For,X-Forwarded-Host,X-Forwarded-Proto,X-Forwarded-Port,X-Forwarded-Prefix,X-Real-IP,Accept")
 			ctx.Header("Access-Control-Allow-Origin", "* http://localhost:3000")
 			ctx.AbortWithStatus(http.StatusNoContent)
 			return
@@ -0,0 +1,274 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+)
+
+type (
+	// reqNotificationsPreferences
+	// Preferences to change, types that are not listed stay as they are.
+	// swagger:model
+	reqNotificationsPreferences struct {
+		// Variations of type: [reminder, overdue, share, assignment, mention, workspace]
+		// required: true
+		// example: [{"type": "overdue", "inApp": true, "email": false}]
+		Preferences []notifications.Preference `json:"preferences"`
+	}
+)
+
+func (s *Server) notificationsError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, notifications.ErrNoSuchNotification):
+		status = http.StatusNotFound
+	case errors.Is(err, notifications.ErrUnknownType):
+		status = http.StatusBadRequest
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+// swagger:route GET /notifications notifications NotificationsGetAll
+//
+// Get my notifications
+//
+// Newest notifications go first. Response has the number of all unread
+// notifications as well. New notifications are also pushed as
+// notification.created events to /todos/stream and to "notifications"
+// topic of /ws.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: pageSize
+//         in: query
+//         required: false
+//         type: integer
+//         example: 20
+//       + name: page
+//         in: query
+//         required: false
+//         type: integer
+//         example: 0
+//       + name: unread
+//         in: query
+//         required: false
+//         description: If true we will return only unread ones
+//         type: boolean
+//         example: true
+//
+//     Responses:
+//       200: description: notifications and number of unread ones
+//       400: stdResponse
+//       401: stdResponse
+func (s *Server) NotificationsGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	inp := notifications.GetAllInput{UserID: u.ID}
+	if v := ctx.Query("pageSize"); v != "" {
+		if inp.PageSize, err = strconv.Atoi(v); err != nil {
+			respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+			return
+		}
+	}
+	if v := ctx.Query("page"); v != "" {
+		if inp.Page, err = strconv.Atoi(v); err != nil {
+			respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+			return
+		}
+	}
+	if v := ctx.Query("unread"); v != "" {
+		if inp.UnreadOnly, err = strconv.ParseBool(v); err != nil {
+			respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+			return
+		}
+	}
+
+	inbox, err := s.notificationsService.GetAll(ctx, inp)
+	if err != nil {
+		s.notificationsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, inbox, nil)
+}
+
+// swagger:route PUT /notifications/{id}/read notifications NotificationsMarkRead
+//
+// Mark a notification as read
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: notification
+//       404: stdResponse
+func (s *Server) NotificationsMarkRead(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	n, err := s.notificationsService.MarkRead(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.notificationsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, n, nil)
+}
+
+// swagger:route PUT /notifications/read notifications NotificationsMarkAllRead
+//
+// Mark all my notifications as read
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: stdResponse
+//       401: stdResponse
+func (s *Server) NotificationsMarkAllRead(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.notificationsService.MarkAllRead(ctx, u.ID); err != nil {
+		s.notificationsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route GET /notifications/preferences notifications NotificationsGetPreferences
+//
+// Get my notification preferences
+//
+// There is a preference for every type of notifications. It says if
+// we notify you in the app, by email or both.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: list of preferences
+//       401: stdResponse
+func (s *Server) NotificationsGetPreferences(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	prefs, err := s.notificationsService.GetPreferences(ctx, u.ID)
+	if err != nil {
+		s.notificationsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, prefs, nil)
+}
+
+// swagger:route PUT /notifications/preferences notifications NotificationsUpdatePreferences
+//
+// Update my notification preferences
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: preferences
+//         in: body
+//         required: true
+//         type: reqNotificationsPreferences
+//
+//     Responses:
+//       200: description: list of all preferences
+//       400: stdResponse
+//       401: stdResponse
+func (s *Server) NotificationsUpdatePreferences(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	var req reqNotificationsPreferences
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrRequestBodyNotProvided
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	prefs, err := s.notificationsService.UpdatePreferences(ctx, u.ID, req.Preferences)
+	if err != nil {
+		s.notificationsError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, prefs, nil)
+}
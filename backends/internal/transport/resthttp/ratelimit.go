@@ -0,0 +1,56 @@
+package resthttp
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+var ErrRateLimited = errors.New("too many requests, slow down")
+
+// rateLimit limits requests for a group of routes. Authenticated users are
+// identified by their id, so it has to go after requireAuth. Everyone else
+// is identified by ip. If limiter is broken we let requests through.
+func (s *Server) rateLimit(group string, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if s.limiter == nil || limit.Disabled() {
+			ctx.Next()
+			return
+		}
+
+		key := group + ":ip:" + ctx.ClientIP()
+		if user, err := getUserData(ctx); err == nil {
+			key = group + ":user:" + user.ID
+		}
+
+		res, err := s.limiter.Allow(ctx, key, limit)
+		if err != nil {
+			defer s.logger.Sync()
+			s.logger.Error("resthttp: rateLimit(): limiter failed", logging.String("error", err.Error()))
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		ctx.Header("RateLimit-Reset", ceilSeconds(res.Reset))
+		if !res.Allowed {
+			ctx.Header("Retry-After", ceilSeconds(res.RetryAfter))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, stdResponse{
+				Errors: []string{ErrRateLimited.Error()},
+			})
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
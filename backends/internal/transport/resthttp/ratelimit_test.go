@@ -0,0 +1,62 @@
+package resthttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/memory"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+// newTestRouter has all the routes and middlewares of the api,
+// services that tests don't set are nil
+func newTestRouter(t *testing.T, cfg config.Config) (*Server, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := logging.NewNop()
+	limiter := ratelimit.NewService(memory.NewStore().Buckets(), logger)
+	s := NewServer(cfg, logger, nil, limiter, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	router := gin.New()
+	if err := s.setRoutes(router); err != nil {
+		t.Fatal(err)
+	}
+	return s, router
+}
+
+func TestRateLimitIgnoresForwardedFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		// codes of two requests from one address with different forwarded ips
+		want []int
+	}{
+		{name: "no trusted proxies", want: []int{http.StatusOK, http.StatusTooManyRequests}},
+		{name: "trusted proxy", proxies: []string{"203.0.113.0/24"}, want: []int{http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, router := newTestRouter(t, config.Config{TrustedProxies: tt.proxies})
+			limit := ratelimit.Limit{Requests: 1, Per: time.Minute}
+			router.GET("/limited", s.rateLimit("test", limit), func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			for i, forwarded := range []string{"198.51.100.1", "198.51.100.2"} {
+				req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+				req.RemoteAddr = "203.0.113.7:4321"
+				req.Header.Set("X-Forwarded-For", forwarded)
+				req.Header.Set("X-Real-IP", forwarded)
+				rec := httptest.NewRecorder()
+				router.ServeHTTP(rec, req)
+				if rec.Code != tt.want[i] {
+					t.Errorf("request %d: expected %d, got %d", i+1, tt.want[i], rec.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/reminders"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+)
+
+type (
+	// reqRemindersCreate
+	// This is a model used for adding a reminder to a todo.
+	// Set either at or offsetMinutes
+	// swagger:model
+	reqRemindersCreate struct {
+		// Exact time of the reminder
+		// example: 2022-10-25T09:00:00Z
+		At *time.Time `json:"at"`
+
+		// Minutes before the deadline of the todo
+		// example: 60
+		OffsetMinutes int `json:"offsetMinutes"`
+	}
+)
+
+func (s *Server) remindersError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, reminders.ErrNoSuchReminder), errors.Is(err, todos.ErrNoSuchTodo):
+		status = http.StatusNotFound
+	case errors.Is(err, reminders.ErrNotAllowed):
+		status = http.StatusForbidden
+	case errors.Is(err, reminders.ErrInvalidTime), errors.Is(err, reminders.ErrInPast),
+		errors.Is(err, reminders.ErrNoDeadline):
+		status = http.StatusBadRequest
+	case errors.Is(err, reminders.ErrTooManyPerTodo):
+		status = http.StatusConflict
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+// swagger:route POST /todos/{id}/reminders reminders RemindersCreate
+//
+// Add a reminder to a todo
+//
+// A reminder fires once, either at an exact time or some minutes before
+// the deadline of the todo. We notify you in real-time channels, by email
+// and with todo.reminder webhooks. When a deadline passes you get
+// a todo.overdue notification as well.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: reminder
+//         in: body
+//         required: true
+//         type: reqRemindersCreate
+//
+//     Responses:
+//       201: description: created reminder
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) RemindersCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	var req reqRemindersCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrRequestBodyNotProvided
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	r, err := s.remindersService.Create(ctx, u.ID, reminders.CreateInput{
+		TodoID:        ctx.Param("id"),
+		At:            req.At,
+		OffsetMinutes: req.OffsetMinutes,
+	})
+	if err != nil {
+		s.remindersError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, r, nil)
+}
+
+// swagger:route GET /todos/{id}/reminders reminders RemindersGetAll
+//
+// Get reminders of a todo
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: list of reminders
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) RemindersGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.remindersService.GetAll(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.remindersError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route DELETE /todos/{id}/reminders/{reminderId} reminders RemindersDelete
+//
+// Delete a reminder
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: reminderId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) RemindersDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	err = s.remindersService.Delete(ctx, u.ID, ctx.Param("id"), ctx.Param("reminderId"))
+	if err != nil {
+		s.remindersError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
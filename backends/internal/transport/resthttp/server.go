@@ -48,31 +48,88 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/attachments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/comments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/exports"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/links"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/lists"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/reminders"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/templates"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/webhooks"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/validation"
 )
 
 type Server struct {
-	server  *http.Server
-	address string
+	server      *http.Server
+	address     string
+	corsOrigins string
+	// trustedProxies can tell the real ip of a client, nobody else can
+	trustedProxies []string
 
 	// utility dependencies
 	logger    *logging.Logger
 	validator *validation.Validator
+	limiter   ratelimit.Service
+	limits    routeLimits
+	events    *events.Bus
 
 	// domain logic dependencies
-	usersService users.Service
-	todosService todos.Service
+	usersService     users.Service
+	todosService     todos.Service
+	exportsService   exports.Service
+	webhooksService  webhooks.Service
+	remindersService reminders.Service
+
+	notificationsService notifications.Service
+	digestsService       digests.Service
+	listsService         lists.Service
+	sharesService        shares.Service
+	commentsService      comments.Service
+	attachmentsService   attachments.Service
+	linksService         links.Service
+	workspacesService    workspaces.Service
+	templatesService     templates.Service
+
+	// maxUpload is the biggest file users can attach to todos
+	maxUpload int64
+}
+
+// routeLimits are rate limits for each group of routes
+type routeLimits struct {
+	auth  ratelimit.Limit
+	users ratelimit.Limit
+	todos ratelimit.Limit
 }
 
 func NewServer(
 	cfg config.Config,
 	logger *logging.Logger,
 	validator *validation.Validator,
+	limiter ratelimit.Service,
+	bus *events.Bus,
 	usersService users.Service,
 	todosService todos.Service,
+	exportsService exports.Service,
+	webhooksService webhooks.Service,
+	remindersService reminders.Service,
+	notificationsService notifications.Service,
+	digestsService digests.Service,
+	listsService lists.Service,
+	sharesService shares.Service,
+	commentsService comments.Service,
+	attachmentsService attachments.Service,
+	linksService links.Service,
+	workspacesService workspaces.Service,
+	templatesService templates.Service,
 ) *Server {
 	return &Server{
 		server: &http.Server{
@@ -80,18 +137,43 @@ func NewServer(
 			ReadTimeout:  cfg.ReadTimeout,
 			WriteTimeout: cfg.WriteTimeout,
 		},
-		address:      cfg.Port,
-		logger:       logger,
-		validator:    validator,
-		usersService: usersService,
-		todosService: todosService,
+		address:        cfg.Port,
+		corsOrigins:    cfg.CORSorigins,
+		trustedProxies: cfg.TrustedProxies,
+		logger:         logger,
+		validator:      validator,
+		limiter:        limiter,
+		events:         bus,
+		limits: routeLimits{
+			auth:  ratelimit.Limit{Requests: cfg.Limits.AuthRequests, Per: cfg.Limits.AuthPer},
+			users: ratelimit.Limit{Requests: cfg.Limits.UsersRequests, Per: cfg.Limits.UsersPer},
+			todos: ratelimit.Limit{Requests: cfg.Limits.TodosRequests, Per: cfg.Limits.TodosPer},
+		},
+		usersService:     usersService,
+		todosService:     todosService,
+		exportsService:   exportsService,
+		webhooksService:  webhooksService,
+		remindersService: remindersService,
+
+		notificationsService: notificationsService,
+		digestsService:       digestsService,
+		listsService:         listsService,
+		sharesService:        sharesService,
+		commentsService:      commentsService,
+		attachmentsService:   attachmentsService,
+		linksService:         linksService,
+		workspacesService:    workspacesService,
+		templatesService:     templatesService,
+		maxUpload:            cfg.Attachments.MaxSize,
 	}
 }
 
 func (s *Server) Run() error {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
-	s.setRoutes(router)
+	if err := s.setRoutes(router); err != nil {
+		return err
+	}
 	s.server.Handler = router
 	return s.server.ListenAndServe()
 }
@@ -99,18 +181,31 @@ func (s *Server) Run() error {
 //go:embed swaggerui
 var swagger embed.FS
 
-func (s *Server) setRoutes(router *gin.Engine) {
+func (s *Server) setRoutes(router *gin.Engine) error {
+	// services get the workspace of a request from its context
+	router.ContextWithFallback = true
+	// rate limits and lockouts go by ip, so a client
+	// should not be able to pick it with a header
+	if err := router.SetTrustedProxies(s.trustedProxies); err != nil {
+		return err
+	}
+
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"*, PUT, GET, POST, DELETE, OPTIONS, HEAD, PATCH"},
-		AllowHeaders:     []string{"*", "Content-type", "Authorization"},
+		AllowHeaders:     []string{"*", "Content-type", "Authorization", workspaceHeader},
 		AllowCredentials: true,
 		AllowWildcard:    true,
 	}))
 
 	router.Use(gin.Recovery())
 	router.Use(gin.Logger())
-	router.Use(gzip.Gzip(gzip.BestCompression))
+	// gzip buffers responses, so streams would never reach clients.
+	// Attached files are mostly compressed already.
+	router.Use(gzip.Gzip(gzip.BestCompression,
+		gzip.WithExcludedPaths([]string{"/api/todos/stream", "/api/ws"}),
+		gzip.WithExcludedPathsRegexs([]string{`^/api/todos/[^/]+/attachments/[^/]+(/thumbnails/[^/]+)?$`}),
+	))
 	api := router.Group("api")
 
 	dir, err := fs.Sub(swagger, "swaggerui")
@@ -129,26 +224,152 @@ func (s *Server) setRoutes(router *gin.Engine) {
 	{
 		// TODO: make handlers private functions
 		// TODO: separate users logic and auth
-		usersGroup.POST("/auth/signup", s.UsersSignUp)
-		usersGroup.POST("/auth/signin", s.UsersSignIn)
-		usersGroup.POST("/auth/refresh", s.UsersRefresh)
+		authLimit := s.rateLimit("auth", s.limits.auth)
+		usersGroup.POST("/auth/signup", authLimit, s.UsersSignUp)
+		usersGroup.POST("/auth/signin", authLimit, s.UsersSignIn)
+		usersGroup.POST("/auth/refresh", authLimit, s.UsersRefresh)
 		usersGroup.DELETE("/auth/logout", s.UsersLogout)
 
-		usersGroup.DELETE("/:id", s.requireAuth, s.isAdmin, s.UsersDelete)
-		usersGroup.GET("/:id", s.requireAuth, s.usersMe)
+		usersLimit := s.rateLimit("users", s.limits.users)
+		usersGroup.GET("/locked", s.requireAuth, s.isAdmin, usersLimit, s.UsersGetLocked)
+		usersGroup.DELETE("/locked/:key", s.requireAuth, s.isAdmin, usersLimit, s.UsersUnlock)
+
+		usersGroup.DELETE("/me", s.requireAuth, usersLimit, s.UsersDeleteMe)
+		usersGroup.POST("/me/export", s.requireAuth, usersLimit, s.ExportsStart)
+		usersGroup.GET("/me/export/:id", s.requireAuth, usersLimit, s.ExportsGet)
+		usersGroup.GET("/me/digest", s.requireAuth, usersLimit, s.DigestsGet)
+		usersGroup.PUT("/me/digest", s.requireAuth, usersLimit, s.DigestsUpdate)
+
+		usersGroup.DELETE("/:id", s.requireAuth, s.isAdmin, usersLimit, s.UsersDelete)
+		usersGroup.GET("/:id", s.requireAuth, usersLimit, s.usersMe)
+	}
+
+	// it authenticates by itself, since browsers can't set headers for websockets
+	api.GET("/ws", s.rateLimit("todos", s.limits.todos), s.WebSocket)
+
+	// these links are signed, so no auth is needed
+	api.GET("/exports/:id/download", s.rateLimit("exports", s.limits.users), s.ExportsDownload)
+
+	// anybody can open public links, passwords are guessed no faster than logins
+	publicGroup := api.Group("public", s.rateLimit("auth", s.limits.auth))
+	{
+		publicGroup.GET("/:token", s.PublicGet)
+		publicGroup.GET("/:token/page", s.PublicPage)
+		publicGroup.POST("/:token/page", s.PublicPage)
+	}
+
+	linksGroup := api.Group("links", s.requireAuth, s.rateLimit("users", s.limits.users))
+	{
+		linksGroup.POST("", s.LinksCreate)
+		linksGroup.GET("", s.LinksGetAll)
+		linksGroup.DELETE("/:id", s.LinksRevoke)
+	}
+
+	workspacesGroup := api.Group("workspaces", s.requireAuth, s.rateLimit("users", s.limits.users))
+	{
+		workspacesGroup.POST("", s.WorkspacesCreate)
+		workspacesGroup.GET("", s.WorkspacesGetAll)
+		workspacesGroup.GET("/invitations", s.WorkspacesGetMyInvitations)
+		workspacesGroup.PUT("/invitations/:id/accept", s.WorkspacesAcceptInvitation)
+		workspacesGroup.PUT("/invitations/:id/decline", s.WorkspacesDeclineInvitation)
+		workspacesGroup.GET("/:id", s.WorkspacesGet)
+		workspacesGroup.PATCH("/:id", s.WorkspacesUpdate)
+		workspacesGroup.DELETE("/:id", s.WorkspacesDelete)
+		workspacesGroup.GET("/:id/members", s.WorkspacesGetMembers)
+		workspacesGroup.PATCH("/:id/members/:userId", s.WorkspacesSetRole)
+		workspacesGroup.DELETE("/:id/members/:userId", s.WorkspacesRemoveMember)
+		workspacesGroup.POST("/:id/invitations", s.WorkspacesInvite)
+		workspacesGroup.GET("/:id/invitations", s.WorkspacesGetInvitations)
+		workspacesGroup.DELETE("/:id/invitations/:invitationId", s.WorkspacesRevokeInvitation)
+	}
+
+	webhooksGroup := api.Group("webhooks", s.requireAuth, s.rateLimit("users", s.limits.users))
+	{
+		webhooksGroup.POST("", s.WebhooksCreate)
+		webhooksGroup.GET("", s.WebhooksGetAll)
+		webhooksGroup.GET("/:id", s.WebhooksGet)
+		webhooksGroup.PATCH("/:id", s.WebhooksUpdate)
+		webhooksGroup.DELETE("/:id", s.WebhooksDelete)
+		webhooksGroup.POST("/:id/test", s.WebhooksTest)
+		webhooksGroup.GET("/:id/deliveries", s.WebhooksGetDeliveries)
+		webhooksGroup.GET("/:id/deliveries/:deliveryId", s.WebhooksGetDelivery)
+		webhooksGroup.POST("/:id/deliveries/:deliveryId/redeliver", s.WebhooksRedeliver)
+	}
+
+	notificationsGroup := api.Group("notifications", s.requireAuth, s.rateLimit("users", s.limits.users))
+	{
+		notificationsGroup.GET("", s.NotificationsGetAll)
+		notificationsGroup.PUT("/read", s.NotificationsMarkAllRead)
+		notificationsGroup.PUT("/:id/read", s.NotificationsMarkRead)
+		notificationsGroup.GET("/preferences", s.NotificationsGetPreferences)
+		notificationsGroup.PUT("/preferences", s.NotificationsUpdatePreferences)
+	}
+
+	listsGroup := api.Group("lists", s.requireAuth, s.rateLimit("todos", s.limits.todos))
+	{
+		listsGroup.POST("", s.ListsCreate)
+		listsGroup.GET("", s.ListsGetAll)
+		listsGroup.GET("/:id", s.ListsGet)
+		listsGroup.PATCH("/:id", s.ListsUpdate)
+		listsGroup.PUT("/:id/workflow", s.ListsSetWorkflow)
+		listsGroup.PUT("/:id/fields", s.ListsSetFields)
+		listsGroup.GET("/:id/plan", s.ListsPlan)
+		listsGroup.DELETE("/:id", s.ListsDelete)
+	}
+
+	templatesGroup := api.Group("templates", s.requireAuth, s.rateLimit("todos", s.limits.todos))
+	{
+		templatesGroup.POST("", s.TemplatesCreate)
+		templatesGroup.GET("", s.TemplatesGetAll)
+		templatesGroup.GET("/:id", s.TemplatesGet)
+		templatesGroup.PUT("/:id", s.TemplatesUpdate)
+		templatesGroup.DELETE("/:id", s.TemplatesDelete)
+		templatesGroup.POST("/:id/instantiate", s.TemplatesInstantiate)
+	}
+
+	sharesGroup := api.Group("shares", s.requireAuth, s.rateLimit("users", s.limits.users))
+	{
+		sharesGroup.POST("", s.SharesInvite)
+		sharesGroup.GET("/incoming", s.SharesGetIncoming)
+		sharesGroup.GET("/outgoing", s.SharesGetOutgoing)
+		sharesGroup.PUT("/:id/accept", s.SharesAccept)
+		sharesGroup.PUT("/:id/decline", s.SharesDecline)
+		sharesGroup.DELETE("/:id", s.SharesRevoke)
 	}
 
-	todosGroup := api.Group("todos", s.requireAuth)
+	todosGroup := api.Group("todos", s.requireAuth, s.rateLimit("todos", s.limits.todos))
 	{
 		todosGroup.POST("", s.TodosCreate)
+		todosGroup.POST("/quick", s.TodosQuickAdd)
+		todosGroup.GET("/stream", s.TodosStream)
 		todosGroup.GET("/:id", s.TodosGet)
 		todosGroup.GET("", s.TodosGetAll)
 		todosGroup.PATCH("/:id", s.TodosUpdate)
 		todosGroup.PUT("/:id/complete", s.TodosMarkComplete)
 		todosGroup.PUT("/:id/incomplete", s.TodosMarkNotComplete)
+		todosGroup.PUT("/:id/status", s.TodosSetStatus)
+		todosGroup.POST("/:id/move", s.TodosMove)
+		todosGroup.PUT("/:id/fields", s.TodosSetFields)
+		todosGroup.POST("/:id/blockers", s.TodosAddBlocker)
+		todosGroup.DELETE("/:id/blockers/:blockerId", s.TodosRemoveBlocker)
+		todosGroup.PUT("/:id/assignee", s.TodosAssign)
+		todosGroup.GET("/:id/history", s.TodosGetHistory)
+		todosGroup.POST("/:id/comments", s.CommentsCreate)
+		todosGroup.GET("/:id/comments", s.CommentsGetAll)
+		todosGroup.PATCH("/:id/comments/:commentId", s.CommentsUpdate)
+		todosGroup.DELETE("/:id/comments/:commentId", s.CommentsDelete)
+		todosGroup.POST("/:id/attachments", s.AttachmentsUpload)
+		todosGroup.GET("/:id/attachments", s.AttachmentsGetAll)
+		todosGroup.GET("/:id/attachments/:attachmentId", s.AttachmentsDownload)
+		todosGroup.GET("/:id/attachments/:attachmentId/thumbnails/:size", s.AttachmentsDownloadThumbnail)
+		todosGroup.DELETE("/:id/attachments/:attachmentId", s.AttachmentsDelete)
+		todosGroup.POST("/:id/reminders", s.RemindersCreate)
+		todosGroup.GET("/:id/reminders", s.RemindersGetAll)
+		todosGroup.DELETE("/:id/reminders/:reminderId", s.RemindersDelete)
 
 		todosGroup.DELETE("/:id", s.TodosDelete)
 	}
+	return nil
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
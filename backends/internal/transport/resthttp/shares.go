@@ -0,0 +1,334 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+)
+
+type (
+	// reqSharesInvite
+	// This is a model used for sharing a todo or a list
+	// swagger:model
+	reqSharesInvite struct {
+		// Variations: [todo, list]
+		// required: true
+		// example: todo
+		ResourceType shares.ResourceType `json:"resourceType"`
+
+		// required: true
+		// format: uuid
+		ResourceID string `json:"resourceId"`
+
+		// Email of the user you share it with
+		// required: true
+		// example: friend@example.com
+		Email string `json:"email"`
+
+		// Variations: [viewer, editor]
+		// required: true
+		// example: editor
+		Permission shares.Permission `json:"permission"`
+	}
+)
+
+func (s *Server) sharesError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, shares.ErrNoSuchShare),
+		errors.Is(err, shares.ErrNoSuchResource),
+		errors.Is(err, users.ErrNoSuchUser):
+		status = http.StatusNotFound
+	case errors.Is(err, shares.ErrNotAllowed):
+		status = http.StatusForbidden
+	case errors.Is(err, shares.ErrSelfShare):
+		status = http.StatusBadRequest
+	case errors.Is(err, shares.ErrAlreadyShared), errors.Is(err, shares.ErrNotPending):
+		status = http.StatusConflict
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+// swagger:route POST /shares shares SharesInvite
+//
+// Share a todo or a list
+//
+// Only the owner can share a todo or a list. The invitation is sent to
+// an email, a user with it gets a notification and has to accept it.
+// People without an account see it after they sign up with that email.
+// Viewers can only read todos, editors can change and complete them as well.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: share
+//         in: body
+//         required: true
+//         type: reqSharesInvite
+//
+//     Responses:
+//       201: description: share
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) SharesInvite(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	req := reqSharesInvite{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	share, err := s.sharesService.Invite(ctx, shares.InviteInput{
+		OwnerID:      u.ID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Email:        req.Email,
+		Permission:   req.Permission,
+	})
+	if err != nil {
+		s.sharesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, share, nil)
+}
+
+// swagger:route GET /shares/incoming shares SharesGetIncoming
+//
+// Get things shared with me
+//
+// Returns invitations sent to you. Todos themselves are
+// returned by GET /todos?shared=true
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: status
+//         in: query
+//         required: false
+//         description: "Variations: [pending, accepted, declined], all of them if empty"
+//         type: string
+//         example: pending
+//
+//     Responses:
+//       200: description: shares
+//       401: stdResponse
+func (s *Server) SharesGetIncoming(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.sharesService.GetIncoming(ctx, u.ID, shares.Status(ctx.Query("status")))
+	if err != nil {
+		s.sharesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route GET /shares/outgoing shares SharesGetOutgoing
+//
+// Get shares of my todo or list
+//
+// Only the owner can see whom a todo or a list is shared with.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: resourceType
+//         in: query
+//         required: true
+//         type: string
+//         example: list
+//       + name: resourceId
+//         in: query
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: shares
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) SharesGetOutgoing(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.sharesService.GetOutgoing(
+		ctx, u.ID, shares.ResourceType(ctx.Query("resourceType")), ctx.Query("resourceId"),
+	)
+	if err != nil {
+		s.sharesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route PUT /shares/{id}/accept shares SharesAccept
+//
+// Accept an invitation
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: share
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) SharesAccept(ctx *gin.Context) {
+	s.sharesRespond(ctx, true)
+}
+
+// swagger:route PUT /shares/{id}/decline shares SharesDecline
+//
+// Decline an invitation
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: share
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) SharesDecline(ctx *gin.Context) {
+	s.sharesRespond(ctx, false)
+}
+
+func (s *Server) sharesRespond(ctx *gin.Context, accept bool) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	share, err := s.sharesService.Respond(ctx, shares.RespondInput{
+		UserID: u.ID,
+		ID:     ctx.Param("id"),
+		Accept: accept,
+	})
+	if err != nil {
+		s.sharesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, share, nil)
+}
+
+// swagger:route DELETE /shares/{id} shares SharesRevoke
+//
+// Revoke a share
+//
+// Owner can take access away and invitee can leave
+// a todo or a list that is shared with them.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       404: stdResponse
+func (s *Server) SharesRevoke(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.sharesService.Revoke(ctx, u.ID, ctx.Param("id")); err != nil {
+		s.sharesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
@@ -0,0 +1,149 @@
+package resthttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+const (
+	// streamHeartbeat is the longest we stay silent, streams
+	// that live shorter than two of them ping more often
+	streamHeartbeat = 15 * time.Second
+	// streams are closed a bit before server's WriteTimeout,
+	// clients will reconnect in streamRetry with Last-Event-ID
+	streamTimeoutMargin = 2 * time.Second
+	streamRetry         = time.Second
+
+	// sent when we could not replay everything after Last-Event-ID,
+	// client has to refetch its todos
+	streamEventResync = "resync"
+)
+
+// swagger:route GET /todos/stream todo TodosStream
+//
+// Stream todo changes
+//
+// This is a Server-Sent Events stream of changes to your todos. Event names are
+// todo.created, todo.updated, todo.completed, todo.uncompleted, todo.status_changed and todo.deleted,
+// data is the todo itself. Reconnect with Last-Event-ID header to get missed events.
+// If we can't replay them you'll get a "resync" event and should refetch your todos.
+//
+//     Produces:
+//     - text/event-stream
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: Last-Event-ID
+//         in: header
+//         required: false
+//         type: string
+//
+//     Responses:
+//       200: description: event stream
+//       401: stdResponse
+func (s *Server) TodosStream(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	lastID := ctx.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		// for clients that can't set headers
+		lastID = ctx.Query("lastEventId")
+	}
+
+	sub, replay, ok, err := s.events.Subscribe(u.ID, lastID)
+	if err != nil {
+		respond(ctx, http.StatusServiceUnavailable, nil, []string{err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	if !ok {
+		fmt.Fprintf(ctx.Writer, "event: %s\ndata: {}\n\n", streamEventResync)
+	}
+	for _, e := range replay {
+		if err := writeEvent(ctx.Writer, e); err != nil {
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	lifetime, interval := streamTimings(s.server.WriteTimeout)
+	var deadline <-chan time.Time
+	if lifetime > 0 {
+		timer := time.NewTimer(lifetime)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-deadline:
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(ctx.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		case e, open := <-sub.C():
+			if !open {
+				return
+			}
+			if err := writeEvent(ctx.Writer, e); err != nil {
+				defer s.logger.Sync()
+				s.logger.Debug("resthttp: TodosStream(): could not write event", logging.String("error", err.Error()))
+				return
+			}
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// streamTimings returns for how long a stream may live, zero is forever,
+// and how often we ping it. Proxies should see a ping at least once
+// before we close the stream, otherwise they may drop it themselves.
+func streamTimings(writeTimeout time.Duration) (lifetime, heartbeat time.Duration) {
+	if writeTimeout <= streamTimeoutMargin {
+		return 0, streamHeartbeat
+	}
+	lifetime, heartbeat = writeTimeout-streamTimeoutMargin, streamHeartbeat
+	if half := lifetime / 2; half < heartbeat {
+		heartbeat = half
+	}
+	return lifetime, heartbeat
+}
+
+func writeEvent(w io.Writer, e events.Event) error {
+	data := e.Data
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
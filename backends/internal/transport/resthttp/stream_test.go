@@ -0,0 +1,25 @@
+package resthttp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rasulov-emirlan/todo-app/backends/config"
+)
+
+func TestStreamPingsBeforeDeadline(t *testing.T) {
+	cfg, err := config.LoadConfigs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, writeTimeout := range []time.Duration{cfg.WriteTimeout, 5 * time.Second, time.Minute} {
+		lifetime, heartbeat := streamTimings(writeTimeout)
+		if heartbeat <= 0 || heartbeat > lifetime/2 {
+			t.Errorf("write timeout %v: heartbeat %v does not fit twice into lifetime %v", writeTimeout, heartbeat, lifetime)
+		}
+	}
+
+	if lifetime, heartbeat := streamTimings(0); lifetime != 0 || heartbeat != streamHeartbeat {
+		t.Errorf("expected streams without write timeout to live forever, got %v and %v", lifetime, heartbeat)
+	}
+}
@@ -0,0 +1,364 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/templates"
+)
+
+type (
+	// reqTemplatesInstantiate
+	// Creates todos of a template
+	// swagger:model
+	reqTemplatesInstantiate struct {
+		// Id of a list you are an editor of, todos go to no list without it
+		// format: uuid
+		ListID string `json:"listId"`
+
+		// Deadlines of tasks are counted from this date
+		// required: true
+		// example: 2022-11-07
+		Start string `json:"start"`
+
+		// Values of placeholders of the template other than {{date}} and {{deadline}}
+		// example: {"version": "v1.2"}
+		Variables map[string]string `json:"variables"`
+	}
+
+	// respTemplatesInstantiate
+	// These are ids of created todos, the todo of the template goes first
+	// and its subtasks follow it depth first.
+	// swagger:model
+	respTemplatesInstantiate struct {
+		IDs []string `json:"ids"`
+	}
+)
+
+func (s *Server) templatesError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	switch {
+	case errors.Is(err, templates.ErrNoSuchTemplate):
+		respond(ctx, http.StatusNotFound, nil, []string{err.Error()})
+	case errors.Is(err, templates.ErrNotAllowed):
+		respond(ctx, http.StatusForbidden, nil, []string{err.Error()})
+	case errors.Is(err, templates.ErrTooManyTasks), errors.Is(err, templates.ErrUnknownPlaceholder):
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+	default:
+		// instantiating a template creates todos, so errors can be theirs
+		s.todosError(ctx, err)
+	}
+}
+
+func bindTemplatesSave(ctx *gin.Context) (templates.Task, bool) {
+	req := templates.Task{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return req, false
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return req, false
+	}
+	return req, true
+}
+
+// swagger:route POST /templates templates TemplatesCreate
+//
+// Create a template
+//
+// Templates create a todo with its subtasks at once. Subtasks can have their
+// own subtasks, up to 3 levels and 100 tasks in total. Titles and bodies can
+// have placeholders like {{date}}, deadlineOffsetDays is counted from the date
+// the template is instantiated at. Templates created in a workspace can be
+// used by all of its members.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: template
+//         in: body
+//         required: true
+//         description: {"title": "Release {{version}}", "deadlineOffsetDays": 7, "subtasks": [{"title": "Write changelog", "body": "Due {{deadline}}", "deadlineOffsetDays": 5}]}
+//
+//     Responses:
+//       201: description: template
+//       400: stdResponse
+//       401: stdResponse
+func (s *Server) TemplatesCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req, ok := bindTemplatesSave(ctx)
+	if !ok {
+		return
+	}
+
+	t, err := s.templatesService.Create(ctx, templates.CreateInput{UserID: u.ID, Task: req})
+	if err != nil {
+		s.templatesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, t, nil)
+}
+
+// swagger:route GET /templates templates TemplatesGetAll
+//
+// Get templates
+//
+// Returns your templates, in a workspace it returns all templates of the workspace.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: templates
+//       401: stdResponse
+func (s *Server) TemplatesGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	all, err := s.templatesService.GetAll(ctx, u.ID)
+	if err != nil {
+		s.templatesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, all, nil)
+}
+
+// swagger:route GET /templates/{id} templates TemplatesGet
+//
+// Get a template
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: template
+//       404: stdResponse
+func (s *Server) TemplatesGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	t, err := s.templatesService.Get(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.templatesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, t, nil)
+}
+
+// swagger:route PUT /templates/{id} templates TemplatesUpdate
+//
+// Replace a template
+//
+// Only the owner can change a template, the body replaces all of its tasks.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: template
+//         in: body
+//         required: true
+//         description: {"title": "Release {{version}}", "subtasks": [{"title": "Tag {{version}}"}]}
+//
+//     Responses:
+//       200: description: template
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TemplatesUpdate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req, ok := bindTemplatesSave(ctx)
+	if !ok {
+		return
+	}
+
+	t, err := s.templatesService.Update(ctx, u.ID, templates.UpdateInput{ID: ctx.Param("id"), Task: req})
+	if err != nil {
+		s.templatesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, t, nil)
+}
+
+// swagger:route DELETE /templates/{id} templates TemplatesDelete
+//
+// Delete a template
+//
+// Only the owner can delete a template, todos created from it stay.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TemplatesDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.templatesService.Delete(ctx, u.ID, ctx.Param("id")); err != nil {
+		s.templatesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route POST /templates/{id}/instantiate templates TemplatesInstantiate
+//
+// Instantiate a template
+//
+// Creates todos of a template all at once, either all of them are created or
+// none. Subtasks block their parent todos. Deadlines are the start date plus
+// offsets of tasks, {{date}} is replaced with the start date, {{deadline}} with
+// the deadline of a task and other placeholders with variables.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: instantiation
+//         in: body
+//         required: true
+//         type: reqTemplatesInstantiate
+//
+//     Responses:
+//       201: respTemplatesInstantiate
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TemplatesInstantiate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := reqTemplatesInstantiate{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+	start, err := time.Parse(templates.DateLayout, req.Start)
+	if err != nil {
+		respond(ctx, http.StatusBadRequest, nil, []string{"start has to be a date like 2022-11-07"})
+		return
+	}
+
+	ids, err := s.templatesService.Instantiate(ctx, u.ID, templates.InstantiateInput{
+		ID:        ctx.Param("id"),
+		ListID:    req.ListID,
+		Start:     start,
+		Variables: req.Variables,
+	})
+	if err != nil {
+		s.templatesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, respTemplatesInstantiate{IDs: ids}, nil)
+}
@@ -1,17 +1,20 @@
 package resthttp
 
 import (
-	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/quickadd"
 )
 
 type (
@@ -30,6 +33,18 @@ type (
 
 		// example: 2022-06-23T22:16:50.782647Z
 		Deadline time.Time `json:"deadline"`
+
+		// Id of a list you are an editor of
+		// format: uuid
+		ListID string `json:"listId"`
+
+		// Status from the workflow of the list, defaults to its first status
+		// example: backlog
+		Status string `json:"status"`
+
+		// Values of custom fields of the list by their keys
+		// example: {"estimate": 3, "customer": "ACME"}
+		Fields map[string]json.RawMessage `json:"fields"`
 	}
 
 	// respTodosCreate
@@ -39,7 +54,39 @@ type (
 		ID string `json:"id"`
 	}
 
-	// This is info needed for updating a todo. Its 100% identical to reqTodosCreate
+	// reqTodosQuickAdd
+	// A single line that is parsed into a todo
+	// swagger:model
+	reqTodosQuickAdd struct {
+		// required: true
+		// example: Pay rent tomorrow 9am #home !high every month
+		// max length: 500
+		Text string `json:"text"`
+
+		// Id of a list you are an editor of
+		// format: uuid
+		ListID string `json:"listId"`
+
+		// IANA name of the timezone dates and times are in,
+		// defaults to the timezone of your digest settings
+		// example: Asia/Bishkek
+		Timezone string `json:"timezone"`
+	}
+
+	// respTodosQuickAdd
+	// This is what the text was parsed into and an id of the created todo,
+	// there is no id in a dry run.
+	// swagger:model
+	respTodosQuickAdd struct {
+		ID     string          `json:"id,omitempty"`
+		Parsed quickadd.Result `json:"parsed"`
+		// Parts of the text that were parsed but are not saved, todos
+		// don't keep tags, priority and recurrence yet
+		// Variations: [tags, priority, recurrence]
+		Dropped []string `json:"dropped,omitempty"`
+	}
+
+	// This is info needed for updating a todo. Its almost identical to reqTodosCreate
 	// swagger:model
 	reqTodosUpdate struct {
 		// required: true
@@ -53,6 +100,51 @@ type (
 
 		// example: 2022-06-23T22:16:50.782647Z
 		Deadline time.Time `json:"deadline"`
+
+		// Moves the todo to a list, empty string takes it out of its list.
+		// Only the owner of the todo can change it
+		// format: uuid
+		ListID *string `json:"listId"`
+	}
+
+	// reqTodosAssign
+	// Empty assigneeId unassigns a todo
+	// swagger:model
+	reqTodosAssign struct {
+		// format: uuid
+		AssigneeID string `json:"assigneeId"`
+	}
+
+	// reqTodosMove
+	// Puts a todo right after afterId and right before beforeId,
+	// one of them is enough. They have to be todos of the same list.
+	// swagger:model
+	reqTodosMove struct {
+		// format: uuid
+		BeforeID string `json:"beforeId"`
+		// format: uuid
+		AfterID string `json:"afterId"`
+	}
+
+	// reqTodosAddBlocker
+	// Todo of blockerId has to be completed before this one
+	// swagger:model
+	reqTodosAddBlocker struct {
+		// required: true
+		// format: uuid
+		BlockerID string `json:"blockerId"`
+	}
+
+	// reqTodosSetStatus
+	// Moves a todo to another status of its list's workflow
+	// swagger:model
+	reqTodosSetStatus struct {
+		// required: true
+		// example: in_progress
+		Status string `json:"status"`
+
+		// Moves to a terminal status even if blockers of the todo are open
+		Force bool `json:"force"`
 	}
 
 	// todo
@@ -84,9 +176,39 @@ type (
 		Title string `json:"title"`
 		Body  string `json:"body"`
 
+		// Key of a status from the workflow of the todo's list
+		// example: in_progress
+		Status string `json:"status"`
+
+		// True when the status is a terminal one
 		Completed bool      `json:"completed"`
 		Deadline  time.Time `json:"deadline"`
 
+		// format: uuid
+		ListID string `json:"listId,omitempty"`
+
+		// Place of the todo in its list when sorting by manual
+		Position float64 `json:"position"`
+
+		// Ids of todos that have to be completed before this one
+		BlockedBy []string `json:"blockedBy"`
+		// Ids of todos waiting for this one
+		Blocks []string `json:"blocks"`
+
+		// Values of custom fields of the list by their keys
+		Fields map[string]interface{} `json:"fields,omitempty"`
+
+		// Only id is set when you get many todos
+		Assignee *struct {
+			// type: string
+			// format: uuid
+			ID       string `json:"id"`
+			Username string `json:"username"`
+			Email    string `json:"email"`
+		} `json:"assignee,omitempty"`
+
+		CommentsCount int `json:"commentsCount"`
+
 		CreatedAt time.Time `json:"createdAt"`
 		UpdatedAt time.Time `json:"updatedAt"`
 	}
@@ -123,6 +245,8 @@ type (
 //       default: respTodosCreate
 //       200: respTodosCreate
 //       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
 //       422: stdResponse
 func (s *Server) TodosCreate(ctx *gin.Context) {
 	user, err := getUserData(ctx)
@@ -141,18 +265,17 @@ func (s *Server) TodosCreate(ctx *gin.Context) {
 		return
 	}
 
-	id, err := s.todosService.Create(context.Background(), todos.CreateInput{
+	id, err := s.todosService.Create(ctx, todos.CreateInput{
 		UserID:   user.ID,
 		Title:    req.Title,
 		Body:     req.Body,
 		Deadline: req.Deadline,
+		ListID:   req.ListID,
+		Status:   todos.Status(req.Status),
+		Fields:   req.Fields,
 	})
 	if err != nil {
-		if errs := s.validator.UnpackErrors(err); errs != nil {
-			respond(ctx, http.StatusBadRequest, nil, errs)
-			return
-		}
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		s.todosError(ctx, err)
 		return
 	}
 
@@ -161,6 +284,122 @@ func (s *Server) TodosCreate(ctx *gin.Context) {
 	}, nil)
 }
 
+// maxQuickAdd is the longest line quick add parses
+const maxQuickAdd = 500
+
+// swagger:route POST /todos/quick todo TodosQuickAdd
+//
+// Quick add a todo
+//
+// Parses a single line like "Pay rent tomorrow 9am #home !high every month"
+// and creates a todo from it. Dates can be today, tomorrow, weekdays,
+// "next friday", "in 3 days", "dec 24" or 2022-12-24, times can be 9am,
+// 9:30 pm, 21:00, "at 9" and noon. Dates without a time are due at the end
+// of the day. #tags, !low, !medium, !high, !urgent and recurrences like
+// "every 2 weeks" or "every monday" are parsed too, but only the title and
+// the deadline are saved, the rest is listed in dropped. Words in double
+// quotes are never parsed. With dryRun=true nothing is created.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: dryRun
+//         in: query
+//         type: boolean
+//       + name: line
+//         in: body
+//         required: true
+//         type: reqTodosQuickAdd
+//
+//     Responses:
+//       200: respTodosQuickAdd
+//       201: respTodosQuickAdd
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TodosQuickAdd(ctx *gin.Context) {
+	user, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	req := reqTodosQuickAdd{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+	if strings.TrimSpace(req.Text) == "" || len(req.Text) > maxQuickAdd {
+		respond(ctx, http.StatusBadRequest, nil, []string{"text has to be from 1 to " + strconv.Itoa(maxQuickAdd) + " characters long"})
+		return
+	}
+	dryRun, _ := strconv.ParseBool(ctx.Query("dryRun"))
+
+	loc, err := s.digestsService.Location(ctx, user.ID, req.Timezone)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, digests.ErrInvalidTimezone) {
+			status = http.StatusBadRequest
+		}
+		respond(ctx, status, nil, []string{err.Error()})
+		return
+	}
+
+	parsed := quickadd.Parse(req.Text, time.Now().In(loc))
+	resp := respTodosQuickAdd{Parsed: parsed, Dropped: quickAddDropped(parsed)}
+	if dryRun {
+		respond(ctx, http.StatusOK, resp, nil)
+		return
+	}
+
+	inp := todos.CreateInput{
+		UserID: user.ID,
+		Title:  parsed.Title,
+		ListID: req.ListID,
+	}
+	if parsed.Deadline != nil {
+		inp.Deadline = parsed.Deadline.UTC()
+	}
+	id, err := s.todosService.Create(ctx, inp)
+	if err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	resp.ID = id
+	respond(ctx, http.StatusCreated, resp, nil)
+}
+
+// quickAddDropped names parts of a parsed line that todos can't keep
+func quickAddDropped(r quickadd.Result) []string {
+	var dropped []string
+	if len(r.Tags) != 0 {
+		dropped = append(dropped, "tags")
+	}
+	if r.Priority != "" {
+		dropped = append(dropped, "priority")
+	}
+	if r.Recurrence != nil {
+		dropped = append(dropped, "recurrence")
+	}
+	return dropped
+}
+
 // swagger:route PATCH /todos/{id} todo TodosUpdate
 //
 // Update a todo
@@ -196,6 +435,8 @@ func (s *Server) TodosCreate(ctx *gin.Context) {
 //     Responses:
 //       200: stdResponse
 //       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
 //       422: stdResponse
 func (s *Server) TodosUpdate(ctx *gin.Context) {
 	u, err := getUserData(ctx)
@@ -224,13 +465,10 @@ func (s *Server) TodosUpdate(ctx *gin.Context) {
 		Title:    req.Title,
 		Body:     req.Body,
 		Deadline: req.Deadline,
+		ListID:   req.ListID,
 	})
 	if err != nil {
-		if errs := s.validator.UnpackErrors(err); errs != nil {
-			respond(ctx, http.StatusBadRequest, nil, errs)
-			return
-		}
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		s.todosError(ctx, err)
 		return
 	}
 
@@ -241,7 +479,8 @@ func (s *Server) TodosUpdate(ctx *gin.Context) {
 //
 // Get a todo
 //
-// This will return a todo
+// This will return a todo if you are its author
+// or it is shared with you
 //
 //     Consumes:
 //     - application/json
@@ -267,28 +506,39 @@ func (s *Server) TodosUpdate(ctx *gin.Context) {
 //     Responses:
 //       200: todo
 //       400: stdResponse
+//       404: stdResponse
 //       422: stdResponse
 func (s *Server) TodosGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
 	id := ctx.Param("id")
 	if len(id) == 0 {
 		respond(ctx, http.StatusBadRequest, nil, []string{ErrParamNotProvided.Error()})
 		return
 	}
 
-	todo, err := s.todosService.Get(ctx, id)
+	todo, err := s.todosService.Get(ctx, u.ID, id)
 	if err != nil {
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		s.todosError(ctx, err)
 		return
 	}
 
 	respond(ctx, http.StatusOK, todo, nil)
 }
 
+// fieldFilterPrefix marks query parameters that filter by custom fields
+const fieldFilterPrefix = "field."
+
 var sortVariants = map[string]todos.SortBy{
 	"creationASC":  todos.SortByCreationASC,
 	"creationDESC": todos.SortByCreationDESC,
 	"deadlineASC":  todos.SortByDeadlineASC,
 	"deadlineDESC": todos.SortByDeadlineDESC,
+	"manual":       todos.SortByManual,
 }
 
 // swagger:route GET /todos todo TodosGetAll
@@ -331,13 +581,59 @@ var sortVariants = map[string]todos.SortBy{
 //       + name: sortBy
 //         in: query
 //         required: false
-//         description: How to sort it. Variations: [deadlineDESC, deadlineASC, creationDESC, creationASC]
+//         description: How to sort it. Variations: [deadlineDESC, deadlineASC, creationDESC, creationASC, manual]
 //         type: string
 //         example: deadlineDESC
+//       + name: listId
+//         in: query
+//         required: false
+//         description: Return todos of this list instead of yours
+//         type: string
+//       + name: shared
+//         in: query
+//         required: false
+//         description: If true we will return todos shared with you
+//         type: boolean
+//         example: true
+//       + name: assignee
+//         in: query
+//         required: false
+//         description: Return only todos assigned to this user, "me" is for todos assigned to you
+//         type: string
+//         example: me
+//       + name: status
+//         in: query
+//         required: false
+//         description: Return only todos in these statuses, can be repeated or comma separated
+//         type: string
+//         example: backlog,in_progress
+//       + name: field.{key}
+//         in: query
+//         required: false
+//         description: Return only todos with this value of a custom field, values are compared as text
+//         type: string
+//         example: field.sprint=12
+//       + name: sortField
+//         in: query
+//         required: false
+//         description: Sort by a custom field instead of sortBy, todos without it go last
+//         type: string
+//         example: estimate
+//       + name: sortFieldDesc
+//         in: query
+//         required: false
+//         type: boolean
+//       + name: groupBy
+//         in: query
+//         required: false
+//         description: With "status" todos come grouped in columns of the list's workflow. Variations: [status]
+//         type: string
+//         example: status
 //
 //     Responses:
 //       200: []todo
 //       400: stdResponse
+//       404: stdResponse
 //       422: stdResponse
 func (s *Server) TodosGetAll(ctx *gin.Context) {
 	user, err := getUserData(ctx)
@@ -386,15 +682,64 @@ func (s *Server) TodosGetAll(ctx *gin.Context) {
 		fOnlyCompleted = true
 	}
 
-	t, err := s.todosService.GetAll(ctx, todos.GetAllInput{
+	assignee := ctx.Query("assignee")
+	if assignee == "me" {
+		assignee = user.ID
+	}
+
+	var fieldFilters map[string]string
+	for key, values := range ctx.Request.URL.Query() {
+		if !strings.HasPrefix(key, fieldFilterPrefix) || len(values) == 0 {
+			continue
+		}
+		if fieldFilters == nil {
+			fieldFilters = map[string]string{}
+		}
+		fieldFilters[strings.TrimPrefix(key, fieldFilterPrefix)] = values[0]
+	}
+
+	var statuses []todos.Status
+	for _, v := range ctx.QueryArray("status") {
+		for _, st := range strings.Split(v, ",") {
+			if st = strings.TrimSpace(st); st != "" {
+				statuses = append(statuses, todos.Status(st))
+			}
+		}
+	}
+
+	config := todos.GetAllInput{
 		UserID:            user.ID,
 		PageSize:          fPageSize,
 		Page:              fPage,
 		ShowOnlyCompleted: fOnlyCompleted,
 		SortBy:            fSortBy,
-	})
+		ListID:            ctx.Query("listId"),
+		Shared:            ctx.Query("shared") == "true",
+		AssigneeID:        assignee,
+		Statuses:          statuses,
+		FieldFilters:      fieldFilters,
+		SortField:         ctx.Query("sortField"),
+		SortFieldDesc:     ctx.Query("sortFieldDesc") == "true",
+	}
+
+	switch ctx.Query("groupBy") {
+	case "":
+	case "status":
+		b, err := s.todosService.GetBoard(ctx, config)
+		if err != nil {
+			s.todosError(ctx, err)
+			return
+		}
+		respond(ctx, http.StatusOK, b, nil)
+		return
+	default:
+		respond(ctx, http.StatusBadRequest, nil, []string{"groupBy can only be status"})
+		return
+	}
+
+	t, err := s.todosService.GetAll(ctx, config)
 	if err != nil {
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		s.todosError(ctx, err)
 		return
 	}
 
@@ -426,9 +771,17 @@ func (s *Server) TodosGetAll(ctx *gin.Context) {
 //         required: true
 //         description: Id for the todo
 //         type: string
+//       + name: force
+//         in: query
+//         required: false
+//         description: If true the todo is completed even if its blockers are not
+//         type: boolean
 //
 //     Responses:
 //       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       409: stdResponse
 //       422: stdResponse
 func (s *Server) TodosMarkComplete(ctx *gin.Context) {
 	u, err := getUserData(ctx)
@@ -443,8 +796,8 @@ func (s *Server) TodosMarkComplete(ctx *gin.Context) {
 		return
 	}
 
-	if err := s.todosService.MarkAsComplete(ctx, u.ID, id); err != nil {
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+	if err := s.todosService.MarkAsComplete(ctx, u.ID, id, ctx.Query("force") == "true"); err != nil {
+		s.todosError(ctx, err)
 		return
 	}
 
@@ -479,6 +832,8 @@ func (s *Server) TodosMarkComplete(ctx *gin.Context) {
 //
 //     Responses:
 //       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
 //       422: stdResponse
 func (s *Server) TodosMarkNotComplete(ctx *gin.Context) {
 	u, err := getUserData(ctx)
@@ -493,18 +848,20 @@ func (s *Server) TodosMarkNotComplete(ctx *gin.Context) {
 	}
 
 	if err := s.todosService.MarkAsNotComplete(ctx, u.ID, id); err != nil {
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+		s.todosError(ctx, err)
 		return
 	}
 
 	ctx.Status(http.StatusOK)
 }
 
-// swagger:route DELETE /todos/{id} todo TodosDelete
+// swagger:route PUT /todos/{id}/status todo TodosSetStatus
 //
-// Delete a todo
+// Set status
 //
-// This will delete a todo forever
+// This will move a todo to another status of its list's workflow.
+// Completed is derived from the status, so moving to a terminal
+// status completes the todo and moving out of it uncompletes it.
 //
 //     Consumes:
 //     - application/json
@@ -525,11 +882,17 @@ func (s *Server) TodosMarkNotComplete(ctx *gin.Context) {
 //         required: true
 //         description: Id for the todo
 //         type: string
+//       + name: status
+//         in: body
+//         required: true
+//         type: reqTodosSetStatus
 //
 //     Responses:
 //       400: stdResponse
-//       422: stdResponse
-func (s *Server) TodosDelete(ctx *gin.Context) {
+//       403: stdResponse
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) TodosSetStatus(ctx *gin.Context) {
 	u, err := getUserData(ctx)
 	if err != nil {
 		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
@@ -541,10 +904,465 @@ func (s *Server) TodosDelete(ctx *gin.Context) {
 		return
 	}
 
-	if err := s.todosService.Delete(ctx, u.ID, id); err != nil {
-		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+	req := reqTodosSetStatus{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.todosService.SetStatus(ctx, u.ID, todos.SetStatusInput{
+		ID:     id,
+		Status: todos.Status(req.Status),
+		Force:  req.Force,
+	}); err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// swagger:route POST /todos/{id}/move todo TodosMove
+//
+// Move a todo
+//
+// This will put a todo between other todos of its list, so they
+// come in this order when sorting by manual. Simultaneous moves
+// in one list are applied one after another.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//       + name: anchors
+//         in: body
+//         required: true
+//         type: reqTodosMove
+//
+//     Responses:
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TodosMove(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	id := ctx.Param("id")
+	if len(id) == 0 {
+		respond(ctx, http.StatusBadRequest, nil, []string{ErrParamNotProvided.Error()})
+		return
+	}
+
+	req := reqTodosMove{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.todosService.Move(ctx, u.ID, todos.MoveInput{
+		ID:       id,
+		BeforeID: req.BeforeID,
+		AfterID:  req.AfterID,
+	}); err != nil {
+		s.todosError(ctx, err)
 		return
 	}
 
 	ctx.Status(http.StatusOK)
 }
+
+// swagger:route PUT /todos/{id}/fields todo TodosSetFields
+//
+// Set custom fields
+//
+// This will change values of custom fields of a todo, other fields stay
+// as they are and null removes a value. Values have to fit fields of the
+// list of the todo: strings for text, date (2006-01-02) and select fields,
+// numbers and booleans for number and checkbox fields.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//       + name: fields
+//         in: body
+//         required: true
+//         description: {"estimate": 5, "customer": null}
+//
+//     Responses:
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TodosSetFields(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	id := ctx.Param("id")
+	if len(id) == 0 {
+		respond(ctx, http.StatusBadRequest, nil, []string{ErrParamNotProvided.Error()})
+		return
+	}
+
+	req := map[string]json.RawMessage{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.todosService.SetFields(ctx, u.ID, todos.SetFieldsInput{
+		ID:     id,
+		Fields: req,
+	}); err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// swagger:route POST /todos/{id}/blockers todo TodosAddBlocker
+//
+// Add a blocker
+//
+// This will make a todo wait for another one, it can't be completed
+// until the blocker is, unless forced. You have to be an editor of
+// the todo and see the blocker. Todos can't block each other in a circle.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//       + name: blocker
+//         in: body
+//         required: true
+//         type: reqTodosAddBlocker
+//
+//     Responses:
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) TodosAddBlocker(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	id := ctx.Param("id")
+	if len(id) == 0 {
+		respond(ctx, http.StatusBadRequest, nil, []string{ErrParamNotProvided.Error()})
+		return
+	}
+
+	req := reqTodosAddBlocker{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.todosService.AddBlocker(ctx, u.ID, todos.DependencyInput{
+		ID:        id,
+		BlockerID: req.BlockerID,
+	}); err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// swagger:route DELETE /todos/{id}/blockers/{blockerId} todo TodosRemoveBlocker
+//
+// Remove a blocker
+//
+// This will let a todo be completed without waiting for the blocker.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//       + name: blockerId
+//         in: params
+//         required: true
+//         description: Id for the blocker
+//         type: string
+//
+//     Responses:
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TodosRemoveBlocker(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.todosService.RemoveBlocker(ctx, u.ID, todos.DependencyInput{
+		ID:        ctx.Param("id"),
+		BlockerID: ctx.Param("blockerId"),
+	}); err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// swagger:route DELETE /todos/{id} todo TodosDelete
+//
+// Delete a todo
+//
+// This will delete a todo forever
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//
+//     Responses:
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       422: stdResponse
+func (s *Server) TodosDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	id := ctx.Param("id")
+	if len(id) == 0 {
+		respond(ctx, http.StatusBadRequest, nil, []string{ErrParamNotProvided.Error()})
+		return
+	}
+
+	if err := s.todosService.Delete(ctx, u.ID, id); err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// swagger:route PUT /todos/{id}/assignee todo TodosAssign
+//
+// Assign a todo
+//
+// Makes somebody responsible for a todo, the assignee has to have access
+// to it and gets a notification. Owner and editors can assign todos that
+// are not assigned yet or assigned to themselves, only the owner can
+// take a todo away from another assignee.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//       + name: assignee
+//         in: body
+//         required: true
+//         type: reqTodosAssign
+//
+//     Responses:
+//       200: stdResponse
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) TodosAssign(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	req := reqTodosAssign{}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	err = s.todosService.Assign(ctx, u.ID, todos.AssignInput{
+		ID:         ctx.Param("id"),
+		AssigneeID: req.AssigneeID,
+	})
+	if err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// swagger:route GET /todos/{id}/history todo TodosGetHistory
+//
+// Get history of a todo
+//
+// Oldest entries go first. Variations of action: [assigned, unassigned, status_changed]
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         description: Id for the todo
+//         type: string
+//
+//     Responses:
+//       200: description: history entries
+//       404: stdResponse
+func (s *Server) TodosGetHistory(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	history, err := s.todosService.GetHistory(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.todosError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, history, nil)
+}
+
+func (s *Server) todosError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, todos.ErrNoSuchTodo), errors.Is(err, todos.ErrNoSuchList), errors.Is(err, todos.ErrNoSuchDependency):
+		respond(ctx, http.StatusNotFound, nil, []string{err.Error()})
+	case errors.Is(err, todos.ErrNotAllowed), errors.Is(err, todos.ErrReassign):
+		respond(ctx, http.StatusForbidden, nil, []string{err.Error()})
+	case errors.Is(err, todos.ErrAssigneeNoAccess), errors.Is(err, todos.ErrUnknownStatus),
+		errors.Is(err, todos.ErrInvalidWorkflow), errors.Is(err, todos.ErrInvalidAnchor),
+		errors.Is(err, todos.ErrUnknownField), errors.Is(err, todos.ErrInvalidFieldValue):
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+	case errors.Is(err, todos.ErrTransition), errors.Is(err, todos.ErrCycle), errors.Is(err, todos.ErrBlocked):
+		respond(ctx, http.StatusConflict, nil, []string{err.Error()})
+	default:
+		if errs := s.validator.UnpackErrors(err); errs != nil {
+			respond(ctx, http.StatusBadRequest, nil, errs)
+			return
+		}
+		respond(ctx, http.StatusInternalServerError, nil, []string{err.Error()})
+	}
+}
@@ -91,6 +91,25 @@ type (
 		UpdatedAt time.Time `json:"updatedAt"`
 	}
 
+	// reqUsersDeleteMe
+	// You have to confirm your password to delete your account
+	//
+	// swagger:model
+	reqUsersDeleteMe struct {
+		// required: true
+		// example: password
+		Password string `json:"password"`
+	}
+
+	// respUsersDeleteMe
+	// Your account will be deleted after this time.
+	// Sign in before that to cancel deletion.
+	//
+	// swagger:model
+	respUsersDeleteMe struct {
+		DeleteAfter time.Time `json:"deleteAfter"`
+	}
+
 	// reqUsersRefresh is used for mobile clients. They should send their refresh keys in this model to refresh endpoint for updating their keys
 	//
 	// swagger:model
@@ -218,7 +237,9 @@ func (s *Server) UsersSignUp(ctx *gin.Context) {
 //     Responses:
 //       default: usersKeys
 //       200: usersKeys
+//       401: stdResponse
 //       422: stdResponse
+//       429: stdResponse
 func (s *Server) UsersSignIn(ctx *gin.Context) {
 	var inp reqUsersSignIn
 
@@ -236,11 +257,19 @@ func (s *Server) UsersSignIn(ctx *gin.Context) {
 		ctx,
 		inp.Email,
 		inp.Password,
+		ctx.ClientIP(),
 	)
 	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, users.ErrInvalidCredentials):
+			status = http.StatusUnauthorized
+		case errors.Is(err, users.ErrTooManyAttempts):
+			status = http.StatusTooManyRequests
+		}
 		respond(
 			ctx,
-			http.StatusInternalServerError,
+			status,
 			nil,
 			[]string{err.Error()},
 		)
@@ -396,6 +425,7 @@ func (s *Server) UsersLogout(ctx *gin.Context) {
 //
 //     Responses:
 //       default: stdResponse
+//       409: stdResponse
 func (s *Server) UsersDelete(ctx *gin.Context) {
 	id := ctx.Param("id")
 	if len(id) == 0 {
@@ -409,9 +439,13 @@ func (s *Server) UsersDelete(ctx *gin.Context) {
 	}
 
 	if err := s.usersService.Delete(ctx, id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, users.ErrOwnsWorkspace) {
+			status = http.StatusConflict
+		}
 		respond(
 			ctx,
-			http.StatusInternalServerError,
+			status,
 			nil,
 			[]string{err.Error()},
 		)
@@ -421,6 +455,86 @@ func (s *Server) UsersDelete(ctx *gin.Context) {
 	respond(ctx, http.StatusOK, nil, nil)
 }
 
+// swagger:route DELETE /users/me users UsersDeleteMe
+//
+// Delete my account
+//
+// This will schedule deletion of your account with all of your todos.
+// Nothing is deleted until grace period is over, sign in to cancel it.
+// Todos and lists you made in workspaces and in lists of others are
+// given to their owners. Workspaces you own have to be deleted first.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: password
+//         in: body
+//         required: true
+//         type: reqUsersDeleteMe
+//
+//     Responses:
+//       default: respUsersDeleteMe
+//       401: stdResponse
+//       409: stdResponse
+//       429: stdResponse
+func (s *Server) UsersDeleteMe(ctx *gin.Context) {
+	d, err := getUserData(ctx)
+	if err != nil {
+		respond(
+			ctx,
+			http.StatusUnauthorized,
+			nil,
+			[]string{err.Error()},
+		)
+		return
+	}
+
+	var inp reqUsersDeleteMe
+	if err := ctx.ShouldBindJSON(&inp); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrRequestBodyNotProvided
+		}
+		respond(
+			ctx,
+			http.StatusBadRequest,
+			nil,
+			[]string{err.Error()},
+		)
+		return
+	}
+
+	deleteAfter, err := s.usersService.ScheduleDeletion(ctx, d.ID, inp.Password)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, users.ErrWrongPassword):
+			status = http.StatusUnauthorized
+		case errors.Is(err, users.ErrTooManyAttempts):
+			status = http.StatusTooManyRequests
+		}
+		respond(
+			ctx,
+			status,
+			nil,
+			[]string{err.Error()},
+		)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, respUsersDeleteMe{DeleteAfter: deleteAfter}, nil)
+}
+
 // swagger:route GET /users/me users UsersMe
 //
 // Get current user
@@ -467,3 +581,94 @@ func (s *Server) usersMe(ctx *gin.Context) {
 
 	respond(ctx, http.StatusOK, out, nil)
 }
+
+// swagger:route GET /users/locked users UsersGetLocked
+//
+// Get locked accounts
+//
+// This will return all accounts and ips that are currently locked
+// because of too many failed sign in attempts. Only for admins.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       default: stdResponse
+func (s *Server) UsersGetLocked(ctx *gin.Context) {
+	locked, err := s.usersService.GetLocked(ctx)
+	if err != nil {
+		respond(
+			ctx,
+			http.StatusInternalServerError,
+			nil,
+			[]string{err.Error()},
+		)
+		return
+	}
+
+	respond(ctx, http.StatusOK, locked, nil)
+}
+
+// swagger:route DELETE /users/locked/{key} users UsersUnlock
+//
+// Unlock an account
+//
+// This will forget all failed sign in attempts for a key. Only for admins.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: key
+//         in: params
+//         required: true
+//         description: Key from the list of locked accounts
+//         type: string
+//         example: 'account:user@example.com'
+//
+//     Responses:
+//       default: stdResponse
+func (s *Server) UsersUnlock(ctx *gin.Context) {
+	key := ctx.Param("key")
+	if len(key) == 0 {
+		respond(
+			ctx,
+			http.StatusBadRequest,
+			nil,
+			[]string{ErrParamNotProvided.Error()},
+		)
+		return
+	}
+
+	if err := s.usersService.Unlock(ctx, key); err != nil {
+		respond(
+			ctx,
+			http.StatusInternalServerError,
+			nil,
+			[]string{err.Error()},
+		)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
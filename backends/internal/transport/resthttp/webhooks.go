@@ -0,0 +1,485 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/webhooks"
+)
+
+type (
+	// reqWebhooksCreate
+	// This is a model used for registering a webhook
+	// swagger:model
+	reqWebhooksCreate struct {
+		// required: true
+		// example: https://example.com/hooks/todos
+		URL string `json:"url"`
+
+		// Variations: [todo.created, todo.updated, todo.completed, todo.uncompleted, todo.deleted, todo.assigned, todo.reminder, todo.overdue, todo.status_changed]
+		// required: true
+		// example: ["todo.created", "todo.completed"]
+		Events []events.Type `json:"events"`
+
+		// Used to sign payloads. If empty we will generate one
+		// min length: 16
+		Secret string `json:"secret"`
+	}
+
+	// reqWebhooksUpdate
+	// Only provided fields are updated. Enabling a disabled webhook resets its failures
+	// swagger:model
+	reqWebhooksUpdate struct {
+		URL     *string       `json:"url"`
+		Events  []events.Type `json:"events"`
+		Enabled *bool         `json:"enabled"`
+	}
+
+	// respWebhooksCreate
+	// This is a newly created webhook with its secret.
+	// Save the secret, we will not show it again
+	// swagger:model
+	respWebhooksCreate struct {
+		webhooks.Webhook
+		Secret string `json:"secret"`
+	}
+
+	// respWebhooksDelivery
+	// This is a delivery of an event with all attempts to send it
+	// swagger:model
+	respWebhooksDelivery struct {
+		webhooks.Delivery
+		AttemptsLog []webhooks.Attempt `json:"attemptsLog"`
+	}
+)
+
+func (s *Server) webhooksError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, webhooks.ErrNoSuchWebhook), errors.Is(err, webhooks.ErrNoSuchDelivery):
+		status = http.StatusNotFound
+	case errors.Is(err, webhooks.ErrInvalidURL), errors.Is(err, webhooks.ErrForbiddenHost):
+		status = http.StatusBadRequest
+	case errors.Is(err, webhooks.ErrDisabled):
+		status = http.StatusConflict
+	}
+	respond(ctx, status, nil, []string{err.Error()})
+}
+
+// swagger:route POST /webhooks webhooks WebhooksCreate
+//
+// Register a webhook
+//
+// We will POST events you subscribed to to this url. Every request has headers
+// X-Webhook-Id, X-Webhook-Delivery, X-Webhook-Event, X-Webhook-Timestamp and
+// X-Webhook-Signature. Signature is "sha256=" followed by hex of HMAC-SHA256
+// of "{timestamp}.{body}" with your secret. Respond with 2xx, anything else
+// is retried with exponential backoff. A webhook that fails too often is disabled.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: webhook
+//         in: body
+//         required: true
+//         type: reqWebhooksCreate
+//
+//     Responses:
+//       201: respWebhooksCreate
+//       400: stdResponse
+//       401: stdResponse
+func (s *Server) WebhooksCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	var req reqWebhooksCreate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrRequestBodyNotProvided
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	w, err := s.webhooksService.Create(ctx, webhooks.CreateInput{
+		UserID: u.ID,
+		URL:    req.URL,
+		Events: req.Events,
+		Secret: req.Secret,
+	})
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, respWebhooksCreate{Webhook: w, Secret: w.Secret}, nil)
+}
+
+// swagger:route GET /webhooks webhooks WebhooksGetAll
+//
+// Get my webhooks
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: list of webhooks
+//       401: stdResponse
+func (s *Server) WebhooksGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.webhooksService.GetAll(ctx, u.ID)
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route GET /webhooks/{id} webhooks WebhooksGet
+//
+// Get a webhook
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: webhook
+//       404: stdResponse
+func (s *Server) WebhooksGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	w, err := s.webhooksService.Get(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, w, nil)
+}
+
+// swagger:route PATCH /webhooks/{id} webhooks WebhooksUpdate
+//
+// Update a webhook
+//
+// Use it to change url or events, or to enable a webhook that was disabled
+// after too many failures.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: webhook
+//         in: body
+//         required: true
+//         type: reqWebhooksUpdate
+//
+//     Responses:
+//       200: description: updated webhook
+//       400: stdResponse
+//       404: stdResponse
+func (s *Server) WebhooksUpdate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	var req reqWebhooksUpdate
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = ErrRequestBodyNotProvided
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return
+	}
+
+	w, err := s.webhooksService.Update(ctx, u.ID, webhooks.UpdateInput{
+		ID:      ctx.Param("id"),
+		URL:     req.URL,
+		Events:  req.Events,
+		Enabled: req.Enabled,
+	})
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, w, nil)
+}
+
+// swagger:route DELETE /webhooks/{id} webhooks WebhooksDelete
+//
+// Delete a webhook
+//
+// Pending deliveries of this webhook are dropped.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       404: stdResponse
+func (s *Server) WebhooksDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.webhooksService.Delete(ctx, u.ID, ctx.Param("id")); err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route POST /webhooks/{id}/test webhooks WebhooksTest
+//
+// Send a test event
+//
+// This will send a webhook.test event to the webhook. It is delivered
+// the same way as real events, check its delivery to see how it went.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       202: description: delivery of the test event
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) WebhooksTest(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	d, err := s.webhooksService.Test(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, d, nil)
+}
+
+// swagger:route GET /webhooks/{id}/deliveries webhooks WebhooksGetDeliveries
+//
+// Get recent deliveries of a webhook
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: list of deliveries, newest first
+//       404: stdResponse
+func (s *Server) WebhooksGetDeliveries(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.webhooksService.GetDeliveries(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route GET /webhooks/{id}/deliveries/{deliveryId} webhooks WebhooksGetDelivery
+//
+// Get a delivery with its attempts
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: deliveryId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: respWebhooksDelivery
+//       404: stdResponse
+func (s *Server) WebhooksGetDelivery(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	d, attempts, err := s.webhooksService.GetDelivery(ctx, u.ID, ctx.Param("id"), ctx.Param("deliveryId"))
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, respWebhooksDelivery{Delivery: d, AttemptsLog: attempts}, nil)
+}
+
+// swagger:route POST /webhooks/{id}/deliveries/{deliveryId}/redeliver webhooks WebhooksRedeliver
+//
+// Redeliver an event
+//
+// This will send the delivery again with the same id, even if it was
+// delivered or failed before.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: deliveryId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       202: description: delivery that will be sent again
+//       404: stdResponse
+func (s *Server) WebhooksRedeliver(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	d, err := s.webhooksService.Redeliver(ctx, u.ID, ctx.Param("id"), ctx.Param("deliveryId"))
+	if err != nil {
+		s.webhooksError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusAccepted, d, nil)
+}
+
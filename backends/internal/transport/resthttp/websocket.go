@@ -0,0 +1,598 @@
+package resthttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+const (
+	wsReadLimit     = 64 * 1024
+	wsWriteWait     = 10 * time.Second
+	wsPongWait      = 60 * time.Second
+	wsPingInterval  = wsPongWait * 9 / 10
+	wsSendBuffer    = 64
+	wsExpiryWarning = time.Minute
+	// wsAuthWait is how long we wait for the first auth message
+	// of a connection that came without Authorization header
+	wsAuthWait = 10 * time.Second
+
+	// close codes in 4000-4999 range are for applications
+	wsCloseUnauthorized = 4001
+	wsCloseForbidden    = 4003
+
+	// topic of all the todos of a user
+	wsTopicTodos = "todos"
+	// topic of a single todo, followed by its id
+	wsTopicTodoPrefix = "todo:"
+	// topic of todos in a list, followed by its id
+	wsTopicListPrefix = "list:"
+	// topic of in-app notifications of a user
+	wsTopicNotifications = "notifications"
+)
+
+// client messages
+const (
+	wsTypeAuth            = "auth"
+	wsTypePing            = "ping"
+	wsTypeSubscribe       = "subscribe"
+	wsTypeUnsubscribe     = "unsubscribe"
+	wsTypeTodosCreate     = "todos.create"
+	wsTypeTodosUpdate     = "todos.update"
+	wsTypeTodosComplete   = "todos.complete"
+	wsTypeTodosIncomplete = "todos.incomplete"
+	wsTypeTodosDelete     = "todos.delete"
+)
+
+// server messages
+const (
+	wsTypeResult       = "result"
+	wsTypeEvent        = "event"
+	wsTypePong         = "pong"
+	wsTypeAuthExpiring = "auth.expiring"
+)
+
+var (
+	ErrWSUnknownType   = errors.New("unknown message type")
+	ErrWSUnknownTopic  = errors.New("unknown topic")
+	ErrWSKeyExpired    = errors.New("access key has expired, send a new one with auth message")
+	ErrWSUserMismatch  = errors.New("access key belongs to another user")
+	ErrWSInvalidFormat = errors.New("message has to be a json object")
+	ErrWSAuthFirst     = errors.New("first message has to be auth with an access key")
+)
+
+type (
+	// wsMessage
+	// Every message in /ws goes in both directions in this envelope.
+	// Results of requests have the same id as requests had.
+	// swagger:model
+	wsMessage struct {
+		// Correlation id chosen by client
+		ID string `json:"id,omitempty"`
+		// Variations for clients: [auth, ping, subscribe, unsubscribe,
+		// todos.create, todos.update, todos.complete, todos.incomplete, todos.delete]
+		// Variations for server: [result, event, pong, auth.expiring]
+		Type string `json:"type"`
+		// Variations: [todos, todo:{id}, list:{id}, notifications]
+		Topic  string          `json:"topic,omitempty"`
+		Data   json.RawMessage `json:"data,omitempty"`
+		Errors []string        `json:"errors,omitempty"`
+	}
+
+	wsAuthData struct {
+		AccessKey string `json:"accessKey"`
+	}
+
+	wsTodoIDData struct {
+		ID string `json:"id"`
+		// Force completes a todo even if its blockers are open
+		Force bool `json:"force,omitempty"`
+	}
+
+	wsTodosUpdateData struct {
+		ID string `json:"id"`
+		reqTodosUpdate
+	}
+
+	wsAuthExpiringData struct {
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+
+	wsConn struct {
+		s *Server
+		// ctx keeps the workspace scope of the upgrade request,
+		// everything the connection does works in it
+		ctx  context.Context
+		conn *websocket.Conn
+		send chan wsMessage
+		sub  *events.Subscription
+		// done is closed when writeLoop exits
+		done chan struct{}
+		// reauthed tells writeLoop that access key has changed
+		reauthed chan struct{}
+
+		mu     sync.Mutex
+		claims users.JWTaccess
+		topics map[string]struct{}
+	}
+)
+
+// swagger:route GET /ws websocket WebSocket
+//
+// WebSocket api
+//
+// This upgrades connection to a WebSocket. Authenticate with Bearer token in
+// Authorization header. Browsers can't set it, so without the header the first
+// message has to be "auth" with an access key, otherwise connection is closed with 4001.
+// Pick a workspace with X-Workspace-ID header or workspaceId query parameter.
+// All messages are wsMessage. Subscribe to "todos", "todo:{id}" or "list:{id}" topics
+// to get the same events as in /todos/stream, and to "notifications" topic
+// to get in-app notifications. Send a fresh access key with
+// "auth" message after you get "auth.expiring", or connection will be closed with 4001.
+//
+//     Schemes: ws, wss
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: workspaceId
+//         in: query
+//         required: false
+//         type: string
+//
+//     Responses:
+//       101: description: switching protocols
+//       403: stdResponse
+func (s *Server) WebSocket(ctx *gin.Context) {
+	var (
+		claims users.JWTaccess
+		err    error
+	)
+	// access keys are never taken from the query,
+	// urls end up in logs of every proxy on the way
+	tokens := strings.Fields(ctx.GetHeader("Authorization"))
+	authed := len(tokens) == 2 && tokens[0] == "Bearer"
+	if authed {
+		claims, err = s.usersService.UnpackAccessKey(ctx, tokens[1])
+		if err != nil {
+			respond(ctx, http.StatusForbidden, nil, []string{ErrNoCredentials.Error()})
+			return
+		}
+	}
+	workspaceID := ctx.GetHeader(workspaceHeader)
+	if workspaceID == "" {
+		workspaceID = ctx.Query("workspaceId")
+	}
+	if authed && workspaceID != "" {
+		if _, err := s.workspacesService.Member(ctx, workspaceID, claims.ID); err != nil {
+			respond(ctx, workspacesStatus(err), nil, []string{err.Error()})
+			return
+		}
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: s.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// upgrader has already responded
+		return
+	}
+
+	var auth wsMessage
+	if !authed {
+		auth, claims, err = s.wsAuthenticate(ctx, conn)
+		if err != nil {
+			wsReject(conn, wsCloseUnauthorized, err)
+			return
+		}
+		if workspaceID != "" {
+			if _, err := s.workspacesService.Member(ctx, workspaceID, claims.ID); err != nil {
+				wsReject(conn, wsCloseForbidden, err)
+				return
+			}
+		}
+	}
+
+	sub, _, _, err := s.events.Subscribe(claims.ID, "")
+	if err != nil {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, err.Error()))
+		conn.Close()
+		return
+	}
+
+	c := &wsConn{
+		s:        s,
+		ctx:      workspaces.WithScope(ctx.Request.Context(), workspaceID),
+		conn:     conn,
+		send:     make(chan wsMessage, wsSendBuffer),
+		sub:      sub,
+		done:     make(chan struct{}),
+		reauthed: make(chan struct{}, 1),
+		claims:   claims,
+		topics:   make(map[string]struct{}),
+	}
+	if !authed {
+		// send is buffered, the result goes out once writeLoop starts
+		c.reply(auth, nil, nil)
+	}
+	go c.writeLoop()
+	c.readLoop()
+}
+
+// wsAuthenticate reads the first message of a connection
+// that has no Authorization header, it has to be an auth message
+func (s *Server) wsAuthenticate(ctx context.Context, conn *websocket.Conn) (wsMessage, users.JWTaccess, error) {
+	conn.SetReadLimit(wsReadLimit)
+	conn.SetReadDeadline(time.Now().Add(wsAuthWait))
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != wsTypeAuth {
+		return msg, users.JWTaccess{}, ErrWSAuthFirst
+	}
+	var data wsAuthData
+	if err := json.Unmarshal(msg.Data, &data); err != nil || data.AccessKey == "" {
+		return msg, users.JWTaccess{}, ErrWSAuthFirst
+	}
+	claims, err := s.usersService.UnpackAccessKey(ctx, data.AccessKey)
+	if err != nil {
+		return msg, users.JWTaccess{}, err
+	}
+	return msg, claims, nil
+}
+
+// wsReject closes a connection that is not ready for writeLoop yet
+func wsReject(conn *websocket.Conn, code int, err error) {
+	conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, err.Error()),
+		time.Now().Add(wsWriteWait),
+	)
+	conn.Close()
+}
+
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range strings.Split(s.corsOrigins, ",") {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// readLoop handles requests one by one, so results come in the same order
+func (c *wsConn) readLoop() {
+	defer func() {
+		c.sub.Close()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(wsReadLimit)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				c.reply(wsMessage{}, nil, ErrWSInvalidFormat)
+				continue
+			}
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		c.handle(msg)
+	}
+}
+
+// writeLoop is the only place where we write into connection
+func (c *wsConn) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	expiry := time.NewTimer(c.untilWarning())
+	defer func() {
+		ping.Stop()
+		expiry.Stop()
+		close(c.done)
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			if err := c.write(msg); err != nil {
+				return
+			}
+		case e, ok := <-c.sub.C():
+			if !ok {
+				// we were too slow or server is shutting down
+				c.close(websocket.CloseTryAgainLater, "")
+				return
+			}
+			topic, ok := c.matchTopic(e)
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if err := c.write(wsMessage{Type: wsTypeEvent, Topic: topic, Data: data}); err != nil {
+				return
+			}
+		case <-c.reauthed:
+			if !expiry.Stop() {
+				select {
+				case <-expiry.C:
+				default:
+				}
+			}
+			expiry.Reset(c.untilWarning())
+		case <-expiry.C:
+			// timer fires first when it is time to warn
+			// and second time when key has expired
+			exp := c.expiresAt()
+			if !time.Now().Before(exp) {
+				c.close(wsCloseUnauthorized, ErrWSKeyExpired.Error())
+				return
+			}
+			data, _ := json.Marshal(wsAuthExpiringData{ExpiresAt: exp})
+			if err := c.write(wsMessage{Type: wsTypeAuthExpiring, Data: data}); err != nil {
+				return
+			}
+			expiry.Reset(time.Until(exp))
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *wsConn) close(code int, reason string) {
+	c.conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(wsWriteWait),
+	)
+}
+
+func (c *wsConn) write(msg wsMessage) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *wsConn) expiresAt() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Unix(c.claims.ExpiresAt, 0)
+}
+
+// untilWarning is time left until we should ask for a new key
+func (c *wsConn) untilWarning() time.Duration {
+	return time.Until(c.expiresAt().Add(-wsExpiryWarning))
+}
+
+func (c *wsConn) userID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.claims.ID
+}
+
+func (c *wsConn) matchTopic(e events.Event) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e.Type == events.NotificationCreated || e.Type == events.NotificationRead {
+		_, ok := c.topics[wsTopicNotifications]
+		return wsTopicNotifications, ok
+	}
+	if _, ok := c.topics[wsTopicTodos]; ok {
+		return wsTopicTodos, true
+	}
+	topic := wsTopicTodoPrefix + e.TodoID
+	if _, ok := c.topics[topic]; ok && e.TodoID != "" {
+		return topic, true
+	}
+	topic = wsTopicListPrefix + e.ListID
+	if _, ok := c.topics[topic]; ok && e.ListID != "" {
+		return topic, true
+	}
+	return "", false
+}
+
+func (c *wsConn) reply(req wsMessage, data any, err error) {
+	msg := wsMessage{ID: req.ID, Type: wsTypeResult, Topic: req.Topic}
+	if err != nil {
+		if errs := c.s.validator.UnpackErrors(err); errs != nil {
+			msg.Errors = errs
+		} else {
+			msg.Errors = []string{err.Error()}
+		}
+	} else if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			msg.Errors = []string{err.Error()}
+		}
+		msg.Data = raw
+	}
+	c.push(msg)
+}
+
+// push never blocks after connection is gone
+func (c *wsConn) push(msg wsMessage) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	}
+}
+
+func (c *wsConn) handle(msg wsMessage) {
+	switch msg.Type {
+	case wsTypePing:
+		c.push(wsMessage{ID: msg.ID, Type: wsTypePong})
+		return
+	case wsTypeAuth:
+		c.reply(msg, nil, c.reauth(msg.Data))
+		return
+	}
+
+	if time.Now().After(c.expiresAt()) {
+		c.reply(msg, nil, ErrWSKeyExpired)
+		return
+	}
+
+	switch msg.Type {
+	case wsTypeSubscribe:
+		c.reply(msg, nil, c.subscribe(msg.Topic))
+	case wsTypeUnsubscribe:
+		c.mu.Lock()
+		delete(c.topics, msg.Topic)
+		c.mu.Unlock()
+		c.reply(msg, nil, nil)
+	case wsTypeTodosCreate, wsTypeTodosUpdate, wsTypeTodosComplete, wsTypeTodosIncomplete, wsTypeTodosDelete:
+		c.mutate(msg)
+	default:
+		c.reply(msg, nil, ErrWSUnknownType)
+	}
+}
+
+func (c *wsConn) reauth(raw json.RawMessage) error {
+	var data wsAuthData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return err
+	}
+	claims, err := c.s.usersService.UnpackAccessKey(c.ctx, data.AccessKey)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if claims.ID != c.claims.ID {
+		return ErrWSUserMismatch
+	}
+	c.claims = claims
+	select {
+	case c.reauthed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *wsConn) subscribe(topic string) error {
+	switch {
+	case topic == wsTopicTodos, topic == wsTopicNotifications:
+	case strings.HasPrefix(topic, wsTopicTodoPrefix):
+		// todos that user can't see are reported as missing
+		if _, err := c.s.todosService.Get(c.ctx, c.userID(), strings.TrimPrefix(topic, wsTopicTodoPrefix)); err != nil {
+			return err
+		}
+	case strings.HasPrefix(topic, wsTopicListPrefix):
+		if err := c.authorizeList(strings.TrimPrefix(topic, wsTopicListPrefix)); err != nil {
+			return err
+		}
+	default:
+		return ErrWSUnknownTopic
+	}
+	c.mu.Lock()
+	c.topics[topic] = struct{}{}
+	c.mu.Unlock()
+	return nil
+}
+
+// authorizeList lets only those who can view a list to follow its todos
+func (c *wsConn) authorizeList(id string) error {
+	_, err := c.s.listsService.Get(c.ctx, c.userID(), id)
+	return err
+}
+
+// mutate does the same things as REST handlers for todos
+func (c *wsConn) mutate(msg wsMessage) {
+	ctx := c.ctx
+	userID := c.userID()
+
+	if res, err := c.s.limiter.Allow(ctx, "todos:user:"+userID, c.s.limits.todos); err == nil && !res.Allowed {
+		c.reply(msg, nil, ErrRateLimited)
+		return
+	}
+
+	switch msg.Type {
+	case wsTypeTodosCreate:
+		var req reqTodosCreate
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			c.reply(msg, nil, err)
+			return
+		}
+		id, err := c.s.todosService.Create(ctx, todos.CreateInput{
+			UserID:   userID,
+			Title:    req.Title,
+			Body:     req.Body,
+			Deadline: req.Deadline,
+			ListID:   req.ListID,
+			Status:   todos.Status(req.Status),
+			Fields:   req.Fields,
+		})
+		if err != nil {
+			c.reply(msg, nil, err)
+			return
+		}
+		c.reply(msg, respTodosCreate{ID: id}, nil)
+	case wsTypeTodosUpdate:
+		var req wsTodosUpdateData
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			c.reply(msg, nil, err)
+			return
+		}
+		c.reply(msg, nil, c.s.todosService.Update(ctx, userID, todos.UpdateInput{
+			ID:       req.ID,
+			Title:    req.Title,
+			Body:     req.Body,
+			Deadline: req.Deadline,
+			ListID:   req.ListID,
+		}))
+	default:
+		var req wsTodoIDData
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			c.reply(msg, nil, err)
+			return
+		}
+		if req.ID == "" {
+			c.reply(msg, nil, ErrParamNotProvided)
+			return
+		}
+		var err error
+		switch msg.Type {
+		case wsTypeTodosComplete:
+			err = c.s.todosService.MarkAsComplete(ctx, userID, req.ID, req.Force)
+		case wsTypeTodosIncomplete:
+			err = c.s.todosService.MarkAsNotComplete(ctx, userID, req.ID)
+		case wsTypeTodosDelete:
+			err = c.s.todosService.Delete(ctx, userID, req.ID)
+		}
+		if err != nil {
+			defer c.s.logger.Sync()
+			c.s.logger.Debug("resthttp: wsConn.mutate(): "+msg.Type+" failed", logging.String("error", err.Error()))
+		}
+		c.reply(msg, nil, err)
+	}
+}
@@ -0,0 +1,83 @@
+package resthttp
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/websocket"
+	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/memory"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
+)
+
+type tokenUsersRepo struct{ users.Repository }
+
+func (tokenUsersRepo) TokenVersion(ctx context.Context, id string) (int, error) {
+	return 0, nil
+}
+
+func TestWebSocketAuthenticatesWithFirstMessage(t *testing.T) {
+	secret := []byte("secret")
+	s, router := newTestRouter(t, config.Config{})
+	usersService, err := users.NewService(tokenUsersRepo{}, memory.NewStore().Attempts(), logging.NewNop(), nil, secret, users.LockoutPolicy{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.usersService = usersService
+	s.events = events.NewBus(16)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	key, err := jwt.NewWithClaims(jwt.SigningMethodHS256, users.JWTaccess{
+		ID:             "u1",
+		Role:           users.RoleUser,
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := strings.Replace(ts.URL, "http", "ws", 1) + "/api/ws"
+
+	tests := []struct {
+		name  string
+		query string
+		first string
+		// close code we expect after the first message, zero if it is accepted
+		want int
+	}{
+		{name: "auth message", first: `{"id":"1","type":"auth","data":{"accessKey":"` + key + `"}}`},
+		{name: "invalid key", first: `{"id":"1","type":"auth","data":{"accessKey":"nope"}}`, want: wsCloseUnauthorized},
+		{name: "no auth message", first: `{"id":"1","type":"subscribe","topic":"todos"}`, want: wsCloseUnauthorized},
+		{name: "key in query", query: "?accessKey=" + key, first: `{"id":"1","type":"subscribe","topic":"todos"}`, want: wsCloseUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, _, err := websocket.DefaultDialer.Dial(url+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.first)); err != nil {
+				t.Fatal(err)
+			}
+
+			var msg wsMessage
+			err = conn.ReadJSON(&msg)
+			var closeErr *websocket.CloseError
+			switch {
+			case tt.want != 0 && (!errors.As(err, &closeErr) || closeErr.Code != tt.want):
+				t.Errorf("expected connection to be closed with %d, got %v", tt.want, err)
+			case tt.want == 0 && (err != nil || msg.ID != "1" || msg.Type != wsTypeResult || len(msg.Errors) != 0):
+				t.Errorf("expected auth to succeed, got %v with %+v", err, msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,710 @@
+package resthttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+)
+
+type (
+	// reqWorkspacesSave
+	// This is a model used for creating and renaming workspaces
+	// swagger:model
+	reqWorkspacesSave struct {
+		// required: true
+		// example: Acme Inc.
+		// max length: 100
+		Name string `json:"name"`
+	}
+
+	// reqWorkspacesSetRole
+	// This is a model used for changing roles of members
+	// swagger:model
+	reqWorkspacesSetRole struct {
+		// Variations: [admin, member]
+		// required: true
+		// example: admin
+		Role workspaces.Role `json:"role"`
+	}
+
+	// reqWorkspacesInvite
+	// This is a model used for inviting people to workspaces
+	// swagger:model
+	reqWorkspacesInvite struct {
+		// They don't need an account yet, they can sign up with this email
+		// required: true
+		// example: colleague@example.com
+		Email string `json:"email"`
+
+		// Variations: [admin, member]
+		// required: true
+		// example: member
+		Role workspaces.Role `json:"role"`
+	}
+)
+
+func (s *Server) workspacesError(ctx *gin.Context, err error) {
+	if errs := s.validator.UnpackErrors(err); errs != nil {
+		respond(ctx, http.StatusBadRequest, nil, errs)
+		return
+	}
+	respond(ctx, workspacesStatus(err), nil, []string{err.Error()})
+}
+
+func workspacesStatus(err error) int {
+	switch {
+	case errors.Is(err, workspaces.ErrNoSuchWorkspace),
+		errors.Is(err, workspaces.ErrNoSuchMember),
+		errors.Is(err, workspaces.ErrNoSuchInvitation),
+		errors.Is(err, users.ErrNoSuchUser):
+		return http.StatusNotFound
+	case errors.Is(err, workspaces.ErrNotAllowed), errors.Is(err, workspaces.ErrOwner):
+		return http.StatusForbidden
+	case errors.Is(err, workspaces.ErrAlreadyMember),
+		errors.Is(err, workspaces.ErrAlreadyInvited),
+		errors.Is(err, workspaces.ErrNotPending):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func bindWorkspaces(ctx *gin.Context, req interface{}) bool {
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		if errors.Is(err, io.EOF) {
+			respond(ctx, http.StatusBadRequest, nil, []string{ErrRequestBodyNotProvided.Error()})
+			return false
+		}
+		respond(ctx, http.StatusBadRequest, nil, []string{err.Error()})
+		return false
+	}
+	return true
+}
+
+// swagger:route POST /workspaces workspaces WorkspacesCreate
+//
+// Create a workspace
+//
+// Workspaces let teams work on the same todos and lists. Send the id
+// of a workspace in the X-Workspace-ID header to work in it, requests
+// without the header work in your personal space.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: workspace
+//         in: body
+//         required: true
+//         type: reqWorkspacesSave
+//
+//     Responses:
+//       201: description: workspace
+//       400: stdResponse
+func (s *Server) WorkspacesCreate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := reqWorkspacesSave{}
+	if !bindWorkspaces(ctx, &req) {
+		return
+	}
+
+	w, err := s.workspacesService.Create(ctx, workspaces.CreateInput{UserID: u.ID, Name: req.Name})
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, w, nil)
+}
+
+// swagger:route GET /workspaces workspaces WorkspacesGetAll
+//
+// Get my workspaces
+//
+// Every workspace comes with your role in it.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: workspaces
+func (s *Server) WorkspacesGetAll(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.workspacesService.GetAll(ctx, u.ID)
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route GET /workspaces/{id} workspaces WorkspacesGet
+//
+// Get a workspace
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: workspace
+//       404: stdResponse
+func (s *Server) WorkspacesGet(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	w, err := s.workspacesService.Get(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, w, nil)
+}
+
+// swagger:route PATCH /workspaces/{id} workspaces WorkspacesUpdate
+//
+// Rename a workspace
+//
+// Only admins can do that.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: workspace
+//         in: body
+//         required: true
+//         type: reqWorkspacesSave
+//
+//     Responses:
+//       200: description: workspace
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) WorkspacesUpdate(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := reqWorkspacesSave{}
+	if !bindWorkspaces(ctx, &req) {
+		return
+	}
+
+	w, err := s.workspacesService.Update(ctx, u.ID, workspaces.UpdateInput{ID: ctx.Param("id"), Name: req.Name})
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, w, nil)
+}
+
+// swagger:route DELETE /workspaces/{id} workspaces WorkspacesDelete
+//
+// Delete a workspace
+//
+// Only the owner can do that. All todos and lists
+// of the workspace are deleted with it.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) WorkspacesDelete(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.workspacesService.Delete(ctx, u.ID, ctx.Param("id")); err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route GET /workspaces/{id}/members workspaces WorkspacesGetMembers
+//
+// Get members of a workspace
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: members
+//       404: stdResponse
+func (s *Server) WorkspacesGetMembers(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	members, err := s.workspacesService.GetMembers(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, members, nil)
+}
+
+// swagger:route PATCH /workspaces/{id}/members/{userId} workspaces WorkspacesSetRole
+//
+// Change the role of a member
+//
+// Only admins can do that. Nobody can change the role of the owner.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: userId
+//         in: params
+//         required: true
+//         type: string
+//       + name: role
+//         in: body
+//         required: true
+//         type: reqWorkspacesSetRole
+//
+//     Responses:
+//       200: description: member
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) WorkspacesSetRole(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := reqWorkspacesSetRole{}
+	if !bindWorkspaces(ctx, &req) {
+		return
+	}
+
+	m, err := s.workspacesService.SetRole(ctx, u.ID, workspaces.SetRoleInput{
+		WorkspaceID: ctx.Param("id"),
+		UserID:      ctx.Param("userId"),
+		Role:        req.Role,
+	})
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, m, nil)
+}
+
+// swagger:route DELETE /workspaces/{id}/members/{userId} workspaces WorkspacesRemoveMember
+//
+// Remove a member from a workspace
+//
+// Admins can remove members and members can leave by removing themselves.
+// The owner can't leave, the workspace has to be deleted instead.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: userId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) WorkspacesRemoveMember(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	if err := s.workspacesService.RemoveMember(ctx, u.ID, ctx.Param("id"), ctx.Param("userId")); err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route POST /workspaces/{id}/invitations workspaces WorkspacesInvite
+//
+// Invite someone to a workspace
+//
+// Only admins can do that. The invitation is sent by email,
+// people who already have an account get a notification too.
+//
+//     Consumes:
+//     - application/json
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: invitation
+//         in: body
+//         required: true
+//         type: reqWorkspacesInvite
+//
+//     Responses:
+//       201: description: invitation
+//       400: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) WorkspacesInvite(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+	req := reqWorkspacesInvite{}
+	if !bindWorkspaces(ctx, &req) {
+		return
+	}
+
+	inv, err := s.workspacesService.Invite(ctx, workspaces.InviteInput{
+		WorkspaceID: ctx.Param("id"),
+		InviterID:   u.ID,
+		Email:       req.Email,
+		Role:        req.Role,
+	})
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusCreated, inv, nil)
+}
+
+// swagger:route GET /workspaces/{id}/invitations workspaces WorkspacesGetInvitations
+//
+// Get pending invitations of a workspace
+//
+// Only admins can do that.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: invitations
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) WorkspacesGetInvitations(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.workspacesService.GetInvitations(ctx, u.ID, ctx.Param("id"))
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route DELETE /workspaces/{id}/invitations/{invitationId} workspaces WorkspacesRevokeInvitation
+//
+// Revoke an invitation
+//
+// Only admins can do that.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//       + name: invitationId
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: stdResponse
+//       403: stdResponse
+//       404: stdResponse
+func (s *Server) WorkspacesRevokeInvitation(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	err = s.workspacesService.RevokeInvitation(ctx, u.ID, ctx.Param("id"), ctx.Param("invitationId"))
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, nil, nil)
+}
+
+// swagger:route GET /workspaces/invitations workspaces WorkspacesGetMyInvitations
+//
+// Get my pending invitations to workspaces
+//
+// These are invitations sent to the email of your account.
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Responses:
+//       200: description: invitations
+func (s *Server) WorkspacesGetMyInvitations(ctx *gin.Context) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	list, err := s.workspacesService.GetMyInvitations(ctx, u.ID)
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, list, nil)
+}
+
+// swagger:route PUT /workspaces/invitations/{id}/accept workspaces WorkspacesAcceptInvitation
+//
+// Accept an invitation to a workspace
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: invitation
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) WorkspacesAcceptInvitation(ctx *gin.Context) {
+	s.workspacesRespond(ctx, true)
+}
+
+// swagger:route PUT /workspaces/invitations/{id}/decline workspaces WorkspacesDeclineInvitation
+//
+// Decline an invitation to a workspace
+//
+//     Produces:
+//     - application/json
+//
+//     Schemes: http, https
+//
+//     Deprecated: false
+//
+//     Security:
+//      - Bearer: []
+//
+//     Parameters:
+//       + name: id
+//         in: params
+//         required: true
+//         type: string
+//
+//     Responses:
+//       200: description: invitation
+//       404: stdResponse
+//       409: stdResponse
+func (s *Server) WorkspacesDeclineInvitation(ctx *gin.Context) {
+	s.workspacesRespond(ctx, false)
+}
+
+func (s *Server) workspacesRespond(ctx *gin.Context, accept bool) {
+	u, err := getUserData(ctx)
+	if err != nil {
+		respond(ctx, http.StatusUnauthorized, nil, []string{err.Error()})
+		return
+	}
+
+	inv, err := s.workspacesService.Respond(ctx, workspaces.RespondInput{
+		UserID: u.ID,
+		ID:     ctx.Param("id"),
+		Accept: accept,
+	})
+	if err != nil {
+		s.workspacesError(ctx, err)
+		return
+	}
+
+	respond(ctx, http.StatusOK, inv, nil)
+}
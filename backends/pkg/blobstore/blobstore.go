@@ -0,0 +1,33 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// Store keeps blobs of bytes by keys. Keys look like paths,
+// e.g. "attachments/<todo id>/<attachment id>". We have a local
+// filesystem store for development and an S3 compatible one.
+type Store interface {
+	// Put overwrites a blob if it already exists. Size is a
+	// number of bytes in r, stores might need it upfront.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get should return ErrNotFound if there is no such blob
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete does nothing if there is no such blob
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey doesn't let keys escape a directory or a bucket
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,8 @@
+package blobstore
+
+import "errors"
+
+var (
+	ErrNotFound   = errors.New("blobstore: no such blob")
+	ErrInvalidKey = errors.New("blobstore: invalid key")
+)
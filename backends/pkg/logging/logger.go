@@ -100,6 +100,14 @@ func NewLogger(cfg config.Config) (*Logger, error) {
 	return &logger, nil
 }
 
+// NewNop returns a logger that writes nothing, it is handy in tests
+func NewNop() *Logger {
+	return &Logger{
+		logger: zap.NewNop(),
+		closer: func() error { return nil },
+	}
+}
+
 func (l *Logger) Close() error {
 	return l.closer()
 }
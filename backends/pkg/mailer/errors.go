@@ -0,0 +1,7 @@
+package mailer
+
+import "errors"
+
+var (
+	ErrNoRecipients = errors.New("mailer: message has no recipients")
+)
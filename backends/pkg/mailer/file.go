@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+type fileMailer struct {
+	dir  string
+	from string
+	seq  uint64
+}
+
+// NewFile returns a mailer that writes every message to its own
+// .eml file in dir instead of sending it. Files can be opened
+// with any mail client.
+func NewFile(dir, from string) Mailer {
+	return &fileMailer{dir: dir, from: from}
+}
+
+func (f *fileMailer) Send(ctx context.Context, m Message) error {
+	msg, err := build(f.from, m)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.dir, 0700); err != nil {
+		return err
+	}
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + "-" +
+		strconv.FormatUint(atomic.AddUint64(&f.seq, 1), 10) + ".eml"
+	return os.WriteFile(filepath.Join(f.dir, name), msg, 0600)
+}
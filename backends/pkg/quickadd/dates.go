@@ -0,0 +1,388 @@
+package quickadd
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// deadlines with only a date are at the end of their day
+	endOfDayHour, endOfDayMinute = 23, 59
+	// tonight is a date with this hour
+	tonightHour = 20
+)
+
+var (
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+	dayPattern   = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+
+	weekdays = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+
+	// weekdayAbbrs are also plain words of titles like "Buy sun cream"
+	// and "Fix sat nav", so they are days only after on, by, due, next or every
+	weekdayAbbrs = map[string]time.Weekday{
+		"sun": time.Sunday,
+		"mon": time.Monday,
+		"tue": time.Tuesday, "tues": time.Tuesday,
+		"wed": time.Wednesday,
+		"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+		"fri": time.Friday,
+		"sat": time.Saturday,
+	}
+
+	months = map[string]time.Month{
+		"january": time.January, "jan": time.January,
+		"february": time.February, "feb": time.February,
+		"march": time.March, "mar": time.March,
+		"april": time.April, "apr": time.April,
+		"may":  time.May,
+		"june": time.June, "jun": time.June,
+		"july": time.July, "jul": time.July,
+		"august": time.August, "aug": time.August,
+		"september": time.September, "sep": time.September, "sept": time.September,
+		"october": time.October, "oct": time.October,
+		"november": time.November, "nov": time.November,
+		"december": time.December, "dec": time.December,
+	}
+
+	// periods are units of recurrences
+	periods = map[string]Frequency{
+		"day": Daily, "days": Daily,
+		"week": Weekly, "weeks": Weekly,
+		"month": Monthly, "months": Monthly,
+		"year": Yearly, "years": Yearly,
+	}
+
+	adverbs = map[string]Frequency{
+		"daily":    Daily,
+		"weekly":   Weekly,
+		"monthly":  Monthly,
+		"yearly":   Yearly,
+		"annually": Yearly,
+	}
+)
+
+// recurrence reads "every month", "every 2 weeks", "every other monday" and "daily"
+func (p *parser) recurrence(i int) int {
+	if p.res.Recurrence != nil {
+		return 0
+	}
+	if f, ok := adverbs[p.key(i)]; ok {
+		p.res.Recurrence = &Recurrence{Frequency: f, Interval: 1}
+		return 1
+	}
+	if p.key(i) != "every" {
+		return 0
+	}
+	interval, n := 1, 1
+	if k := p.key(i + 1); k == "other" {
+		interval, n = 2, 2
+	} else if c, ok := count(k); ok {
+		interval, n = c, 2
+	}
+	if f, ok := periods[p.key(i+n)]; ok {
+		p.res.Recurrence = &Recurrence{Frequency: f, Interval: interval}
+		return n + 1
+	}
+	if d, ok := weekday(p.key(i+n), true); ok {
+		p.res.Recurrence = &Recurrence{Frequency: Weekly, Interval: interval, Weekday: &d}
+		return n + 1
+	}
+	return 0
+}
+
+// when reads a date or a time with a preposition before it
+func (p *parser) when(i int) int {
+	switch p.key(i) {
+	case "on", "by", "due":
+		if n := p.date(i+1, true); n > 0 {
+			return n + 1
+		}
+		if n := p.clock(i+1, false); n > 0 {
+			return n + 1
+		}
+		return 0
+	case "at":
+		if n := p.clock(i+1, true); n > 0 {
+			return n + 1
+		}
+		return 0
+	}
+	if n := p.date(i, false); n > 0 {
+		return n
+	}
+	return p.clock(i, false)
+}
+
+// date reads a day, abbreviated weekdays only after a preposition
+func (p *parser) date(i int, prep bool) int {
+	if !p.day.IsZero() || !p.exact.IsZero() {
+		return 0
+	}
+	today := midnight(p.now)
+	switch k := p.key(i); k {
+	case "today":
+		p.day = today
+		return 1
+	case "tonight":
+		p.day = today
+		if !p.hasTime {
+			p.hour, p.minute, p.hasTime = tonightHour, 0, true
+		}
+		return 1
+	case "tomorrow", "tmrw", "tmr":
+		p.day = today.AddDate(0, 0, 1)
+		return 1
+	case "next":
+		return p.next(i, today)
+	case "in":
+		return p.in(i, today)
+	}
+	if d, ok := weekday(p.key(i), prep); ok {
+		p.day, p.weekday = nearest(today, d), true
+		return 1
+	}
+	if t, err := time.ParseInLocation("2006-01-02", p.key(i), p.now.Location()); err == nil {
+		p.day = t
+		return 1
+	}
+	return p.monthDay(i, today)
+}
+
+// next reads "next week", "next month", "next year" that are their first
+// days and "next friday" that is a week after the nearest friday
+func (p *parser) next(i int, today time.Time) int {
+	switch p.key(i + 1) {
+	case "week":
+		days := (int(time.Monday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		p.day = today.AddDate(0, 0, days)
+		return 2
+	case "month":
+		p.day = time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location())
+		return 2
+	case "year":
+		p.day = time.Date(today.Year()+1, time.January, 1, 0, 0, 0, 0, today.Location())
+		return 2
+	}
+	if d, ok := weekday(p.key(i+1), true); ok {
+		p.day = nearest(today, d).AddDate(0, 0, 7)
+		return 2
+	}
+	return 0
+}
+
+// in reads "in 3 days", "in a week" and "in 2 hours"
+func (p *parser) in(i int, today time.Time) int {
+	n, ok := count(p.key(i + 1))
+	if !ok {
+		return 0
+	}
+	switch p.key(i + 2) {
+	case "minute", "minutes", "min", "mins":
+		if p.hasTime {
+			return 0
+		}
+		p.exact = p.now.Add(time.Duration(n) * time.Minute)
+	case "hour", "hours", "hr", "hrs":
+		if p.hasTime {
+			return 0
+		}
+		p.exact = p.now.Add(time.Duration(n) * time.Hour)
+	case "day", "days":
+		p.day = today.AddDate(0, 0, n)
+	case "week", "weeks":
+		p.day = today.AddDate(0, 0, 7*n)
+	case "month", "months":
+		p.day = today.AddDate(0, n, 0)
+	case "year", "years":
+		p.day = today.AddDate(n, 0, 0)
+	default:
+		return 0
+	}
+	return 3
+}
+
+// monthDay reads "nov 7", "7th of november" and "november 7 2023". Dates
+// without a year that have already passed this year are next year.
+func (p *parser) monthDay(i int, today time.Time) int {
+	var (
+		m   time.Month
+		day int
+		n   int
+	)
+	if mm, ok := months[p.key(i)]; ok {
+		if d, ok := dayOf(p.key(i + 1)); ok {
+			m, day, n = mm, d, 2
+		}
+	} else if d, ok := dayOf(p.key(i)); ok {
+		of := 0
+		if p.key(i+1) == "of" {
+			of = 1
+		}
+		if mm, ok := months[p.key(i+1+of)]; ok {
+			m, day, n = mm, d, 2+of
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	year, explicit := today.Year(), false
+	if k := p.key(i + n); len(k) == 4 {
+		if y, err := strconv.Atoi(k); err == nil {
+			year, explicit = y, true
+			n++
+		}
+	}
+	t, ok := dateOf(year, m, day, today.Location())
+	if !ok {
+		return 0
+	}
+	if !explicit && t.Before(today) {
+		if t, ok = dateOf(year+1, m, day, today.Location()); !ok {
+			return 0
+		}
+	}
+	p.day = t
+	return n
+}
+
+// clock reads "9am", "9:30 pm", "21:00" and "noon". Plain numbers are
+// hours only after "at", otherwise they are parts of titles.
+func (p *parser) clock(i int, at bool) int {
+	if p.hasTime || !p.exact.IsZero() {
+		return 0
+	}
+	k := p.key(i)
+	if k == "noon" {
+		p.hour, p.minute, p.hasTime = 12, 0, true
+		return 1
+	}
+	m := clockPattern.FindStringSubmatch(k)
+	if m == nil {
+		return 0
+	}
+	n, suffix := 1, m[3]
+	if s := p.key(i + 1); suffix == "" && (s == "am" || s == "pm") {
+		n, suffix = 2, s
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute := 0
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	switch {
+	case minute > 59:
+		return 0
+	case suffix != "":
+		if hour < 1 || hour > 12 {
+			return 0
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	case m[2] != "" || at:
+		if hour > 23 {
+			return 0
+		}
+	default:
+		return 0
+	}
+	p.hour, p.minute, p.hasTime = hour, minute, true
+	return n
+}
+
+// deadline puts date and time together. A time without a date is the next
+// time the clock shows it, weekly recurrences on a day start on that day.
+func (p *parser) deadline() *time.Time {
+	if !p.exact.IsZero() {
+		return &p.exact
+	}
+	day := p.day
+	if r := p.res.Recurrence; day.IsZero() && r != nil && r.Weekday != nil {
+		day, p.weekday = nearest(midnight(p.now), *r.Weekday), true
+	}
+	if day.IsZero() && !p.hasTime {
+		return nil
+	}
+	hour, minute := endOfDayHour, endOfDayMinute
+	if p.hasTime {
+		hour, minute = p.hour, p.minute
+	}
+	if day.IsZero() {
+		t := clockAt(midnight(p.now), hour, minute)
+		if !t.After(p.now) {
+			t = clockAt(midnight(p.now).AddDate(0, 0, 1), hour, minute)
+		}
+		return &t
+	}
+	t := clockAt(day, hour, minute)
+	if p.weekday && !t.After(p.now) {
+		t = clockAt(day.AddDate(0, 0, 7), hour, minute)
+	}
+	return &t
+}
+
+// count reads small numbers of "in 3 days" and "every 2 weeks",
+// "a" and "an" are ones
+func count(k string) (int, bool) {
+	if k == "a" || k == "an" {
+		return 1, true
+	}
+	if len(k) > 3 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(k)
+	return n, err == nil && n > 0
+}
+
+func weekday(k string, abbr bool) (time.Weekday, bool) {
+	if d, ok := weekdays[k]; ok {
+		return d, true
+	}
+	if !abbr {
+		return 0, false
+	}
+	d, ok := weekdayAbbrs[k]
+	return d, ok
+}
+
+func dayOf(k string) (int, bool) {
+	m := dayPattern.FindStringSubmatch(k)
+	if m == nil {
+		return 0, false
+	}
+	d, _ := strconv.Atoi(m[1])
+	return d, d >= 1 && d <= 31
+}
+
+// dateOf is false for days a month doesn't have
+func dateOf(year int, m time.Month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, m, day, 0, 0, 0, 0, loc)
+	return t, t.Month() == m
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func clockAt(day time.Time, hour, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+// nearest returns the first day on a weekday starting from today
+func nearest(today time.Time, d time.Weekday) time.Time {
+	return today.AddDate(0, 0, (int(d)-int(today.Weekday())+7)%7)
+}
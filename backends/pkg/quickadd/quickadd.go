@@ -0,0 +1,190 @@
+// Package quickadd parses a single line like
+// "Pay rent tomorrow 9am #home !high every month" into parts of a todo.
+// Words it understands are taken out of the title, words in double quotes
+// are never parsed.
+package quickadd
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+	Yearly  Frequency = "yearly"
+)
+
+type (
+	Priority  string
+	Frequency string
+
+	Recurrence struct {
+		Frequency Frequency `json:"frequency"`
+		// Interval is how many periods pass between todos,
+		// 2 means every other day, week and so on
+		Interval int `json:"interval"`
+		// Weekday is set for weekly recurrences on a certain day
+		Weekday *time.Weekday `json:"weekday,omitempty"`
+	}
+
+	// Result is what a line means. Title is what is left after everything
+	// else is taken out of the line, it can be empty.
+	Result struct {
+		Title      string      `json:"title"`
+		Deadline   *time.Time  `json:"deadline,omitempty"`
+		Tags       []string    `json:"tags,omitempty"`
+		Priority   Priority    `json:"priority,omitempty"`
+		Recurrence *Recurrence `json:"recurrence,omitempty"`
+	}
+)
+
+var (
+	tagPattern = regexp.MustCompile(`^#[\p{L}\p{N}_-]+$`)
+
+	priorities = map[string]Priority{
+		"!low":    PriorityLow,
+		"!medium": PriorityMedium,
+		"!med":    PriorityMedium,
+		"!high":   PriorityHigh,
+		"!urgent": PriorityUrgent,
+	}
+)
+
+type (
+	word struct {
+		// text is the word as it was typed
+		text string
+		// key is the word in lower case without punctuation after it,
+		// it is empty for words in quotes
+		key string
+	}
+
+	parser struct {
+		words []word
+		used  []bool
+		now   time.Time
+
+		// day is midnight of the deadline, zero if line has no date
+		day time.Time
+		// weekday tells that day came from a name of a weekday,
+		// it moves a week forward if its time has already passed
+		weekday      bool
+		hour, minute int
+		hasTime      bool
+		// exact is a moment like "in 2 hours", it has both date and time
+		exact time.Time
+
+		res Result
+	}
+)
+
+// Parse reads a line typed at now. Dates and times are in the location
+// of now, deadlines without a time are at the end of their day. Only the
+// first date, time, priority and recurrence count, repeated ones stay in
+// the title.
+func Parse(line string, now time.Time) Result {
+	p := &parser{words: split(line), now: now}
+	p.used = make([]bool, len(p.words))
+	for i := 0; i < len(p.words); i++ {
+		if p.words[i].key == "" {
+			continue
+		}
+		for _, match := range []func(int) int{p.tag, p.priority, p.recurrence, p.when} {
+			if n := match(i); n > 0 {
+				for j := i; j < i+n; j++ {
+					p.used[j] = true
+				}
+				i += n - 1
+				break
+			}
+		}
+	}
+	p.res.Title = p.title()
+	p.res.Deadline = p.deadline()
+	return p.res
+}
+
+// split breaks a line into words, spaces in double quotes don't break it
+func split(line string) []word {
+	var (
+		words  []word
+		b      strings.Builder
+		quoted bool
+	)
+	flush := func() {
+		text := b.String()
+		b.Reset()
+		if text == "" {
+			return
+		}
+		w := word{text: text}
+		if !strings.Contains(text, `"`) {
+			w.key = strings.ToLower(strings.TrimRight(text, ",.;:!?"))
+		}
+		words = append(words, w)
+	}
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			b.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// key returns a key of a word or nothing if there is no such word
+func (p *parser) key(i int) string {
+	if i >= len(p.words) || p.used[i] {
+		return ""
+	}
+	return p.words[i].key
+}
+
+func (p *parser) title() string {
+	var rest []string
+	for i, w := range p.words {
+		if !p.used[i] {
+			rest = append(rest, w.text)
+		}
+	}
+	return strings.Join(rest, " ")
+}
+
+func (p *parser) tag(i int) int {
+	k := p.key(i)
+	if !tagPattern.MatchString(k) {
+		return 0
+	}
+	tag := k[1:]
+	for _, t := range p.res.Tags {
+		if t == tag {
+			return 1
+		}
+	}
+	p.res.Tags = append(p.res.Tags, tag)
+	return 1
+}
+
+func (p *parser) priority(i int) int {
+	pr, ok := priorities[p.key(i)]
+	if !ok || p.res.Priority != "" {
+		return 0
+	}
+	p.res.Priority = pr
+	return 1
+}
@@ -0,0 +1,137 @@
+package quickadd
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	loc := time.FixedZone("Asia/Bishkek", 6*60*60)
+	// it is Wednesday morning
+	now := time.Date(2022, 11, 9, 10, 0, 0, 0, loc)
+	at := func(month time.Month, day, hour, minute int) *time.Time {
+		t := time.Date(2022, month, day, hour, minute, 0, 0, loc)
+		return &t
+	}
+	nextYear := func(month time.Month, day, hour, minute int) *time.Time {
+		t := time.Date(2023, month, day, hour, minute, 0, 0, loc)
+		return &t
+	}
+	monday, saturday := time.Monday, time.Saturday
+
+	tests := []struct {
+		line string
+		want Result
+	}{
+		{"", Result{}},
+		{"Call mom", Result{Title: "Call mom"}},
+		{
+			"Pay rent tomorrow 9am #home !high every month",
+			Result{
+				Title:      "Pay rent",
+				Deadline:   at(11, 10, 9, 0),
+				Tags:       []string{"home"},
+				Priority:   PriorityHigh,
+				Recurrence: &Recurrence{Frequency: Monthly, Interval: 1},
+			},
+		},
+
+		// dates
+		{"Call mom today", Result{Title: "Call mom", Deadline: at(11, 9, 23, 59)}},
+		{"Call mom tmrw", Result{Title: "Call mom", Deadline: at(11, 10, 23, 59)}},
+		{"Finish report tonight", Result{Title: "Finish report", Deadline: at(11, 9, 20, 0)}},
+		{"Review wednesday", Result{Title: "Review", Deadline: at(11, 9, 23, 59)}},
+		{"Dentist friday 3pm", Result{Title: "Dentist", Deadline: at(11, 11, 15, 0)}},
+		{"Dentist on Fri", Result{Title: "Dentist", Deadline: at(11, 11, 23, 59)}},
+		{"Send invoice by tue", Result{Title: "Send invoice", Deadline: at(11, 15, 23, 59)}},
+		{"Demo due thurs 2pm", Result{Title: "Demo", Deadline: at(11, 10, 14, 0)}},
+		{"Retro next fri", Result{Title: "Retro", Deadline: at(11, 18, 23, 59)}},
+		{"Buy sun cream", Result{Title: "Buy sun cream"}},
+		{"Fix sat nav", Result{Title: "Fix sat nav"}},
+		{"Pack wed dress", Result{Title: "Pack wed dress"}},
+		{"Review on wednesday 9am", Result{Title: "Review", Deadline: at(11, 16, 9, 0)}},
+		{"Retro next friday", Result{Title: "Retro", Deadline: at(11, 18, 23, 59)}},
+		{"Plan next week", Result{Title: "Plan", Deadline: at(11, 14, 23, 59)}},
+		{"Budget next month", Result{Title: "Budget", Deadline: at(12, 1, 23, 59)}},
+		{"Taxes next year", Result{Title: "Taxes", Deadline: nextYear(1, 1, 23, 59)}},
+		{"Ship it in 3 days", Result{Title: "Ship it", Deadline: at(11, 12, 23, 59)}},
+		{"Ship it in a week", Result{Title: "Ship it", Deadline: at(11, 16, 23, 59)}},
+		{"Renew passport in 2 months", Result{Title: "Renew passport", Deadline: nextYear(1, 9, 23, 59)}},
+		{"Check oven in 20 minutes", Result{Title: "Check oven", Deadline: at(11, 9, 10, 20)}},
+		{"Call back in an hour", Result{Title: "Call back", Deadline: at(11, 9, 11, 0)}},
+		{"Birthday party dec 24th 7pm", Result{Title: "Birthday party", Deadline: at(12, 24, 19, 0)}},
+		{"Exam 3rd of march", Result{Title: "Exam", Deadline: nextYear(3, 3, 23, 59)}},
+		{"Conference nov 1", Result{Title: "Conference", Deadline: nextYear(11, 1, 23, 59)}},
+		{"Conference November 1 2022", Result{Title: "Conference", Deadline: at(11, 1, 23, 59)}},
+		{"Launch 2022-12-01 10:00", Result{Title: "Launch", Deadline: at(12, 1, 10, 0)}},
+		{"Deadline feb 30", Result{Title: "Deadline feb 30"}},
+		{"Bake tomorrow today", Result{Title: "Bake today", Deadline: at(11, 10, 23, 59)}},
+		{"Call tomorrow, then relax", Result{Title: "Call then relax", Deadline: at(11, 10, 23, 59)}},
+
+		// times
+		{"Standup at 9", Result{Title: "Standup", Deadline: at(11, 10, 9, 0)}},
+		{"Lunch at noon", Result{Title: "Lunch", Deadline: at(11, 9, 12, 0)}},
+		{"Gym 18:30", Result{Title: "Gym", Deadline: at(11, 9, 18, 30)}},
+		{"Pay by 5pm", Result{Title: "Pay", Deadline: at(11, 9, 17, 0)}},
+		{"Pay Monday 9:30 AM", Result{Title: "Pay", Deadline: at(11, 14, 9, 30)}},
+		{"Midnight snack 12am", Result{Title: "Midnight snack", Deadline: at(11, 10, 0, 0)}},
+		{"Read chapter 9", Result{Title: "Read chapter 9"}},
+		{"Meet tomorrow at 25", Result{Title: "Meet at 25", Deadline: at(11, 10, 23, 59)}},
+		{"Meet 13pm", Result{Title: "Meet 13pm"}},
+
+		// tags and priorities
+		{"Email #Work #c++", Result{Title: "Email #c++", Tags: []string{"work"}}},
+		{"Weed #home #garden #home", Result{Title: "Weed", Tags: []string{"home", "garden"}}},
+		{"Report !urgent !low", Result{Title: "Report !low", Priority: PriorityUrgent}},
+		{"Report !med", Result{Title: "Report", Priority: PriorityMedium}},
+		{"Wow !nice", Result{Title: "Wow !nice"}},
+
+		// recurrences
+		{"Backup weekly", Result{Title: "Backup", Recurrence: &Recurrence{Frequency: Weekly, Interval: 1}}},
+		{"Water plants every other day", Result{Title: "Water plants", Recurrence: &Recurrence{Frequency: Daily, Interval: 2}}},
+		{"Report every 3 weeks", Result{Title: "Report", Recurrence: &Recurrence{Frequency: Weekly, Interval: 3}}},
+		{
+			"Team sync every monday 10am",
+			Result{
+				Title:      "Team sync",
+				Deadline:   at(11, 14, 10, 0),
+				Recurrence: &Recurrence{Frequency: Weekly, Interval: 1, Weekday: &monday},
+			},
+		},
+		{
+			"Water plants every sat",
+			Result{
+				Title:      "Water plants",
+				Deadline:   at(11, 12, 23, 59),
+				Recurrence: &Recurrence{Frequency: Weekly, Interval: 1, Weekday: &saturday},
+			},
+		},
+		{"Tell everyone", Result{Title: "Tell everyone"}},
+		{"Do it every time", Result{Title: "Do it every time"}},
+
+		// quotes
+		{`Watch "next friday" movie`, Result{Title: `Watch "next friday" movie`}},
+		{`Watch "Dune" friday`, Result{Title: `Watch "Dune"`, Deadline: at(11, 11, 23, 59)}},
+	}
+	for _, tt := range tests {
+		got := Parse(tt.line, now)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) =\n%s\nwant\n%s", tt.line, describe(got), describe(tt.want))
+		}
+	}
+}
+
+func describe(r Result) string {
+	deadline := "none"
+	if r.Deadline != nil {
+		deadline = r.Deadline.Format(time.RFC3339)
+	}
+	recurrence := "none"
+	if r.Recurrence != nil {
+		recurrence = fmt.Sprintf("%+v", *r.Recurrence)
+	}
+	return fmt.Sprintf("title %q, deadline %s, tags %v, priority %q, recurrence %s",
+		r.Title, deadline, r.Tags, r.Priority, recurrence)
+}
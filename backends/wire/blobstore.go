@@ -0,0 +1,24 @@
+package wire
+
+import (
+	"fmt"
+
+	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/blobstore"
+)
+
+func newBlobStore(cfg config.Config) (blobstore.Store, error) {
+	switch cfg.Attachments.Store {
+	case "local", "":
+		return blobstore.NewLocal(cfg.Attachments.Dir), nil
+	case "s3":
+		return blobstore.NewS3(blobstore.S3Config{
+			Endpoint:  cfg.Attachments.S3Endpoint,
+			Region:    cfg.Attachments.S3Region,
+			Bucket:    cfg.Attachments.S3Bucket,
+			AccessKey: cfg.Attachments.S3AccessKey,
+			SecretKey: cfg.Attachments.S3SecretKey,
+		}, cfg.Attachments.S3Timeout), nil
+	}
+	return nil, fmt.Errorf("wire: unknown attachments store %q", cfg.Attachments.Store)
+}
@@ -0,0 +1,21 @@
+package wire
+
+import (
+	"fmt"
+
+	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/pkg/mailer"
+)
+
+// newMailer returns nil if sending emails is turned off
+func newMailer(cfg config.Config) (mailer.Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "none", "":
+		return nil, nil
+	case "smtp":
+		return mailer.NewSMTP(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.User, cfg.Mail.Pass, cfg.Mail.From, cfg.Mail.Timeout), nil
+	case "file":
+		return mailer.NewFile(cfg.Mail.Dir, cfg.Mail.From), nil
+	}
+	return nil, fmt.Errorf("wire: unknown mail driver %q", cfg.Mail.Driver)
+}
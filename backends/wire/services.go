@@ -0,0 +1,50 @@
+package wire
+
+import (
+	"context"
+
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/attachments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/comments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/exports"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/links"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/lists"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/reminders"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/templates"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/webhooks"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+)
+
+// Services are all of our domain services. They are shared
+// between transports and background jobs started in main.
+type Services struct {
+	Users         users.Service
+	Todos         todos.Service
+	Exports       exports.Service
+	Webhooks      webhooks.Service
+	Reminders     reminders.Service
+	Notifications notifications.Service
+	Digests       digests.Service
+	Lists         lists.Service
+	Shares        shares.Service
+	Comments      comments.Service
+	Attachments   attachments.Service
+	Links         links.Service
+	Workspaces    workspaces.Service
+	Templates     templates.Service
+	Limiter       ratelimit.Service
+	Events        *events.Bus
+	Relay         *events.Relay
+
+	// EventsListener receives events from other instances of the api,
+	// it is nil if we deliver events only locally
+	EventsListener interface {
+		Listen(ctx context.Context)
+	}
+}
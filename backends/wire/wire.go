@@ -4,10 +4,27 @@
 package wire
 
 import (
+	"context"
+
 	"github.com/google/wire"
 	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/attachments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/comments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/exports"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/links"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/lists"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/reminders"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/templates"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/webhooks"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/memory"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/postgres"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/transport/resthttp"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
@@ -29,19 +46,158 @@ func InitializeValidator() (*validation.Validator, error) {
 	return &validation.Validator{}, nil
 }
 
-func InitializeRestApi(
+func InitializeServices(
 	config config.Config,
 	logger *logging.Logger,
 	validator *validation.Validator,
 	repository *postgres.Repository,
-) (*resthttp.Server, error) {
-	uS, err := users.NewService(repository.Users(), logger, validator, []byte(config.JWTsecret))
+) (*Services, error) {
+	store := memory.NewStore()
+	var attempts users.AttemptsRepository = store.Attempts()
+	if config.Lockout.Backend == "postgres" {
+		attempts = repository.Attempts()
+	}
+	var buckets ratelimit.Repository = store.Buckets()
+	if config.Limits.Backend == "postgres" {
+		buckets = repository.Buckets()
+	}
+	uS, err := users.NewService(
+		repository.Users(),
+		attempts,
+		logger,
+		validator,
+		[]byte(config.JWTsecret),
+		users.LockoutPolicy{
+			MaxAttempts: config.Lockout.MaxAttempts,
+			BaseDelay:   config.Lockout.BaseDelay,
+			MaxDelay:    config.Lockout.MaxDelay,
+			Window:      config.Lockout.Window,
+		},
+		config.Deletion.GracePeriod,
+	)
+	if err != nil {
+		return nil, err
+	}
+	bus := events.NewBus(config.Events.ReplayBuffer)
+	var publisher todos.Publisher = bus
+	var listener interface{ Listen(ctx context.Context) }
+	if config.Events.Backend == "postgres" {
+		broadcaster := repository.Broadcaster(config.Events.Channel, bus)
+		publisher, listener = broadcaster, broadcaster
+	}
+	nS := notifications.NewService(repository.Notifications(), publisher, logger, validator)
+	if err := todos.RegisterFieldValidation(validator); err != nil {
+		return nil, err
+	}
+	tS := todos.NewService(repository.Todos(), repository.Users(), repository.Shares(), repository.Lists(), nS, publisher, logger, validator)
+	eS := exports.NewService(
+		repository.Exports(),
+		uS,
+		repository.Todos(),
+		logger,
+		[]byte(config.JWTsecret),
+		config.Export.Dir,
+		config.Export.TTL,
+	)
+	wS := webhooks.NewService(
+		repository.Webhooks(),
+		logger,
+		validator,
+		webhooks.NewClient(config.Webhooks.Timeout, config.Webhooks.AllowPrivate),
+		webhooks.RetryPolicy{
+			MaxAttempts:  config.Webhooks.MaxAttempts,
+			BaseDelay:    config.Webhooks.BaseDelay,
+			MaxDelay:     config.Webhooks.MaxDelay,
+			DisableAfter: config.Webhooks.DisableAfter,
+			Retention:    config.Webhooks.Retention,
+		},
+	)
+	relay := events.NewRelay(repository.Outbox(), logger, events.RelayPolicy{
+		BatchSize: config.Outbox.BatchSize,
+		Lease:     config.Outbox.Lease,
+		BaseDelay: config.Outbox.BaseDelay,
+		MaxDelay:  config.Outbox.MaxDelay,
+		Retention: config.Outbox.Retention,
+	})
+	relay.Register("webhooks", wS)
+	rS := reminders.NewService(repository.Reminders(), repository.Todos(), tS, logger, validator, config.Reminders.BatchSize)
+	relay.Register("reminders.inapp", reminders.Handler(reminders.NewInAppNotifier(nS)))
+	m, err := newMailer(config)
 	if err != nil {
 		return nil, err
 	}
-	tS := todos.NewService(repository.Todos(), repository.Users(), logger, validator)
+	if m != nil {
+		relay.Register("reminders.email", reminders.Handler(reminders.NewEmailNotifier(m, repository.Users(), nS)))
+	}
+	dS := digests.NewService(repository.Digests(), m, logger, validator, digests.Policy{
+		AppURL:    config.Digest.AppURL,
+		BatchSize: config.Digest.BatchSize,
+		Lease:     config.Digest.Lease,
+	})
+	lS := lists.NewService(repository.Lists(), repository.Shares(), logger, validator)
+	sS := shares.NewService(repository.Shares(), repository.Users(), nS, logger, validator)
+	liS := links.NewService(repository.Links(), repository.Shares(), tS, logger, validator, []byte(config.JWTsecret))
+	woS := workspaces.NewService(repository.Workspaces(), repository.Users(), nS, m, logger, validator, config.Digest.AppURL)
+	cS := comments.NewService(repository.Comments(), tS, nS, logger, validator)
+	teS := templates.NewService(repository.Templates(), tS, logger, validator)
+	blobs, err := newBlobStore(config)
 	if err != nil {
 		return nil, err
 	}
-	return resthttp.NewServer(config, logger, validator, uS, tS), nil
+	aS := attachments.NewService(repository.Attachments(), tS, blobs, logger, validator, attachments.Policy{
+		MaxSize:        config.Attachments.MaxSize,
+		Quota:          config.Attachments.Quota,
+		ContentTypes:   config.Attachments.AllowedTypes,
+		ThumbnailLease: config.Attachments.ThumbnailLease,
+	})
+	return &Services{
+		Users:         uS,
+		Todos:         tS,
+		Exports:       eS,
+		Webhooks:      wS,
+		Reminders:     rS,
+		Notifications: nS,
+		Digests:       dS,
+		Lists:         lS,
+		Shares:        sS,
+		Comments:      cS,
+		Attachments:   aS,
+		Links:         liS,
+		Workspaces:    woS,
+		Templates:     teS,
+		Limiter:       ratelimit.NewService(buckets, logger),
+		Events:        bus,
+		Relay:         relay,
+
+		EventsListener: listener,
+	}, nil
+}
+
+func InitializeRestApi(
+	config config.Config,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	services *Services,
+) (*resthttp.Server, error) {
+	return resthttp.NewServer(
+		config,
+		logger,
+		validator,
+		services.Limiter,
+		services.Events,
+		services.Users,
+		services.Todos,
+		services.Exports,
+		services.Webhooks,
+		services.Reminders,
+		services.Notifications,
+		services.Digests,
+		services.Lists,
+		services.Shares,
+		services.Comments,
+		services.Attachments,
+		services.Links,
+		services.Workspaces,
+		services.Templates,
+	), nil
 }
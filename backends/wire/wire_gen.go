@@ -7,9 +7,26 @@
 package wire
 
 import (
+	"context"
+
 	"github.com/rasulov-emirlan/todo-app/backends/config"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/attachments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/comments"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/digests"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/events"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/exports"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/links"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/lists"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/notifications"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/ratelimit"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/reminders"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/shares"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/templates"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/todos"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/users"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/webhooks"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/domain/workspaces"
+	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/memory"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/storage/postgres"
 	"github.com/rasulov-emirlan/todo-app/backends/internal/transport/resthttp"
 	"github.com/rasulov-emirlan/todo-app/backends/pkg/logging"
@@ -41,19 +58,158 @@ func InitializeValidator() (*validation.Validator, error) {
 
 // wire.go:
 
-func InitializeRestApi(config2 config.Config,
-
+func InitializeServices(
+	config2 config.Config,
 	logger *logging.Logger,
 	validator *validation.Validator,
 	repository *postgres.Repository,
-) (*resthttp.Server, error) {
-	uS, err := users.NewService(repository.Users(), logger, validator, []byte(config2.JWTsecret))
+) (*Services, error) {
+	store := memory.NewStore()
+	var attempts users.AttemptsRepository = store.Attempts()
+	if config2.Lockout.Backend == "postgres" {
+		attempts = repository.Attempts()
+	}
+	var buckets ratelimit.Repository = store.Buckets()
+	if config2.Limits.Backend == "postgres" {
+		buckets = repository.Buckets()
+	}
+	uS, err := users.NewService(
+		repository.Users(),
+		attempts,
+		logger,
+		validator,
+		[]byte(config2.JWTsecret),
+		users.LockoutPolicy{
+			MaxAttempts: config2.Lockout.MaxAttempts,
+			BaseDelay:   config2.Lockout.BaseDelay,
+			MaxDelay:    config2.Lockout.MaxDelay,
+			Window:      config2.Lockout.Window,
+		},
+		config2.Deletion.GracePeriod,
+	)
+	if err != nil {
+		return nil, err
+	}
+	bus := events.NewBus(config2.Events.ReplayBuffer)
+	var publisher todos.Publisher = bus
+	var listener interface{ Listen(ctx context.Context) }
+	if config2.Events.Backend == "postgres" {
+		broadcaster := repository.Broadcaster(config2.Events.Channel, bus)
+		publisher, listener = broadcaster, broadcaster
+	}
+	nS := notifications.NewService(repository.Notifications(), publisher, logger, validator)
+	if err := todos.RegisterFieldValidation(validator); err != nil {
+		return nil, err
+	}
+	tS := todos.NewService(repository.Todos(), repository.Users(), repository.Shares(), repository.Lists(), nS, publisher, logger, validator)
+	eS := exports.NewService(
+		repository.Exports(),
+		uS,
+		repository.Todos(),
+		logger,
+		[]byte(config2.JWTsecret),
+		config2.Export.Dir,
+		config2.Export.TTL,
+	)
+	wS := webhooks.NewService(
+		repository.Webhooks(),
+		logger,
+		validator,
+		webhooks.NewClient(config2.Webhooks.Timeout, config2.Webhooks.AllowPrivate),
+		webhooks.RetryPolicy{
+			MaxAttempts:  config2.Webhooks.MaxAttempts,
+			BaseDelay:    config2.Webhooks.BaseDelay,
+			MaxDelay:     config2.Webhooks.MaxDelay,
+			DisableAfter: config2.Webhooks.DisableAfter,
+			Retention:    config2.Webhooks.Retention,
+		},
+	)
+	relay := events.NewRelay(repository.Outbox(), logger, events.RelayPolicy{
+		BatchSize: config2.Outbox.BatchSize,
+		Lease:     config2.Outbox.Lease,
+		BaseDelay: config2.Outbox.BaseDelay,
+		MaxDelay:  config2.Outbox.MaxDelay,
+		Retention: config2.Outbox.Retention,
+	})
+	relay.Register("webhooks", wS)
+	rS := reminders.NewService(repository.Reminders(), repository.Todos(), tS, logger, validator, config2.Reminders.BatchSize)
+	relay.Register("reminders.inapp", reminders.Handler(reminders.NewInAppNotifier(nS)))
+	m, err := newMailer(config2)
 	if err != nil {
 		return nil, err
 	}
-	tS := todos.NewService(repository.Todos(), repository.Users(), logger, validator)
+	if m != nil {
+		relay.Register("reminders.email", reminders.Handler(reminders.NewEmailNotifier(m, repository.Users(), nS)))
+	}
+	dS := digests.NewService(repository.Digests(), m, logger, validator, digests.Policy{
+		AppURL:    config2.Digest.AppURL,
+		BatchSize: config2.Digest.BatchSize,
+		Lease:     config2.Digest.Lease,
+	})
+	lS := lists.NewService(repository.Lists(), repository.Shares(), logger, validator)
+	sS := shares.NewService(repository.Shares(), repository.Users(), nS, logger, validator)
+	liS := links.NewService(repository.Links(), repository.Shares(), tS, logger, validator, []byte(config2.JWTsecret))
+	woS := workspaces.NewService(repository.Workspaces(), repository.Users(), nS, m, logger, validator, config2.Digest.AppURL)
+	cS := comments.NewService(repository.Comments(), tS, nS, logger, validator)
+	teS := templates.NewService(repository.Templates(), tS, logger, validator)
+	blobs, err := newBlobStore(config2)
 	if err != nil {
 		return nil, err
 	}
-	return resthttp.NewServer(config2, logger, validator, uS, tS), nil
+	aS := attachments.NewService(repository.Attachments(), tS, blobs, logger, validator, attachments.Policy{
+		MaxSize:        config2.Attachments.MaxSize,
+		Quota:          config2.Attachments.Quota,
+		ContentTypes:   config2.Attachments.AllowedTypes,
+		ThumbnailLease: config2.Attachments.ThumbnailLease,
+	})
+	return &Services{
+		Users:         uS,
+		Todos:         tS,
+		Exports:       eS,
+		Webhooks:      wS,
+		Reminders:     rS,
+		Notifications: nS,
+		Digests:       dS,
+		Lists:         lS,
+		Shares:        sS,
+		Comments:      cS,
+		Attachments:   aS,
+		Links:         liS,
+		Workspaces:    woS,
+		Templates:     teS,
+		Limiter:       ratelimit.NewService(buckets, logger),
+		Events:        bus,
+		Relay:         relay,
+
+		EventsListener: listener,
+	}, nil
+}
+
+func InitializeRestApi(
+	config2 config.Config,
+	logger *logging.Logger,
+	validator *validation.Validator,
+	services *Services,
+) (*resthttp.Server, error) {
+	return resthttp.NewServer(
+		config2,
+		logger,
+		validator,
+		services.Limiter,
+		services.Events,
+		services.Users,
+		services.Todos,
+		services.Exports,
+		services.Webhooks,
+		services.Reminders,
+		services.Notifications,
+		services.Digests,
+		services.Lists,
+		services.Shares,
+		services.Comments,
+		services.Attachments,
+		services.Links,
+		services.Workspaces,
+		services.Templates,
+	), nil
 }